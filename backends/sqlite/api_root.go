@@ -3,6 +3,7 @@ package sqlite
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"strings"
 
 	// import sqlite dependency
@@ -30,7 +31,7 @@ func (s APIRootService) APIRoot(ctx context.Context, path string) (cabby.APIRoot
 func (s APIRootService) apiRoot(path string) (cabby.APIRoot, error) {
 	sql := `select api_root_path, title, description, versions, max_content_length
 				  from api_root
-				  where api_root_path = ?`
+				  where api_root_path = ? and deleted_at is null`
 	args := []interface{}{path}
 
 	a := cabby.APIRoot{}
@@ -65,7 +66,8 @@ func (s APIRootService) APIRoots(ctx context.Context) ([]cabby.APIRoot, error) {
 
 func (s APIRootService) apiRoots() ([]cabby.APIRoot, error) {
 	sql := `select api_root_path, title, description, versions, max_content_length
-				  from api_root`
+				  from api_root
+				  where deleted_at is null`
 	args := []interface{}{}
 
 	as := []cabby.APIRoot{}
@@ -120,24 +122,68 @@ func (s APIRootService) createAPIRoot(a cabby.APIRoot) error {
 	return err
 }
 
-// DeleteAPIRoot creates a user in the data store
-func (s APIRootService) DeleteAPIRoot(ctx context.Context, id string) error {
+// DeleteAPIRoot moves an API root to the trash; its collections and their objects go with it when the delete cascades
+func (s APIRootService) DeleteAPIRoot(ctx context.Context, path string, cascade bool) error {
 	resource, action := "APIRoot", "delete"
 	start := cabby.LogServiceStart(ctx, resource, action)
-	err := s.deleteAPIRoot(id)
+	err := s.deleteAPIRoot(cabby.TakeUser(ctx).Email, path, cascade)
 	cabby.LogServiceEnd(ctx, resource, action, start)
 	return err
 }
 
-func (s APIRootService) deleteAPIRoot(path string) error {
-	sql := `delete from api_root where api_root_path = ?`
+func (s APIRootService) deleteAPIRoot(user, path string, cascade bool) error {
+	d, err := s.apiRootDependents(path)
+	if err != nil {
+		return err
+	}
+	if d.Cascades() && !cascade {
+		return errors.New("API root has collections; cascade the delete to remove them with it")
+	}
+
+	// everything deleted together has the same deleted_at, so it's restored together
+	deletedAt := newDeletedAt()
+
+	return s.DataStore.writeAll(
+		statement{`update objects
+							 set deleted_at = ?, deleted_by = ?
+							 where
+								 deleted_at is null
+								 and collection_id in (select id from collection where api_root_path = ? and deleted_at is null)`,
+			[]interface{}{deletedAt, user, path}},
+		statement{`update collection
+							 set deleted_at = ?, deleted_by = ?
+							 where api_root_path = ? and deleted_at is null`, []interface{}{deletedAt, user, path}},
+		statement{`update api_root
+							 set deleted_at = ?, deleted_by = ?
+							 where api_root_path = ? and deleted_at is null`, []interface{}{deletedAt, user, path}})
+}
+
+// APIRootDependents counts what would be deleted with an API root
+func (s APIRootService) APIRootDependents(ctx context.Context, path string) (cabby.Dependents, error) {
+	resource, action := "APIRootDependents", "read"
+	start := cabby.LogServiceStart(ctx, resource, action)
+	result, err := s.apiRootDependents(path)
+	cabby.LogServiceEnd(ctx, resource, action, start)
+	return result, err
+}
+
+func (s APIRootService) apiRootDependents(path string) (cabby.Dependents, error) {
+	sql := `with collections as (
+						select id from collection where api_root_path = ? and deleted_at is null
+					)
+					select
+						(select count(*) from collections),
+						(select count(*) from objects where collection_id in collections and deleted_at is null),
+						(select count(*) from user_collection where collection_id in collections)`
 	args := []interface{}{path}
 
-	_, err := s.DB.Exec(sql, args...)
+	var d cabby.Dependents
+
+	err := s.DB.QueryRow(sql, args...).Scan(&d.Collections, &d.Objects, &d.UserCollections)
 	if err != nil {
 		logSQLError(sql, args, err)
 	}
-	return err
+	return d, err
 }
 
 // UpdateAPIRoot creates a user in the data store
@@ -2,6 +2,7 @@ package sqlite
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
 	"github.com/pladdy/cabby"
@@ -31,7 +32,7 @@ func TestAPIRootServiceAPIRootQueryErr(t *testing.T) {
 	ds := testDataStore()
 	s := ds.APIRootService()
 
-	_, err := ds.DB.Exec("drop table api_root")
+	err := dropTable(ds, "api_root")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -69,7 +70,7 @@ func TestAPIRootsServiceAPIRootsQueryErr(t *testing.T) {
 	ds := testDataStore()
 	s := ds.APIRootService()
 
-	_, err := ds.DB.Exec("drop table api_root")
+	err := dropTable(ds, "api_root")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -120,7 +121,7 @@ func TestUserServiceCreateAPIRootQueryFail(t *testing.T) {
 	ds := testDataStore()
 	s := ds.APIRootService()
 
-	_, err := ds.DB.Exec("drop table api_root")
+	err := dropTable(ds, "api_root")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -139,7 +140,7 @@ func TestUserServiceDeleteAPIRoot(t *testing.T) {
 	expected := tester.APIRoot
 
 	// delete and verify user is gone
-	err := s.DeleteAPIRoot(context.Background(), expected.Path)
+	err := s.DeleteAPIRoot(context.Background(), expected.Path, true)
 	if err != nil {
 		t.Error("Got:", err)
 	}
@@ -154,17 +155,79 @@ func TestUserServiceDeleteAPIRoot(t *testing.T) {
 	}
 }
 
+func TestAPIRootServiceDeleteAPIRootCascade(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+	s := ds.APIRootService()
+
+	// an api root with collections isn't deleted unless the delete cascades
+	err := s.DeleteAPIRoot(tester.Context, tester.APIRootPath, false)
+	expected := "API root has collections; cascade the delete to remove them with it"
+	if fmt.Sprint(err) != expected {
+		t.Error("Got:", err, "Expected:", expected)
+	}
+
+	a, _ := s.APIRoot(context.Background(), tester.APIRootPath)
+	if a.Path != tester.APIRootPath {
+		t.Error("Got:", a.Path, "Expected:", tester.APIRootPath)
+	}
+
+	err = s.DeleteAPIRoot(tester.Context, tester.APIRootPath, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, _ := ds.CollectionService().Collection(tester.Context, tester.APIRootPath, tester.CollectionID)
+	if !c.ID.IsEmpty() {
+		t.Error("Got:", c.ID, "Expected: the collection deleted with the api root")
+	}
+
+	var deletedAt int
+	err = ds.DB.QueryRow("select count(distinct deleted_at) from objects where collection_id = ?", tester.CollectionID).
+		Scan(&deletedAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deletedAt != 1 {
+		t.Error("Got:", deletedAt, "Expected:", 1)
+	}
+}
+
+func TestAPIRootServiceAPIRootDependents(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+	s := ds.APIRootService()
+
+	tests := []struct {
+		path     string
+		expected cabby.Dependents
+	}{
+		{tester.APIRootPath, cabby.Dependents{Collections: 1, Objects: 1, UserCollections: 1}},
+		{"foo", cabby.Dependents{}},
+	}
+
+	for _, test := range tests {
+		result, err := s.APIRootDependents(context.Background(), test.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result != test.expected {
+			t.Error("Got:", result, "Expected:", test.expected)
+		}
+	}
+}
+
 func TestUserServiceDeleteAPIRootQueryFail(t *testing.T) {
 	setupSQLite()
 	ds := testDataStore()
 	s := ds.APIRootService()
 
-	_, err := ds.DB.Exec("drop table api_root")
+	err := dropTable(ds, "api_root")
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	err = s.DeleteAPIRoot(context.Background(), "foo")
+	err = s.DeleteAPIRoot(context.Background(), "foo", false)
 	if err == nil {
 		t.Error("Got:", err, "Expected an error")
 	}
@@ -213,7 +276,7 @@ func TestUserServiceUpdateAPIRootQueryFail(t *testing.T) {
 	ds := testDataStore()
 	s := ds.APIRootService()
 
-	_, err := ds.DB.Exec("drop table api_root")
+	err := dropTable(ds, "api_root")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -3,6 +3,8 @@ package sqlite
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"strings"
 
 	// import sqlite dependency
@@ -10,6 +12,7 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"github.com/pladdy/cabby"
+	"github.com/pladdy/stones"
 )
 
 // CollectionService implements a SQLite version of the CollectionService interface
@@ -28,12 +31,15 @@ func (s CollectionService) Collection(ctx context.Context, apiRootPath, collecti
 }
 
 func (s CollectionService) collection(user, apiRootPath, collectionID string) (cabby.Collection, error) {
-	sql := `select c.id, c.title, c.description, uc.can_read, uc.can_write, c.media_types
+	sql := `select c.id, c.title, c.description, coalesce(uc.can_read, 0) or c.anonymous_read, coalesce(uc.can_write, 0),
+						c.media_types, c.ingest_media_types, c.ingest_allowed_types, c.ingest_denied_types, c.ingest_duplicates,
+						c.retention_max_age_days, c.retention_max_versions, c.retention_drop_expired
 					from
 						collection c
-						inner join user_collection uc
+						left join user_collection uc
 							on c.id = uc.collection_id
-					where uc.email = ? and c.api_root_path = ? and c.id = ? and uc.can_read = 1`
+							and uc.email = ?
+					where c.api_root_path = ? and c.id = ? and (uc.can_read = 1 or c.anonymous_read = 1) and c.deleted_at is null`
 	args := []interface{}{user, apiRootPath, collectionID}
 
 	c := cabby.Collection{}
@@ -47,12 +53,15 @@ func (s CollectionService) collection(user, apiRootPath, collectionID string) (c
 	defer rows.Close()
 
 	for rows.Next() {
-		var mediaTypes string
+		var mediaTypes, ingestMediaTypes, allowedTypes, deniedTypes, duplicates string
 
-		if err := rows.Scan(&c.ID, &c.Title, &c.Description, &c.CanRead, &c.CanWrite, &mediaTypes); err != nil {
+		if err := rows.Scan(&c.ID, &c.Title, &c.Description, &c.CanRead, &c.CanWrite, &mediaTypes,
+			&ingestMediaTypes, &allowedTypes, &deniedTypes, &duplicates, &c.RetentionPolicy.MaxAgeDays,
+			&c.RetentionPolicy.MaxVersions, &c.RetentionPolicy.DropExpired); err != nil {
 			return c, err
 		}
-		c.MediaTypes = strings.Split(mediaTypes, ",")
+		c.MediaTypes = splitList(mediaTypes)
+		c.IngestPolicy = newIngestPolicy(ingestMediaTypes, allowedTypes, deniedTypes, duplicates)
 	}
 
 	err = rows.Err()
@@ -72,15 +81,22 @@ func (s CollectionService) collections(user, apiRootPath string, p *cabby.Page)
 	sql := `with data as (
 					  select id, title, description, can_read, can_write, media_types, 1 count
 					  from (
-						  select c.id, c.title, c.description, uc.can_read, uc.can_write, c.media_types
+						  select
+							  c.id,
+							  c.title,
+							  c.description,
+							  coalesce(uc.can_read, 0) or c.anonymous_read can_read,
+							  coalesce(uc.can_write, 0) can_write,
+							  c.media_types
 						  from
 							  collection c
-							  inner join user_collection uc
+							  left join user_collection uc
 								  on c.id = uc.collection_id
+								  and uc.email = ?
 						  where
-						 	 uc.email = ?
-							 and c.api_root_path = ?
-					 		 and (uc.can_read = 1 or uc.can_write = 1)
+							 c.api_root_path = ?
+					 		 and (uc.can_read = 1 or uc.can_write = 1 or c.anonymous_read = 1)
+							 and c.deleted_at is null
 					  )
 				  )
 				  select
@@ -108,7 +124,7 @@ func (s CollectionService) collections(user, apiRootPath string, p *cabby.Page)
 		if err := rows.Scan(&c.ID, &c.Title, &c.Description, &c.CanRead, &c.CanWrite, &mediaTypes, &p.Total); err != nil {
 			return cs, err
 		}
-		c.MediaTypes = strings.Split(mediaTypes, ",")
+		c.MediaTypes = splitList(mediaTypes)
 		cs.Collections = append(cs.Collections, c)
 	}
 
@@ -126,7 +142,7 @@ func (s CollectionService) CollectionsInAPIRoot(ctx context.Context, apiRootPath
 }
 
 func (s CollectionService) collectionsInAPIRoot(apiRootPath string) (cabby.CollectionsInAPIRoot, error) {
-	sql := `select c.api_root_path, c.id from collection c where c.api_root_path = ?`
+	sql := `select c.api_root_path, c.id from collection c where c.api_root_path = ? and c.deleted_at is null`
 	args := []interface{}{apiRootPath}
 
 	ac := cabby.CollectionsInAPIRoot{}
@@ -152,6 +168,34 @@ func (s CollectionService) collectionsInAPIRoot(apiRootPath string) (cabby.Colle
 	return ac, err
 }
 
+// CollectionLastModified returns when objects were last added to a collection; the timestamp is zero if the collection
+// has no objects
+func (s CollectionService) CollectionLastModified(ctx context.Context, collectionID string) (stones.Timestamp, error) {
+	resource, action := "CollectionLastModified", "read"
+	start := cabby.LogServiceStart(ctx, resource, action)
+	result, err := s.collectionLastModified(collectionID)
+	cabby.LogServiceEnd(ctx, resource, action, start)
+	return result, err
+}
+
+func (s CollectionService) collectionLastModified(collectionID string) (stones.Timestamp, error) {
+	sql := `select coalesce(max(created_at), '') from objects where collection_id = ? and deleted_at is null`
+	args := []interface{}{collectionID}
+
+	var lastModified string
+
+	err := s.DB.QueryRow(sql, args...).Scan(&lastModified)
+	if err != nil {
+		logSQLError(sql, args, err)
+		return stones.Timestamp{}, err
+	}
+
+	if lastModified == "" {
+		return stones.Timestamp{}, nil
+	}
+	return stones.TimestampFromString(lastModified)
+}
+
 // CreateCollection creates a user in the data store
 func (s CollectionService) CreateCollection(ctx context.Context, c cabby.Collection) error {
 	resource, action := "Collection", "create"
@@ -169,9 +213,14 @@ func (s CollectionService) CreateCollection(ctx context.Context, c cabby.Collect
 }
 
 func (s CollectionService) createCollection(c cabby.Collection) error {
-	sql := `insert into collection (id, api_root_path, title, description, media_types)
-					values (?, ?, ?, ?, ?)`
-	args := []interface{}{c.ID.String(), c.APIRootPath, c.Title, c.Description, strings.Join(c.MediaTypes, ",")}
+	// media types are kept in sync with the objects in the collection
+	sql := `insert into collection (id, api_root_path, title, description, anonymous_read,
+						ingest_media_types, ingest_allowed_types, ingest_denied_types, ingest_duplicates,
+						retention_max_age_days, retention_max_versions, retention_drop_expired)
+					values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	args := append([]interface{}{c.ID.String(), c.APIRootPath, c.Title, c.Description, c.AnonymousRead},
+		ingestPolicyArgs(c.IngestPolicy)...)
+	args = append(args, retentionPolicyArgs(c.RetentionPolicy)...)
 
 	err := s.DataStore.write(sql, args...)
 	if err != nil {
@@ -180,34 +229,69 @@ func (s CollectionService) createCollection(c cabby.Collection) error {
 	return err
 }
 
-// DeleteCollection creates a user in the data store
-func (s CollectionService) DeleteCollection(ctx context.Context, id string) error {
+// DeleteCollection moves a collection to the trash; its objects go with it when the delete cascades
+func (s CollectionService) DeleteCollection(ctx context.Context, id string, cascade bool) error {
 	resource, action := "Collection", "delete"
 	start := cabby.LogServiceStart(ctx, resource, action)
-	err := s.deleteCollection(id)
+	err := s.deleteCollection(cabby.TakeUser(ctx).Email, id, cascade)
 	cabby.LogServiceEnd(ctx, resource, action, start)
 	return err
 }
 
-func (s CollectionService) deleteCollection(id string) error {
-	sql := `delete from collection where id = ?`
-	args := []interface{}{id}
+func (s CollectionService) deleteCollection(user, id string, cascade bool) error {
+	d, err := s.collectionDependents(id)
+	if err != nil {
+		return err
+	}
+	if d.Cascades() && !cascade {
+		return errors.New("Collection has objects; cascade the delete to remove them with it")
+	}
+
+	// everything deleted together has the same deleted_at, so it's restored together
+	deletedAt := newDeletedAt()
 
-	_, err := s.DB.Exec(sql, args...)
+	return s.DataStore.writeAll(
+		statement{`update objects
+							 set deleted_at = ?, deleted_by = ?
+							 where collection_id = ? and deleted_at is null`, []interface{}{deletedAt, user, id}},
+		statement{`update collection
+							 set deleted_at = ?, deleted_by = ?
+							 where id = ? and deleted_at is null`, []interface{}{deletedAt, user, id}})
+}
+
+// CollectionDependents counts what would be deleted with a collection
+func (s CollectionService) CollectionDependents(ctx context.Context, collectionID string) (cabby.Dependents, error) {
+	resource, action := "CollectionDependents", "read"
+	start := cabby.LogServiceStart(ctx, resource, action)
+	result, err := s.collectionDependents(collectionID)
+	cabby.LogServiceEnd(ctx, resource, action, start)
+	return result, err
+}
+
+func (s CollectionService) collectionDependents(collectionID string) (cabby.Dependents, error) {
+	sql := `select
+						(select count(*) from objects where collection_id = ? and deleted_at is null),
+						(select count(*) from user_collection where collection_id = ?)`
+	args := []interface{}{collectionID, collectionID}
+
+	var d cabby.Dependents
+
+	err := s.DB.QueryRow(sql, args...).Scan(&d.Objects, &d.UserCollections)
 	if err != nil {
 		logSQLError(sql, args, err)
 	}
-	return err
+	return d, err
 }
 
-// UpdateCollection creates a user in the data store
-func (s CollectionService) UpdateCollection(ctx context.Context, c cabby.Collection) error {
+// UpdateCollection updates a collection in the data store; only the named fields are changed, all of them if none are
+// named
+func (s CollectionService) UpdateCollection(ctx context.Context, c cabby.Collection, fields ...string) error {
 	resource, action := "Collection", "update"
 	start := cabby.LogServiceStart(ctx, resource, action)
 
 	err := c.Validate()
 	if err == nil {
-		err = s.updateCollection(c)
+		err = s.updateCollection(c, fields)
 	} else {
 		log.WithFields(log.Fields{"collection": c, "error": err}).Error("Invalid Collection")
 	}
@@ -216,9 +300,25 @@ func (s CollectionService) UpdateCollection(ctx context.Context, c cabby.Collect
 	return err
 }
 
-func (s CollectionService) updateCollection(c cabby.Collection) error {
-	sql := `update collection set api_root_path = ?, title = ?, description = ? where id = ?`
-	args := []interface{}{c.APIRootPath, c.Title, c.Description, c.ID.String()}
+func (s CollectionService) updateCollection(c cabby.Collection, fields []string) error {
+	columns := collectionColumns(c)
+	if len(fields) == 0 {
+		fields = collectionFields
+	}
+
+	var sets []string
+	var args []interface{}
+	for _, field := range fields {
+		column, ok := columns[field]
+		if !ok {
+			return fmt.Errorf("Invalid collection field: %v", field)
+		}
+		sets = append(sets, column.name+" = ?")
+		args = append(args, column.value)
+	}
+
+	sql := `update collection set ` + strings.Join(sets, ", ") + ` where id = ?`
+	args = append(args, c.ID.String())
 
 	err := s.DataStore.write(sql, args...)
 	if err != nil {
@@ -226,3 +326,60 @@ func (s CollectionService) updateCollection(c cabby.Collection) error {
 	}
 	return err
 }
+
+/* helpers */
+
+// collectionFields are the fields of a collection that can be updated, named by their cabby-cli flags
+var collectionFields = []string{
+	"api_root_path", "title", "description", "anonymous_read", "media_types", "allowed_types", "denied_types",
+	"duplicates", "max_age_days", "max_versions", "drop_expired"}
+
+type collectionColumn struct {
+	name  string
+	value interface{}
+}
+
+// collectionColumns maps the fields of a collection to the columns they're stored in
+func collectionColumns(c cabby.Collection) map[string]collectionColumn {
+	ingest := ingestPolicyArgs(c.IngestPolicy)
+	retention := retentionPolicyArgs(c.RetentionPolicy)
+
+	return map[string]collectionColumn{
+		"api_root_path":  {"api_root_path", c.APIRootPath},
+		"title":          {"title", c.Title},
+		"description":    {"description", c.Description},
+		"anonymous_read": {"anonymous_read", c.AnonymousRead},
+		"media_types":    {"ingest_media_types", ingest[0]},
+		"allowed_types":  {"ingest_allowed_types", ingest[1]},
+		"denied_types":   {"ingest_denied_types", ingest[2]},
+		"duplicates":     {"ingest_duplicates", ingest[3]},
+		"max_age_days":   {"retention_max_age_days", retention[0]},
+		"max_versions":   {"retention_max_versions", retention[1]},
+		"drop_expired":   {"retention_drop_expired", retention[2]}}
+}
+
+func ingestPolicyArgs(p cabby.IngestPolicy) []interface{} {
+	return []interface{}{
+		strings.Join(p.MediaTypes, ","), strings.Join(p.AllowedTypes, ","), strings.Join(p.DeniedTypes, ","),
+		p.DuplicatePolicy()}
+}
+
+func newIngestPolicy(mediaTypes, allowedTypes, deniedTypes, duplicates string) cabby.IngestPolicy {
+	return cabby.IngestPolicy{
+		MediaTypes:   splitList(mediaTypes),
+		AllowedTypes: splitList(allowedTypes),
+		DeniedTypes:  splitList(deniedTypes),
+		Duplicates:   duplicates}
+}
+
+func retentionPolicyArgs(p cabby.RetentionPolicy) []interface{} {
+	return []interface{}{p.MaxAgeDays, p.MaxVersions, p.DropExpired}
+}
+
+// splitList returns the values of a comma separated list; an empty list has none
+func splitList(list string) []string {
+	if list == "" {
+		return nil
+	}
+	return strings.Split(list, ",")
+}
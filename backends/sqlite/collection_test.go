@@ -2,7 +2,11 @@ package sqlite
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/pladdy/cabby"
 	"github.com/pladdy/cabby/tester"
@@ -26,12 +30,161 @@ func TestCollectionServiceCollection(t *testing.T) {
 	}
 }
 
+func TestCollectionServiceCollectionAnonymousRead(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+	s := ds.CollectionService()
+
+	anonymous := cabby.WithUser(context.Background(), cabby.NewAnonymousUser(cabby.UserCollectionList{}))
+	c := tester.Collection
+
+	result, err := s.Collection(anonymous, c.APIRootPath, c.ID.String())
+	if err != nil {
+		t.Error("Got:", err, "Expected no error")
+	}
+	if !result.ID.IsEmpty() {
+		t.Error("Got:", result.ID, "Expected no collection")
+	}
+
+	c.AnonymousRead = true
+	err = s.UpdateCollection(tester.Context, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err = s.Collection(anonymous, c.APIRootPath, c.ID.String())
+	if err != nil {
+		t.Error("Got:", err, "Expected no error")
+	}
+	if result.ID.String() != c.ID.String() || !result.CanRead || result.CanWrite {
+		t.Error("Got:", result, "Expected a read only collection")
+	}
+
+	cs, err := s.Collections(anonymous, c.APIRootPath, &cabby.Page{})
+	if err != nil {
+		t.Error("Got:", err, "Expected no error")
+	}
+	if len(cs.Collections) != 1 {
+		t.Error("Got:", len(cs.Collections), "Expected:", 1)
+	}
+
+	// users with access keep it
+	result, err = s.Collection(tester.Context, c.APIRootPath, c.ID.String())
+	if err != nil {
+		t.Error("Got:", err, "Expected no error")
+	}
+	if !result.CanRead || !result.CanWrite {
+		t.Error("Got:", result, "Expected a read write collection")
+	}
+}
+
+func TestCollectionServiceCollectionMediaTypes(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+	s := ds.CollectionService()
+	osv := ds.ObjectService()
+
+	o, _, err := cabby.ObjectFromBytes([]byte(`{"type": "ipv4-addr", "spec_version": "2.1",
+		"id": "ipv4-addr--ff26c055-6336-5bc5-b98d-13d6226742dd", "value": "198.51.100.3"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		change   func() error
+		expected []string
+	}{
+		{func() error { return nil }, []string{cabby.StixContentType20}},
+		{func() error { return osv.CreateObject(tester.Context, tester.CollectionID, o) },
+			[]string{cabby.StixContentType20, "application/stix+json;version=2.1"}},
+		{func() error {
+			return osv.DeleteObject(tester.Context, tester.CollectionID, tester.ObjectID, cabby.Filter{})
+		},
+			[]string{"application/stix+json;version=2.1"}},
+		{func() error {
+			return osv.DeleteObject(tester.Context, tester.CollectionID, o.ID.String(), cabby.Filter{})
+		},
+			[]string{}},
+	}
+
+	for _, test := range tests {
+		err := test.change()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		result, err := s.Collection(tester.Context, tester.APIRootPath, tester.CollectionID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if strings.Join(result.MediaTypes, ",") != strings.Join(test.expected, ",") {
+			t.Error("Got:", result.MediaTypes, "Expected:", test.expected)
+		}
+	}
+}
+
+func TestCollectionServiceCollectionIngestPolicy(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+	s := ds.CollectionService()
+
+	expected := cabby.IngestPolicy{
+		MediaTypes: []string{cabby.StixContentType20, cabby.StixMediaType}, DeniedTypes: []string{"malware", "tool"}}
+
+	c := tester.Collection
+	c.IngestPolicy = expected
+	err := s.UpdateCollection(context.Background(), c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := s.Collection(tester.Context, c.APIRootPath, c.ID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Join(result.IngestPolicy.MediaTypes, ",") != strings.Join(expected.MediaTypes, ",") {
+		t.Error("Got:", result.IngestPolicy.MediaTypes, "Expected:", expected.MediaTypes)
+	}
+	if result.IngestPolicy.AllowedTypes != nil {
+		t.Error("Got:", result.IngestPolicy.AllowedTypes, "Expected: no allowed types")
+	}
+	if strings.Join(result.IngestPolicy.DeniedTypes, ",") != strings.Join(expected.DeniedTypes, ",") {
+		t.Error("Got:", result.IngestPolicy.DeniedTypes, "Expected:", expected.DeniedTypes)
+	}
+}
+
+func TestCollectionServiceCollectionRetentionPolicy(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+	s := ds.CollectionService()
+
+	expected := cabby.RetentionPolicy{MaxAgeDays: 90, MaxVersions: 3, DropExpired: true}
+
+	c := tester.Collection
+	c.RetentionPolicy = expected
+	err := s.UpdateCollection(context.Background(), c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := s.Collection(tester.Context, c.APIRootPath, c.ID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.RetentionPolicy != expected {
+		t.Error("Got:", result.RetentionPolicy, "Expected:", expected)
+	}
+}
+
 func TestCollectionServiceCollectionQueryErr(t *testing.T) {
 	setupSQLite()
 	ds := testDataStore()
 	s := ds.CollectionService()
 
-	_, err := ds.DB.Exec("drop table collection")
+	err := dropTable(ds, "collection")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -85,7 +238,7 @@ func TestCollectionsServiceCollectionsQueryErr(t *testing.T) {
 	ds := testDataStore()
 	s := ds.CollectionService()
 
-	_, err := ds.DB.Exec("drop table collection")
+	err := dropTable(ds, "collection")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -128,7 +281,7 @@ func TestCollectionsServiceCollectionsInAPIRootQueryErr(t *testing.T) {
 	ds := testDataStore()
 	s := ds.CollectionService()
 
-	_, err := ds.DB.Exec("drop table collection")
+	err := dropTable(ds, "collection")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -139,6 +292,47 @@ func TestCollectionsServiceCollectionsInAPIRootQueryErr(t *testing.T) {
 	}
 }
 
+func TestCollectionServiceCollectionLastModified(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+	s := ds.CollectionService()
+
+	result, err := s.CollectionLastModified(tester.Context, tester.CollectionID)
+	if err != nil {
+		t.Error("Got:", err, "Expected no error")
+	}
+	if time.Since(result.Time) > time.Minute {
+		t.Error("Got:", result, "Expected when the setup object was created")
+	}
+
+	id, _ := cabby.NewID()
+	createCollection(ds, id.String())
+
+	result, err = s.CollectionLastModified(tester.Context, id.String())
+	if err != nil {
+		t.Error("Got:", err, "Expected no error")
+	}
+	if !result.IsZero() {
+		t.Error("Got:", result, "Expected a zero timestamp for an empty collection")
+	}
+}
+
+func TestCollectionServiceCollectionLastModifiedQueryErr(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+	s := ds.CollectionService()
+
+	_, err := ds.DB.Exec("drop table objects")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = s.CollectionLastModified(tester.Context, tester.CollectionID)
+	if err == nil {
+		t.Error("Got:", err, "Expected an error")
+	}
+}
+
 func TestCollectionServiceCreateCollection(t *testing.T) {
 	setupSQLite()
 	ds := testDataStore()
@@ -182,7 +376,7 @@ func TestCollectionServiceCreateCollectionQueryFail(t *testing.T) {
 	ds := testDataStore()
 	s := ds.CollectionService()
 
-	_, err := ds.DB.Exec("drop table collection")
+	err := dropTable(ds, "collection")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -200,7 +394,7 @@ func TestCollectionServiceDeleteCollection(t *testing.T) {
 
 	// create and verify a user
 	collectionID, _ := cabby.NewID()
-	expected := cabby.Collection{ID: collectionID, Title: "a title"}
+	expected := cabby.Collection{ID: collectionID, APIRootPath: tester.APIRootPath, Title: "a title"}
 
 	err := s.CreateCollection(context.Background(), expected)
 	if err != nil {
@@ -219,13 +413,13 @@ func TestCollectionServiceDeleteCollection(t *testing.T) {
 		t.Error("collection not created")
 	}
 
-	// delete and verify collection is gone
-	err = s.DeleteCollection(context.Background(), expected.ID.String())
+	// delete and verify collection is in the trash
+	err = s.DeleteCollection(context.Background(), expected.ID.String(), false)
 	if err != nil {
 		t.Error("Got:", err)
 	}
 
-	rows, _ = ds.DB.Query("select id from collection where id = ?", expected.ID.String())
+	rows, _ = ds.DB.Query("select id from collection where id = ? and deleted_at is null", expected.ID.String())
 	defer rows.Close()
 
 	result = ""
@@ -238,17 +432,73 @@ func TestCollectionServiceDeleteCollection(t *testing.T) {
 	}
 }
 
+func TestCollectionServiceDeleteCollectionCascade(t *testing.T) {
+	tests := []struct {
+		cascade  bool
+		deleted  bool
+		expected error
+	}{
+		{false, false, errors.New("Collection has objects; cascade the delete to remove them with it")},
+		{true, true, nil},
+	}
+
+	for _, test := range tests {
+		setupSQLite()
+		ds := testDataStore()
+		s := ds.CollectionService()
+
+		err := s.DeleteCollection(tester.Context, tester.CollectionID, test.cascade)
+		if fmt.Sprint(err) != fmt.Sprint(test.expected) {
+			t.Error("Got:", err, "Expected:", test.expected)
+		}
+
+		c, _ := s.Collection(tester.Context, tester.APIRootPath, tester.CollectionID)
+		if c.ID.IsEmpty() != test.deleted {
+			t.Error("Got:", c.ID, "Expected deleted:", test.deleted, "Cascade:", test.cascade)
+		}
+
+		objects, _ := ds.ObjectService().Object(context.Background(), tester.CollectionID, tester.ObjectID, cabby.Filter{})
+		if (len(objects) == 0) != test.deleted {
+			t.Error("Got:", len(objects), "Expected deleted:", test.deleted, "Cascade:", test.cascade)
+		}
+	}
+}
+
+func TestCollectionServiceCollectionDependents(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+	s := ds.CollectionService()
+
+	tests := []struct {
+		collectionID string
+		expected     cabby.Dependents
+	}{
+		{tester.CollectionID, cabby.Dependents{Objects: 1, UserCollections: 1}},
+		{"foo", cabby.Dependents{}},
+	}
+
+	for _, test := range tests {
+		result, err := s.CollectionDependents(context.Background(), test.collectionID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result != test.expected {
+			t.Error("Got:", result, "Expected:", test.expected)
+		}
+	}
+}
+
 func TestCollectionServiceDeleteCollectionQueryFail(t *testing.T) {
 	setupSQLite()
 	ds := testDataStore()
 	s := ds.CollectionService()
 
-	_, err := ds.DB.Exec("drop table collection")
+	err := dropTable(ds, "collection")
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	err = s.DeleteCollection(context.Background(), "foo")
+	err = s.DeleteCollection(context.Background(), "foo", false)
 	if err == nil {
 		t.Error("Got:", err, "Expected an error")
 	}
@@ -293,6 +543,61 @@ func TestCollectionServiceUpdateCollection(t *testing.T) {
 	}
 }
 
+func TestCollectionServiceUpdateCollectionFields(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+	s := ds.CollectionService()
+
+	c := tester.Collection
+	c.AnonymousRead = true
+	c.IngestPolicy = cabby.IngestPolicy{AllowedTypes: []string{"indicator"}, Duplicates: cabby.DuplicateOverwrite}
+	c.RetentionPolicy = cabby.RetentionPolicy{MaxVersions: 2}
+
+	err := s.UpdateCollection(context.Background(), c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// only the title is changed, the other settings keep their values
+	update := tester.Collection
+	update.Title = "an updated title"
+
+	err = s.UpdateCollection(context.Background(), update, "title")
+	if err != nil {
+		t.Error("Got:", err)
+	}
+
+	var title, allowedTypes, duplicates string
+	var anonymousRead bool
+	var maxVersions int
+	err = ds.DB.QueryRow(`select title, anonymous_read, ingest_allowed_types, ingest_duplicates, retention_max_versions
+		from collection where id = ?`, c.ID.String()).Scan(&title, &anonymousRead, &allowedTypes, &duplicates, &maxVersions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if title != update.Title {
+		t.Error("Got:", title, "Expected:", update.Title)
+	}
+	if !anonymousRead {
+		t.Error("Got:", anonymousRead, "Expected:", true)
+	}
+	if allowedTypes != "indicator" {
+		t.Error("Got:", allowedTypes, "Expected:", "indicator")
+	}
+	if duplicates != cabby.DuplicateOverwrite {
+		t.Error("Got:", duplicates, "Expected:", cabby.DuplicateOverwrite)
+	}
+	if maxVersions != 2 {
+		t.Error("Got:", maxVersions, "Expected:", 2)
+	}
+
+	err = s.UpdateCollection(context.Background(), update, "no_such_field")
+	if err == nil {
+		t.Error("Got:", err, "Expected an error")
+	}
+}
+
 func TestCollectionServiceUpdateCollectionInvalid(t *testing.T) {
 	setupSQLite()
 	ds := testDataStore()
@@ -309,7 +614,7 @@ func TestCollectionServiceUpdateCollectionQueryFail(t *testing.T) {
 	ds := testDataStore()
 	s := ds.CollectionService()
 
-	_, err := ds.DB.Exec("drop table collection")
+	err := dropTable(ds, "collection")
 	if err != nil {
 		t.Fatal(err)
 	}
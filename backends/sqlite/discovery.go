@@ -3,6 +3,7 @@ package sqlite
 import (
 	"context"
 	"database/sql"
+	"strings"
 
 	// import sqlite dependency
 	_ "github.com/mattn/go-sqlite3"
@@ -64,28 +65,53 @@ func (s DiscoveryService) deleteDiscovery() error {
 	return err
 }
 
-// Discovery will read from the data store and return the resource
-func (s DiscoveryService) Discovery(ctx context.Context) (cabby.Discovery, error) {
+// Discovery will read from the data store and return the resource; only API roots the user can read a collection in,
+// or that are among the API root paths of the user, are included unless the user is an admin
+func (s DiscoveryService) Discovery(ctx context.Context, u cabby.User) (cabby.Discovery, error) {
 	resource, action := "Discovery", "read"
 	start := cabby.LogServiceStart(ctx, resource, action)
-	result, err := s.discovery()
+	result, err := s.discovery(u)
 	cabby.LogServiceEnd(ctx, resource, action, start)
 	return result, err
 }
 
-func (s DiscoveryService) discovery() (cabby.Discovery, error) {
-	sql := `select td.title, td.description, td.contact, td.default_url,
-						 case
-							 when tar.api_root_path is null then 'No API Roots defined' else tar.api_root_path
-						 end discovery_path
+func (s DiscoveryService) discovery(u cabby.User) (cabby.Discovery, error) {
+	sql := `select td.title, td.description, td.contact, td.default_url, coalesce(tar.api_root_path, '')
 					 from
 						 discovery td
 						 left join api_root tar
-							 on td.id = tar.discovery_id`
-	args := []interface{}{}
+							 on td.id = tar.discovery_id
+							 and tar.deleted_at is null
+							 and (
+								 ? = 1
+								 or exists (
+									 select 1
+									 from
+										 collection c
+										 inner join user_collection uc
+											 on c.id = uc.collection_id
+									 where
+										 c.api_root_path = tar.api_root_path
+										 and c.deleted_at is null
+										 and uc.email = ?
+										 and uc.can_read = 1
+								 )
+								 or exists (
+									 select 1
+									 from collection c
+									 where c.api_root_path = tar.api_root_path and c.anonymous_read = 1 and c.deleted_at is null
+								 )
+								 or trim(tar.api_root_path, '/') in ($apiRootPaths)
+							 )`
+	sql = strings.Replace(sql, "$apiRootPaths", placeholders(len(u.APIRootPaths)), 1)
+
+	args := []interface{}{u.CanAdmin, u.Email}
+	for _, path := range u.APIRootPaths {
+		args = append(args, strings.Trim(path, "/"))
+	}
 
 	d := cabby.Discovery{}
-	var apiRoots []string
+	apiRoots := []string{}
 
 	rows, err := s.DB.Query(sql, args...)
 	if err != nil {
@@ -99,7 +125,8 @@ func (s DiscoveryService) discovery() (cabby.Discovery, error) {
 		if err := rows.Scan(&d.Title, &d.Description, &d.Contact, &d.Default, &apiRoot); err != nil {
 			return d, err
 		}
-		if apiRoot != "No API Roots defined" {
+		// the discovery is returned without api roots if the user can't see any
+		if apiRoot != "" {
 			apiRoots = append(apiRoots, apiRoot)
 		}
 	}
@@ -22,7 +22,7 @@ func TestUserServiceCreateDiscovery(t *testing.T) {
 		t.Error("Got:", err)
 	}
 
-	result, err := s.Discovery(context.Background())
+	result, err := s.Discovery(context.Background(), tester.User)
 	if err != nil {
 		t.Error("Got:", err)
 	}
@@ -68,7 +68,7 @@ func TestUserServiceDeleteDiscovery(t *testing.T) {
 	// create and verify a user
 	expected := tester.Discovery
 
-	result, err := s.Discovery(context.Background())
+	result, err := s.Discovery(context.Background(), tester.User)
 	if err != nil {
 		t.Error("Got:", err)
 	}
@@ -84,7 +84,7 @@ func TestUserServiceDeleteDiscovery(t *testing.T) {
 		t.Error("Got:", err)
 	}
 
-	result, err = s.Discovery(context.Background())
+	result, err = s.Discovery(context.Background(), tester.User)
 	if err != nil {
 		t.Error("Got:", err)
 	}
@@ -117,7 +117,7 @@ func TestDiscoveryServiceDiscovery(t *testing.T) {
 
 	expected := tester.DiscoveryDataStore
 
-	result, err := s.Discovery(context.Background())
+	result, err := s.Discovery(context.Background(), tester.User)
 	if err != nil {
 		t.Error("Got:", err, "Expected no error")
 	}
@@ -128,6 +128,114 @@ func TestDiscoveryServiceDiscovery(t *testing.T) {
 	}
 }
 
+func TestDiscoveryServiceDiscoveryUserAPIRoots(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+	s := ds.DiscoveryService()
+
+	// an api root without readable collections
+	hidden := tester.APIRoot
+	hidden.Path = "hidden_root"
+	err := ds.APIRootService().CreateAPIRoot(context.Background(), hidden)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a reader can only write to a collection in the hidden api root
+	reader := "reader@cabby.com"
+	id, _ := cabby.NewID()
+	c := tester.Collection
+	c.ID, c.APIRootPath = id, hidden.Path
+	err = ds.CollectionService().CreateCollection(context.Background(), c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ds.UserService().CreateUser(context.Background(), cabby.User{Email: reader}, tester.UserPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ca := cabby.CollectionAccess{ID: id, CanRead: false, CanWrite: true}
+	err = ds.UserService().CreateUserCollection(context.Background(), reader, ca)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		user     cabby.User
+		expected []string
+	}{
+		{cabby.User{Email: "admin@cabby.com", CanAdmin: true}, []string{tester.APIRootPath, hidden.Path}},
+		{cabby.User{Email: tester.UserEmail}, []string{tester.APIRootPath}},
+		{cabby.User{Email: reader}, []string{}},
+		{cabby.User{}, []string{}},
+	}
+
+	for _, test := range tests {
+		result, err := s.Discovery(context.Background(), test.user)
+		if err != nil {
+			t.Error("Got:", err, "Expected no error")
+		}
+
+		if result.Title != tester.Discovery.Title {
+			t.Error("Got:", result.Title, "Expected:", tester.Discovery.Title)
+		}
+		if result.APIRoots == nil || len(result.APIRoots) != len(test.expected) {
+			t.Error("Got:", result.APIRoots, "Expected:", test.expected, "User:", test.user.Email)
+			continue
+		}
+		for i := range test.expected {
+			if result.APIRoots[i] != test.expected[i] {
+				t.Error("Got:", result.APIRoots[i], "Expected:", test.expected[i])
+			}
+		}
+	}
+}
+
+func TestDiscoveryServiceDiscoveryAnonymousAPIRoots(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+	s := ds.DiscoveryService()
+
+	anonymous := cabby.NewAnonymousUser(cabby.UserCollectionList{})
+
+	result, err := s.Discovery(context.Background(), anonymous)
+	if err != nil {
+		t.Error("Got:", err, "Expected no error")
+	}
+	if len(result.APIRoots) != 0 {
+		t.Error("Got:", result.APIRoots, "Expected no api roots")
+	}
+
+	// api roots configured to be anonymous are listed without an anonymous collection
+	configured := anonymous
+	configured.APIRootPaths = []string{"/" + tester.APIRootPath + "/"}
+
+	result, err = s.Discovery(context.Background(), configured)
+	if err != nil {
+		t.Error("Got:", err, "Expected no error")
+	}
+	if len(result.APIRoots) != 1 || result.APIRoots[0] != tester.APIRootPath {
+		t.Error("Got:", result.APIRoots, "Expected:", []string{tester.APIRootPath})
+	}
+
+	c := tester.Collection
+	c.AnonymousRead = true
+	err = ds.CollectionService().UpdateCollection(context.Background(), c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err = s.Discovery(context.Background(), anonymous)
+	if err != nil {
+		t.Error("Got:", err, "Expected no error")
+	}
+	if len(result.APIRoots) != 1 || result.APIRoots[0] != tester.APIRootPath {
+		t.Error("Got:", result.APIRoots, "Expected:", []string{tester.APIRootPath})
+	}
+}
+
 func TestDiscoveryServiceDiscoveryQueryErr(t *testing.T) {
 	setupSQLite()
 	ds := testDataStore()
@@ -138,7 +246,7 @@ func TestDiscoveryServiceDiscoveryQueryErr(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	_, err = s.Discovery(context.Background())
+	_, err = s.Discovery(context.Background(), tester.User)
 	if err == nil {
 		t.Error("Got:", err, "Expected an error")
 	}
@@ -149,7 +257,7 @@ func TestDiscoveryServiceDiscoveryNoDiscovery(t *testing.T) {
 	ds := testDataStore()
 	s := DiscoveryService{DB: ds.DB}
 
-	_, err := s.Discovery(context.Background())
+	_, err := s.Discovery(context.Background(), tester.User)
 	if err != nil {
 		t.Error("Got:", err, "Expected no error")
 	}
@@ -169,7 +277,7 @@ func TestUserServiceUpdateDiscovery(t *testing.T) {
 	}
 
 	// check it
-	result, err := s.Discovery(context.Background())
+	result, err := s.Discovery(context.Background(), tester.User)
 	if err != nil {
 		t.Error("Got:", err)
 	}
@@ -3,6 +3,7 @@ package sqlite
 import (
 	"context"
 	"os"
+	"testing"
 
 	"github.com/pladdy/cabby"
 	"github.com/pladdy/cabby/tester"
@@ -53,6 +54,25 @@ func createDiscovery(ds *DataStore) {
 	}
 }
 
+func createRawObject(t *testing.T, ds *DataStore, collectionID, raw string) {
+	o, _, err := cabby.ObjectFromBytes([]byte(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ds.ObjectService().CreateObject(context.Background(), collectionID, o)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// createLabeledVersion adds a version of the test object with labels and without a creator
+func createLabeledVersion(t *testing.T, ds *DataStore) {
+	createRawObject(t, ds, tester.CollectionID, `{"type": "malware", "id": "`+tester.ObjectID+`",
+		"created": "2016-04-06T20:07:09.000Z", "modified": "2017-04-06T20:07:09.000Z", "name": "Poison Ivy",
+		"labels": ["remote-access-trojan", "backdoor"]}`)
+}
+
 func createObject(ds *DataStore, id string) {
 	o := tester.Object
 	sid, _ := stones.IdentifierFromString(id)
@@ -83,6 +103,41 @@ func createObjectVersion(ds *DataStore, id, version string) {
 	}
 }
 
+// createOverlappingVersions gives the test object versions that were added out of order; the 2017 version is added
+// after the 2018 version, and the 2018 version is deleted after that
+func createOverlappingVersions(t *testing.T, ds *DataStore) {
+	createObjectVersion(ds, tester.ObjectID, "2018-01-01T00:00:00.000Z")
+	createObjectVersion(ds, tester.ObjectID, "2017-01-01T00:00:00.000Z")
+
+	added := []struct {
+		version string
+		addedAt string
+	}{
+		{"2016-04-06T20:07:09Z", "2019-01-01T00:00:00.000Z"},
+		{"2018-01-01T00:00:00Z", "2019-02-01T00:00:00.000Z"},
+		{"2017-01-01T00:00:00Z", "2019-03-01T00:00:00.000Z"},
+	}
+
+	for _, a := range added {
+		_, err := ds.DB.Exec("update objects set created_at = ? where id = ? and modified = ?", a.addedAt, tester.ObjectID,
+			a.version)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err := ds.DB.Exec("update objects set deleted_at = '2019-04-01T00:00:00.000Z' where id = ? and modified = ?",
+		tester.ObjectID, "2018-01-01T00:00:00Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readerContext has the test user without admin rights, so it only reads the collections it's given access to
+func readerContext() context.Context {
+	return cabby.WithUser(context.Background(), cabby.User{Email: tester.UserEmail})
+}
+
 func createUser(ds *DataStore) {
 	err := ds.UserService().CreateUser(context.Background(), tester.User, tester.UserPassword)
 	if err != nil {
@@ -90,6 +145,48 @@ func createUser(ds *DataStore) {
 	}
 }
 
+// postEnvelope creates the objects in an envelope and returns the status of the request
+// dropTable drops a table without the deletes of its foreign keys; deletes cascading to objects write to the full-text
+// index, which can't be written while a table is dropped
+func dropTable(ds *DataStore, table string) error {
+	ctx := context.Background()
+
+	conn, err := ds.DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.ExecContext(ctx, "pragma foreign_keys = off")
+	if err != nil {
+		return err
+	}
+	/* #nosec G104 */
+	defer conn.ExecContext(ctx, "pragma foreign_keys = on")
+
+	_, err = conn.ExecContext(ctx, "drop table "+table)
+	return err
+}
+
+func postEnvelope(ds *DataStore, e cabby.Envelope) cabby.Status {
+	st, err := cabby.NewStatus(len(e.Objects))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = ds.StatusService().CreateStatus(context.Background(), st)
+	if err != nil {
+		log.Fatal(err)
+	}
+	ds.ObjectService().CreateEnvelope(context.Background(), e, tester.CollectionID, st, ds.StatusService())
+
+	result, err := ds.StatusService().Status(context.Background(), tester.APIRootPath, st.ID.String())
+	if err != nil {
+		log.Fatal(err)
+	}
+	return result
+}
+
 func setupSQLite() {
 	tearDownSQLite()
 	log.Debug("Setting up test sqlite db:", testDBPath)
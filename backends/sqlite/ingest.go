@@ -0,0 +1,223 @@
+package sqlite
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/pladdy/cabby"
+	"github.com/pladdy/stones"
+)
+
+const overwriteObjectSQL = `insert into objects (id, type, created, modified, object, collection_id, spec_version, media_type,
+                                                 observables)
+                            values (?, ?, ?, ?, ?, ?, ?, ?, ?)
+                            on conflict (id, modified) do update
+                              set type = excluded.type,
+                                  created = excluded.created,
+                                  object = excluded.object,
+                                  spec_version = excluded.spec_version,
+                                  media_type = excluded.media_type,
+                                  observables = excluded.observables
+                              where collection_id = excluded.collection_id`
+
+// ingestCheck reads objects added to a collection and checks them against the collection's ingest policy and the
+// object versions already stored
+type ingestCheck struct {
+	ObjectService
+	collectionID string
+	policy       cabby.IngestPolicy
+	policyErr    error
+}
+
+// ingestResult is an object that passed an ingest check; duplicates aren't always written and message says what
+// happened to one
+type ingestResult struct {
+	object      stones.Object
+	specVersion string
+	write       bool
+	message     string
+	err         error
+}
+
+// storedVersion is the collection and content of a stored object version and whether it's in the trash
+type storedVersion struct {
+	collectionID string
+	object       []byte
+	deleted      bool
+}
+
+func (s ObjectService) newIngestCheck(collectionID string) *ingestCheck {
+	ic := ingestCheck{ObjectService: s, collectionID: collectionID}
+	ic.policy, ic.policyErr = s.ingestPolicy(collectionID)
+	return &ic
+}
+
+// checkAll checks objects as they're received and calls result with the result of each.  Objects are checked in
+// batches so the versions already stored are read once a batch; a version repeated in a batch fails, a version
+// repeated across batches is left to the unique constraint of the objects table.
+func (ic *ingestCheck) checkAll(objects <-chan json.RawMessage, result func(ingestResult)) {
+	batch := []json.RawMessage{}
+
+	for raw := range objects {
+		batch = append(batch, raw)
+		if len(batch) < batchBufferSize {
+			continue
+		}
+
+		for _, r := range ic.check(batch) {
+			result(r)
+		}
+		batch = []json.RawMessage{}
+	}
+
+	for _, r := range ic.check(batch) {
+		result(r)
+	}
+}
+
+// check returns the result of each object in a batch
+func (ic *ingestCheck) check(batch []json.RawMessage) []ingestResult {
+	results := make([]ingestResult, len(batch))
+	valid := []stones.Object{}
+
+	for i, raw := range batch {
+		r := &results[i]
+
+		r.object, r.specVersion, r.err = objectFromBytes(raw)
+		if r.err == nil {
+			r.err = ic.policyErr
+		}
+		if r.err == nil {
+			r.err = ic.policy.Check(r.object, cabby.SpecVersionMediaType(r.specVersion))
+		}
+		if r.err == nil {
+			valid = append(valid, r.object)
+		}
+	}
+
+	stored, err := ic.storedVersions(valid)
+	received := map[string]bool{}
+
+	for i := range results {
+		r := &results[i]
+		if r.err != nil {
+			continue
+		}
+
+		switch {
+		case err != nil:
+			r.err = errors.New("Unable to check if the object version exists")
+		case received[objectVersion(r.object)]:
+			r.err = errors.New("Object version is in the envelope more than once")
+		default:
+			r.write, r.message, r.err = ic.duplicate(r.object, stored[objectVersion(r.object)])
+			received[objectVersion(r.object)] = true
+		}
+	}
+	return results
+}
+
+// duplicate applies the duplicate policy to an object version that's already stored; existing is nil if it isn't
+func (ic *ingestCheck) duplicate(o stones.Object, existing *storedVersion) (write bool, message string, err error) {
+	switch {
+	case existing == nil:
+		return true, "", nil
+	case existing.deleted:
+		return false, "", errors.New("Object version is in the trash; restore or purge it first")
+	case existing.collectionID != ic.collectionID:
+		return false, "", errors.New("Object version already exists in another collection")
+	}
+
+	switch ic.policy.DuplicatePolicy() {
+	case cabby.DuplicateIdentical:
+		if !sameContent(existing.object, o.Source) {
+			return false, "", errors.New("Object version already exists with different content")
+		}
+		return false, "Object version already exists with the same content", nil
+	case cabby.DuplicateOverwrite:
+		return true, "Object version overwrites the existing one", nil
+	}
+	return false, "", errors.New("Object version already exists")
+}
+
+// storedVersions returns the stored versions of objects by their object version
+func (ic *ingestCheck) storedVersions(objects []stones.Object) (stored map[string]*storedVersion, err error) {
+	stored = map[string]*storedVersion{}
+	if len(objects) == 0 {
+		return
+	}
+
+	sql := `select id, modified, collection_id, object, deleted_at is not null
+					from objects
+					where (id, modified) in (values $versions)`
+	args := []interface{}{}
+
+	versions := []string{}
+	for _, o := range objects {
+		versions = append(versions, "(?, ?)")
+		args = append(args, o.ID.String(), o.Modified.String())
+	}
+	sql = strings.Replace(sql, "$versions", strings.Join(versions, ", "), 1)
+
+	rows, err := ic.DB.Query(sql, args...)
+	if err != nil {
+		logSQLError(sql, args, err)
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, modified string
+		var sv storedVersion
+
+		if err = rows.Scan(&id, &modified, &sv.collectionID, &sv.object, &sv.deleted); err != nil {
+			return
+		}
+		stored[id+"@"+modified] = &sv
+	}
+
+	err = rows.Err()
+	return
+}
+
+// writeSQL returns the statement objects are written with; overwrites replace the object version in the collection
+func (ic *ingestCheck) writeSQL() string {
+	if ic.policy.DuplicatePolicy() == cabby.DuplicateOverwrite {
+		return overwriteObjectSQL
+	}
+	return createObjectSQL
+}
+
+func (s ObjectService) ingestPolicy(collectionID string) (cabby.IngestPolicy, error) {
+	sql := `select ingest_media_types, ingest_allowed_types, ingest_denied_types, ingest_duplicates
+					from collection where id = ? and deleted_at is null`
+	args := []interface{}{collectionID}
+
+	var mediaTypes, allowedTypes, deniedTypes, duplicates string
+
+	err := s.DB.QueryRow(sql, args...).Scan(&mediaTypes, &allowedTypes, &deniedTypes, &duplicates)
+	if err != nil {
+		logSQLError(sql, args, err)
+		return cabby.IngestPolicy{}, errors.New("Unable to read the ingest policy of the collection")
+	}
+
+	return newIngestPolicy(mediaTypes, allowedTypes, deniedTypes, duplicates), nil
+}
+
+/* helpers */
+
+func objectVersion(o stones.Object) string {
+	return o.ID.String() + "@" + o.Modified.String()
+}
+
+// sameContent compares objects as JSON so formatting and property order don't matter
+func sameContent(a, b []byte) bool {
+	var objectA, objectB interface{}
+
+	if json.Unmarshal(a, &objectA) != nil || json.Unmarshal(b, &objectB) != nil {
+		return false
+	}
+	return reflect.DeepEqual(objectA, objectB)
+}
@@ -26,22 +26,41 @@ func (s ManifestService) Manifest(ctx context.Context, collectionID string, p *c
 	return result, err
 }
 
+// manifestPerObjectSQL pages over the objects in the manifest data and returns every version of those in the page
+const manifestPerObjectSQL = `,
+					manifest_objects as (
+						select id, min(rowid) first_rowid from data group by id
+					),
+					page as (
+						select id from manifest_objects order by first_rowid $paginate
+					)
+					select id, date_added, version, media_type, (select count(*) from manifest_objects) total
+					from data
+					where id in (select id from page)
+					order by rowid`
+
 func (s ManifestService) manifest(collectionID string, p *cabby.Page, f cabby.Filter) (cabby.Manifest, error) {
 	sql := `with data as (
-						select rowid, id, min(created_at) date_added, modified version, 1 count
-						-- media_types omitted...should that be in this table?
-						from objects_data
+						select rowid, id, min(created_at) date_added, modified version, media_type, 1 count
+						from $objectsData
 						where
 							collection_id = ?
 							and $filter
 						group by rowid, id, modified
-					)
-					select id, date_added, version, (select sum(count) from data) total
+					)`
+
+	if p.PerObject {
+		sql += manifestPerObjectSQL
+	} else {
+		sql += `
+					select id, date_added, version, media_type, (select sum(count) from data) total
 					from data
 					$paginate`
+	}
 
 	args := []interface{}{collectionID}
 
+	sql, args = applyAsOf(sql, f, args)
 	sql, args = applyFiltering(sql, f, args)
 	sql, args = applyPaging(sql, p, args)
 
@@ -56,9 +75,9 @@ func (s ManifestService) manifest(collectionID string, p *cabby.Page, f cabby.Fi
 
 	for rows.Next() {
 		me := cabby.ManifestEntry{}
-		var dateAdded, version string
+		var dateAdded, version, mediaType string
 
-		if err := rows.Scan(&me.ID, &dateAdded, &version, &p.Total); err != nil {
+		if err := rows.Scan(&me.ID, &dateAdded, &version, &mediaType, &p.Total); err != nil {
 			return m, err
 		}
 
@@ -75,7 +94,7 @@ func (s ManifestService) manifest(collectionID string, p *cabby.Page, f cabby.Fi
 		me.Version = ts
 
 		p.SetAddedAfters(me.DateAdded.String())
-		me.MediaTypes = []string{cabby.StixContentType}
+		me.MediaTypes = []string{mediaType}
 		m.Objects = append(m.Objects, me)
 	}
 
@@ -78,6 +78,130 @@ func TestManifestServiceManifestFilter(t *testing.T) {
 	}
 }
 
+func TestManifestServiceManifestPerObject(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+	createLabeledVersion(t, ds)
+
+	others := []string{"malware--6b4f1a2e-9c3d-4e5f-8a7b-1c2d3e4f5a6b", "malware--0c7b5b88-8ff7-4a4d-aa9d-feb398cd0061"}
+	for _, id := range others {
+		createObject(ds, id)
+	}
+
+	tests := []struct {
+		page        cabby.Page
+		expectedIDs []string
+	}{
+		// every version of an object in the page is returned
+		{cabby.Page{Limit: 1, PerObject: true}, []string{tester.ObjectID, tester.ObjectID}},
+		{cabby.Page{Limit: 2, Offset: 1, PerObject: true}, others},
+		{cabby.Page{Limit: 1}, []string{tester.ObjectID}},
+	}
+
+	for _, test := range tests {
+		p := test.page
+		result, err := ds.ManifestService().Manifest(context.Background(), tester.CollectionID, &p, cabby.Filter{Versions: "all"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ids := []string{}
+		for _, entry := range result.Objects {
+			ids = append(ids, entry.ID)
+		}
+		if strings.Join(ids, ",") != strings.Join(test.expectedIDs, ",") {
+			t.Error("Got:", ids, "Expected:", test.expectedIDs, "Page:", test.page)
+		}
+
+		expectedTotal := uint64(4)
+		if test.page.PerObject {
+			expectedTotal = 3
+		}
+		if p.Total != expectedTotal {
+			t.Error("Got:", p.Total, "Expected:", expectedTotal, "Page:", test.page)
+		}
+	}
+}
+
+func TestManifestServiceManifestFilterProperties(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+	createLabeledVersion(t, ds)
+
+	creator := "identity--f431f809-377b-45e0-aa1c-6a4751cae5ff"
+
+	tests := []struct {
+		properties       map[string][]string
+		expectedVersions []string
+	}{
+		{map[string][]string{}, []string{"2016-04-06T20:07:09Z", "2017-04-06T20:07:09Z"}},
+		{map[string][]string{"labels": {"backdoor"}}, []string{"2017-04-06T20:07:09Z"}},
+		{map[string][]string{"labels": {"worm", "remote-access-trojan"}}, []string{"2017-04-06T20:07:09Z"}},
+		{map[string][]string{"labels": {"worm"}}, []string{}},
+		{map[string][]string{"created_by_ref": {creator}}, []string{"2016-04-06T20:07:09Z"}},
+		{map[string][]string{"created_by_ref": {creator}, "labels": {"backdoor"}}, []string{}},
+		{map[string][]string{"name": {"Poison Ivy"}}, []string{"2016-04-06T20:07:09Z", "2017-04-06T20:07:09Z"}},
+		{map[string][]string{"unknown": {"x"}}, []string{"2016-04-06T20:07:09Z", "2017-04-06T20:07:09Z"}},
+	}
+
+	for _, test := range tests {
+		f := cabby.Filter{Properties: test.properties, Versions: "all"}
+
+		results, err := ds.ManifestService().Manifest(context.Background(), tester.CollectionID, &cabby.Page{}, f)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(results.Objects) != len(test.expectedVersions) {
+			t.Fatal("Got:", results.Objects, "Expected:", test.expectedVersions, "Properties:", test.properties)
+		}
+		for i, entry := range results.Objects {
+			if entry.Version.String() != test.expectedVersions[i] {
+				t.Error("Got:", entry.Version.String(), "Expected:", test.expectedVersions[i], "Properties:", test.properties)
+			}
+		}
+	}
+}
+
+func TestManifestServiceManifestAsOf(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+	createOverlappingVersions(t, ds)
+
+	tests := []struct {
+		asOf              string
+		expectedVersion   string
+		expectedDateAdded string
+	}{
+		{"2019-01-15T00:00:00Z", "2016-04-06T20:07:09Z", "2019-01-01T00:00:00Z"},
+		{"2019-03-15T00:00:00Z", "2018-01-01T00:00:00Z", "2019-02-01T00:00:00Z"},
+		{"2019-04-15T00:00:00Z", "2017-01-01T00:00:00Z", "2019-03-01T00:00:00Z"},
+	}
+
+	for _, test := range tests {
+		asOf, err := stones.TimestampFromString(test.asOf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		f := cabby.Filter{AsOf: asOf, Versions: "last"}
+		result, err := ds.ManifestService().Manifest(context.Background(), tester.CollectionID, &cabby.Page{}, f)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(result.Objects) != 1 {
+			t.Fatal("Got:", len(result.Objects), "Expected:", 1, "As of:", test.asOf)
+		}
+		if result.Objects[0].Version.String() != test.expectedVersion {
+			t.Error("Got:", result.Objects[0].Version.String(), "Expected:", test.expectedVersion, "As of:", test.asOf)
+		}
+		if result.Objects[0].DateAdded.String() != test.expectedDateAdded {
+			t.Error("Got:", result.Objects[0].DateAdded.String(), "Expected:", test.expectedDateAdded, "As of:", test.asOf)
+		}
+	}
+}
+
 func TestManifestServiceManifestPage(t *testing.T) {
 	setupSQLite()
 	ds := testDataStore()
@@ -158,3 +282,40 @@ func TestManifestServiceManifestInvalidDateAdded(t *testing.T) {
 		t.Error("Got:", err, "Expected an error")
 	}
 }
+
+func TestManifestServiceManifestMediaTypes(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+	s := ds.ManifestService()
+
+	raw := []byte(`{"type": "ipv4-addr", "spec_version": "2.1", "id": "ipv4-addr--ff26c055-6336-5bc5-b98d-13d6226742dd",
+		"value": "198.51.100.3"}`)
+	o, _, err := cabby.ObjectFromBytes(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ds.ObjectService().CreateObject(context.Background(), tester.CollectionID, o)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		filter    cabby.Filter
+		mediaType string
+	}{
+		{cabby.Filter{SpecVersions: cabby.SpecVersion20}, cabby.StixContentType20},
+		{cabby.Filter{SpecVersions: cabby.SpecVersion21}, "application/stix+json;version=2.1"},
+	}
+
+	for _, test := range tests {
+		result, err := s.Manifest(context.Background(), tester.CollectionID, &cabby.Page{}, test.filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(result.Objects) != 1 || result.Objects[0].MediaTypes[0] != test.mediaType {
+			t.Error("Got:", result.Objects, "Expected an entry with media type:", test.mediaType)
+		}
+	}
+}
@@ -11,7 +11,18 @@ import (
 // add migrations here to the below far
 // each struct has the version number associated to it and it's functions for migration up and down
 var migrationsToSetup = []migrationList{
-	migrationList{1, migrations.Up1, migrations.Down1}}
+	migrationList{1, migrations.Up1, migrations.Down1},
+	migrationList{2, migrations.Up2, migrations.Down2},
+	migrationList{3, migrations.Up3, migrations.Down3},
+	migrationList{4, migrations.Up4, migrations.Down4},
+	migrationList{5, migrations.Up5, migrations.Down5},
+	migrationList{6, migrations.Up6, migrations.Down6},
+	migrationList{7, migrations.Up7, migrations.Down7},
+	migrationList{8, migrations.Up8, migrations.Down8},
+	migrationList{9, migrations.Up9, migrations.Down9},
+	migrationList{10, migrations.Up10, migrations.Down10},
+	migrationList{11, migrations.Up11, migrations.Down11},
+	migrationList{12, migrations.Up12, migrations.Down12}}
 
 type migrationList struct {
 	version int
@@ -79,10 +90,10 @@ func (s MigrationService) Up() error {
 
 		_, err = s.DB.Exec(migration())
 		if err != nil {
-			break
+			return err
 		}
 	}
-	return err
+	return indexSearch(s.DB)
 }
 
 func (s MigrationService) registerMigration(version int, up, down migrationFn) {
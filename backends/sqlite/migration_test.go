@@ -26,8 +26,8 @@ func TestMigrationServiceCurrentVersion(t *testing.T) {
 	s := ds.MigrationService()
 
 	version, err := s.CurrentVersion()
-	if version != 1 {
-		t.Error("Got:", version, "Expected:", 1, "Error:", err)
+	if version != 12 {
+		t.Error("Got:", version, "Expected:", 12, "Error:", err)
 	}
 }
 
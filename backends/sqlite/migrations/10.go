@@ -0,0 +1,63 @@
+package migrations
+
+// Up10 gets the database to version 10
+func Up10() string {
+	sql := `
+  -- who made the request and the collection it was made to; statuses created before were made by no one
+  alter table status add column email text;
+  alter table status add column collection_id text;
+
+  create index status_email_created_at on status (email, created_at);
+  create index status_created_at on status (created_at);
+
+  -- update version
+  update schema_version set version = 10;
+  `
+	return sql
+}
+
+// Down10 takes the db down from 10
+func Down10() string {
+	sql := `
+  create table status_9 (
+    id                text not null,
+    status            text not null,
+    request_timestamp text,
+    total_count       integer not null,
+    success_count     integer not null,
+    successes         text,
+    failure_count     integer not null,
+    failures          text,
+    pending_count     integer not null,
+    pendings          text,
+    /* internal */
+    created_at    text,
+    updated_at    text
+  );
+
+  insert into status_9 (id, status, request_timestamp, total_count, success_count, successes, failure_count, failures,
+                        pending_count, pendings, created_at, updated_at)
+    select id, status, request_timestamp, total_count, success_count, successes, failure_count, failures,
+           pending_count, pendings, created_at, updated_at
+    from status;
+
+  drop table status;
+  alter table status_9 rename to status;
+
+    create trigger status_ai_created_at after insert on status
+      begin
+        update status set created_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now') where id = new.id;
+        update status set updated_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now') where id = new.id;
+      end;
+
+    create trigger status_au_updated_at after update on status
+      begin
+        update status set updated_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now') where id = new.id;
+      end;
+
+    create index status_id on status (id);
+
+  update schema_version set version = 9;
+  `
+	return sql
+}
@@ -0,0 +1,119 @@
+package migrations
+
+// Up11 gets the database to version 11
+func Up11() string {
+	sql := `
+  -- observables extracted from an object when it's added, as a json list of {"type", "value"}; objects added before
+  -- are null until they're indexed
+  alter table objects add column observables text;
+
+  -- observables index the object versions they were extracted from
+  create table observable (
+    object_id text not null,
+    modified  text not null,
+    type      text not null,
+    value     text not null,
+
+    primary key (object_id, modified, type, value),
+    foreign key (object_id, modified) references objects(id, modified) on update cascade on delete cascade
+  );
+
+  create index observable_value on observable (value collate nocase);
+` + observableTriggers11() + `
+  -- update version
+  update schema_version set version = 11;
+  `
+	return sql
+}
+
+// Down11 takes the db down from 11
+func Down11() string {
+	sql := `
+  PRAGMA foreign_keys = OFF;
+
+  drop trigger if exists objects_ai_observables;
+  drop trigger if exists objects_au_observables;
+  drop table if exists observable;
+
+  drop trigger if exists objects_ai_collection_media_types;
+  drop trigger if exists objects_ad_collection_media_types;
+  drop trigger if exists objects_au_collection_media_types;
+  drop view if exists objects_data;
+  drop view if exists objects_id_aggregate;
+
+  create table objects_10 (
+    id            text not null,
+    type          text not null,
+    created       text not null,
+    modified      text not null,
+    object        text not null,
+    collection_id text not null,
+    created_at    text,
+    updated_at    text,
+    spec_version  text not null default '2.0',
+    media_type    text not null default 'application/vnd.oasis.stix+json;version=2.0',
+    deleted_at    text,
+    deleted_by    text,
+
+    constraint valid_id check(id like '%--________-____-____-____-____________'),
+    constraint valid_json check(json_valid(object) = 1),
+
+    primary key (id, modified),
+
+    foreign key (collection_id) references collection(id) on delete cascade
+  );
+
+  insert into objects_10 (id, type, created, modified, object, collection_id, created_at, updated_at, spec_version,
+                          media_type, deleted_at, deleted_by)
+    select id, type, created, modified, object, collection_id, created_at, updated_at, spec_version, media_type,
+           deleted_at, deleted_by
+    from objects;
+
+  drop table objects;
+  alter table objects_10 rename to objects;
+
+    create trigger objects_ai_created_at after insert on objects
+      begin
+        update objects set created_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now') where rowid = new.rowid;
+        update objects set updated_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now') where rowid = new.rowid;
+      end;
+
+    create trigger objects_au_updated_at after update on objects
+      begin
+        update objects set updated_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now') where id = new.id;
+      end;
+
+    create index objects_id on objects (id);
+    create index objects_type on objects (type);
+    create index objects_version on objects (id, type, modified);
+    create index objects_collection_media_type on objects (collection_id, media_type);
+    create index objects_deleted_at on objects (deleted_at) where deleted_at is not null;
+` + objectsViews8() + collectionMediaTypeTriggers8() + `
+  PRAGMA foreign_keys = ON;
+
+  update schema_version set version = 10;
+  `
+	return sql
+}
+
+// observableTriggers11 keep the observable index in sync with the observables of objects
+func observableTriggers11() string {
+	return `
+    create trigger objects_ai_observables after insert on objects
+      when new.observables is not null
+      begin
+        insert or ignore into observable (object_id, modified, type, value)
+          select new.id, new.modified, json_extract(value, '$.type'), json_extract(value, '$.value')
+          from json_each(new.observables);
+      end;
+
+    create trigger objects_au_observables after update of observables on objects
+      begin
+        delete from observable where object_id = old.id and modified = old.modified;
+
+        insert or ignore into observable (object_id, modified, type, value)
+          select new.id, new.modified, json_extract(value, '$.type'), json_extract(value, '$.value')
+          from json_each(new.observables);
+      end;
+`
+}
@@ -0,0 +1,99 @@
+package migrations
+
+// Up12 gets the database to version 12
+func Up12() string {
+	sql := `
+  -- the searchable text of object versions that aren't in the trash; search_id is the rowid of the full-text index,
+  -- which is created when the database is opened by a build with fts5.  triggers on objects keep it in sync
+  create table object_search (
+    search_id           integer primary key,
+    object_id           text not null,
+    modified            text not null,
+    name                text,
+    description         text,
+    labels              text,
+    external_references text,
+
+    unique (object_id, modified)
+  );
+
+  create trigger objects_ai_search after insert on objects
+    begin
+      ` + objectSearchInsert12("new") + `
+    end;
+
+  create trigger objects_au_search after update of id, modified, object on objects when new.deleted_at is null
+    begin
+      delete from object_search where object_id = old.id and modified = old.modified;
+      ` + objectSearchInsert12("new") + `
+    end;
+
+  create trigger objects_ad_search after delete on objects
+    begin
+      delete from object_search where object_id = old.id and modified = old.modified;
+    end;
+
+  -- versions moved to the trash aren't searched, restored ones are searched again
+  create trigger objects_au_trash_search after update of deleted_at on objects
+    when new.deleted_at is not null and old.deleted_at is null
+    begin
+      delete from object_search where object_id = old.id and modified = old.modified;
+    end;
+
+  create trigger objects_au_restore_search after update of deleted_at on objects
+    when new.deleted_at is null and old.deleted_at is not null
+    begin
+      ` + objectSearchInsert12("new") + `
+    end;
+
+  -- index the objects already added
+  ` + objectSearchInsert12("objects") + `
+
+  -- update version
+  update schema_version set version = 12;
+  `
+	return sql
+}
+
+// Down12 takes the db down from 12
+func Down12() string {
+	sql := `
+  drop trigger if exists objects_ai_search;
+  drop trigger if exists objects_au_search;
+  drop trigger if exists objects_ad_search;
+  drop trigger if exists objects_au_trash_search;
+  drop trigger if exists objects_au_restore_search;
+  drop trigger if exists object_search_ai;
+  drop trigger if exists object_search_ad;
+  drop trigger if exists object_search_au;
+  drop table if exists objects_fts;
+  drop table if exists object_search;
+
+  update schema_version set version = 11;
+  `
+	return sql
+}
+
+// objectSearchInsert12 inserts the searchable text of the object versions in table (the row in a trigger) that aren't in
+// the trash
+func objectSearchInsert12(table string) string {
+	from := ""
+	if table != "new" {
+		from = "from " + table + " where deleted_at is null"
+	}
+
+	return `insert into object_search (object_id, modified, name, description, labels, external_references)
+        select
+          ` + table + `.id,
+          ` + table + `.modified,
+          json_extract(` + table + `.object, '$.name'),
+          json_extract(` + table + `.object, '$.description'),
+          (select group_concat(value, ' ') from json_each(` + table + `.object, '$.labels')),
+          (select group_concat(
+                    trim(coalesce(json_extract(value, '$.source_name'), '') || ' ' ||
+                         coalesce(json_extract(value, '$.external_id'), '') || ' ' ||
+                         coalesce(json_extract(value, '$.description'), '') || ' ' ||
+                         coalesce(json_extract(value, '$.url'), '')), ' ')
+           from json_each(` + table + `.object, '$.external_references'))
+        ` + from + `;`
+}
@@ -0,0 +1,49 @@
+package migrations
+
+// Up2 gets the database to version 2
+func Up2() string {
+	sql := `
+  -- collections that can be read without authenticating
+  alter table collection add column anonymous_read integer check(anonymous_read in (0, 1)) not null default 0;
+
+  -- update version
+  update schema_version set version = 2;
+  `
+	return sql
+}
+
+// Down2 takes the db down from 2
+func Down2() string {
+	sql := `
+  -- sqlite can't drop a column, so the table is rebuilt without it
+  create table collection_1 (
+    id            text not null primary key,
+    api_root_path text not null,
+    title         text,
+    description   text,
+    media_types   text default '',
+    created_at    text,
+    updated_at    text
+  );
+
+  insert into collection_1 (id, api_root_path, title, description, media_types, created_at, updated_at)
+    select id, api_root_path, title, description, media_types, created_at, updated_at from collection;
+
+  drop table collection;
+  alter table collection_1 rename to collection;
+
+    create trigger collection_ai_created_at after insert on collection
+      begin
+        update collection set created_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now') where id = new.id;
+        update collection set updated_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now') where id = new.id;
+      end;
+
+    create trigger collection_au_updated_at after update on collection
+      begin
+        update collection set updated_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now') where id = new.id;
+      end;
+
+  update schema_version set version = 1;
+  `
+	return sql
+}
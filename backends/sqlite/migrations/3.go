@@ -0,0 +1,119 @@
+package migrations
+
+// Up3 gets the database to version 3
+func Up3() string {
+	sql := `
+  -- objects without a spec_version are stix 2.0
+  alter table objects add column spec_version text not null default '2.0';
+
+  drop view if exists objects_data;
+
+    create view objects_data as
+      select
+        so.rowid,
+        so.id,
+        so.type,
+        so.created,
+        so.modified,
+        so.object,
+        so.collection_id,
+        case when so.modified = sa.first and so.modified = sa.last then 'only'
+             when so.modified = sa.last then 'last'
+             when so.modified = sa.first then 'first'
+        end version,
+        so.created_at,
+        so.updated_at,
+        so.spec_version
+      from
+        objects so
+        left join objects_id_aggregate sa
+          on so.id = sa.id
+          and so.collection_id = sa.collection_id;
+
+  -- update version
+  update schema_version set version = 3;
+  `
+	return sql
+}
+
+// Down3 takes the db down from 3
+func Down3() string {
+	sql := `
+  -- sqlite can't drop a column, so the table and the views on it are rebuilt without it
+  drop view if exists objects_data;
+  drop view if exists objects_id_aggregate;
+
+  create table objects_2 (
+    id            text not null,
+    type          text not null,
+    created       text not null,
+    modified      text not null,
+    object        text not null,
+    collection_id text not null,
+    created_at    text,
+    updated_at    text,
+
+    constraint valid_id check(id like '%--________-____-____-____-____________'),
+    constraint valid_json check(json_valid(object) = 1),
+
+    primary key (id, modified)
+  );
+
+  insert into objects_2 (id, type, created, modified, object, collection_id, created_at, updated_at)
+    select id, type, created, modified, object, collection_id, created_at, updated_at from objects;
+
+  drop table objects;
+  alter table objects_2 rename to objects;
+
+    create trigger objects_ai_created_at after insert on objects
+      begin
+        update objects set created_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now') where id = new.id;
+        update objects set updated_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now') where id = new.id;
+      end;
+
+    create trigger objects_au_updated_at after update on objects
+      begin
+        update objects set updated_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now') where id = new.id;
+      end;
+
+    create index objects_id on objects (id);
+    create index objects_type on objects (type);
+    create index objects_version on objects (id, type, modified);
+
+    create view objects_id_aggregate as
+      select rowid,
+             id,
+             type,
+             collection_id,
+             min(modified) first,
+             max(modified) last
+      from objects
+      group by id,
+               type,
+               collection_id;
+
+    create view objects_data as
+      select
+        so.rowid,
+        so.id,
+        so.type,
+        so.created,
+        so.modified,
+        so.object,
+        so.collection_id,
+        case when so.modified = sa.first and so.modified = sa.last then 'only'
+             when so.modified = sa.last then 'last'
+             when so.modified = sa.first then 'first'
+        end version,
+        so.created_at,
+        so.updated_at
+      from
+        objects so
+        left join objects_id_aggregate sa
+          on so.id = sa.id
+          and so.collection_id = sa.collection_id;
+
+  update schema_version set version = 2;
+  `
+	return sql
+}
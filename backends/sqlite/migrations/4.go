@@ -0,0 +1,160 @@
+package migrations
+
+// Up4 gets the database to version 4
+func Up4() string {
+	sql := `
+  -- the media type of each object version, set when it's added
+  alter table objects add column media_type text not null default 'application/vnd.oasis.stix+json;version=2.0';
+
+  update objects
+  set media_type = 'application/stix+json;version=' || spec_version
+  where spec_version != '2.0';
+
+  create index objects_collection_media_type on objects (collection_id, media_type);
+
+  drop view if exists objects_data;
+
+    create view objects_data as
+      select
+        so.rowid,
+        so.id,
+        so.type,
+        so.created,
+        so.modified,
+        so.object,
+        so.collection_id,
+        case when so.modified = sa.first and so.modified = sa.last then 'only'
+             when so.modified = sa.last then 'last'
+             when so.modified = sa.first then 'first'
+        end version,
+        so.created_at,
+        so.updated_at,
+        so.spec_version,
+        so.media_type
+      from
+        objects so
+        left join objects_id_aggregate sa
+          on so.id = sa.id
+          and so.collection_id = sa.collection_id;
+
+  -- a collection's media types are the media types of the objects in it
+  update collection
+  set media_types = coalesce((
+    select group_concat(media_type)
+    from (select distinct media_type from objects where collection_id = collection.id order by media_type)
+  ), '');
+
+    create trigger objects_ai_collection_media_types after insert on objects
+      begin
+        update collection
+        set media_types = case when media_types = '' then new.media_type
+                               else media_types || ',' || new.media_type
+                          end
+        where id = new.collection_id
+          and ',' || media_types || ',' not like '%,' || new.media_type || ',%';
+      end;
+
+    create trigger objects_ad_collection_media_types after delete on objects
+      when not exists (select 1 from objects where collection_id = old.collection_id and media_type = old.media_type)
+      begin
+        update collection
+        set media_types = coalesce((
+          select group_concat(media_type)
+          from (select distinct media_type from objects where collection_id = old.collection_id order by media_type)
+        ), '')
+        where id = old.collection_id;
+      end;
+
+  -- update version
+  update schema_version set version = 4;
+  `
+	return sql
+}
+
+// Down4 takes the db down from 4
+func Down4() string {
+	sql := `
+  drop trigger if exists objects_ai_collection_media_types;
+  drop trigger if exists objects_ad_collection_media_types;
+  drop index if exists objects_collection_media_type;
+
+  -- sqlite can't drop a column, so the table and the views on it are rebuilt without it
+  drop view if exists objects_data;
+  drop view if exists objects_id_aggregate;
+
+  create table objects_3 (
+    id            text not null,
+    type          text not null,
+    created       text not null,
+    modified      text not null,
+    object        text not null,
+    collection_id text not null,
+    created_at    text,
+    updated_at    text,
+    spec_version  text not null default '2.0',
+
+    constraint valid_id check(id like '%--________-____-____-____-____________'),
+    constraint valid_json check(json_valid(object) = 1),
+
+    primary key (id, modified)
+  );
+
+  insert into objects_3 (id, type, created, modified, object, collection_id, created_at, updated_at, spec_version)
+    select id, type, created, modified, object, collection_id, created_at, updated_at, spec_version from objects;
+
+  drop table objects;
+  alter table objects_3 rename to objects;
+
+    create trigger objects_ai_created_at after insert on objects
+      begin
+        update objects set created_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now') where id = new.id;
+        update objects set updated_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now') where id = new.id;
+      end;
+
+    create trigger objects_au_updated_at after update on objects
+      begin
+        update objects set updated_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now') where id = new.id;
+      end;
+
+    create index objects_id on objects (id);
+    create index objects_type on objects (type);
+    create index objects_version on objects (id, type, modified);
+
+    create view objects_id_aggregate as
+      select rowid,
+             id,
+             type,
+             collection_id,
+             min(modified) first,
+             max(modified) last
+      from objects
+      group by id,
+               type,
+               collection_id;
+
+    create view objects_data as
+      select
+        so.rowid,
+        so.id,
+        so.type,
+        so.created,
+        so.modified,
+        so.object,
+        so.collection_id,
+        case when so.modified = sa.first and so.modified = sa.last then 'only'
+             when so.modified = sa.last then 'last'
+             when so.modified = sa.first then 'first'
+        end version,
+        so.created_at,
+        so.updated_at,
+        so.spec_version
+      from
+        objects so
+        left join objects_id_aggregate sa
+          on so.id = sa.id
+          and so.collection_id = sa.collection_id;
+
+  update schema_version set version = 3;
+  `
+	return sql
+}
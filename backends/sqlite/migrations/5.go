@@ -0,0 +1,76 @@
+package migrations
+
+// Up5 gets the database to version 5
+func Up5() string {
+	sql := `
+  -- ingest policy of a collection; comma separated lists where an empty list doesn't limit anything
+  alter table collection add column ingest_media_types text not null default '';
+  alter table collection add column ingest_allowed_types text not null default '';
+  alter table collection add column ingest_denied_types text not null default '';
+
+  -- update version
+  update schema_version set version = 5;
+  `
+	return sql
+}
+
+// Down5 takes the db down from 5
+func Down5() string {
+	sql := `
+  -- sqlite can't drop a column, so the table and the triggers that update it are rebuilt without them
+  drop trigger if exists objects_ai_collection_media_types;
+  drop trigger if exists objects_ad_collection_media_types;
+
+  create table collection_4 (
+    id             text not null primary key,
+    api_root_path  text not null,
+    title          text,
+    description    text,
+    media_types    text default '',
+    created_at     text,
+    updated_at     text,
+    anonymous_read integer check(anonymous_read in (0, 1)) not null default 0
+  );
+
+  insert into collection_4 (id, api_root_path, title, description, media_types, created_at, updated_at, anonymous_read)
+    select id, api_root_path, title, description, media_types, created_at, updated_at, anonymous_read from collection;
+
+  drop table collection;
+  alter table collection_4 rename to collection;
+
+    create trigger collection_ai_created_at after insert on collection
+      begin
+        update collection set created_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now') where id = new.id;
+        update collection set updated_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now') where id = new.id;
+      end;
+
+    create trigger collection_au_updated_at after update on collection
+      begin
+        update collection set updated_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now') where id = new.id;
+      end;
+
+    create trigger objects_ai_collection_media_types after insert on objects
+      begin
+        update collection
+        set media_types = case when media_types = '' then new.media_type
+                               else media_types || ',' || new.media_type
+                          end
+        where id = new.collection_id
+          and ',' || media_types || ',' not like '%,' || new.media_type || ',%';
+      end;
+
+    create trigger objects_ad_collection_media_types after delete on objects
+      when not exists (select 1 from objects where collection_id = old.collection_id and media_type = old.media_type)
+      begin
+        update collection
+        set media_types = coalesce((
+          select group_concat(media_type)
+          from (select distinct media_type from objects where collection_id = old.collection_id order by media_type)
+        ), '')
+        where id = old.collection_id;
+      end;
+
+  update schema_version set version = 4;
+  `
+	return sql
+}
@@ -0,0 +1,99 @@
+package migrations
+
+// Up6 gets the database to version 6
+func Up6() string {
+	sql := `
+  -- what happens to object versions that already exist: 'reject', 'identical' or 'overwrite'
+  alter table collection add column ingest_duplicates text not null default 'reject';
+
+  -- statuses of requests made with an 'Idempotency-Key'; keys are unique to a user and collection
+  create table idempotency_key (
+    email           text not null,
+    collection_id   text not null,
+    idempotency_key text not null,
+    status_id       text not null,
+    created_at      text,
+
+    primary key (email, collection_id, idempotency_key)
+  );
+
+    create trigger idempotency_key_ai_created_at after insert on idempotency_key
+      begin
+        update idempotency_key set created_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now')
+        where email = new.email and collection_id = new.collection_id and idempotency_key = new.idempotency_key;
+      end;
+
+  -- update version
+  update schema_version set version = 6;
+  `
+	return sql
+}
+
+// Down6 takes the db down from 6
+func Down6() string {
+	sql := `
+  drop table if exists idempotency_key;
+
+  -- sqlite can't drop a column, so the table and the triggers that update it are rebuilt without them
+  drop trigger if exists objects_ai_collection_media_types;
+  drop trigger if exists objects_ad_collection_media_types;
+
+  create table collection_5 (
+    id                   text not null primary key,
+    api_root_path        text not null,
+    title                text,
+    description          text,
+    media_types          text default '',
+    created_at           text,
+    updated_at           text,
+    anonymous_read       integer check(anonymous_read in (0, 1)) not null default 0,
+    ingest_media_types   text not null default '',
+    ingest_allowed_types text not null default '',
+    ingest_denied_types  text not null default ''
+  );
+
+  insert into collection_5 (id, api_root_path, title, description, media_types, created_at, updated_at, anonymous_read,
+                            ingest_media_types, ingest_allowed_types, ingest_denied_types)
+    select id, api_root_path, title, description, media_types, created_at, updated_at, anonymous_read,
+           ingest_media_types, ingest_allowed_types, ingest_denied_types
+    from collection;
+
+  drop table collection;
+  alter table collection_5 rename to collection;
+
+    create trigger collection_ai_created_at after insert on collection
+      begin
+        update collection set created_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now') where id = new.id;
+        update collection set updated_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now') where id = new.id;
+      end;
+
+    create trigger collection_au_updated_at after update on collection
+      begin
+        update collection set updated_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now') where id = new.id;
+      end;
+
+    create trigger objects_ai_collection_media_types after insert on objects
+      begin
+        update collection
+        set media_types = case when media_types = '' then new.media_type
+                               else media_types || ',' || new.media_type
+                          end
+        where id = new.collection_id
+          and ',' || media_types || ',' not like '%,' || new.media_type || ',%';
+      end;
+
+    create trigger objects_ad_collection_media_types after delete on objects
+      when not exists (select 1 from objects where collection_id = old.collection_id and media_type = old.media_type)
+      begin
+        update collection
+        set media_types = coalesce((
+          select group_concat(media_type)
+          from (select distinct media_type from objects where collection_id = old.collection_id order by media_type)
+        ), '')
+        where id = old.collection_id;
+      end;
+
+  update schema_version set version = 5;
+  `
+	return sql
+}
@@ -0,0 +1,282 @@
+package migrations
+
+// Up7 gets the database to version 7
+func Up7() string {
+	sql := `
+  -- deleted resources go to the trash; they're hidden until they're restored or purged
+  alter table api_root add column deleted_at text;
+  alter table api_root add column deleted_by text;
+  alter table collection add column deleted_at text;
+  alter table collection add column deleted_by text;
+  alter table objects add column deleted_at text;
+  alter table objects add column deleted_by text;
+
+  create index objects_deleted_at on objects (deleted_at) where deleted_at is not null;
+
+  drop view if exists objects_data;
+  drop view if exists objects_id_aggregate;
+
+    create view objects_id_aggregate as
+      select rowid,
+             id,
+             type,
+             collection_id,
+             min(modified) first,
+             max(modified) last
+      from objects
+      where deleted_at is null
+      group by id,
+               type,
+               collection_id;
+
+    create view objects_data as
+      select
+        so.rowid,
+        so.id,
+        so.type,
+        so.created,
+        so.modified,
+        so.object,
+        so.collection_id,
+        case when so.modified = sa.first and so.modified = sa.last then 'only'
+             when so.modified = sa.last then 'last'
+             when so.modified = sa.first then 'first'
+        end version,
+        so.created_at,
+        so.updated_at,
+        so.spec_version,
+        so.media_type
+      from
+        objects so
+        left join objects_id_aggregate sa
+          on so.id = sa.id
+          and so.collection_id = sa.collection_id
+      where so.deleted_at is null;
+
+  -- a collection's media types are the media types of the objects in it that aren't in the trash
+  drop trigger if exists objects_ad_collection_media_types;
+
+    create trigger objects_ad_collection_media_types after delete on objects
+      when old.deleted_at is null
+        and not exists (
+          select 1 from objects where collection_id = old.collection_id and media_type = old.media_type and deleted_at is null
+        )
+      begin
+        update collection
+        set media_types = coalesce((
+          select group_concat(media_type)
+          from (
+            select distinct media_type from objects
+            where collection_id = old.collection_id and deleted_at is null
+            order by media_type
+          )
+        ), '')
+        where id = old.collection_id;
+      end;
+
+    create trigger objects_au_collection_media_types after update of deleted_at on objects
+      begin
+        update collection
+        set media_types = coalesce((
+          select group_concat(media_type)
+          from (
+            select distinct media_type from objects
+            where collection_id = new.collection_id and deleted_at is null
+            order by media_type
+          )
+        ), '')
+        where id = new.collection_id;
+      end;
+
+  -- update version
+  update schema_version set version = 7;
+  `
+	return sql
+}
+
+// Down7 takes the db down from 7
+func Down7() string {
+	sql := `
+  -- resources in the trash can't be hidden without the columns, so they're purged
+  delete from objects where deleted_at is not null;
+  delete from collection where deleted_at is not null;
+  delete from api_root where deleted_at is not null;
+
+  -- sqlite can't drop a column, so the tables and the views and triggers on them are rebuilt without them
+  drop trigger if exists objects_ai_collection_media_types;
+  drop trigger if exists objects_ad_collection_media_types;
+  drop trigger if exists objects_au_collection_media_types;
+  drop index if exists objects_deleted_at;
+  drop view if exists objects_data;
+  drop view if exists objects_id_aggregate;
+
+  create table api_root_6 (
+    id                 integer not null primary key,
+    discovery_id       integer check(discovery_id = 1) default 1,
+    api_root_path      text    check(api_root_path != "") not null,
+    title              text    not null,
+    description        text,
+    versions           text,
+    max_content_length integer not null,
+    created_at         text,
+    updated_at         text,
+
+    unique(api_root_path) on conflict fail
+  );
+
+  insert into api_root_6 (id, discovery_id, api_root_path, title, description, versions, max_content_length, created_at,
+                          updated_at)
+    select id, discovery_id, api_root_path, title, description, versions, max_content_length, created_at, updated_at
+    from api_root;
+
+  drop table api_root;
+  alter table api_root_6 rename to api_root;
+
+    create trigger api_root_ai_created_at after insert on api_root
+      begin
+        update api_root set created_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now') where id = new.id;
+        update api_root set updated_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now') where id = new.id;
+      end;
+
+    create trigger api_root_au_updated_at after update on api_root
+      begin
+        update api_root set updated_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now') where id = new.id;
+      end;
+
+  create table collection_6 (
+    id                   text not null primary key,
+    api_root_path        text not null,
+    title                text,
+    description          text,
+    media_types          text default '',
+    created_at           text,
+    updated_at           text,
+    anonymous_read       integer check(anonymous_read in (0, 1)) not null default 0,
+    ingest_media_types   text not null default '',
+    ingest_allowed_types text not null default '',
+    ingest_denied_types  text not null default '',
+    ingest_duplicates    text not null default 'reject'
+  );
+
+  insert into collection_6 (id, api_root_path, title, description, media_types, created_at, updated_at, anonymous_read,
+                            ingest_media_types, ingest_allowed_types, ingest_denied_types, ingest_duplicates)
+    select id, api_root_path, title, description, media_types, created_at, updated_at, anonymous_read,
+           ingest_media_types, ingest_allowed_types, ingest_denied_types, ingest_duplicates
+    from collection;
+
+  drop table collection;
+  alter table collection_6 rename to collection;
+
+    create trigger collection_ai_created_at after insert on collection
+      begin
+        update collection set created_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now') where id = new.id;
+        update collection set updated_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now') where id = new.id;
+      end;
+
+    create trigger collection_au_updated_at after update on collection
+      begin
+        update collection set updated_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now') where id = new.id;
+      end;
+
+  create table objects_6 (
+    id            text not null,
+    type          text not null,
+    created       text not null,
+    modified      text not null,
+    object        text not null,
+    collection_id text not null,
+    created_at    text,
+    updated_at    text,
+    spec_version  text not null default '2.0',
+    media_type    text not null default 'application/vnd.oasis.stix+json;version=2.0',
+
+    constraint valid_id check(id like '%--________-____-____-____-____________'),
+    constraint valid_json check(json_valid(object) = 1),
+
+    primary key (id, modified)
+  );
+
+  insert into objects_6 (id, type, created, modified, object, collection_id, created_at, updated_at, spec_version,
+                         media_type)
+    select id, type, created, modified, object, collection_id, created_at, updated_at, spec_version, media_type
+    from objects;
+
+  drop table objects;
+  alter table objects_6 rename to objects;
+
+    create trigger objects_ai_created_at after insert on objects
+      begin
+        update objects set created_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now') where id = new.id;
+        update objects set updated_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now') where id = new.id;
+      end;
+
+    create trigger objects_au_updated_at after update on objects
+      begin
+        update objects set updated_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now') where id = new.id;
+      end;
+
+    create index objects_id on objects (id);
+    create index objects_type on objects (type);
+    create index objects_version on objects (id, type, modified);
+    create index objects_collection_media_type on objects (collection_id, media_type);
+
+    create view objects_id_aggregate as
+      select rowid,
+             id,
+             type,
+             collection_id,
+             min(modified) first,
+             max(modified) last
+      from objects
+      group by id,
+               type,
+               collection_id;
+
+    create view objects_data as
+      select
+        so.rowid,
+        so.id,
+        so.type,
+        so.created,
+        so.modified,
+        so.object,
+        so.collection_id,
+        case when so.modified = sa.first and so.modified = sa.last then 'only'
+             when so.modified = sa.last then 'last'
+             when so.modified = sa.first then 'first'
+        end version,
+        so.created_at,
+        so.updated_at,
+        so.spec_version,
+        so.media_type
+      from
+        objects so
+        left join objects_id_aggregate sa
+          on so.id = sa.id
+          and so.collection_id = sa.collection_id;
+
+    create trigger objects_ai_collection_media_types after insert on objects
+      begin
+        update collection
+        set media_types = case when media_types = '' then new.media_type
+                               else media_types || ',' || new.media_type
+                          end
+        where id = new.collection_id
+          and ',' || media_types || ',' not like '%,' || new.media_type || ',%';
+      end;
+
+    create trigger objects_ad_collection_media_types after delete on objects
+      when not exists (select 1 from objects where collection_id = old.collection_id and media_type = old.media_type)
+      begin
+        update collection
+        set media_types = coalesce((
+          select group_concat(media_type)
+          from (select distinct media_type from objects where collection_id = old.collection_id order by media_type)
+        ), '')
+        where id = old.collection_id;
+      end;
+
+  update schema_version set version = 6;
+  `
+	return sql
+}
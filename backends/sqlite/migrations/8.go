@@ -0,0 +1,298 @@
+package migrations
+
+import "strings"
+
+// Up8 gets the database to version 8
+func Up8() string {
+	sql := `
+  -- tables are rebuilt to add foreign keys, which can't be enforced while the old tables are swapped out
+  PRAGMA foreign_keys = OFF;
+
+  -- rows pointing at resources that don't exist can't be read and can't be kept once foreign keys are enforced
+  delete from collection where api_root_path not in (select api_root_path from api_root);
+  delete from objects where collection_id not in (select id from collection);
+  delete from user_collection where collection_id not in (select id from collection);
+  delete from idempotency_key
+  where collection_id not in (select id from collection) or email not in (select email from user);
+  ` + rebuild8(
+		`foreign key (api_root_path) references api_root(api_root_path) on update cascade on delete cascade`,
+		`foreign key (collection_id) references collection(id) on delete cascade`,
+		`foreign key (email) references user(email) on delete cascade,
+    foreign key (collection_id) references collection(id) on delete cascade`,
+		`foreign key (email) references user(email) on delete cascade,
+    foreign key (collection_id) references collection(id) on delete cascade`) + `
+  PRAGMA foreign_keys = ON;
+
+  -- update version
+  update schema_version set version = 8;
+  `
+	return sql
+}
+
+// Down8 takes the db down from 8
+func Down8() string {
+	sql := `
+  PRAGMA foreign_keys = OFF;
+  ` + rebuild8(
+		"",
+		"",
+		`foreign key (email) references user(email) on delete cascade`,
+		"") + `
+  PRAGMA foreign_keys = ON;
+
+  update schema_version set version = 7;
+  `
+	return sql
+}
+
+// rebuild8 rebuilds the tables that reference other resources with the given foreign keys, along with the triggers,
+// indexes and views on them
+func rebuild8(collectionKeys, objectsKeys, userCollectionKeys, idempotencyKeyKeys string) string {
+	sql := `
+  drop trigger if exists objects_ai_collection_media_types;
+  drop trigger if exists objects_ad_collection_media_types;
+  drop trigger if exists objects_au_collection_media_types;
+  drop view if exists objects_data;
+  drop view if exists objects_id_aggregate;
+
+  create table collection_new (
+    id                   text not null primary key,
+    api_root_path        text not null,
+    title                text,
+    description          text,
+    media_types          text default '',
+    created_at           text,
+    updated_at           text,
+    anonymous_read       integer check(anonymous_read in (0, 1)) not null default 0,
+    ingest_media_types   text not null default '',
+    ingest_allowed_types text not null default '',
+    ingest_denied_types  text not null default '',
+    ingest_duplicates    text not null default 'reject',
+    deleted_at           text,
+    deleted_by           text$collectionKeys
+  );
+
+  insert into collection_new (id, api_root_path, title, description, media_types, created_at, updated_at, anonymous_read,
+                              ingest_media_types, ingest_allowed_types, ingest_denied_types, ingest_duplicates,
+                              deleted_at, deleted_by)
+    select id, api_root_path, title, description, media_types, created_at, updated_at, anonymous_read,
+           ingest_media_types, ingest_allowed_types, ingest_denied_types, ingest_duplicates, deleted_at, deleted_by
+    from collection;
+
+  drop table collection;
+  alter table collection_new rename to collection;
+
+    create trigger collection_ai_created_at after insert on collection
+      begin
+        update collection set created_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now') where id = new.id;
+        update collection set updated_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now') where id = new.id;
+      end;
+
+    create trigger collection_au_updated_at after update on collection
+      begin
+        update collection set updated_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now') where id = new.id;
+      end;
+
+  create table objects_new (
+    id            text not null,
+    type          text not null,
+    created       text not null,
+    modified      text not null,
+    object        text not null,
+    collection_id text not null,
+    created_at    text,
+    updated_at    text,
+    spec_version  text not null default '2.0',
+    media_type    text not null default 'application/vnd.oasis.stix+json;version=2.0',
+    deleted_at    text,
+    deleted_by    text,
+
+    constraint valid_id check(id like '%--________-____-____-____-____________'),
+    constraint valid_json check(json_valid(object) = 1),
+
+    primary key (id, modified)$objectsKeys
+  );
+
+  insert into objects_new (id, type, created, modified, object, collection_id, created_at, updated_at, spec_version,
+                           media_type, deleted_at, deleted_by)
+    select id, type, created, modified, object, collection_id, created_at, updated_at, spec_version, media_type,
+           deleted_at, deleted_by
+    from objects;
+
+  drop table objects;
+  alter table objects_new rename to objects;
+
+    create trigger objects_ai_created_at after insert on objects
+      begin
+        update objects set created_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now') where id = new.id;
+        update objects set updated_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now') where id = new.id;
+      end;
+
+    create trigger objects_au_updated_at after update on objects
+      begin
+        update objects set updated_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now') where id = new.id;
+      end;
+
+    create index objects_id on objects (id);
+    create index objects_type on objects (type);
+    create index objects_version on objects (id, type, modified);
+    create index objects_collection_media_type on objects (collection_id, media_type);
+    create index objects_deleted_at on objects (deleted_at) where deleted_at is not null;
+
+` + objectsViews8() + `
+` + collectionMediaTypeTriggers8() + `
+  create table user_collection_new (
+    id            integer primary key not null,
+    email         text    not null,
+    collection_id text    not null,
+    can_read      integer check(can_read in (1, 0)) not null,
+    can_write     integer check(can_read in (1, 0)) not null,
+    created_at    text,
+    updated_at    text,
+
+    unique (email, collection_id) on conflict ignore$userCollectionKeys
+  );
+
+  insert into user_collection_new (id, email, collection_id, can_read, can_write, created_at, updated_at)
+    select id, email, collection_id, can_read, can_write, created_at, updated_at from user_collection;
+
+  drop table user_collection;
+  alter table user_collection_new rename to user_collection;
+
+    create trigger user_collection_ai_created_at after insert on user_collection
+      begin
+        update user_collection set created_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now') where email = new.email;
+        update user_collection set updated_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now') where email = new.email;
+      end;
+
+    create trigger user_collection_au_updated_at after update on user_collection
+      begin
+        update user_collection set updated_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now') where email = new.email;
+      end;
+
+  create table idempotency_key_new (
+    email           text not null,
+    collection_id   text not null,
+    idempotency_key text not null,
+    status_id       text not null,
+    created_at      text,
+
+    primary key (email, collection_id, idempotency_key)$idempotencyKeyKeys
+  );
+
+  insert into idempotency_key_new (email, collection_id, idempotency_key, status_id, created_at)
+    select email, collection_id, idempotency_key, status_id, created_at from idempotency_key;
+
+  drop table idempotency_key;
+  alter table idempotency_key_new rename to idempotency_key;
+
+    create trigger idempotency_key_ai_created_at after insert on idempotency_key
+      begin
+        update idempotency_key set created_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now')
+        where email = new.email and collection_id = new.collection_id and idempotency_key = new.idempotency_key;
+      end;
+  `
+
+	return strings.NewReplacer(
+		"$collectionKeys", constraint8(collectionKeys),
+		"$objectsKeys", constraint8(objectsKeys),
+		"$userCollectionKeys", constraint8(userCollectionKeys),
+		"$idempotencyKeyKeys", constraint8(idempotencyKeyKeys)).Replace(sql)
+}
+
+// collectionMediaTypeTriggers8 keeps the media types of collections in sync with their objects
+func collectionMediaTypeTriggers8() string {
+	return `
+    create trigger objects_ai_collection_media_types after insert on objects
+      begin
+        update collection
+        set media_types = case when media_types = '' then new.media_type
+                               else media_types || ',' || new.media_type
+                          end
+        where id = new.collection_id
+          and ',' || media_types || ',' not like '%,' || new.media_type || ',%';
+      end;
+
+    create trigger objects_ad_collection_media_types after delete on objects
+      when old.deleted_at is null
+        and not exists (
+          select 1 from objects where collection_id = old.collection_id and media_type = old.media_type and deleted_at is null
+        )
+      begin
+        update collection
+        set media_types = coalesce((
+          select group_concat(media_type)
+          from (
+            select distinct media_type from objects
+            where collection_id = old.collection_id and deleted_at is null
+            order by media_type
+          )
+        ), '')
+        where id = old.collection_id;
+      end;
+
+    create trigger objects_au_collection_media_types after update of deleted_at on objects
+      begin
+        update collection
+        set media_types = coalesce((
+          select group_concat(media_type)
+          from (
+            select distinct media_type from objects
+            where collection_id = new.collection_id and deleted_at is null
+            order by media_type
+          )
+        ), '')
+        where id = new.collection_id;
+      end;
+  `
+}
+
+// constraint8 adds a constraint to the end of a table definition
+func constraint8(constraint string) string {
+	if constraint == "" {
+		return ""
+	}
+	return ",\n\n    " + constraint
+}
+
+// objectsViews8 are the views on objects as of version 8
+func objectsViews8() string {
+	return `
+    create view objects_id_aggregate as
+      select rowid,
+             id,
+             type,
+             collection_id,
+             min(modified) first,
+             max(modified) last
+      from objects
+      where deleted_at is null
+      group by id,
+               type,
+               collection_id;
+
+    create view objects_data as
+      select
+        so.rowid,
+        so.id,
+        so.type,
+        so.created,
+        so.modified,
+        so.object,
+        so.collection_id,
+        case when so.modified = sa.first and so.modified = sa.last then 'only'
+             when so.modified = sa.last then 'last'
+             when so.modified = sa.first then 'first'
+        end version,
+        so.created_at,
+        so.updated_at,
+        so.spec_version,
+        so.media_type
+      from
+        objects so
+        left join objects_id_aggregate sa
+          on so.id = sa.id
+          and so.collection_id = sa.collection_id
+      where so.deleted_at is null;
+`
+}
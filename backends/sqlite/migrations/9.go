@@ -0,0 +1,90 @@
+package migrations
+
+// Up9 gets the database to version 9
+func Up9() string {
+	sql := `
+  -- retention policy of a collection; zero values don't limit anything
+  alter table collection add column retention_max_age_days integer not null default 0;
+  alter table collection add column retention_max_versions integer not null default 0;
+  alter table collection add column retention_drop_expired integer
+    check(retention_drop_expired in (0, 1)) not null default 0;
+
+  -- date_added is when a version was added; adding a version doesn't change when the others were added
+  drop trigger if exists objects_ai_created_at;
+
+  create trigger objects_ai_created_at after insert on objects
+    begin
+      update objects set created_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now') where rowid = new.rowid;
+      update objects set updated_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now') where rowid = new.rowid;
+    end;
+
+  -- update version
+  update schema_version set version = 9;
+  `
+	return sql
+}
+
+// Down9 takes the db down from 9
+func Down9() string {
+	sql := `
+  PRAGMA foreign_keys = OFF;
+
+  drop trigger if exists objects_ai_created_at;
+
+  create trigger objects_ai_created_at after insert on objects
+    begin
+      update objects set created_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now') where id = new.id;
+      update objects set updated_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now') where id = new.id;
+    end;
+
+  -- triggers on objects update the collection, so they go while it's rebuilt
+  drop trigger if exists objects_ai_collection_media_types;
+  drop trigger if exists objects_ad_collection_media_types;
+  drop trigger if exists objects_au_collection_media_types;
+
+  create table collection_8 (
+    id                   text not null primary key,
+    api_root_path        text not null,
+    title                text,
+    description          text,
+    media_types          text default '',
+    created_at           text,
+    updated_at           text,
+    anonymous_read       integer check(anonymous_read in (0, 1)) not null default 0,
+    ingest_media_types   text not null default '',
+    ingest_allowed_types text not null default '',
+    ingest_denied_types  text not null default '',
+    ingest_duplicates    text not null default 'reject',
+    deleted_at           text,
+    deleted_by           text,
+
+    foreign key (api_root_path) references api_root(api_root_path) on update cascade on delete cascade
+  );
+
+  insert into collection_8 (id, api_root_path, title, description, media_types, created_at, updated_at, anonymous_read,
+                            ingest_media_types, ingest_allowed_types, ingest_denied_types, ingest_duplicates,
+                            deleted_at, deleted_by)
+    select id, api_root_path, title, description, media_types, created_at, updated_at, anonymous_read,
+           ingest_media_types, ingest_allowed_types, ingest_denied_types, ingest_duplicates, deleted_at, deleted_by
+    from collection;
+
+  drop table collection;
+  alter table collection_8 rename to collection;
+
+    create trigger collection_ai_created_at after insert on collection
+      begin
+        update collection set created_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now') where id = new.id;
+        update collection set updated_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now') where id = new.id;
+      end;
+
+    create trigger collection_au_updated_at after update on collection
+      begin
+        update collection set updated_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now') where id = new.id;
+      end;
+  ` + collectionMediaTypeTriggers8() + `
+  PRAGMA foreign_keys = ON;
+
+  update schema_version set version = 8;
+  `
+	return sql
+}
@@ -14,10 +14,12 @@ import (
 )
 
 const (
-	createObjectSQL = `insert into objects (id, type, created, modified, object, collection_id)
-				             values (?, ?, ?, ?, ?, ?)`
-	deleteObjectSQL = `delete from objects where collection_id = ? and id = ?`
+	createObjectSQL = `insert into objects (id, type, created, modified, object, collection_id, spec_version, media_type,
+				                                 observables)
+				             values (?, ?, ?, ?, ?, ?, ?, ?, ?)`
 	batchBufferSize = 50
+	// statuses list the details of at most this many failures and successes; the counts cover every object
+	maxStatusDetails = 1000
 )
 
 // ObjectService implements a SQLite version of the ObjectService interface
@@ -26,7 +28,7 @@ type ObjectService struct {
 	DataStore *DataStore
 }
 
-// CreateEnvelope will read from the data store and return the resource
+// CreateEnvelope will write the objects of an envelope to the data store
 func (s ObjectService) CreateEnvelope(ctx context.Context, e cabby.Envelope, collectionID string, st cabby.Status, ss cabby.StatusService) {
 	resource, action := "Envelope", "create"
 	start := cabby.LogServiceStart(ctx, resource, action)
@@ -35,34 +37,70 @@ func (s ObjectService) CreateEnvelope(ctx context.Context, e cabby.Envelope, col
 }
 
 func (s ObjectService) createEnvelope(ctx context.Context, e cabby.Envelope, collectionID string, st cabby.Status, ss cabby.StatusService) {
-	errs := make(chan error, len(e.Objects))
+	objects := make(chan json.RawMessage, batchBufferSize)
+
+	go func() {
+		for _, raw := range e.Objects {
+			objects <- raw
+		}
+		close(objects)
+	}()
+
+	s.createObjects(ctx, objects, collectionID, st, ss)
+}
+
+// CreateObjects will write objects to the data store as they are received; when the channel is closed the status
+// is updated with the total number of objects received
+func (s ObjectService) CreateObjects(ctx context.Context, objects <-chan json.RawMessage, collectionID string, st cabby.Status, ss cabby.StatusService) {
+	resource, action := "Objects", "create"
+	start := cabby.LogServiceStart(ctx, resource, action)
+	s.createObjects(ctx, objects, collectionID, st, ss)
+	cabby.LogServiceEnd(ctx, resource, action, start)
+}
+
+func (s ObjectService) createObjects(ctx context.Context, objects <-chan json.RawMessage, collectionID string, st cabby.Status, ss cabby.StatusService) {
+	errs := make(chan error, batchBufferSize)
 	toWrite := make(chan interface{}, batchBufferSize)
+	writeFailures := make(chan int64)
 
-	go s.DataStore.batchWrite(createObjectSQL, toWrite, errs)
+	ic := s.newIngestCheck(collectionID)
 
-	for _, raw := range e.Objects {
-		var o stones.Object
-		err := json.Unmarshal(raw, &o)
-		if err != nil {
-			log.WithFields(log.Fields{"raw object": string(raw), "error": err}).Error("Failed to convert bytes to Object")
-			errs <- err
-			continue
-		}
+	go s.DataStore.batchWrite(ic.writeSQL(), toWrite, errs)
+	go countErrors(errs, writeFailures)
+
+	var total, failures int64
+	ic.checkAll(objects, func(r ingestResult) {
+		total++
 
-		valid, validationErrs := o.Valid()
-		if !valid {
-			err = stones.ErrorsToString(validationErrs)
-			log.WithFields(log.Fields{"raw object": string(raw), "error": err}).Error("Invalid object")
-			errs <- err
-			continue
+		if r.err != nil {
+			failures++
+			st.Failures = appendStatusDetails(st.Failures, cabby.NewStatusDetails(r.object, r.err))
+			return
+		}
+		if r.message != "" {
+			sd := cabby.NewStatusDetails(r.object, nil)
+			sd.Message = r.message
+			st.Successes = appendStatusDetails(st.Successes, sd)
+		}
+		if !r.write {
+			return
 		}
 
-		log.WithFields(log.Fields{"id": o.ID.String()}).Info("Sending to data store")
-		toWrite <- []interface{}{o.ID.String(), o.Type, o.Created.String(), o.Modified.String(), o.Source, collectionID}
-	}
+		log.WithFields(log.Fields{"id": r.object.ID.String()}).Info("Sending to data store")
+		toWrite <- objectArgs(r.object, collectionID, r.specVersion)
+	})
 	close(toWrite)
 
-	updateStatus(ctx, st, errs, ss)
+	// all objects are received, record the total while the writes finish
+	st.TotalCount = total
+	st.PendingCount = total
+	err := ss.UpdateStatus(ctx, st)
+	if err != nil {
+		log.WithFields(log.Fields{"error": err, "status": st}).Error("An error occured when updating the status")
+	}
+
+	st.FailureCount = failures + <-writeFailures
+	updateStatus(ctx, st, ss)
 }
 
 // CreateObject will create an object in the datastore
@@ -76,7 +114,7 @@ func (s ObjectService) CreateObject(ctx context.Context, collectionID string, ob
 
 func (s ObjectService) createObject(collectionID string, o stones.Object) error {
 	sql := createObjectSQL
-	args := []interface{}{o.ID.String(), o.Type, o.Created.String(), o.Modified.String(), o.Source, collectionID}
+	args := objectArgs(o, collectionID, cabby.ObjectSpecVersion(o.Source))
 
 	err := s.DataStore.write(createObjectSQL, args...)
 	if err != nil {
@@ -85,20 +123,31 @@ func (s ObjectService) createObject(collectionID string, o stones.Object) error
 	return err
 }
 
-// DeleteObject will delete an object from a collection
-func (s ObjectService) DeleteObject(ctx context.Context, collectionID, objectID string) error {
+// DeleteObject will move the versions of an object in a collection that match the filter to the trash
+func (s ObjectService) DeleteObject(ctx context.Context, collectionID, objectID string, f cabby.Filter) error {
 	resource, action := "Object", "delete"
 	start := cabby.LogServiceStart(ctx, resource, action)
-	err := s.deleteObject(collectionID, objectID)
+	err := s.deleteObject(cabby.TakeUser(ctx).Email, collectionID, objectID, f)
 	cabby.LogServiceEnd(ctx, resource, action, start)
 	return err
 }
 
-func (s ObjectService) deleteObject(collectionID, objectID string) error {
-	sql := deleteObjectSQL
-	args := []interface{}{collectionID, objectID}
+func (s ObjectService) deleteObject(user, collectionID, objectID string, f cabby.Filter) error {
+	sql := `update objects
+					set deleted_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now'), deleted_by = ?
+	        where rowid in (
+						select rowid
+						from objects_data
+						where
+							collection_id = ?
+							and id = ?
+							and $filter
+					)`
+
+	args := []interface{}{user, collectionID, objectID}
+	sql, args = applyFiltering(sql, f, args)
 
-	err := s.DataStore.write(deleteObjectSQL, args...)
+	err := s.DataStore.write(sql, args...)
 	if err != nil {
 		logSQLError(sql, args, err)
 	}
@@ -116,13 +165,14 @@ func (s ObjectService) Object(ctx context.Context, collectionID, objectID string
 
 func (s ObjectService) object(collectionID, objectID string, f cabby.Filter) ([]stones.Object, error) {
 	sql := `select id, type, created, modified, object
-	        from objects_data
+	        from $objectsData
 					where
 					  collection_id = ?
 						and id = ?
 						and $filter`
 
 	args := []interface{}{collectionID, objectID}
+	sql, args = applyAsOf(sql, f, args)
 	sql, args = applyFiltering(sql, f, args)
 
 	objects := []stones.Object{}
@@ -167,7 +217,7 @@ func (s ObjectService) Objects(ctx context.Context, collectionID string, p *cabb
 func (s ObjectService) objects(collectionID string, p *cabby.Page, f cabby.Filter) ([]stones.Object, error) {
 	sql := `with data as (
 						select rowid, id, type, created, modified, collection_id, object, created_at date_added, 1 count
-						from objects_data
+						from $objectsData
 						where
 							collection_id = ?
 							and $filter
@@ -182,6 +232,7 @@ func (s ObjectService) objects(collectionID string, p *cabby.Page, f cabby.Filte
 
 	args := []interface{}{collectionID}
 
+	sql, args = applyAsOf(sql, f, args)
 	sql, args = applyFiltering(sql, f, args)
 	sql, args = applyPaging(sql, p, args)
 
@@ -217,6 +268,36 @@ func (s ObjectService) objects(collectionID string, p *cabby.Page, f cabby.Filte
 	return objects, err
 }
 
+// ValidateObjects reads objects and checks them as CreateObjects would, but nothing is written; the result of each
+// object is reported when the channel is closed
+func (s ObjectService) ValidateObjects(ctx context.Context, objects <-chan json.RawMessage, collectionID string) cabby.ValidationReport {
+	resource, action := "Objects", "validate"
+	start := cabby.LogServiceStart(ctx, resource, action)
+	result := s.validateObjects(objects, collectionID)
+	cabby.LogServiceEnd(ctx, resource, action, start)
+	return result
+}
+
+func (s ObjectService) validateObjects(objects <-chan json.RawMessage, collectionID string) (vr cabby.ValidationReport) {
+	ic := s.newIngestCheck(collectionID)
+
+	ic.checkAll(objects, func(r ingestResult) {
+		vr.TotalCount++
+
+		if r.err != nil {
+			vr.FailureCount++
+			vr.Failures = appendStatusDetails(vr.Failures, cabby.NewStatusDetails(r.object, r.err))
+			return
+		}
+
+		vr.SuccessCount++
+		sd := cabby.NewStatusDetails(r.object, nil)
+		sd.Message = r.message
+		vr.Successes = appendStatusDetails(vr.Successes, sd)
+	})
+	return
+}
+
 /* helpers */
 
 func unmarshalObject(o stones.Object, id, created, modified string) (new stones.Object, err error) {
@@ -241,14 +322,59 @@ func unmarshalObject(o stones.Object, id, created, modified string) (new stones.
 	return
 }
 
-func updateStatus(ctx context.Context, st cabby.Status, errs chan error, ss cabby.StatusService) {
+// appendStatusDetails adds the details of an object unless there are already as many as a status lists
+func appendStatusDetails(details []cabby.StatusDetails, sd cabby.StatusDetails) []cabby.StatusDetails {
+	if len(details) >= maxStatusDetails {
+		return details
+	}
+	return append(details, sd)
+}
+
+func countErrors(errs chan error, count chan int64) {
+	var failures int64
 	for err := range errs {
 		if err != nil {
 			log.WithFields(log.Fields{"error": err}).Warn("Found an error")
-			st.FailureCount++
+			failures++
 		}
 	}
+	count <- failures
+}
+
+// objectArgs are the values written for an object version; the media type is kept with the version so manifests and
+// collections can list it, and the observables so it can be found by them
+func objectArgs(o stones.Object, collectionID, specVersion string) []interface{} {
+	return []interface{}{
+		o.ID.String(),
+		o.Type,
+		o.Created.String(),
+		o.Modified.String(),
+		o.Source,
+		collectionID,
+		specVersion,
+		cabby.SpecVersionMediaType(specVersion),
+		observablesToJSON(cabby.ExtractObservables(o.Source))}
+}
+
+// observablesToJSON returns observables as they're stored
+func observablesToJSON(observables []cabby.Observable) string {
+	b, err := json.Marshal(observables)
+	if err != nil {
+		log.WithFields(log.Fields{"observables": observables, "error": err}).Error("Unable to marshal observables")
+		return "[]"
+	}
+	return string(b)
+}
+
+func objectFromBytes(raw json.RawMessage) (o stones.Object, specVersion string, err error) {
+	o, specVersion, err = cabby.ObjectFromBytes(raw)
+	if err != nil {
+		log.WithFields(log.Fields{"raw object": string(raw), "error": err}).Error("Invalid object")
+	}
+	return
+}
 
+func updateStatus(ctx context.Context, st cabby.Status, ss cabby.StatusService) {
 	// don't allow more failures than objects that can be written; TODO: provide better status updates
 	if st.FailureCount > st.TotalCount {
 		st.FailureCount = st.TotalCount
@@ -4,9 +4,10 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"errors"
+	"fmt"
 	"io/ioutil"
 	"os"
+	"sort"
 	"strings"
 	"testing"
 	"time"
@@ -62,7 +63,7 @@ func TestObjectServiceDeleteObject(t *testing.T) {
 	}
 
 	// delete object
-	err = s.DeleteObject(context.Background(), tester.CollectionID, testObject.ID.String())
+	err = s.DeleteObject(context.Background(), tester.CollectionID, testObject.ID.String(), cabby.Filter{})
 	if err != nil {
 		t.Error("Got:", err)
 	}
@@ -78,6 +79,46 @@ func TestObjectServiceDeleteObject(t *testing.T) {
 	}
 }
 
+func TestObjectServiceDeleteObjectFilter(t *testing.T) {
+	tests := []struct {
+		filter            cabby.Filter
+		remainingVersions int
+	}{
+		{cabby.Filter{}, 0},
+		{cabby.Filter{Versions: "all"}, 0},
+		{cabby.Filter{Versions: "first"}, 2},
+		{cabby.Filter{Versions: "last"}, 2},
+		{cabby.Filter{Versions: "2016-04-06T20:03:48.000Z,2018-04-06T20:03:48.000Z"}, 1},
+		{cabby.Filter{SpecVersions: cabby.SpecVersion21}, 3},
+		{cabby.Filter{SpecVersions: cabby.SpecVersion20}, 0},
+	}
+
+	for _, test := range tests {
+		setupSQLite()
+		ds := testDataStore()
+		s := ds.ObjectService()
+
+		id, _ := stones.NewIdentifier("malware")
+		for _, version := range []string{"2016-04-06T20:03:48.000Z", "2017-04-06T20:03:48.000Z", "2018-04-06T20:03:48.000Z"} {
+			createObjectVersion(ds, id.String(), version)
+		}
+
+		err := s.DeleteObject(context.Background(), tester.CollectionID, id.String(), test.filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		results, err := s.Object(context.Background(), tester.CollectionID, id.String(), cabby.Filter{Versions: "all"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(results) != test.remainingVersions {
+			t.Error("Got:", len(results), "Expected:", test.remainingVersions, "Filter:", test.filter)
+		}
+	}
+}
+
 func TestObjectServiceDeleteObjectFail(t *testing.T) {
 	setupSQLite()
 	ds := testDataStore()
@@ -89,7 +130,7 @@ func TestObjectServiceDeleteObjectFail(t *testing.T) {
 	}
 
 	obj := tester.GenerateObject("malware")
-	err = s.DeleteObject(context.Background(), tester.CollectionID, obj.ID.String())
+	err = s.DeleteObject(context.Background(), tester.CollectionID, obj.ID.String(), cabby.Filter{})
 	if err == nil {
 		t.Error("Expected error")
 	}
@@ -200,7 +241,14 @@ func TestObjectsServiceObjectsFilter(t *testing.T) {
 		time.Sleep(100 * time.Millisecond)
 	}
 
-	ts, err := stones.TimestampFromString(versions[0])
+	// the last five objects added are the versions; four were added after the first one was
+	var firstAdded string
+	err := ds.DB.QueryRow("select created_at from objects order by created_at desc limit 1 offset 4").Scan(&firstAdded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts, err := stones.TimestampFromString(firstAdded)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -215,7 +263,7 @@ func TestObjectsServiceObjectsFilter(t *testing.T) {
 		{cabby.Filter{IDs: ids[0].String()}, 1},
 		{cabby.Filter{IDs: strings.Join([]string{ids[0].String(), ids[4].String(), ids[8].String()}, ",")}, 3},
 		{cabby.Filter{Versions: versions[2]}, 1},
-		{cabby.Filter{AddedAfter: ts}, 5},
+		{cabby.Filter{AddedAfter: ts}, 4},
 	}
 
 	cr := cabby.Page{}
@@ -244,6 +292,94 @@ func TestObjectsServiceObjectsFilter(t *testing.T) {
 	}
 }
 
+func TestObjectsServiceObjectsFilterProperties(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+	createLabeledVersion(t, ds)
+
+	tests := []struct {
+		filter          cabby.Filter
+		expectedObjects int
+	}{
+		// the last version is the labeled one
+		{cabby.Filter{Properties: map[string][]string{"labels": {"backdoor"}}}, 1},
+		{cabby.Filter{Properties: map[string][]string{"labels": {"backdoor"}}, Versions: "first"}, 0},
+		{cabby.Filter{Properties: map[string][]string{"labels": {"backdoor"}}, Types: "indicator"}, 0},
+		{cabby.Filter{Properties: map[string][]string{"created_by_ref": {"identity--f431f809-377b-45e0-aa1c-6a4751cae5ff"}},
+			Versions: "all"}, 1},
+	}
+
+	for _, test := range tests {
+		results, err := ds.ObjectService().Objects(context.Background(), tester.CollectionID, &cabby.Page{}, test.filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(results) != test.expectedObjects {
+			t.Error("Got:", len(results), "Expected:", test.expectedObjects, "Filter:", test.filter)
+		}
+	}
+}
+
+func TestObjectsServiceObjectsAsOf(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+	createOverlappingVersions(t, ds)
+
+	tests := []struct {
+		asOf     string
+		versions string
+		expected []string
+	}{
+		{"", "last", []string{"2017-01-01T00:00:00Z"}},
+		{"2018-12-01T00:00:00Z", "last", []string{}},
+		{"2019-01-15T00:00:00Z", "last", []string{"2016-04-06T20:07:09Z"}},
+		{"2019-01-15T00:00:00Z", "first", []string{"2016-04-06T20:07:09Z"}},
+		{"2019-02-15T00:00:00Z", "last", []string{"2018-01-01T00:00:00Z"}},
+		{"2019-02-15T00:00:00Z", "all", []string{"2016-04-06T20:07:09Z", "2018-01-01T00:00:00Z"}},
+		{"2019-03-15T00:00:00Z", "last", []string{"2018-01-01T00:00:00Z"}},
+		{"2019-03-15T00:00:00Z", "first", []string{"2016-04-06T20:07:09Z"}},
+		{"2019-03-15T00:00:00Z", "all",
+			[]string{"2016-04-06T20:07:09Z", "2017-01-01T00:00:00Z", "2018-01-01T00:00:00Z"}},
+		{"2019-04-15T00:00:00Z", "last", []string{"2017-01-01T00:00:00Z"}},
+		{"2019-04-15T00:00:00Z", "all", []string{"2016-04-06T20:07:09Z", "2017-01-01T00:00:00Z"}},
+	}
+
+	for _, test := range tests {
+		f := cabby.Filter{Versions: test.versions}
+		if test.asOf != "" {
+			asOf, err := stones.TimestampFromString(test.asOf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f.AsOf = asOf
+		}
+
+		results, err := ds.ObjectService().Objects(context.Background(), tester.CollectionID, &cabby.Page{}, f)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		versions := []string{}
+		for _, o := range results {
+			versions = append(versions, o.Modified.String())
+		}
+		sort.Strings(versions)
+
+		if strings.Join(versions, ",") != strings.Join(test.expected, ",") {
+			t.Error("Got:", versions, "Expected:", test.expected, "As of:", test.asOf, "Versions:", test.versions)
+		}
+
+		object, err := ds.ObjectService().Object(context.Background(), tester.CollectionID, tester.ObjectID, f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(object) != len(test.expected) {
+			t.Error("Got:", len(object), "Expected:", len(test.expected), "As of:", test.asOf)
+		}
+	}
+}
+
 func TestObjectsServiceObjectsPage(t *testing.T) {
 	setupSQLite()
 	ds := testDataStore()
@@ -368,6 +504,366 @@ func TestObjectServiceCreateEnvelopeWithInvalidObject(t *testing.T) {
 	}
 }
 
+func TestObjectServiceCreateObjects(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+	osv := ds.ObjectService()
+	ssv := ds.StatusService()
+
+	_, err := ds.DB.Exec("delete from objects")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	envelopeFile, _ := os.Open("testdata/invalid_objects_envelope.json")
+	content, _ := ioutil.ReadAll(envelopeFile)
+
+	var envelope cabby.Envelope
+	err = json.Unmarshal(content, &envelope)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the status starts out with a single pending object; the total is set once all objects are received
+	st, _ := cabby.NewStatus(1)
+	err = ssv.CreateStatus(context.Background(), st)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	objects := make(chan json.RawMessage)
+	go func() {
+		for _, raw := range envelope.Objects {
+			objects <- raw
+		}
+		close(objects)
+	}()
+
+	osv.CreateObjects(context.Background(), objects, tester.CollectionID, st, ssv)
+
+	result, err := ssv.Status(context.Background(), tester.APIRootPath, st.ID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.TotalCount != int64(len(envelope.Objects)) {
+		t.Error("Got:", result.TotalCount, "Expected:", len(envelope.Objects))
+	}
+	if result.SuccessCount != 2 {
+		t.Error("Got:", result.SuccessCount, "Expected:", 2)
+	}
+	if result.Status != "complete" {
+		t.Error("Got:", result.Status, "Expected: complete")
+	}
+}
+
+func TestObjectServiceCreateEnvelopeSpecVersions(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+	osv := ds.ObjectService()
+	ssv := ds.StatusService()
+
+	_, err := ds.DB.Exec("delete from objects")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	envelopeFile, _ := os.Open("testdata/stix21_envelope.json")
+	content, _ := ioutil.ReadAll(envelopeFile)
+
+	var envelope cabby.Envelope
+	err = json.Unmarshal(content, &envelope)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	st := tester.Status
+	osv.CreateEnvelope(context.Background(), envelope, tester.CollectionID, st, ssv)
+
+	tests := []struct {
+		filter          cabby.Filter
+		expectedObjects int
+	}{
+		{cabby.Filter{}, 3},
+		{cabby.Filter{SpecVersions: cabby.SpecVersion20}, 1},
+		{cabby.Filter{SpecVersions: cabby.SpecVersion21}, 2},
+		{cabby.Filter{SpecVersions: "2.0,2.1"}, 3},
+		{cabby.Filter{SpecVersions: cabby.SpecVersion21, Types: "ipv4-addr"}, 1},
+	}
+
+	for _, test := range tests {
+		results, err := osv.Objects(context.Background(), tester.CollectionID, &cabby.Page{}, test.filter)
+		if err != nil {
+			t.Error("Got:", err, "Expected no error")
+		}
+
+		if len(results) != test.expectedObjects {
+			t.Error("Got:", len(results), "Expected:", test.expectedObjects, "Filter:", test.filter)
+		}
+	}
+
+	// cyber observables without 'created' or 'modified' are all the same version
+	results, err := osv.Object(
+		context.Background(), tester.CollectionID, "ipv4-addr--ff26c055-6336-5bc5-b98d-13d6226742dd", cabby.Filter{})
+	if err != nil || len(results) != 1 {
+		t.Fatal("Got:", results, err, "Expected: 1 object")
+	}
+	if results[0].Modified.IsZero() || results[0].Modified != results[0].Created {
+		t.Error("Got:", results[0].Modified, "Expected:", results[0].Created)
+	}
+}
+
+func TestObjectServiceCreateEnvelopeIngestPolicy(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+	osv := ds.ObjectService()
+	ssv := ds.StatusService()
+
+	_, err := ds.DB.Exec("delete from objects")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := tester.Collection
+	c.IngestPolicy = cabby.IngestPolicy{MediaTypes: []string{cabby.StixMediaType}, DeniedTypes: []string{"ipv4-addr"}}
+	err = ds.CollectionService().UpdateCollection(context.Background(), c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	envelopeFile, _ := os.Open("testdata/stix21_envelope.json")
+	content, _ := ioutil.ReadAll(envelopeFile)
+
+	var envelope cabby.Envelope
+	err = json.Unmarshal(content, &envelope)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	st, _ := cabby.NewStatus(len(envelope.Objects))
+	err = ssv.CreateStatus(context.Background(), st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	osv.CreateEnvelope(context.Background(), envelope, tester.CollectionID, st, ssv)
+
+	results, _ := osv.Objects(context.Background(), tester.CollectionID, &cabby.Page{}, cabby.Filter{})
+	if len(results) != 1 || results[0].Type != "indicator" {
+		t.Error("Got:", results, "Expected: only the indicator")
+	}
+
+	result, err := ssv.Status(context.Background(), tester.APIRootPath, st.ID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []cabby.StatusDetails{
+		{ID: "malware--fdd60b30-b67c-41e3-b0b9-f01faf20d111", Version: "2016-04-06T20:07:09Z",
+			Message: "Media type 'application/vnd.oasis.stix+json;version=2.0' isn't accepted by the collection, " +
+				"accepted media types: application/stix+json"},
+		{ID: "ipv4-addr--ff26c055-6336-5bc5-b98d-13d6226742dd", Message: "Type 'ipv4-addr' is denied by the collection"}}
+
+	if result.FailureCount != 2 || len(result.Failures) != len(expected) {
+		t.Fatal("Got:", result, "Expected failures:", expected)
+	}
+	for i, f := range expected {
+		if result.Failures[i].ID != f.ID || result.Failures[i].Message != f.Message {
+			t.Error("Got:", result.Failures[i], "Expected:", f)
+		}
+	}
+	if result.Failures[0].Version != expected[0].Version {
+		t.Error("Got:", result.Failures[0].Version, "Expected:", expected[0].Version)
+	}
+}
+
+func TestObjectServiceCreateEnvelopeDuplicates(t *testing.T) {
+	malware := json.RawMessage(`{"type": "malware", "id": "malware--fdd60b30-b67c-41e3-b0b9-f01faf20d111",
+		"created": "2016-04-06T20:07:09.000Z", "modified": "2016-04-06T20:07:09.000Z", "name": "Poison Ivy"}`)
+	indicator := json.RawMessage(`{"type": "indicator", "id": "indicator--e2e1a340-4415-4ba8-9671-f7343fbf0836",
+		"created": "2017-01-27T13:49:53.935Z", "modified": "2017-01-27T13:49:53.935Z", "labels": ["malicious-activity"],
+		"pattern": "[url:value = 'http://x4z9arb.cn/4712']", "valid_from": "2017-01-27T13:49:53.935Z"}`)
+	// the same malware formatted differently and an indicator with different content
+	sameMalware := json.RawMessage(`{"name": "Poison Ivy", "type": "malware", "id": "malware--fdd60b30-b67c-41e3-b0b9-f01faf20d111",
+		"modified": "2016-04-06T20:07:09.000Z", "created": "2016-04-06T20:07:09.000Z"}`)
+	changedIndicator := json.RawMessage(`{"type": "indicator", "id": "indicator--e2e1a340-4415-4ba8-9671-f7343fbf0836",
+		"created": "2017-01-27T13:49:53.935Z", "modified": "2017-01-27T13:49:53.935Z", "labels": ["benign"],
+		"pattern": "[url:value = 'http://x4z9arb.cn/4712']", "valid_from": "2017-01-27T13:49:53.935Z"}`)
+
+	tests := []struct {
+		duplicates        string
+		expectedSuccesses []string
+		expectedFailures  []string
+		expectedLabel     string
+	}{
+		{cabby.DuplicateReject, []string{},
+			[]string{"Object version already exists", "Object version already exists"}, "malicious-activity"},
+		{cabby.DuplicateIdentical, []string{"Object version already exists with the same content"},
+			[]string{"Object version already exists with different content"}, "malicious-activity"},
+		{cabby.DuplicateOverwrite,
+			[]string{"Object version overwrites the existing one", "Object version overwrites the existing one"},
+			[]string{}, "benign"},
+	}
+
+	for _, test := range tests {
+		setupSQLite()
+		ds := testDataStore()
+
+		_, err := ds.DB.Exec("delete from objects")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		c := tester.Collection
+		c.IngestPolicy = cabby.IngestPolicy{Duplicates: test.duplicates}
+		err = ds.CollectionService().UpdateCollection(context.Background(), c)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		postEnvelope(ds, cabby.Envelope{Objects: []json.RawMessage{malware, indicator}})
+		result := postEnvelope(ds, cabby.Envelope{Objects: []json.RawMessage{sameMalware, changedIndicator}})
+
+		if len(result.Successes) != len(test.expectedSuccesses) || len(result.Failures) != len(test.expectedFailures) {
+			t.Fatal("Got:", result, "Expected successes:", test.expectedSuccesses, "failures:", test.expectedFailures)
+		}
+		for i, s := range result.Successes {
+			if s.Message != test.expectedSuccesses[i] {
+				t.Error("Got:", s.Message, "Expected:", test.expectedSuccesses[i], "Duplicates:", test.duplicates)
+			}
+		}
+		for i, f := range result.Failures {
+			if f.Message != test.expectedFailures[i] {
+				t.Error("Got:", f.Message, "Expected:", test.expectedFailures[i], "Duplicates:", test.duplicates)
+			}
+		}
+		if result.FailureCount != int64(len(test.expectedFailures)) || result.SuccessCount != 2-result.FailureCount {
+			t.Error("Got:", result, "Expected failures:", len(test.expectedFailures))
+		}
+
+		var label string
+		err = ds.DB.QueryRow(`select json_extract(object, '$.labels[0]') from objects where type = 'indicator'`).Scan(&label)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if label != test.expectedLabel {
+			t.Error("Got:", label, "Expected:", test.expectedLabel, "Duplicates:", test.duplicates)
+		}
+	}
+}
+
+func TestObjectServiceCreateEnvelopeDuplicateBatches(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+
+	_, err := ds.DB.Exec("delete from objects")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the first object is repeated in the next batch and written once
+	e := cabby.Envelope{}
+	for i := 0; i <= batchBufferSize; i++ {
+		e.Objects = append(e.Objects, json.RawMessage(fmt.Sprintf(`{"type": "malware",
+			"id": "malware--fdd60b30-b67c-41e3-b0b9-f01faf20d%03d", "created": "2016-04-06T20:07:09.000Z",
+			"modified": "2016-04-06T20:07:09.000Z", "name": "Poison Ivy"}`, i%batchBufferSize)))
+	}
+
+	result := postEnvelope(ds, e)
+
+	if result.TotalCount != batchBufferSize+1 || result.FailureCount != 1 {
+		t.Error("Got:", result, "Expected:", batchBufferSize+1, "objects with 1 failure")
+	}
+
+	var count int
+	err = ds.DB.QueryRow("select count(*) from objects").Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != batchBufferSize {
+		t.Error("Got:", count, "Expected:", batchBufferSize)
+	}
+}
+
+func TestObjectServiceValidateObjects(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+	osv := ds.ObjectService()
+
+	_, err := ds.DB.Exec("delete from objects")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	envelopeFile, _ := os.Open("testdata/stix21_envelope.json")
+	content, _ := ioutil.ReadAll(envelopeFile)
+
+	var envelope cabby.Envelope
+	err = json.Unmarshal(content, &envelope)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the malware is already in the data store
+	existing, _, err := cabby.ObjectFromBytes(envelope.Objects[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = osv.CreateObject(context.Background(), tester.CollectionID, existing)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	objects := make(chan json.RawMessage, 10)
+	for _, raw := range append(envelope.Objects, envelope.Objects[1], json.RawMessage(`{"type": "malware"}`)) {
+		objects <- raw
+	}
+	close(objects)
+
+	result := osv.ValidateObjects(context.Background(), objects, tester.CollectionID)
+
+	if result.TotalCount != 5 || result.SuccessCount != 2 || result.FailureCount != 3 {
+		t.Error("Got:", result, "Expected: 5 objects with 2 successes and 3 failures")
+	}
+
+	expected := []string{
+		"Object version already exists", "Object version is in the envelope more than once", "Invalid 'Identifier'"}
+	for i, f := range result.Failures {
+		if !strings.HasPrefix(f.Message, expected[i]) {
+			t.Error("Got:", f.Message, "Expected:", expected[i])
+		}
+	}
+
+	// nothing is written
+	var count int
+	err = ds.DB.QueryRow("select count(*) from objects").Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Error("Got:", count, "Expected:", 1)
+	}
+}
+
+func TestObjectServiceValidateObjectsDetailsLimit(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+
+	objects := make(chan json.RawMessage, maxStatusDetails+1)
+	for i := 0; i <= maxStatusDetails; i++ {
+		objects <- json.RawMessage(`{"type": "malware"}`)
+	}
+	close(objects)
+
+	result := ds.ObjectService().ValidateObjects(context.Background(), objects, tester.CollectionID)
+
+	if result.FailureCount != maxStatusDetails+1 {
+		t.Error("Got:", result.FailureCount, "Expected:", maxStatusDetails+1)
+	}
+	if len(result.Failures) != maxStatusDetails {
+		t.Error("Got:", len(result.Failures), "Expected:", maxStatusDetails)
+	}
+}
+
 func TestObjectServiceInvalidIDs(t *testing.T) {
 	setupSQLite()
 	ds := testDataStore()
@@ -448,18 +944,14 @@ func TestUpdateStatusNoError(t *testing.T) {
 	}
 
 	// check the pending status
-	result, _ := ss.Status(context.Background(), expected.ID.String())
+	result, _ := ss.Status(context.Background(), tester.APIRootPath, expected.ID.String())
 	passed := tester.CompareStatus(result, expected)
 	if !passed {
 		t.Error("Comparison failed")
 	}
 
-	// no errors
-	errs := make(chan error, 10)
-	close(errs)
-
-	// updating implies complete
-	updateStatus(context.Background(), expected, errs, ss)
+	// updating with no failures implies complete
+	updateStatus(context.Background(), expected, ss)
 
 	expected.FailureCount = 0
 	expected.PendingCount = 0
@@ -467,7 +959,7 @@ func TestUpdateStatusNoError(t *testing.T) {
 	expected.Status = "complete"
 
 	// query the status to confirm it's accurate
-	result, _ = ss.Status(context.Background(), expected.ID.String())
+	result, _ = ss.Status(context.Background(), tester.APIRootPath, expected.ID.String())
 	passed = tester.CompareStatus(result, expected)
 	if !passed {
 		t.Error("Comparison failed")
@@ -492,19 +984,18 @@ func TestUpdateStatusWithError(t *testing.T) {
 	}
 
 	// check the pending status
-	result, _ := ss.Status(context.Background(), expected.ID.String())
+	result, _ := ss.Status(context.Background(), tester.APIRootPath, expected.ID.String())
 	passed := tester.CompareStatus(result, expected)
 	if !passed {
 		t.Error("Comparison failed")
 	}
 
 	// assume one object failed to write
-	errs := make(chan error, 10)
-	errs <- errors.New("an error")
-	close(errs)
+	st := expected
+	st.FailureCount = 1
 
 	// updating implies complete
-	updateStatus(context.Background(), expected, errs, ss)
+	updateStatus(context.Background(), st, ss)
 
 	expected.FailureCount = 1
 	expected.PendingCount = 0
@@ -512,7 +1003,7 @@ func TestUpdateStatusWithError(t *testing.T) {
 	expected.Status = "complete"
 
 	// query the status to confirm it's accurate
-	result, _ = ss.Status(context.Background(), expected.ID.String())
+	result, _ = ss.Status(context.Background(), tester.APIRootPath, expected.ID.String())
 	passed = tester.CompareStatus(result, expected)
 	if !passed {
 		t.Error("Comparison failed")
@@ -537,20 +1028,18 @@ func TestUpdateStatusWithTooManyErrors(t *testing.T) {
 	}
 
 	// check the pending status
-	result, _ := ss.Status(context.Background(), expected.ID.String())
+	result, _ := ss.Status(context.Background(), tester.APIRootPath, expected.ID.String())
 	passed := tester.CompareStatus(result, expected)
 	if !passed {
 		t.Error("Comparison failed")
 	}
 
-	// create more errors than objects
-	errs := make(chan error, 10)
-	errs <- errors.New("an error")
-	errs <- errors.New("an error")
-	close(errs)
+	// create more failures than objects
+	st := expected
+	st.FailureCount = 2
 
 	// updating implies complete
-	updateStatus(context.Background(), expected, errs, ss)
+	updateStatus(context.Background(), st, ss)
 
 	expected.FailureCount = 1
 	expected.PendingCount = 0
@@ -558,7 +1047,7 @@ func TestUpdateStatusWithTooManyErrors(t *testing.T) {
 	expected.Status = "complete"
 
 	// query the status to confirm it's accurate
-	result, _ = ss.Status(context.Background(), expected.ID.String())
+	result, _ = ss.Status(context.Background(), tester.APIRootPath, expected.ID.String())
 	passed = tester.CompareStatus(result, expected)
 	if !passed {
 		t.Error("Comparison failed")
@@ -593,10 +1082,9 @@ func TestUpdateStatusFail(t *testing.T) {
 	}
 
 	// assume one object failed to write
-	errs := make(chan error, 10)
-	errs <- errors.New("an error")
-	close(errs)
-	updateStatus(context.Background(), tester.Status, errs, ss)
+	st := tester.Status
+	st.FailureCount = 1
+	updateStatus(context.Background(), st, ss)
 
 	// parse log into struct
 	var result tester.ErrorLog
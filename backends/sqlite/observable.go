@@ -0,0 +1,169 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	// import sqlite dependency
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/pladdy/cabby"
+	"github.com/pladdy/stones"
+)
+
+// ObservableService implements a SQLite version of the ObservableService interface
+type ObservableService struct {
+	DB        *sql.DB
+	DataStore *DataStore
+}
+
+// IndexObservables extracts the observables of objects added before they were indexed; it returns how many objects
+// were indexed
+func (s ObservableService) IndexObservables(ctx context.Context) (int64, error) {
+	resource, action := "Observables", "index"
+	start := cabby.LogServiceStart(ctx, resource, action)
+	result, err := s.indexObservables(maxWritesPerBatch)
+	cabby.LogServiceEnd(ctx, resource, action, start)
+	return result, err
+}
+
+// objects are indexed in batches so requests aren't blocked behind the writes
+func (s ObservableService) indexObservables(batchSize int) (indexed int64, err error) {
+	for {
+		statements, err := s.unindexedObjects(batchSize)
+		if err != nil || len(statements) == 0 {
+			return indexed, err
+		}
+
+		if err = s.DataStore.writeAll(statements...); err != nil {
+			return indexed, err
+		}
+		indexed += int64(len(statements))
+	}
+}
+
+// unindexedObjects returns the statements that index the observables of objects that haven't been
+func (s ObservableService) unindexedObjects(limit int) ([]statement, error) {
+	sql := `select id, modified, object from objects where observables is null limit ?`
+	args := []interface{}{limit}
+
+	statements := []statement{}
+
+	rows, err := s.DB.Query(sql, args...)
+	if err != nil {
+		logSQLError(sql, args, err)
+		return statements, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, modified string
+		var object []byte
+
+		if err := rows.Scan(&id, &modified, &object); err != nil {
+			return statements, err
+		}
+
+		statements = append(statements, statement{
+			`update objects set observables = ? where id = ? and modified = ?`,
+			[]interface{}{observablesToJSON(cabby.ExtractObservables(object)), id, modified}})
+	}
+
+	err = rows.Err()
+	return statements, err
+}
+
+// ObservableMatches returns the object versions with any of the observable values in the collections the user can read
+func (s ObservableService) ObservableMatches(ctx context.Context, values []string, p *cabby.Page) (cabby.ObservableMatches, error) {
+	resource, action := "ObservableMatches", "read"
+	start := cabby.LogServiceStart(ctx, resource, action)
+	result, err := s.observableMatches(cabby.TakeUser(ctx), values, p)
+	cabby.LogServiceEnd(ctx, resource, action, start)
+	return result, err
+}
+
+func (s ObservableService) observableMatches(u cabby.User, values []string, p *cabby.Page) (cabby.ObservableMatches, error) {
+	om := cabby.ObservableMatches{}
+
+	if len(values) == 0 {
+		return om, nil
+	}
+
+	sql := `with data as (
+						select
+							c.api_root_path, o.collection_id, o.id, o.modified, ob.type, ob.value, o.object, 1 count
+						from
+							observable ob
+							inner join objects o
+								on ob.object_id = o.id
+								and ob.modified = o.modified
+							inner join collection c
+								on o.collection_id = c.id
+						where
+							ob.value collate nocase in ($values)
+							and $readable
+							and o.deleted_at is null
+							and c.deleted_at is null
+					)
+					select api_root_path, collection_id, id, modified, type, value, object, (select sum(count) from data) total
+					from data
+					order by collection_id, id, modified desc, type, value
+					$paginate`
+
+	sql = strings.Replace(sql, "$values", placeholders(len(values)), -1)
+	sql = strings.Replace(sql, "$readable", readableCollectionsSQL, -1)
+
+	args := []interface{}{}
+	for _, v := range values {
+		args = append(args, v)
+	}
+	args = append(args, readableCollectionsArgs(u)...)
+	sql, args = applyPaging(sql, p, args)
+
+	rows, err := s.DB.Query(sql, args...)
+	if err != nil {
+		logSQLError(sql, args, err)
+		return om, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var m cabby.ObservableMatch
+		var modified, object string
+
+		if err := rows.Scan(&m.APIRootPath, &m.CollectionID, &m.ID, &modified, &m.Observable.Type, &m.Observable.Value,
+			&object, &p.Total); err != nil {
+			return om, err
+		}
+
+		m.Version, err = stones.TimestampFromString(modified)
+		if err != nil {
+			return om, err
+		}
+		m.Object = []byte(object)
+
+		om.Objects = append(om.Objects, m)
+	}
+
+	err = rows.Err()
+	return om, err
+}
+
+/* helpers */
+
+// readableCollectionsSQL is true for the collections, aliased 'c', a user can read: admins read every collection,
+// other users the ones they're given read access to and the ones anyone can read
+const readableCollectionsSQL = `(? = 1
+							 or c.anonymous_read = 1
+							 or c.id in (select collection_id from user_collection where email = ? and can_read = 1))`
+
+// readableCollectionsArgs are the args of readableCollectionsSQL for a user
+func readableCollectionsArgs(u cabby.User) []interface{} {
+	return []interface{}{u.CanAdmin, u.Email}
+}
+
+// placeholders returns the parameters of an 'in' list, IE: "?, ?, ?"
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
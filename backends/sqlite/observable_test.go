@@ -0,0 +1,211 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pladdy/cabby"
+	"github.com/pladdy/cabby/tester"
+)
+
+const (
+	observableIndicatorID = "indicator--e2e1a340-4415-4ba8-9671-f7343fbf0836"
+	observableAddressID   = "ipv4-addr--ff26c055-6336-5bc5-b98d-13d6226742dd"
+	otherCollectionID     = "8f0e6a1c-4c1e-4ab4-9d4e-0d1a1d6e0b6a"
+)
+
+func createObservableObjects(t *testing.T, ds *DataStore) {
+	createRawObject(t, ds, tester.CollectionID, `{"type": "indicator", "spec_version": "2.1", "id": "`+
+		observableIndicatorID+`", "created": "2019-01-01T00:00:00.000Z", "modified": "2019-01-01T00:00:00.000Z",
+		"pattern": "[ipv4-addr:value = '203.0.113.7'] AND [file:hashes.'SHA-256' = 'AEC07045']"}`)
+	createRawObject(t, ds, tester.CollectionID, `{"type": "ipv4-addr", "spec_version": "2.1", "id": "`+
+		observableAddressID+`", "value": "203.0.113.7"}`)
+
+	// the user can't read the other collection
+	createCollection(ds, otherCollectionID)
+	if err := ds.UserService().DeleteUserCollection(context.Background(), tester.UserEmail, otherCollectionID); err != nil {
+		t.Fatal(err)
+	}
+	createRawObject(t, ds, otherCollectionID, `{"type": "indicator", "spec_version": "2.1",
+		"id": "indicator--0b6a0d8e-2a8c-4f26-9b5a-5f6e8a1b2c3d", "created": "2019-01-01T00:00:00.000Z",
+		"modified": "2019-01-01T00:00:00.000Z", "pattern": "[ipv4-addr:value = '203.0.113.7']"}`)
+}
+
+func TestObservableServiceObservableMatches(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+	createObservableObjects(t, ds)
+
+	noAccess := cabby.WithUser(context.Background(), cabby.User{Email: "other@cabby.com"})
+
+	tests := []struct {
+		ctx           context.Context
+		values        []string
+		limit         uint64
+		expectedIDs   []string
+		expectedTotal uint64
+	}{
+		{readerContext(), []string{"203.0.113.7"}, 0, []string{observableIndicatorID, observableAddressID}, 2},
+		{readerContext(), []string{"203.0.113.7"}, 1, []string{observableIndicatorID}, 2},
+		{readerContext(), []string{"aec07045"}, 0, []string{observableIndicatorID}, 1},
+		{readerContext(), []string{"aec07045", "198.51.100.1"}, 0, []string{observableIndicatorID}, 1},
+		{readerContext(), []string{"198.51.100.1"}, 0, []string{}, 0},
+		{readerContext(), []string{}, 0, []string{}, 0},
+		{noAccess, []string{"203.0.113.7"}, 0, []string{}, 0},
+	}
+
+	for _, test := range tests {
+		p := cabby.Page{Limit: test.limit}
+
+		result, err := ds.ObservableService().ObservableMatches(test.ctx, test.values, &p)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(result.Objects) != len(test.expectedIDs) {
+			t.Fatal("Got:", result.Objects, "Expected:", test.expectedIDs, "Values:", test.values)
+		}
+		for i, m := range result.Objects {
+			if m.ID != test.expectedIDs[i] || m.CollectionID != tester.CollectionID || m.APIRootPath != tester.APIRootPath {
+				t.Error("Got:", m, "Expected:", test.expectedIDs[i], "Values:", test.values)
+			}
+		}
+		if p.Total != test.expectedTotal {
+			t.Error("Got:", p.Total, "Expected:", test.expectedTotal, "Values:", test.values)
+		}
+	}
+}
+
+func TestObservableServiceObservableMatchesAdmin(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+	createObservableObjects(t, ds)
+
+	// admins read every collection
+	result, err := ds.ObservableService().ObservableMatches(tester.Context, []string{"203.0.113.7"}, &cabby.Page{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Objects) != 3 {
+		t.Error("Got:", len(result.Objects), "Expected:", 3)
+	}
+}
+
+func TestObservableServiceObservableMatchesRemoved(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+	createObservableObjects(t, ds)
+
+	matches := func() int {
+		result, err := ds.ObservableService().ObservableMatches(readerContext(), []string{"203.0.113.7"}, &cabby.Page{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(result.Objects)
+	}
+
+	// versions in the trash aren't matched
+	err := ds.ObjectService().DeleteObject(tester.Context, tester.CollectionID, observableAddressID,
+		cabby.Filter{Versions: "all"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if matches() != 1 {
+		t.Error("Got:", matches(), "Expected:", 1)
+	}
+
+	// new observables replace the old ones
+	_, err = ds.DB.Exec(`update objects set observables = '[{"type": "ipv4-addr", "value": "198.51.100.1"}]'
+											 where id = ?`, observableIndicatorID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if matches() != 0 {
+		t.Error("Got:", matches(), "Expected:", 0)
+	}
+
+	// removed versions take their observables with them
+	_, err = ds.DB.Exec(`delete from objects`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var count int
+	err = ds.DB.QueryRow(`select count(*) from observable`).Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Error("Got:", count, "Expected:", 0)
+	}
+}
+
+func TestObservableServiceObservableMatchesQueryErr(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+
+	_, err := ds.DB.Exec("drop table observable")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ds.ObservableService().ObservableMatches(readerContext(), []string{"203.0.113.7"}, &cabby.Page{})
+	if err == nil {
+		t.Error("Expected an error")
+	}
+}
+
+func TestObservableServiceIndexObservables(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+	createObservableObjects(t, ds)
+
+	// objects added before observables were indexed don't have them
+	_, err := ds.DB.Exec(`update objects set observables = null`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	indexed, err := ds.ObservableService().(ObservableService).indexObservables(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if indexed != 4 {
+		t.Error("Got:", indexed, "Expected:", 4)
+	}
+
+	result, err := ds.ObservableService().ObservableMatches(readerContext(), []string{"203.0.113.7"}, &cabby.Page{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Objects) != 2 {
+		t.Error("Got:", len(result.Objects), "Expected:", 2)
+	}
+
+	indexed, err = ds.ObservableService().IndexObservables(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if indexed != 0 {
+		t.Error("Got:", indexed, "Expected: nothing left to index")
+	}
+}
+
+func TestObservableServiceIndexObservablesQueryErr(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+
+	_, err := ds.DB.Exec(`update objects set observables = null`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ds.DB.Exec("drop table observable")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ds.ObservableService().IndexObservables(context.Background())
+	if err == nil {
+		t.Error("Expected an error")
+	}
+}
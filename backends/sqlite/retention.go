@@ -0,0 +1,198 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	// import sqlite dependency
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/pladdy/cabby"
+)
+
+// each rule selects the rowids of the object versions it moves to the trash; versions already in the trash are left to
+// the trash purge
+const (
+	maxAgeSQL = `select rowid from objects
+							 where
+								 collection_id = ?
+								 and deleted_at is null
+								 and created_at < strftime('%Y-%m-%dT%H:%M:%fZ', 'now', ?)`
+
+	maxVersionsSQL = `select row_id from (
+									select
+										rowid row_id,
+										row_number() over (partition by id order by modified desc) version
+									from objects
+									where collection_id = ? and deleted_at is null
+								)
+								where version > ?`
+
+	expiredSQL = `select rowid from objects
+								where
+									collection_id = ?
+									and deleted_at is null
+									and json_extract(object, '$.valid_until') < strftime('%Y-%m-%dT%H:%M:%fZ', 'now')`
+)
+
+// retentionDeletedBy is who versions trashed by a retention policy are deleted by
+const retentionDeletedBy = "retention"
+
+// execer executes a statement on the database or in a transaction
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// batchExecer executes each statement in a write transaction of its own, so other writes can run between them
+type batchExecer struct {
+	dataStore *DataStore
+}
+
+func (e batchExecer) Exec(query string, args ...interface{}) (sql.Result, error) {
+	tx, err := e.dataStore.begin()
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := tx.Exec(query, args...)
+	if err != nil {
+		/* #nosec G104 */
+		tx.Rollback()
+		return nil, err
+	}
+	return result, tx.Commit()
+}
+
+// RetentionService implements a SQLite version of the RetentionService interface
+type RetentionService struct {
+	DB        *sql.DB
+	DataStore *DataStore
+}
+
+// EnforceRetention moves the object versions the retention policies of the collections don't keep to the trash, where
+// they're purged like any other deleted version; a dry run counts what would be removed without removing it
+func (s RetentionService) EnforceRetention(ctx context.Context, dryRun bool) ([]cabby.Retention, error) {
+	resource, action := "Retention", "enforce"
+	start := cabby.LogServiceStart(ctx, resource, action)
+	result, err := s.enforceRetention(dryRun)
+	cabby.LogServiceEnd(ctx, resource, action, start)
+	return result, err
+}
+
+func (s RetentionService) enforceRetention(dryRun bool) ([]cabby.Retention, error) {
+	rs := []cabby.Retention{}
+
+	cs, err := s.retentionPolicies()
+	if err != nil {
+		return rs, err
+	}
+
+	for _, c := range cs {
+		r, err := s.enforcePolicy(c.ID.String(), c.RetentionPolicy, dryRun)
+		if err != nil {
+			return rs, err
+		}
+		rs = append(rs, r)
+	}
+	return rs, nil
+}
+
+// each batch of object versions is trashed in its own write transaction; a dry run trashes them all in one that's
+// rolled back
+func (s RetentionService) enforcePolicy(collectionID string, p cabby.RetentionPolicy, dryRun bool) (cabby.Retention, error) {
+	r := cabby.Retention{CollectionID: collectionID}
+
+	var e execer = batchExecer{dataStore: s.DataStore}
+	if dryRun {
+		tx, err := s.DataStore.begin()
+		if err != nil {
+			return r, err
+		}
+		/* #nosec G104 */
+		defer tx.Rollback()
+		e = tx
+	}
+
+	rules := []struct {
+		enforced bool
+		sql      string
+		args     []interface{}
+		count    *int64
+	}{
+		{p.MaxAgeDays > 0, maxAgeSQL, []interface{}{collectionID, fmt.Sprintf("-%d days", p.MaxAgeDays)}, &r.MaxAge},
+		{p.MaxVersions > 0, maxVersionsSQL, []interface{}{collectionID, p.MaxVersions}, &r.MaxVersions},
+		{p.DropExpired, expiredSQL, []interface{}{collectionID}, &r.Expired},
+	}
+
+	for _, rule := range rules {
+		if !rule.enforced {
+			continue
+		}
+
+		count, err := trashInBatches(e, maxWritesPerBatch, rule.sql, rule.args...)
+		*rule.count = count
+		if err != nil {
+			return r, err
+		}
+	}
+	return r, nil
+}
+
+func (s RetentionService) retentionPolicies() ([]cabby.Collection, error) {
+	sql := `select id, retention_max_age_days, retention_max_versions, retention_drop_expired
+					from collection
+					where
+						deleted_at is null
+						and (retention_max_age_days > 0 or retention_max_versions > 0 or retention_drop_expired = 1)
+					order by id`
+	args := []interface{}{}
+
+	cs := []cabby.Collection{}
+
+	rows, err := s.DB.Query(sql, args...)
+	if err != nil {
+		logSQLError(sql, args, err)
+		return cs, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var c cabby.Collection
+		if err := rows.Scan(
+			&c.ID, &c.RetentionPolicy.MaxAgeDays, &c.RetentionPolicy.MaxVersions, &c.RetentionPolicy.DropExpired); err != nil {
+			return cs, err
+		}
+		cs = append(cs, c)
+	}
+
+	err = rows.Err()
+	return cs, err
+}
+
+// trashInBatches moves the object versions selected to the trash until there are none left; batches keep each write
+// short so requests aren't blocked behind it
+func trashInBatches(e execer, batchSize int, selectRowIDs string, args ...interface{}) (deleted int64, err error) {
+	sql := `update objects
+					set deleted_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now'), deleted_by = ?
+					where rowid in (` + selectRowIDs + ` limit ?)`
+	args = append(append([]interface{}{retentionDeletedBy}, args...), batchSize)
+
+	for {
+		result, err := e.Exec(sql, args...)
+		if err != nil {
+			logSQLError(sql, args, err)
+			return deleted, err
+		}
+
+		count, err := result.RowsAffected()
+		if err != nil {
+			return deleted, err
+		}
+
+		deleted += count
+		if count < int64(batchSize) {
+			return deleted, nil
+		}
+	}
+}
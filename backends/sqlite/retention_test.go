@@ -0,0 +1,155 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pladdy/cabby"
+	"github.com/pladdy/cabby/tester"
+)
+
+func setRetentionPolicy(t *testing.T, ds *DataStore, p cabby.RetentionPolicy) {
+	c := tester.Collection
+	c.RetentionPolicy = p
+
+	err := ds.CollectionService().UpdateCollection(context.Background(), c)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func objectVersions(t *testing.T, ds *DataStore) int {
+	var count int
+	err := ds.DB.QueryRow("select count(*) from objects where collection_id = ? and deleted_at is null",
+		tester.CollectionID).Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+func TestRetentionServiceEnforceRetention(t *testing.T) {
+	tests := []struct {
+		policy   cabby.RetentionPolicy
+		dryRun   bool
+		expected cabby.Retention
+	}{
+		{cabby.RetentionPolicy{MaxAgeDays: 7}, false, cabby.Retention{MaxAge: 1}},
+		{cabby.RetentionPolicy{MaxVersions: 1}, false, cabby.Retention{MaxVersions: 2}},
+		{cabby.RetentionPolicy{DropExpired: true}, false, cabby.Retention{Expired: 1}},
+		{cabby.RetentionPolicy{MaxAgeDays: 7, MaxVersions: 1, DropExpired: true}, false,
+			cabby.Retention{MaxAge: 1, MaxVersions: 1}},
+		{cabby.RetentionPolicy{MaxAgeDays: 7, MaxVersions: 1, DropExpired: true}, true,
+			cabby.Retention{MaxAge: 1, MaxVersions: 1}},
+	}
+
+	for _, test := range tests {
+		setupSQLite()
+		ds := testDataStore()
+
+		// the object has three versions: one that's expired, one added long ago and the latest
+		createObjectVersion(ds, tester.ObjectID, "2017-01-01T01:00:00.000Z")
+		createObjectVersion(ds, tester.ObjectID, "2018-01-01T01:00:00.000Z")
+
+		_, err := ds.DB.Exec(`update objects set created_at = '2016-01-01T00:00:00.000Z' where modified = ?`,
+			"2017-01-01T01:00:00Z")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = ds.DB.Exec(`update objects set object = json_set(object, '$.valid_until', '2016-01-01T00:00:00Z')
+												 where modified = ?`, tester.Object.Modified.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		setRetentionPolicy(t, ds, test.policy)
+		before := objectVersions(t, ds)
+
+		results, err := ds.RetentionService().EnforceRetention(context.Background(), test.dryRun)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		test.expected.CollectionID = tester.CollectionID
+		if len(results) != 1 || results[0] != test.expected {
+			t.Error("Got:", results, "Expected:", test.expected, "Policy:", test.policy)
+		}
+
+		removed := int64(before - objectVersions(t, ds))
+		if test.dryRun && removed != 0 {
+			t.Error("Got:", removed, "Expected: nothing removed on a dry run")
+		}
+		if !test.dryRun && removed != test.expected.Total() {
+			t.Error("Got:", removed, "Expected:", test.expected.Total(), "Policy:", test.policy)
+		}
+
+		// removed versions go to the trash
+		var trashed int64
+		err = ds.DB.QueryRow("select count(*) from objects where deleted_by = ?", retentionDeletedBy).Scan(&trashed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if trashed != removed {
+			t.Error("Got:", trashed, "Expected:", removed, "Policy:", test.policy)
+		}
+	}
+}
+
+func TestTrashInBatches(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+
+	for _, version := range []string{"2017-01-01T01:00:00.000Z", "2018-01-01T01:00:00.000Z"} {
+		createObjectVersion(ds, tester.ObjectID, version)
+	}
+
+	tests := []struct {
+		batchSize int
+		expected  int64
+	}{
+		{1, 2},
+		{2, 0},
+	}
+
+	for _, test := range tests {
+		deleted, err := trashInBatches(batchExecer{dataStore: ds}, test.batchSize, maxVersionsSQL, tester.CollectionID, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if deleted != test.expected {
+			t.Error("Got:", deleted, "Expected:", test.expected, "Batch size:", test.batchSize)
+		}
+	}
+
+	if objectVersions(t, ds) != 1 {
+		t.Error("Got:", objectVersions(t, ds), "Expected:", 1)
+	}
+}
+
+func TestRetentionServiceEnforceRetentionNoPolicies(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+
+	results, err := ds.RetentionService().EnforceRetention(context.Background(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 0 {
+		t.Error("Got:", results, "Expected: no policies enforced")
+	}
+}
+
+func TestRetentionServiceEnforceRetentionQueryFail(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+
+	err := dropTable(ds, "collection")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ds.RetentionService().EnforceRetention(context.Background(), false)
+	if err == nil {
+		t.Error("Expected an error")
+	}
+}
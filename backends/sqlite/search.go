@@ -0,0 +1,185 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	// import sqlite dependency
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/pladdy/cabby"
+	"github.com/pladdy/stones"
+)
+
+const (
+	// searchIndexSQL creates the full-text index of object_search and the triggers that keep it in sync; the index is
+	// rebuilt since object_search may have been written without it
+	searchIndexSQL = `
+		create virtual table if not exists objects_fts using fts5 (
+			name, description, labels, external_references, content = 'object_search', content_rowid = 'search_id'
+		);
+
+		create trigger if not exists object_search_ai after insert on object_search
+			begin
+				insert into objects_fts (rowid, name, description, labels, external_references)
+					values (new.search_id, new.name, new.description, new.labels, new.external_references);
+			end;
+
+		create trigger if not exists object_search_ad after delete on object_search
+			begin
+				insert into objects_fts (objects_fts, rowid, name, description, labels, external_references)
+					values ('delete', old.search_id, old.name, old.description, old.labels, old.external_references);
+			end;
+
+		create trigger if not exists object_search_au after update on object_search
+			begin
+				insert into objects_fts (objects_fts, rowid, name, description, labels, external_references)
+					values ('delete', old.search_id, old.name, old.description, old.labels, old.external_references);
+				insert into objects_fts (rowid, name, description, labels, external_references)
+					values (new.search_id, new.name, new.description, new.labels, new.external_references);
+			end;
+
+		insert into objects_fts (objects_fts) values ('rebuild');`
+
+	// dropSearchTriggersSQL stops writing to the full-text index so objects can be written without fts5
+	dropSearchTriggersSQL = `
+		drop trigger if exists object_search_ai;
+		drop trigger if exists object_search_ad;
+		drop trigger if exists object_search_au;`
+)
+
+// SearchService implements a SQLite version of the SearchService interface
+type SearchService struct {
+	DB        *sql.DB
+	DataStore *DataStore
+}
+
+// Search returns the object versions in an api root's collections the user can read whose text matches the query;
+// every term in the query has to match
+func (s SearchService) Search(ctx context.Context, apiRoot, query string, p *cabby.Page) (cabby.SearchHits, error) {
+	resource, action := "Search", "read"
+	start := cabby.LogServiceStart(ctx, resource, action)
+	result, err := s.search(cabby.TakeUser(ctx), apiRoot, query, p)
+	cabby.LogServiceEnd(ctx, resource, action, start)
+	return result, err
+}
+
+func (s SearchService) search(u cabby.User, apiRoot, query string, p *cabby.Page) (cabby.SearchHits, error) {
+	sh := cabby.SearchHits{}
+
+	if !fullTextSearch {
+		return sh, cabby.ErrSearchUnavailable
+	}
+
+	match := searchTerms(query)
+	if match == "" {
+		return sh, nil
+	}
+
+	sql := `with data as (
+						select
+							o.collection_id, o.id, o.created_at date_added, o.modified version, o.media_type, o.object,
+							objects_fts.rank, 1 count
+						from
+							objects_fts
+							inner join object_search os
+								on objects_fts.rowid = os.search_id
+							inner join objects o
+								on os.object_id = o.id
+								and os.modified = o.modified
+							inner join collection c
+								on o.collection_id = c.id
+						where
+							objects_fts match ?
+							and c.api_root_path = ?
+							and $readable
+							and o.deleted_at is null
+							and c.deleted_at is null
+					)
+					select collection_id, id, date_added, version, media_type, object, (select sum(count) from data) total
+					from data
+					order by rank, collection_id, id, version desc
+					$paginate`
+
+	sql = strings.Replace(sql, "$readable", readableCollectionsSQL, -1)
+
+	args := []interface{}{match, apiRoot}
+	args = append(args, readableCollectionsArgs(u)...)
+	sql, args = applyPaging(sql, p, args)
+
+	rows, err := s.DB.Query(sql, args...)
+	if err != nil {
+		logSQLError(sql, args, err)
+		return sh, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var h cabby.SearchHit
+		var dateAdded, version, mediaType, object string
+
+		if err := rows.Scan(&h.CollectionID, &h.ID, &dateAdded, &version, &mediaType, &object, &p.Total); err != nil {
+			return sh, err
+		}
+
+		h.DateAdded, err = stones.TimestampFromString(dateAdded)
+		if err != nil {
+			return sh, err
+		}
+
+		h.Version, err = stones.TimestampFromString(version)
+		if err != nil {
+			return sh, err
+		}
+
+		h.MediaTypes = []string{mediaType}
+		h.Object = []byte(object)
+		sh.Objects = append(sh.Objects, h)
+	}
+
+	err = rows.Err()
+	return sh, err
+}
+
+/* helpers */
+
+// indexSearch creates the full-text index of a database once it has searchable text; without fts5 the triggers that
+// write to the index are dropped instead, and the index is rebuilt when a build with fts5 opens the database again
+func indexSearch(db *sql.DB) error {
+	if !fullTextSearch {
+		_, err := db.Exec(dropSearchTriggersSQL)
+		return err
+	}
+
+	sql := `select
+						(select count(*) from sqlite_master where type = 'table' and name = 'object_search'),
+						(select count(*) from sqlite_master where type = 'trigger' and name = 'object_search_ai')`
+
+	var searchable, indexed bool
+	err := db.QueryRow(sql).Scan(&searchable, &indexed)
+	if err != nil {
+		logSQLError(sql, []interface{}{}, err)
+		return err
+	}
+
+	if !searchable || indexed {
+		return nil
+	}
+
+	_, err = db.Exec(searchIndexSQL)
+	if err != nil {
+		logSQLError(searchIndexSQL, []interface{}{}, err)
+	}
+	return err
+}
+
+// searchTerms quotes each term of a query so it's matched as text instead of parsed as fts5 query syntax, IE:
+// 'poison "ivy' becomes '"poison" """ivy"'
+func searchTerms(query string) string {
+	terms := []string{}
+	for _, t := range strings.Fields(query) {
+		terms = append(terms, `"`+strings.Replace(t, `"`, `""`, -1)+`"`)
+	}
+	return strings.Join(terms, " ")
+}
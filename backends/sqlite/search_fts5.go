@@ -0,0 +1,7 @@
+//go:build fts5
+// +build fts5
+
+package sqlite
+
+// sqlite is built with fts5, object text can be searched
+const fullTextSearch = true
@@ -0,0 +1,7 @@
+//go:build !fts5
+// +build !fts5
+
+package sqlite
+
+// sqlite is built without fts5, object text can't be searched
+const fullTextSearch = false
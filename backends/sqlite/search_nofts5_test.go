@@ -0,0 +1,33 @@
+//go:build !fts5
+// +build !fts5
+
+package sqlite
+
+import (
+	"testing"
+
+	"github.com/pladdy/cabby"
+	"github.com/pladdy/cabby/tester"
+)
+
+func TestSearchServiceSearchUnavailable(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+
+	_, err := ds.SearchService().Search(tester.Context, tester.APIRootPath, "poison ivy", &cabby.Page{})
+	if err != cabby.ErrSearchUnavailable {
+		t.Error("Got:", err, "Expected:", cabby.ErrSearchUnavailable)
+	}
+
+	// objects can still be written
+	createObject(ds, "malware--0c7b5b88-8ff7-4a4d-aa9d-feb398cd0061")
+
+	var count int
+	err = ds.DB.QueryRow(`select count(*) from object_search`).Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Error("Got:", count, "Expected:", 2)
+	}
+}
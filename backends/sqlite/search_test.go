@@ -0,0 +1,236 @@
+//go:build fts5
+// +build fts5
+
+package sqlite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pladdy/cabby"
+	"github.com/pladdy/cabby/tester"
+)
+
+const (
+	searchReportID  = "report--84e4d88f-44ea-4bcd-bbf3-b2c1c320bcb3"
+	searchMalwareID = "malware--0c7b5b88-8ff7-4a4d-aa9d-feb398cd0061"
+)
+
+func createSearchObjects(t *testing.T, ds *DataStore) {
+	createRawObject(t, ds, tester.CollectionID, `{"type": "report", "spec_version": "2.1", "id": "`+searchReportID+`",
+		"created": "2019-01-01T00:00:00.000Z", "modified": "2019-01-01T00:00:00.000Z", "name": "Quarterly threat report",
+		"description": "A Gh0st RAT campaign against the energy sector", "published": "2019-01-01T00:00:00.000Z",
+		"labels": ["malicious-activity"], "object_refs": ["`+searchMalwareID+`"]}`)
+	createRawObject(t, ds, tester.CollectionID, `{"type": "malware", "spec_version": "2.1", "id": "`+searchMalwareID+`",
+		"created": "2019-01-01T00:00:00.000Z", "modified": "2019-01-01T00:00:00.000Z", "name": "Gh0st RAT",
+		"is_family": true, "external_references": [{"source_name": "capec", "external_id": "CAPEC-163",
+		"url": "https://capec.mitre.org/data/definitions/163.html"}]}`)
+
+	// the user can't read the other collection
+	createCollection(ds, otherCollectionID)
+	if err := ds.UserService().DeleteUserCollection(context.Background(), tester.UserEmail, otherCollectionID); err != nil {
+		t.Fatal(err)
+	}
+	createRawObject(t, ds, otherCollectionID, `{"type": "malware", "spec_version": "2.1",
+		"id": "malware--6b4f1a2e-9c3d-4e5f-8a7b-1c2d3e4f5a6b", "created": "2019-01-01T00:00:00.000Z",
+		"modified": "2019-01-01T00:00:00.000Z", "name": "Gh0st RAT", "is_family": true}`)
+}
+
+func TestSearchServiceSearch(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+	createSearchObjects(t, ds)
+
+	noAccess := cabby.WithUser(context.Background(), cabby.User{Email: "other@cabby.com"})
+
+	tests := []struct {
+		ctx           context.Context
+		apiRoot       string
+		query         string
+		limit         uint64
+		expectedIDs   []string
+		expectedTotal uint64
+	}{
+		// the malware's name matches better than the report's description
+		{readerContext(), tester.APIRootPath, "gh0st rat", 0, []string{searchMalwareID, searchReportID}, 2},
+		{readerContext(), tester.APIRootPath, "gh0st rat", 1, []string{searchMalwareID}, 2},
+		{readerContext(), tester.APIRootPath, "ENERGY", 0, []string{searchReportID}, 1},
+		{readerContext(), tester.APIRootPath, "malicious-activity", 0, []string{searchReportID}, 1},
+		{readerContext(), tester.APIRootPath, "CAPEC-163", 0, []string{searchMalwareID}, 1},
+		{readerContext(), tester.APIRootPath, "gh0st ransomware", 0, []string{}, 0},
+		{readerContext(), tester.APIRootPath, `rat" OR NEAR(`, 0, []string{}, 0},
+		{readerContext(), tester.APIRootPath, " ", 0, []string{}, 0},
+		{readerContext(), "other_api_root", "gh0st", 0, []string{}, 0},
+		{noAccess, tester.APIRootPath, "gh0st", 0, []string{}, 0},
+	}
+
+	for _, test := range tests {
+		p := cabby.Page{Limit: test.limit}
+
+		result, err := ds.SearchService().Search(test.ctx, test.apiRoot, test.query, &p)
+		if err != nil {
+			t.Fatal(err, "Query:", test.query)
+		}
+
+		if len(result.Objects) != len(test.expectedIDs) {
+			t.Fatal("Got:", result.Objects, "Expected:", test.expectedIDs, "Query:", test.query)
+		}
+		for i, h := range result.Objects {
+			if h.ID != test.expectedIDs[i] || h.CollectionID != tester.CollectionID || len(h.Object) == 0 {
+				t.Error("Got:", h, "Expected:", test.expectedIDs[i], "Query:", test.query)
+			}
+		}
+		if p.Total != test.expectedTotal {
+			t.Error("Got:", p.Total, "Expected:", test.expectedTotal, "Query:", test.query)
+		}
+	}
+}
+
+func TestSearchServiceSearchAdmin(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+	createSearchObjects(t, ds)
+
+	// admins read every collection
+	result, err := ds.SearchService().Search(tester.Context, tester.APIRootPath, "gh0st rat", &cabby.Page{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Objects) != 3 {
+		t.Error("Got:", len(result.Objects), "Expected:", 3)
+	}
+}
+
+func TestSearchServiceSearchChanged(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+	createSearchObjects(t, ds)
+
+	hits := func(query string) int {
+		result, err := ds.SearchService().Search(readerContext(), tester.APIRootPath, query, &cabby.Page{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(result.Objects)
+	}
+
+	// versions in the trash aren't searched
+	err := ds.ObjectService().DeleteObject(tester.Context, tester.CollectionID, searchMalwareID,
+		cabby.Filter{Versions: "all"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hits("gh0st") != 1 {
+		t.Error("Got:", hits("gh0st"), "Expected:", 1)
+	}
+
+	// an overwritten object is searched by its new text
+	_, err = ds.DB.Exec(`update objects set object = json_set(object, '$.description', 'A Winnti campaign')
+											 where id = ?`, searchReportID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hits("gh0st") != 0 || hits("winnti") != 1 {
+		t.Error("Got:", hits("gh0st"), hits("winnti"), "Expected:", 0, 1)
+	}
+}
+
+func TestSearchServiceSearchRemoved(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+	createSearchObjects(t, ds)
+
+	indexed := func(query string) (count int) {
+		err := ds.DB.QueryRow(`select count(*) from objects_fts where objects_fts match ?`, query).Scan(&count)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	// removed versions are removed from the index
+	_, err := ds.DB.Exec(`delete from objects where collection_id = ?`, otherCollectionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if indexed("gh0st") != 2 {
+		t.Error("Got:", indexed("gh0st"), "Expected:", 2)
+	}
+
+	// versions in the trash are removed from the index until they're restored
+	err = ds.ObjectService().DeleteObject(tester.Context, tester.CollectionID, searchMalwareID, cabby.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if indexed("capec") != 0 {
+		t.Error("Got:", indexed("capec"), "Expected:", 0)
+	}
+
+	err = ds.TrashService().RestoreObject(tester.Context, tester.CollectionID, searchMalwareID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if indexed("capec") != 1 {
+		t.Error("Got:", indexed("capec"), "Expected:", 1)
+	}
+
+	// a removed version that's added again is searched by its new text
+	_, err = ds.DB.Exec(`delete from objects where id = ?`, searchReportID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	createRawObject(t, ds, tester.CollectionID, `{"type": "report", "spec_version": "2.1", "id": "`+searchReportID+`",
+		"created": "2019-01-01T00:00:00.000Z", "modified": "2019-01-01T00:00:00.000Z", "name": "Quarterly threat report",
+		"published": "2019-01-01T00:00:00.000Z", "object_refs": ["`+searchMalwareID+`"]}`)
+
+	if indexed("energy") != 0 || indexed("quarterly") != 1 {
+		t.Error("Got:", indexed("energy"), indexed("quarterly"), "Expected:", 0, 1)
+	}
+}
+
+func TestSearchServiceQueryErr(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+
+	_, err := ds.DB.Exec("drop table object_search")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ds.SearchService().Search(readerContext(), tester.APIRootPath, "gh0st", &cabby.Page{})
+	if err == nil {
+		t.Error("Expected an error")
+	}
+}
+
+func TestIndexSearch(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+	createSearchObjects(t, ds)
+
+	// objects written by a build without fts5 aren't indexed
+	_, err := ds.DB.Exec(dropSearchTriggersSQL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ds.DB.Exec(`delete from objects where id = ?`, searchReportID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	createRawObject(t, ds, tester.CollectionID, `{"type": "report", "spec_version": "2.1", "id": "`+searchReportID+`",
+		"created": "2019-01-01T00:00:00.000Z", "modified": "2019-01-01T00:00:00.000Z", "name": "Quarterly threat report",
+		"published": "2019-01-01T00:00:00.000Z", "object_refs": ["`+searchMalwareID+`"]}`)
+
+	// the index is rebuilt when the database is opened again
+	ds = testDataStore()
+
+	for query, expected := range map[string]int{"quarterly": 1, "energy": 0} {
+		result, err := ds.SearchService().Search(readerContext(), tester.APIRootPath, query, &cabby.Page{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Objects) != expected {
+			t.Error("Got:", len(result.Objects), "Expected:", expected, "Query:", query)
+		}
+	}
+}
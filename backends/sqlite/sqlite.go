@@ -4,7 +4,9 @@ import (
 	"database/sql"
 	"errors"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/pladdy/cabby"
@@ -22,6 +24,12 @@ const (
 type DataStore struct {
 	DB   *sql.DB
 	Path string
+	// write transactions begin on writer, which takes the write lock of the database when they begin so they wait on
+	// writers in other processes, like cabby-cli; a transaction that has read the full-text index before taking the
+	// lock can't wait, sqlite fails it right away.  Write transactions of the data store also hold writing from when
+	// they begin until they end so they don't wait on each other in sqlite
+	writer  *sql.DB
+	writing sync.Mutex
 }
 
 // NewDataStore returns a sqliteDB
@@ -42,9 +50,11 @@ func (s *DataStore) APIRootService() cabby.APIRootService {
 
 // Close connection to datastore
 func (s *DataStore) Close() {
-	err := s.DB.Close()
-	if err != nil {
-		log.WithFields(log.Fields{"error": err}).Error("Failed to close the database connection")
+	for _, db := range []*sql.DB{s.DB, s.writer} {
+		err := db.Close()
+		if err != nil {
+			log.WithFields(log.Fields{"error": err}).Error("Failed to close the database connection")
+		}
 	}
 }
 
@@ -75,21 +85,54 @@ func (s *DataStore) ObjectService() cabby.ObjectService {
 	return ObjectService{DB: s.DB, DataStore: s}
 }
 
+// ObservableService returns a service for observables
+func (s *DataStore) ObservableService() cabby.ObservableService {
+	return ObservableService{DB: s.DB, DataStore: s}
+}
+
 // Open connection to datastore
 func (s *DataStore) Open() (err error) {
 	// set foreign key pragma to true in connection: https://github.com/mattn/go-sqlite3#connection-string
 	s.DB, err = sql.Open("sqlite3", s.Path+"?_fk=true")
 	if err != nil {
 		log.Error(err)
+		return
+	}
+
+	// only write transactions begin immediately, reads don't take the write lock
+	s.writer, err = sql.Open("sqlite3", s.Path+"?_fk=true&_txlock=immediate")
+	if err != nil {
+		log.Error(err)
+		return
+	}
+
+	err = indexSearch(s.DB)
+	if err != nil {
+		log.WithFields(log.Fields{"error": err}).Error("Failed to set up the search index")
 	}
 	return
 }
 
+// RetentionService returns a service for the retention policies of collections
+func (s *DataStore) RetentionService() cabby.RetentionService {
+	return RetentionService{DB: s.DB, DataStore: s}
+}
+
+// SearchService returns a service for searching the text of objects
+func (s *DataStore) SearchService() cabby.SearchService {
+	return SearchService{DB: s.DB, DataStore: s}
+}
+
 // StatusService returns service for status resources
 func (s *DataStore) StatusService() cabby.StatusService {
 	return StatusService{DB: s.DB, DataStore: s}
 }
 
+// TrashService returns a service for deleted resources
+func (s *DataStore) TrashService() cabby.TrashService {
+	return TrashService{DB: s.DB, DataStore: s}
+}
+
 // UserService returns a service for user resources
 func (s *DataStore) UserService() cabby.UserService {
 	return UserService{DB: s.DB, DataStore: s}
@@ -102,46 +145,60 @@ func (s *DataStore) VersionsService() cabby.VersionsService {
 
 /* writer methods */
 
+// batchWrite writes what it receives in batches; a batch is read before its transaction begins so the database isn't
+// locked while the writes are waited for
 func (s *DataStore) batchWrite(query string, toWrite chan interface{}, errs chan error) {
 	defer close(errs)
 
+	batch := [][]interface{}{}
+	for item := range toWrite {
+		batch = append(batch, item.([]interface{}))
+
+		if len(batch) >= maxWritesPerBatch {
+			s.writeBatch(query, batch, errs)
+			batch = [][]interface{}{}
+		}
+	}
+	s.writeBatch(query, batch, errs)
+}
+
+// writeBatch writes a batch in one transaction; each write that fails, or isn't committed, is reported as an error
+func (s *DataStore) writeBatch(query string, batch [][]interface{}, errs chan error) {
+	if len(batch) == 0 {
+		return
+	}
+
 	tx, stmt, err := s.writeOperation(query)
 	if err != nil {
-		errs <- err
+		log.WithFields(log.Fields{"sql": query, "error": err}).Error("Error after call to 'writeOperation'")
+		failBatch(len(batch), errs, err)
 		return
 	}
 	defer stmt.Close()
 
-	i := 0
-	for item := range toWrite {
-		args := item.([]interface{})
-
+	written := 0
+	for _, args := range batch {
 		err := s.execute(stmt, args...)
 		if err != nil {
 			log.WithFields(log.Fields{"sql": query, "error": err}).Error("Error after call to 'execute'")
 			errs <- err
 			continue
 		}
+		written++
+	}
 
-		i++
-		if i >= maxWritesPerBatch {
-			err := tx.Commit() // on commit a statement is closed, create a new transaction for next batch
-			if err != nil {
-				log.WithFields(log.Fields{"sql": query, "error": err}).Error("Error after call to 'Commit'")
-				errs <- err
-				return
-			}
+	err = tx.Commit()
+	if err != nil {
+		log.WithFields(log.Fields{"sql": query, "error": err}).Error("Error after call to 'Commit'")
+		failBatch(written, errs, err)
+	}
+}
 
-			tx, stmt, err = s.writeOperation(query)
-			if err != nil {
-				log.WithFields(log.Fields{"sql": query, "error": err}).Error("Error after call to 'writeOperation'")
-				errs <- err
-				return
-			}
-			i = 0
-		}
+// failBatch reports writes of a batch that failed together
+func failBatch(writes int, errs chan error, err error) {
+	for i := 0; i < writes; i++ {
+		errs <- err
 	}
-	errs <- tx.Commit()
 }
 
 func (s *DataStore) execute(stmt *sql.Stmt, args ...interface{}) error {
@@ -165,8 +222,69 @@ func (s *DataStore) write(query string, args ...interface{}) error {
 	return s.execute(stmt, args...)
 }
 
-func (s *DataStore) writeOperation(query string) (tx *sql.Tx, stmt *sql.Stmt, err error) {
-	tx, err = s.DB.Begin()
+// statement is a query to write and its args
+type statement struct {
+	sql  string
+	args []interface{}
+}
+
+// writeTx is a write transaction of a data store; the data store can begin another one once it's committed or rolled back
+type writeTx struct {
+	*sql.Tx
+	done    sync.Once
+	writing *sync.Mutex
+}
+
+// Commit commits the transaction and lets the next one begin
+func (tx *writeTx) Commit() error {
+	defer tx.end()
+	return tx.Tx.Commit()
+}
+
+// Rollback rolls the transaction back and lets the next one begin
+func (tx *writeTx) Rollback() error {
+	defer tx.end()
+	return tx.Tx.Rollback()
+}
+
+func (tx *writeTx) end() {
+	tx.done.Do(tx.writing.Unlock)
+}
+
+// begin starts a write transaction once the one before it has ended
+func (s *DataStore) begin() (*writeTx, error) {
+	s.writing.Lock()
+
+	tx, err := s.writer.Begin()
+	if err != nil {
+		s.writing.Unlock()
+		return nil, err
+	}
+	return &writeTx{Tx: tx, writing: &s.writing}, nil
+}
+
+// writeAll executes statements in one transaction; nothing is written if one of them fails
+func (s *DataStore) writeAll(statements ...statement) error {
+	tx, err := s.begin()
+	if err != nil {
+		log.WithFields(log.Fields{"err": err}).Error("Failed to begin transaction")
+		return err
+	}
+
+	for _, st := range statements {
+		_, err = tx.Exec(st.sql, st.args...)
+		if err != nil {
+			logSQLError(st.sql, st.args, err)
+			/* #nosec G104 */
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *DataStore) writeOperation(query string) (tx *writeTx, stmt *sql.Stmt, err error) {
+	tx, err = s.begin()
 	if err != nil {
 		log.WithFields(log.Fields{"err": err}).Error("Failed to begin transaction")
 		return
@@ -175,6 +293,8 @@ func (s *DataStore) writeOperation(query string) (tx *sql.Tx, stmt *sql.Stmt, er
 	stmt, err = tx.Prepare(query)
 	if err != nil {
 		log.WithFields(log.Fields{"err": err, "sql": query}).Error("Failed to prepare query")
+		/* #nosec G104 */
+		tx.Rollback()
 	}
 	return
 }
@@ -212,11 +332,74 @@ func (f *Filter) QueryString() (q string, args []interface{}) {
 		}
 	}
 
+	// properties are filtered in order so the same filter makes the same query
+	properties := []string{}
+	for property := range f.Properties {
+		properties = append(properties, property)
+	}
+	sort.Strings(properties)
+
+	for _, property := range properties {
+		filter, newArgs := filterProperty(property, f.Properties[property])
+		if filter != "" {
+			filters = append(filters, filter)
+			args = append(args, newArgs...)
+		}
+	}
+
 	return strings.Join(filters, " and "), args
 }
 
 /* filtering helpers */
 
+// objectsAsOfSQL is objects_data as it was at a point in time: it has the versions added by then that weren't
+// deleted yet, and the first and last versions are of those
+const objectsAsOfSQL = `(
+	select
+		so.rowid,
+		so.id,
+		so.type,
+		so.created,
+		so.modified,
+		so.object,
+		so.collection_id,
+		case when so.modified = sa.first and so.modified = sa.last then 'only'
+				 when so.modified = sa.last then 'last'
+				 when so.modified = sa.first then 'first'
+		end version,
+		so.created_at,
+		so.updated_at,
+		so.spec_version,
+		so.media_type
+	from
+		objects so
+		inner join (
+			select id, collection_id, min(modified) first, max(modified) last
+			from objects
+			where
+				created_at <= strftime('%Y-%m-%dT%H:%M:%fZ', ?)
+				and (deleted_at is null or deleted_at > strftime('%Y-%m-%dT%H:%M:%fZ', ?))
+			group by id, collection_id
+		) sa
+			on so.id = sa.id
+			and so.collection_id = sa.collection_id
+	where
+		so.created_at <= strftime('%Y-%m-%dT%H:%M:%fZ', ?)
+		and (so.deleted_at is null or so.deleted_at > strftime('%Y-%m-%dT%H:%M:%fZ', ?))
+) objects_data`
+
+// applyAsOf replaces $objectsData with the objects_data view, or the objects as they were at the as of time of the
+// filter; $objectsData has to come before the other parameters of the query
+func applyAsOf(sql string, f cabby.Filter, args []interface{}) (string, []interface{}) {
+	if f.AsOf.IsZero() {
+		return strings.Replace(sql, "$objectsData", "objects_data", -1), args
+	}
+
+	asOf := f.AsOf.String()
+	sql = strings.Replace(sql, "$objectsData", objectsAsOfSQL, -1)
+	return sql, append([]interface{}{asOf, asOf, asOf, asOf}, args...)
+}
+
 func applyFiltering(sql string, f cabby.Filter, args []interface{}) (string, []interface{}) {
 	filter := Filter{f}
 	qs, filterArgs := filter.QueryString()
@@ -255,8 +438,28 @@ func filterCreator(raw, field string) (filter string, args []interface{}) {
 
 func filterMapFieldToRawStrings(f *Filter) map[string]string {
 	return map[string]string{
-		"id":   f.IDs,
-		"type": f.Types}
+		"id":           f.IDs,
+		"spec_version": f.SpecVersions,
+		"type":         f.Types}
+}
+
+// filterProperty matches the values of an object property with json1; a list property matches if any of its values
+// does.  properties that can't be filtered on are ignored
+func filterProperty(property string, values []string) (filter string, args []interface{}) {
+	list, ok := cabby.FilterProperty(property)
+	if !ok || len(values) == 0 {
+		return
+	}
+
+	args = append(args, "$."+property)
+	for _, v := range values {
+		args = append(args, v)
+	}
+
+	if list {
+		return "exists (select 1 from json_each(object, ?) where value in (" + placeholders(len(values)) + "))", args
+	}
+	return "json_extract(object, ?) in (" + placeholders(len(values)) + ")", args
 }
 
 func filterRemoveTrailingAnd(sql string) string {
@@ -313,6 +516,11 @@ func (p *Page) QueryString() (q string, args []interface{}) {
 	if p.Valid() {
 		q = "limit ?"
 		args = []interface{}{(p.Limit)}
+
+		if p.Offset > 0 {
+			q += " offset ?"
+			args = append(args, p.Offset)
+		}
 	}
 	return
 }
@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"regexp"
 	"testing"
+	"time"
 
 	"github.com/pladdy/cabby"
+	"github.com/pladdy/cabby/tester"
 	"github.com/pladdy/stones"
 )
 
@@ -46,7 +48,7 @@ func TestSQLiteBatchWriteSmall(t *testing.T) {
 					values (?, ?, ?, ?, ?)`
 
 	go ds.batchWrite(sql, toWrite, errs)
-	toWrite <- []interface{}{"test", "test api root", "test collection", "this is a test collection", "media type"}
+	toWrite <- []interface{}{"test", tester.APIRootPath, "test collection", "this is a test collection", "media type"}
 	close(toWrite)
 
 	for e := range errs {
@@ -80,7 +82,7 @@ func TestSQLiteBatchWriteExecuteLarge(t *testing.T) {
 
 	recordsToWrite := 1000
 	for i := 0; i <= recordsToWrite; i++ {
-		toWrite <- []interface{}{"test" + fmt.Sprint(i), "api root", "collection", "a test collection"}
+		toWrite <- []interface{}{"test" + fmt.Sprint(i), tester.APIRootPath, "collection", "a test collection"}
 	}
 	close(toWrite)
 
@@ -94,6 +96,34 @@ func TestSQLiteBatchWriteExecuteLarge(t *testing.T) {
 	}
 }
 
+func TestSQLiteBatchWriteWaitingDoesNotLock(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+
+	// the write is received once it's sent
+	toWrite := make(chan interface{})
+	errs := make(chan error, 10)
+
+	sql := `insert into collection (id, api_root_path, title, description)
+					values (?, ?, ?, ?)`
+
+	go ds.batchWrite(sql, toWrite, errs)
+	toWrite <- []interface{}{"batched", tester.APIRootPath, "collection", "a test collection"}
+
+	// the batch is still waiting for writes, other writes don't wait on it
+	err := ds.write(sql, "not batched", tester.APIRootPath, "collection", "a test collection")
+	if err != nil {
+		t.Error("Got:", err, "Expected no error")
+	}
+	close(toWrite)
+
+	for e := range errs {
+		if e != nil {
+			t.Error("Got:", e, "Expected no error")
+		}
+	}
+}
+
 func TestSQLiteBatchWriteWriteOperationError(t *testing.T) {
 	setupSQLite()
 	ds := testDataStore()
@@ -128,11 +158,11 @@ func TestSQLiteBatchWriteExecuteError(t *testing.T) {
 	go ds.batchWrite(sql, toWrite, errs)
 
 	for i := 0; i <= maxWritesPerBatch; i++ {
-		if i == maxWritesPerBatch {
-			// a commit is about to happen
+		if i == maxWritesPerBatch-1 {
+			// the next write commits the batch; the transaction after it can't begin
 			ds.Close()
 		}
-		toWrite <- []interface{}{"test" + fmt.Sprint(i), "api root", "collection", "a test collection"}
+		toWrite <- []interface{}{"test" + fmt.Sprint(i), tester.APIRootPath, "collection", "a test collection"}
 	}
 	close(toWrite)
 
@@ -169,6 +199,84 @@ func TestSQLiteBatchWriteCommitError(t *testing.T) {
 	}
 }
 
+func TestDataStoreWriteWaitsForTransaction(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+
+	tx, err := ds.begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = tx.Exec(`update status set status = 'pending'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// writing an object reads the full-text index first; it has to wait for the transaction instead of failing
+	o := tester.Object
+	o.ID, _ = stones.IdentifierFromString("malware--0c7b5b88-8ff7-4a4d-aa9d-feb398cd0061")
+
+	written := make(chan error)
+	go func() {
+		written <- ds.write(createObjectSQL, objectArgs(o, tester.CollectionID, cabby.SpecVersion20)...)
+	}()
+
+	select {
+	case err := <-written:
+		t.Fatal("Got:", err, "Expected the write to wait")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := <-written; err != nil {
+		t.Error("Got:", err, "Expected no error")
+	}
+}
+
+func TestDataStoreWriteWaitsForOtherDataStore(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+	defer ds.Close()
+
+	// another data store on the database is like another process, cabby-cli, writing to it
+	other := testDataStore()
+	defer other.Close()
+
+	tx, err := other.begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = tx.Exec(`update status set status = 'pending'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	o := tester.Object
+	o.ID, _ = stones.IdentifierFromString("malware--0c7b5b88-8ff7-4a4d-aa9d-feb398cd0061")
+
+	written := make(chan error)
+	go func() {
+		written <- ds.write(createObjectSQL, objectArgs(o, tester.CollectionID, cabby.SpecVersion20)...)
+	}()
+
+	select {
+	case err := <-written:
+		t.Fatal("Got:", err, "Expected the write to wait")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := <-written; err != nil {
+		t.Error("Got:", err, "Expected no error")
+	}
+}
+
 func TestDataStoreWriteError(t *testing.T) {
 	setupSQLite()
 	ds := testDataStore()
@@ -231,6 +339,12 @@ func TestFilterQueryString(t *testing.T) {
 		{cabby.Filter{IDs: "indicator--8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f,malware--31b940d4-6f7f-459a-80ea-9c1f17b5891b"},
 			"(id = ? or id = ?)",
 			[]interface{}{"indicator--8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f", "malware--31b940d4-6f7f-459a-80ea-9c1f17b5891b"}},
+		{cabby.Filter{SpecVersions: "2.1"},
+			"(spec_version = ?)",
+			[]interface{}{"2.1"}},
+		{cabby.Filter{SpecVersions: "2.0,2.1"},
+			"(spec_version = ? or spec_version = ?)",
+			[]interface{}{"2.0", "2.1"}},
 		{cabby.Filter{Types: "indicator"},
 			"(type = ?)",
 			[]interface{}{"indicator"}},
@@ -260,6 +374,24 @@ func TestFilterQueryString(t *testing.T) {
 		{cabby.Filter{Versions: "all"},
 			"(1 = 1)",
 			[]interface{}{}},
+		{cabby.Filter{Properties: map[string][]string{"labels": {"malicious-activity"}}},
+			"exists (select 1 from json_each(object, ?) where value in (?))",
+			[]interface{}{"$.labels", "malicious-activity"}},
+		{cabby.Filter{Properties: map[string][]string{
+			"labels": {"malicious-activity"}, "created_by_ref": {"identity--f431f809-377b-45e0-aa1c-6a4751cae5ff", "identity--1"}}},
+			`json_extract(object, ?) in (?, ?) and exists (select 1 from json_each(object, ?) where value in (?))`,
+			[]interface{}{"$.created_by_ref", "identity--f431f809-377b-45e0-aa1c-6a4751cae5ff", "identity--1",
+				"$.labels", "malicious-activity"}},
+		{cabby.Filter{Properties: map[string][]string{"object') or 1 = 1 --": {"x"}, "labels": {}}},
+			``,
+			[]interface{}{}},
+		// values can hold commas
+		{cabby.Filter{Properties: map[string][]string{"name": {"Poison Ivy, Variant"}}},
+			"json_extract(object, ?) in (?)",
+			[]interface{}{"$.name", "Poison Ivy, Variant"}},
+		{cabby.Filter{Properties: map[string][]string{"name": {}}},
+			``,
+			[]interface{}{}},
 	}
 
 	for _, test := range tests {
@@ -295,6 +427,8 @@ func TestPageQueryString(t *testing.T) {
 	}{
 		{Page{&cabby.Page{}}, "", 0},
 		{Page{&cabby.Page{Limit: 1}}, "limit ?", 1},
+		{Page{&cabby.Page{Limit: 1, Offset: 2}}, "limit ? offset ?", 2},
+		{Page{&cabby.Page{Offset: 2}}, "", 0},
 	}
 
 	for _, test := range tests {
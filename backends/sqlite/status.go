@@ -3,9 +3,10 @@ package sqlite
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"time"
 
-	// import sqlite dependency
-	_ "github.com/mattn/go-sqlite3"
+	sqlite3 "github.com/mattn/go-sqlite3"
 	log "github.com/sirupsen/logrus"
 
 	"github.com/pladdy/cabby"
@@ -17,19 +18,71 @@ type StatusService struct {
 	DataStore *DataStore
 }
 
+// CreateIdempotencyKey records the status of a request the user made to a collection with an idempotency key
+func (s StatusService) CreateIdempotencyKey(ctx context.Context, collectionID, key, statusID string) error {
+	resource, action := "IdempotencyKey", "create"
+	start := cabby.LogServiceStart(ctx, resource, action)
+	err := s.createIdempotencyKey(cabby.TakeUser(ctx).Email, collectionID, key, statusID)
+	cabby.LogServiceEnd(ctx, resource, action, start)
+	return err
+}
+
+// the key is the primary key of the table, so of two requests storing it at once only one can
+func (s StatusService) createIdempotencyKey(user, collectionID, key, statusID string) error {
+	sql := `insert into idempotency_key (email, collection_id, idempotency_key, status_id) values (?, ?, ?, ?)`
+	args := []interface{}{user, collectionID, key, statusID}
+
+	err := s.DataStore.write(sql, args...)
+	if e, ok := err.(sqlite3.Error); ok && e.ExtendedCode == sqlite3.ErrConstraintPrimaryKey {
+		return cabby.ErrIdempotencyKeyExists
+	}
+	if err != nil {
+		logSQLError(sql, args, err)
+	}
+	return err
+}
+
 // CreateStatus will read from the data store and return the resource
 func (s StatusService) CreateStatus(ctx context.Context, status cabby.Status) error {
 	resource, action := "Status", "create"
 	start := cabby.LogServiceStart(ctx, resource, action)
-	err := s.createStatus(status)
+	err := s.createStatus(cabby.TakeUser(ctx).Email, status)
+	cabby.LogServiceEnd(ctx, resource, action, start)
+	return err
+}
+
+func (s StatusService) createStatus(user string, st cabby.Status) error {
+	sql := `insert into status (id, status, total_count, success_count, successes, failure_count, failures, pending_count,
+														  email, collection_id)
+					values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	successes, failures, err := statusDetailsToJSON(st)
+	if err != nil {
+		return err
+	}
+	args := []interface{}{
+		st.ID, st.Status, st.TotalCount, st.SuccessCount, successes, st.FailureCount, failures, st.PendingCount, user,
+		st.CollectionID}
+
+	err = s.DataStore.write(sql, args...)
+	if err != nil {
+		logSQLError(sql, args, err)
+	}
+	return err
+}
+
+// DeleteIdempotencyKey removes an idempotency key of the user from a collection so a request can use it again
+func (s StatusService) DeleteIdempotencyKey(ctx context.Context, collectionID, key string) error {
+	resource, action := "IdempotencyKey", "delete"
+	start := cabby.LogServiceStart(ctx, resource, action)
+	err := s.deleteIdempotencyKey(cabby.TakeUser(ctx).Email, collectionID, key)
 	cabby.LogServiceEnd(ctx, resource, action, start)
 	return err
 }
 
-func (s StatusService) createStatus(st cabby.Status) error {
-	sql := `insert into status (id, status, total_count, success_count, failure_count, pending_count)
-					values (?, ?, ?, ?, ?, ?)`
-	args := []interface{}{st.ID, st.Status, st.TotalCount, st.SuccessCount, st.FailureCount, st.PendingCount}
+func (s StatusService) deleteIdempotencyKey(user, collectionID, key string) error {
+	sql := `delete from idempotency_key where email = ? and collection_id = ? and idempotency_key = ?`
+	args := []interface{}{user, collectionID, key}
 
 	err := s.DataStore.write(sql, args...)
 	if err != nil {
@@ -38,38 +91,137 @@ func (s StatusService) createStatus(st cabby.Status) error {
 	return err
 }
 
-// Status will read from the data store and return the resource
-func (s StatusService) Status(ctx context.Context, statusID string) (cabby.Status, error) {
+// ExpireStatuses removes the complete statuses created before the time given, along with the idempotency keys of their
+// requests; pending statuses are kept so objects that are still being written can update theirs
+func (s StatusService) ExpireStatuses(ctx context.Context, createdBefore time.Time) (int64, error) {
+	resource, action := "Status", "expire"
+	start := cabby.LogServiceStart(ctx, resource, action)
+	result, err := s.expireStatuses(createdBefore)
+	cabby.LogServiceEnd(ctx, resource, action, start)
+	return result, err
+}
+
+func (s StatusService) expireStatuses(createdBefore time.Time) (expired int64, err error) {
+	before := createdBefore.UTC().Format(deletedAtLayout)
+
+	tx, err := s.DataStore.begin()
+	if err != nil {
+		return
+	}
+
+	sql := `delete from idempotency_key
+					where status_id in (select id from status where created_at < ? and status = 'complete')`
+	if _, err = tx.Exec(sql, before); err != nil {
+		logSQLError(sql, []interface{}{before}, err)
+		/* #nosec G104 */
+		tx.Rollback()
+		return
+	}
+
+	sql = `delete from status where created_at < ? and status = 'complete'`
+	result, err := tx.Exec(sql, before)
+	if err != nil {
+		logSQLError(sql, []interface{}{before}, err)
+		/* #nosec G104 */
+		tx.Rollback()
+		return
+	}
+
+	if expired, err = result.RowsAffected(); err != nil {
+		/* #nosec G104 */
+		tx.Rollback()
+		return 0, err
+	}
+
+	err = tx.Commit()
+	return
+}
+
+// IdempotentStatus returns the status of a request the user made to a collection with an idempotency key; the status
+// is empty if there wasn't one
+func (s StatusService) IdempotentStatus(ctx context.Context, collectionID, key string) (cabby.Status, error) {
+	resource, action := "IdempotencyKey", "read"
+	start := cabby.LogServiceStart(ctx, resource, action)
+	result, err := s.idempotentStatus(cabby.TakeUser(ctx).Email, collectionID, key)
+	cabby.LogServiceEnd(ctx, resource, action, start)
+	return result, err
+}
+
+func (s StatusService) idempotentStatus(user, collectionID, key string) (cabby.Status, error) {
+	sql := statusSQL + `
+					where id = (
+						select status_id from idempotency_key where email = ? and collection_id = ? and idempotency_key = ?
+					)`
+	return s.readStatus(sql, user, collectionID, key)
+}
+
+// Status returns a status the user created; admins can read any status.  The status is empty if the user can't read it
+func (s StatusService) Status(ctx context.Context, apiRoot, statusID string) (cabby.Status, error) {
 	resource, action := "Status", "read"
 	start := cabby.LogServiceStart(ctx, resource, action)
-	result, err := s.status(statusID)
+	result, err := s.status(cabby.TakeUser(ctx), apiRoot, statusID)
 	cabby.LogServiceEnd(ctx, resource, action, start)
 	return result, err
 }
 
-func (s StatusService) status(statusID string) (cabby.Status, error) {
-	sql := `select id, status, total_count, success_count, pending_count, failure_count
-					from status where id = ?`
+// a status is read through the API root of its collection; statuses created before they recorded their collection
+// can't be scoped, so they're read through any
+func (s StatusService) status(user cabby.User, apiRoot, statusID string) (cabby.Status, error) {
+	sql := statusSQL + ` where
+											 id = ?
+											 and (email = ? or ? = 1)
+											 and (coalesce(collection_id, '') = ''
+														or collection_id in (select id from collection where api_root_path = ?))`
+	return s.readStatus(sql, statusID, user.Email, user.CanAdmin, apiRoot)
+}
 
-	st := cabby.Status{}
-	var err error
+// Statuses returns the statuses of requests the user made to collections in an API root, most recent first
+func (s StatusService) Statuses(ctx context.Context, apiRoot string, p *cabby.Page) (cabby.Statuses, error) {
+	resource, action := "Statuses", "read"
+	start := cabby.LogServiceStart(ctx, resource, action)
+	result, err := s.statuses(cabby.TakeUser(ctx).Email, apiRoot, p)
+	cabby.LogServiceEnd(ctx, resource, action, start)
+	return result, err
+}
+
+func (s StatusService) statuses(user, apiRoot string, p *cabby.Page) (cabby.Statuses, error) {
+	sql := `with data as (
+					  select s.*, 1 count
+					  from
+						  status s
+						  inner join collection c
+							  on s.collection_id = c.id
+					  where
+						  s.email = ?
+						  and c.api_root_path = ?
+				  )
+				  select ` + statusColumns + `, (select sum(count) from data) total
+				  from data
+				  order by created_at desc, id
+				  $paginate`
 
-	rows, err := s.DB.Query(sql, statusID)
+	args := []interface{}{user, apiRoot}
+	sql, args = applyPaging(sql, p, args)
+
+	ss := cabby.Statuses{}
+
+	rows, err := s.DB.Query(sql, args...)
 	if err != nil {
-		log.WithFields(log.Fields{"sql": sql, "error": err}).Error("error in sql")
-		return st, err
+		logSQLError(sql, args, err)
+		return ss, err
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		if err := rows.Scan(
-			&st.ID, &st.Status, &st.TotalCount, &st.SuccessCount, &st.PendingCount, &st.FailureCount); err != nil {
-			return st, err
+		st, err := scanStatus(rows, &p.Total)
+		if err != nil {
+			return ss, err
 		}
+		ss.Statuses = append(ss.Statuses, st)
 	}
 
 	err = rows.Err()
-	return st, err
+	return ss, err
 }
 
 // UpdateStatus will read from the data store and return the resource
@@ -83,7 +235,8 @@ func (s StatusService) UpdateStatus(ctx context.Context, status cabby.Status) er
 
 func (s StatusService) updateStatus(st cabby.Status) error {
 	sql := `update status
-          set status = ?, total_count = ?, success_count = ?, failure_count = ?, pending_count = ?
+          set status = ?, total_count = ?, success_count = ?, successes = ?, failure_count = ?, failures = ?,
+              pending_count = ?
           where id = ?`
 
 	st.PendingCount = st.TotalCount - st.SuccessCount - st.FailureCount
@@ -93,5 +246,88 @@ func (s StatusService) updateStatus(st cabby.Status) error {
 		st.Status = "complete"
 	}
 
-	return s.DataStore.write(sql, st.Status, st.TotalCount, st.SuccessCount, st.FailureCount, st.PendingCount, st.ID)
+	successes, failures, err := statusDetailsToJSON(st)
+	if err != nil {
+		return err
+	}
+
+	return s.DataStore.write(sql,
+		st.Status, st.TotalCount, st.SuccessCount, successes, st.FailureCount, failures, st.PendingCount, st.ID)
+}
+
+/* helpers */
+
+// statusColumns are read by scanStatus
+const statusColumns = `id, status, total_count, success_count, coalesce(successes, ''), pending_count, failure_count,
+											 coalesce(failures, ''), coalesce(collection_id, ''), coalesce(email, '')`
+
+const statusSQL = `select ` + statusColumns + ` from status`
+
+// readStatus returns the status the query selects; it's empty if there isn't one
+func (s StatusService) readStatus(sql string, args ...interface{}) (cabby.Status, error) {
+	st := cabby.Status{}
+
+	rows, err := s.DB.Query(sql, args...)
+	if err != nil {
+		logSQLError(sql, args, err)
+		return st, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if st, err = scanStatus(rows); err != nil {
+			return st, err
+		}
+	}
+
+	err = rows.Err()
+	return st, err
+}
+
+// scanStatus reads the status columns of a row and then any others selected after them
+func scanStatus(rows *sql.Rows, others ...interface{}) (cabby.Status, error) {
+	st := cabby.Status{}
+	var successes, failures string
+
+	dest := []interface{}{&st.ID, &st.Status, &st.TotalCount, &st.SuccessCount, &successes, &st.PendingCount,
+		&st.FailureCount, &failures, &st.CollectionID, &st.CreatedBy}
+
+	if err := rows.Scan(append(dest, others...)...); err != nil {
+		return st, err
+	}
+
+	if err := unmarshalStatusDetails(successes, &st.Successes); err != nil {
+		return st, err
+	}
+	err := unmarshalStatusDetails(failures, &st.Failures)
+	return st, err
+}
+
+// statusDetailsToJSON returns the successes and failures of a status as stored; an empty list is stored as '[]'
+func statusDetailsToJSON(st cabby.Status) (successes, failures string, err error) {
+	successes, err = detailsToJSON(st.Successes)
+	if err == nil {
+		failures, err = detailsToJSON(st.Failures)
+	}
+	return
+}
+
+func detailsToJSON(details []cabby.StatusDetails) (string, error) {
+	if details == nil {
+		details = []cabby.StatusDetails{}
+	}
+
+	b, err := json.Marshal(details)
+	if err != nil {
+		log.WithFields(log.Fields{"details": details, "error": err}).Error("Unable to marshal status details")
+	}
+	return string(b), err
+}
+
+// unmarshalStatusDetails reads stored status details; statuses stored before details were kept have none
+func unmarshalStatusDetails(stored string, details *[]cabby.StatusDetails) error {
+	if stored == "" {
+		return nil
+	}
+	return json.Unmarshal([]byte(stored), details)
 }
@@ -2,8 +2,11 @@ package sqlite
 
 import (
 	"context"
+	"fmt"
 	"testing"
+	"time"
 
+	"github.com/pladdy/cabby"
 	"github.com/pladdy/cabby/tester"
 )
 
@@ -19,7 +22,7 @@ func TestStatusServiceCreateStatus(t *testing.T) {
 		t.Error("Got:", err)
 	}
 
-	result, err := s.Status(context.Background(), test.ID.String())
+	result, err := s.Status(context.Background(), tester.APIRootPath, test.ID.String())
 	if err != nil {
 		t.Error("Got:", err)
 	}
@@ -30,6 +33,36 @@ func TestStatusServiceCreateStatus(t *testing.T) {
 	}
 }
 
+func TestStatusServiceStatusAPIRoot(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+	s := ds.StatusService()
+
+	scoped := tester.Status
+	scoped.CollectionID = tester.CollectionID
+	if err := s.CreateStatus(tester.Context, scoped); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		apiRoot       string
+		expectedFound bool
+	}{
+		{tester.APIRootPath, true},
+		{"another_root", false},
+	}
+
+	for _, test := range tests {
+		result, err := s.Status(tester.Context, test.apiRoot, scoped.ID.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (result.TotalCount > 0) != test.expectedFound {
+			t.Error("Got:", result, "Expected found:", test.expectedFound, "API root:", test.apiRoot)
+		}
+	}
+}
+
 func TestStatusServiceCreateStatusFail(t *testing.T) {
 	setupSQLite()
 	ds := testDataStore()
@@ -60,7 +93,7 @@ func TestStatusServiceStatus(t *testing.T) {
 		t.Error("Got:", err)
 	}
 
-	result, err := s.Status(context.Background(), expected.ID.String())
+	result, err := s.Status(context.Background(), tester.APIRootPath, expected.ID.String())
 	if err != nil {
 		t.Error("Got:", err, "Expected no error")
 	}
@@ -71,6 +104,311 @@ func TestStatusServiceStatus(t *testing.T) {
 	}
 }
 
+func TestStatusServiceStatusDetails(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+	s := ds.StatusService()
+
+	expected := tester.Status
+	expected.Successes = []cabby.StatusDetails{
+		{ID: "malware--1", Version: "2016-04-06T20:07:09Z", Message: "Object version overwrites the existing one"}}
+	expected.Failures = []cabby.StatusDetails{{ID: "malware--2", Message: "Object version already exists"}}
+
+	err := s.CreateStatus(context.Background(), tester.Status)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.UpdateStatus(context.Background(), expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := s.Status(context.Background(), tester.APIRootPath, expected.ID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Successes) != 1 || result.Successes[0] != expected.Successes[0] {
+		t.Error("Got:", result.Successes, "Expected:", expected.Successes)
+	}
+	if len(result.Failures) != 1 || result.Failures[0] != expected.Failures[0] {
+		t.Error("Got:", result.Failures, "Expected:", expected.Failures)
+	}
+}
+
+func TestStatusServiceIdempotentStatus(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+	s := ds.StatusService()
+
+	expected := tester.Status
+	err := s.CreateStatus(tester.Context, expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.CreateIdempotencyKey(tester.Context, tester.CollectionID, "a-key", expected.ID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a key can only be used once
+	err = s.CreateIdempotencyKey(tester.Context, tester.CollectionID, "a-key", expected.ID.String())
+	if err != cabby.ErrIdempotencyKeyExists {
+		t.Error("Got:", err, "Expected:", cabby.ErrIdempotencyKeyExists)
+	}
+
+	otherUser := cabby.WithUser(context.Background(), cabby.User{Email: "other@cabby.com"})
+
+	tests := []struct {
+		ctx        context.Context
+		key        string
+		expectedID string
+	}{
+		{tester.Context, "a-key", expected.ID.String()},
+		{tester.Context, "another-key", ""},
+		{otherUser, "a-key", ""},
+	}
+
+	for _, test := range tests {
+		result, err := s.IdempotentStatus(test.ctx, tester.CollectionID, test.key)
+		if err != nil {
+			t.Error("Got:", err, "Expected no error")
+		}
+
+		resultID := result.ID.String()
+		if result.ID.IsEmpty() {
+			resultID = ""
+		}
+		if resultID != test.expectedID {
+			t.Error("Got:", resultID, "Expected:", test.expectedID, "Key:", test.key)
+		}
+	}
+}
+
+func TestStatusServiceDeleteIdempotencyKey(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+	s := ds.StatusService()
+
+	expected := tester.Status
+	err := s.CreateStatus(tester.Context, expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.CreateIdempotencyKey(tester.Context, tester.CollectionID, "a-key", expected.ID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.DeleteIdempotencyKey(tester.Context, tester.CollectionID, "a-key")
+	if err != nil {
+		t.Error("Got:", err, "Expected no error")
+	}
+
+	// a deleted key can be used again
+	err = s.CreateIdempotencyKey(tester.Context, tester.CollectionID, "a-key", expected.ID.String())
+	if err != nil {
+		t.Error("Got:", err, "Expected no error")
+	}
+}
+
+func TestStatusServiceStatusOwner(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+	s := ds.StatusService()
+
+	owner := cabby.WithUser(context.Background(), cabby.User{Email: "owner@cabby.com"})
+	other := cabby.WithUser(context.Background(), cabby.User{Email: "other@cabby.com"})
+	admin := cabby.WithUser(context.Background(), cabby.User{Email: "admin@cabby.com", CanAdmin: true})
+
+	expected := tester.Status
+	expected.CollectionID = tester.CollectionID
+	err := s.CreateStatus(owner, expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		ctx      context.Context
+		readable bool
+	}{
+		{owner, true},
+		{other, false},
+		{admin, true},
+	}
+
+	for _, test := range tests {
+		result, err := s.Status(test.ctx, tester.APIRootPath, expected.ID.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if readable := result.TotalCount > 0; readable != test.readable {
+			t.Error("Got:", readable, "Expected:", test.readable, "User:", cabby.TakeUser(test.ctx).Email)
+		}
+		if test.readable && (result.CreatedBy != "owner@cabby.com" || result.CollectionID != tester.CollectionID) {
+			t.Error("Got:", result.CreatedBy, result.CollectionID, "Expected:", "owner@cabby.com", tester.CollectionID)
+		}
+	}
+}
+
+func TestStatusServiceStatuses(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+	s := ds.StatusService()
+
+	ids := []string{}
+	for i := 0; i < 3; i++ {
+		st, err := cabby.NewStatus(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		st.CollectionID = tester.CollectionID
+
+		if err = s.CreateStatus(tester.Context, st); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, st.ID.String())
+	}
+
+	// statuses of other users aren't listed
+	other := cabby.WithUser(context.Background(), cabby.User{Email: "other@cabby.com"})
+	st, _ := cabby.NewStatus(1)
+	st.CollectionID = tester.CollectionID
+	if err := s.CreateStatus(other, st); err != nil {
+		t.Fatal(err)
+	}
+
+	// order them by when they were created
+	for i, id := range ids {
+		_, err := ds.DB.Exec("update status set created_at = ? where id = ?", fmt.Sprintf("2018-01-0%dT00:00:00.000Z", i+1), id)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		apiRoot     string
+		limit       int
+		expectedIDs []string
+	}{
+		{tester.APIRootPath, 0, []string{ids[2], ids[1], ids[0]}},
+		{tester.APIRootPath, 2, []string{ids[2], ids[1]}},
+		{"no_such_root", 0, []string{}},
+	}
+
+	for _, test := range tests {
+		p := cabby.Page{Limit: uint64(test.limit)}
+
+		result, err := s.Statuses(tester.Context, test.apiRoot, &p)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resultIDs := []string{}
+		for _, st := range result.Statuses {
+			resultIDs = append(resultIDs, st.ID.String())
+		}
+		if fmt.Sprint(resultIDs) != fmt.Sprint(test.expectedIDs) {
+			t.Error("Got:", resultIDs, "Expected:", test.expectedIDs, "Limit:", test.limit)
+		}
+		if len(test.expectedIDs) > 0 && p.Total != 3 {
+			t.Error("Got:", p.Total, "Expected:", 3)
+		}
+	}
+}
+
+func TestStatusServiceStatusesQueryErr(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+
+	_, err := ds.DB.Exec("drop table status")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := cabby.Page{}
+	_, err = ds.StatusService().Statuses(tester.Context, tester.APIRootPath, &p)
+	if err == nil {
+		t.Error("Expected an error")
+	}
+}
+
+func TestStatusServiceExpireStatuses(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+	s := ds.StatusService()
+
+	expired := tester.Status
+	expired.Status = "complete"
+	expired.CollectionID = tester.CollectionID
+	if err := s.CreateStatus(tester.Context, expired); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CreateIdempotencyKey(tester.Context, tester.CollectionID, "a-key", expired.ID.String()); err != nil {
+		t.Fatal(err)
+	}
+
+	// a status that's still pending is kept however old it is, its objects are still being written
+	pending, _ := cabby.NewStatus(1)
+	if err := s.CreateStatus(tester.Context, pending); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := ds.DB.Exec("update status set created_at = '2018-01-01T00:00:00.000Z' where id in (?, ?)",
+		expired.ID.String(), pending.ID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kept, _ := cabby.NewStatus(1)
+	kept.Status = "complete"
+	if err = s.CreateStatus(tester.Context, kept); err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := s.ExpireStatuses(context.Background(), time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Error("Got:", count, "Expected:", 1)
+	}
+
+	result, _ := s.Status(tester.Context, tester.APIRootPath, expired.ID.String())
+	if result.TotalCount != 0 {
+		t.Error("Got:", result, "Expected: the status to be expired")
+	}
+	result, _ = s.IdempotentStatus(tester.Context, tester.CollectionID, "a-key")
+	if result.TotalCount != 0 {
+		t.Error("Got:", result, "Expected: the idempotency key to be expired")
+	}
+	result, _ = s.Status(tester.Context, tester.APIRootPath, kept.ID.String())
+	if result.TotalCount == 0 {
+		t.Error("Expected the status to be kept")
+	}
+	result, _ = s.Status(tester.Context, tester.APIRootPath, pending.ID.String())
+	if result.TotalCount == 0 {
+		t.Error("Expected the pending status to be kept")
+	}
+}
+
+func TestStatusServiceExpireStatusesFail(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+
+	_, err := ds.DB.Exec("drop table idempotency_key")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ds.StatusService().ExpireStatuses(context.Background(), time.Now())
+	if err == nil {
+		t.Error("Expected an error")
+	}
+}
+
 func TestStatusServiceStatusQueryErr(t *testing.T) {
 	setupSQLite()
 	ds := testDataStore()
@@ -83,7 +421,7 @@ func TestStatusServiceStatusQueryErr(t *testing.T) {
 
 	expected := tester.Status
 
-	_, err = s.Status(context.Background(), expected.ID.String())
+	_, err = s.Status(context.Background(), tester.APIRootPath, expected.ID.String())
 	if err == nil {
 		t.Error("Got:", err, "Expected an error")
 	}
@@ -113,7 +451,7 @@ func TestStatusServiceUpdateStatus(t *testing.T) {
 
 	// verify it's updated
 	expected.PendingCount = 2
-	result, err := s.Status(context.Background(), expected.ID.String())
+	result, err := s.Status(context.Background(), tester.APIRootPath, expected.ID.String())
 	if err != nil {
 		t.Error("Got:", err)
 	}
@@ -134,7 +472,7 @@ func TestStatusServiceUpdateStatus(t *testing.T) {
 	expected.PendingCount = 0
 	expected.Status = "complete"
 
-	result, err = s.Status(context.Background(), expected.ID.String())
+	result, err = s.Status(context.Background(), tester.APIRootPath, expected.ID.String())
 	if err != nil {
 		t.Error("Got:", err)
 	}
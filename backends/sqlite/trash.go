@@ -0,0 +1,183 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	// import sqlite dependency
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/pladdy/cabby"
+)
+
+// deletedAtLayout is how deleted_at is written, so it can be compared as a string
+const deletedAtLayout = "2006-01-02T15:04:05.000Z"
+
+// newDeletedAt returns when something deleted now is deleted
+func newDeletedAt() string {
+	return time.Now().UTC().Format(deletedAtLayout)
+}
+
+// TrashService implements a SQLite version of the TrashService interface
+type TrashService struct {
+	DB        *sql.DB
+	DataStore *DataStore
+}
+
+// DeletedObjects will read the object versions in the trash for a collection
+func (s TrashService) DeletedObjects(ctx context.Context, collectionID string) ([]cabby.DeletedObject, error) {
+	resource, action := "DeletedObjects", "read"
+	start := cabby.LogServiceStart(ctx, resource, action)
+	result, err := s.deletedObjects(collectionID)
+	cabby.LogServiceEnd(ctx, resource, action, start)
+	return result, err
+}
+
+func (s TrashService) deletedObjects(collectionID string) ([]cabby.DeletedObject, error) {
+	sql := `select id, modified, collection_id, deleted_at, coalesce(deleted_by, '')
+					from objects
+					where collection_id = ? and deleted_at is not null
+					order by deleted_at, id, modified`
+	args := []interface{}{collectionID}
+
+	dos := []cabby.DeletedObject{}
+
+	rows, err := s.DB.Query(sql, args...)
+	if err != nil {
+		logSQLError(sql, args, err)
+		return dos, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var do cabby.DeletedObject
+		var deletedAt string
+
+		if err := rows.Scan(&do.ID, &do.Version, &do.CollectionID, &deletedAt, &do.DeletedBy); err != nil {
+			return dos, err
+		}
+
+		do.DeletedAt, err = time.Parse(time.RFC3339Nano, deletedAt)
+		if err != nil {
+			return dos, err
+		}
+		dos = append(dos, do)
+	}
+
+	err = rows.Err()
+	return dos, err
+}
+
+// Purge permanently removes the resources deleted before a time
+func (s TrashService) Purge(ctx context.Context, deletedBefore time.Time) (cabby.Purged, error) {
+	resource, action := "Trash", "purge"
+	start := cabby.LogServiceStart(ctx, resource, action)
+	result, err := s.purge(deletedBefore)
+	cabby.LogServiceEnd(ctx, resource, action, start)
+	return result, err
+}
+
+func (s TrashService) purge(deletedBefore time.Time) (p cabby.Purged, err error) {
+	before := deletedBefore.UTC().Format(deletedAtLayout)
+
+	tx, err := s.DataStore.begin()
+	if err != nil {
+		return
+	}
+
+	purges := []struct {
+		sql   string
+		count *int64
+	}{
+		{`delete from objects where deleted_at < ?`, &p.Objects},
+		{`delete from collection where deleted_at < ?`, &p.Collections},
+		{`delete from api_root where deleted_at < ?`, &p.APIRoots},
+	}
+
+	for _, purge := range purges {
+		var result sql.Result
+		result, err = tx.Exec(purge.sql, before)
+		if err != nil {
+			logSQLError(purge.sql, []interface{}{before}, err)
+			/* #nosec G104 */
+			tx.Rollback()
+			return cabby.Purged{}, err
+		}
+
+		*purge.count, err = result.RowsAffected()
+		if err != nil {
+			/* #nosec G104 */
+			tx.Rollback()
+			return cabby.Purged{}, err
+		}
+	}
+
+	err = tx.Commit()
+	return
+}
+
+// RestoreAPIRoot takes an API root out of the trash with what was deleted with it
+func (s TrashService) RestoreAPIRoot(ctx context.Context, path string) error {
+	resource, action := "APIRoot", "restore"
+	start := cabby.LogServiceStart(ctx, resource, action)
+	err := s.restoreAPIRoot(path)
+	cabby.LogServiceEnd(ctx, resource, action, start)
+	return err
+}
+
+// RestoreCollection takes a collection out of the trash with the objects deleted with it
+func (s TrashService) RestoreCollection(ctx context.Context, collectionID string) error {
+	resource, action := "Collection", "restore"
+	start := cabby.LogServiceStart(ctx, resource, action)
+	err := s.restoreCollection(collectionID)
+	cabby.LogServiceEnd(ctx, resource, action, start)
+	return err
+}
+
+// collections and objects deleted along with an API root have the same deleted_at, so they're restored with it
+func (s TrashService) restoreAPIRoot(path string) error {
+	return s.DataStore.writeAll(
+		statement{`update objects set deleted_at = null, deleted_by = null
+							 where
+								 deleted_at = (select deleted_at from api_root where api_root_path = ?)
+								 and collection_id in (
+									 select id from collection
+									 where
+										 api_root_path = ?
+										 and deleted_at = (select deleted_at from api_root where api_root_path = ?)
+								 )`, []interface{}{path, path, path}},
+		statement{`update collection set deleted_at = null, deleted_by = null
+							 where
+								 api_root_path = ?
+								 and deleted_at = (select deleted_at from api_root where api_root_path = ?)`,
+			[]interface{}{path, path}},
+		statement{`update api_root set deleted_at = null, deleted_by = null where api_root_path = ?`, []interface{}{path}})
+}
+
+// objects deleted along with a collection have the same deleted_at, so they're restored with it
+func (s TrashService) restoreCollection(collectionID string) error {
+	return s.DataStore.writeAll(
+		statement{`update objects set deleted_at = null, deleted_by = null
+							 where collection_id = ? and deleted_at = (select deleted_at from collection where id = ?)`,
+			[]interface{}{collectionID, collectionID}},
+		statement{`update collection set deleted_at = null, deleted_by = null where id = ?`, []interface{}{collectionID}})
+}
+
+// RestoreObject takes the versions of an object in a collection out of the trash
+func (s TrashService) RestoreObject(ctx context.Context, collectionID, objectID string) error {
+	resource, action := "Object", "restore"
+	start := cabby.LogServiceStart(ctx, resource, action)
+	err := s.restore(`update objects set deleted_at = null, deleted_by = null
+										where collection_id = ? and id = ? and deleted_at is not null`, collectionID, objectID)
+	cabby.LogServiceEnd(ctx, resource, action, start)
+	return err
+}
+
+func (s TrashService) restore(sql string, args ...interface{}) error {
+	err := s.DataStore.write(sql, args...)
+	if err != nil {
+		logSQLError(sql, args, err)
+	}
+	return err
+}
@@ -0,0 +1,244 @@
+package sqlite
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/pladdy/cabby"
+	"github.com/pladdy/cabby/tester"
+)
+
+func TestTrashServiceRestoreObject(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+	s := ds.TrashService()
+	objectID := tester.Object.ID.String()
+
+	err := ds.ObjectService().DeleteObject(tester.Context, tester.CollectionID, objectID, cabby.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the object is in the trash and the collection has no media types without it
+	dos, err := s.DeletedObjects(context.Background(), tester.CollectionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dos) != 1 || dos[0].ID != objectID || dos[0].DeletedBy != tester.UserEmail || dos[0].DeletedAt.IsZero() {
+		t.Error("Got:", dos, "Expected:", objectID, "deleted by", tester.UserEmail)
+	}
+
+	c, _ := ds.CollectionService().Collection(tester.Context, tester.APIRootPath, tester.CollectionID)
+	if len(c.MediaTypes) != 0 {
+		t.Error("Got:", c.MediaTypes, "Expected: no media types")
+	}
+
+	err = s.RestoreObject(context.Background(), tester.CollectionID, objectID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the object can be read again
+	objects, _ := ds.ObjectService().Object(context.Background(), tester.CollectionID, objectID, cabby.Filter{})
+	if len(objects) != 1 {
+		t.Error("Got:", len(objects), "Expected:", 1)
+	}
+
+	dos, _ = s.DeletedObjects(context.Background(), tester.CollectionID)
+	if len(dos) != 0 {
+		t.Error("Got:", dos, "Expected: an empty trash")
+	}
+
+	c, _ = ds.CollectionService().Collection(tester.Context, tester.APIRootPath, tester.CollectionID)
+	if len(c.MediaTypes) != 1 || c.MediaTypes[0] != cabby.StixContentType20 {
+		t.Error("Got:", c.MediaTypes, "Expected:", cabby.StixContentType20)
+	}
+}
+
+func TestTrashServiceRestoreCollection(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+	cs := ds.CollectionService()
+
+	err := cs.DeleteCollection(tester.Context, tester.CollectionID, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, _ := cs.Collection(tester.Context, tester.APIRootPath, tester.CollectionID)
+	if !c.ID.IsEmpty() {
+		t.Error("Got:", c, "Expected: no collection")
+	}
+
+	ucl, _ := ds.UserService().UserCollections(context.Background(), tester.UserEmail)
+	if len(ucl.CollectionAccessList) != 0 {
+		t.Error("Got:", ucl.CollectionAccessList, "Expected: no access to a deleted collection")
+	}
+
+	err = ds.TrashService().RestoreCollection(context.Background(), tester.CollectionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, _ = cs.Collection(tester.Context, tester.APIRootPath, tester.CollectionID)
+	if c.ID.String() != tester.CollectionID {
+		t.Error("Got:", c.ID, "Expected:", tester.CollectionID)
+	}
+
+	// the objects deleted with the collection are restored with it
+	objects, _ := ds.ObjectService().Object(context.Background(), tester.CollectionID, tester.ObjectID, cabby.Filter{})
+	if len(objects) != 1 {
+		t.Error("Got:", len(objects), "Expected:", 1)
+	}
+}
+
+func TestTrashServiceRestoreCollectionKeepsTrashedObjects(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+
+	// an object trashed before its collection stays in the trash when the collection is restored
+	err := ds.ObjectService().DeleteObject(tester.Context, tester.CollectionID, tester.ObjectID, cabby.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * time.Millisecond)
+
+	err = ds.CollectionService().DeleteCollection(tester.Context, tester.CollectionID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ds.TrashService().RestoreCollection(context.Background(), tester.CollectionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dos, _ := ds.TrashService().DeletedObjects(context.Background(), tester.CollectionID)
+	if len(dos) != 1 {
+		t.Error("Got:", dos, "Expected:", 1, "object in the trash")
+	}
+}
+
+func TestTrashServiceRestoreAPIRoot(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+	as := ds.APIRootService()
+
+	err := as.DeleteAPIRoot(tester.Context, tester.APIRootPath, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d, _ := ds.DiscoveryService().Discovery(context.Background(), tester.User)
+	if len(d.APIRoots) != 0 {
+		t.Error("Got:", d.APIRoots, "Expected: no API roots")
+	}
+
+	err = ds.TrashService().RestoreAPIRoot(context.Background(), tester.APIRootPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, _ := as.APIRoot(context.Background(), tester.APIRootPath)
+	if a.Path != tester.APIRootPath {
+		t.Error("Got:", a.Path, "Expected:", tester.APIRootPath)
+	}
+
+	// the collections and objects deleted with the api root are restored with it
+	c, _ := ds.CollectionService().Collection(tester.Context, tester.APIRootPath, tester.CollectionID)
+	if c.ID.String() != tester.CollectionID {
+		t.Error("Got:", c.ID, "Expected:", tester.CollectionID)
+	}
+
+	objects, _ := ds.ObjectService().Object(context.Background(), tester.CollectionID, tester.ObjectID, cabby.Filter{})
+	if len(objects) != 1 {
+		t.Error("Got:", len(objects), "Expected:", 1)
+	}
+}
+
+func TestTrashServicePurge(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+	s := ds.TrashService()
+
+	err := ds.ObjectService().DeleteObject(tester.Context, tester.CollectionID, tester.Object.ID.String(), cabby.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ds.CollectionService().DeleteCollection(tester.Context, tester.CollectionID, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ds.APIRootService().DeleteAPIRoot(tester.Context, tester.APIRootPath, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		deletedBefore time.Time
+		expected      cabby.Purged
+	}{
+		{time.Now().Add(-time.Hour), cabby.Purged{}},
+		{time.Now().Add(time.Minute), cabby.Purged{APIRoots: 1, Collections: 1, Objects: 1}},
+		{time.Now().Add(time.Minute), cabby.Purged{}},
+	}
+
+	for _, test := range tests {
+		result, err := s.Purge(context.Background(), test.deletedBefore)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result != test.expected {
+			t.Error("Got:", result, "Expected:", test.expected)
+		}
+	}
+
+	// purging the collection removes access to it too
+	for _, table := range []string{"objects", "user_collection"} {
+		var count int
+		err = ds.DB.QueryRow("select count(*) from " + table).Scan(&count)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 0 {
+			t.Error("Got:", count, "Expected:", 0, "Table:", table)
+		}
+	}
+}
+
+func TestTrashServicePurgeFail(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+
+	err := dropTable(ds, "collection")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := ds.TrashService().Purge(context.Background(), time.Now())
+	if err == nil {
+		t.Error("Expected an error")
+	}
+	if result != (cabby.Purged{}) {
+		t.Error("Got:", result, "Expected nothing purged")
+	}
+}
+
+func TestObjectServiceCreateEnvelopeTrashedVersion(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+
+	err := ds.ObjectService().DeleteObject(tester.Context, tester.CollectionID, tester.Object.ID.String(), cabby.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := postEnvelope(ds, cabby.Envelope{Objects: []json.RawMessage{tester.Object.Source}})
+
+	expected := "Object version is in the trash; restore or purge it first"
+	if len(result.Failures) != 1 || result.Failures[0].Message != expected {
+		t.Error("Got:", result.Failures, "Expected:", expected)
+	}
+}
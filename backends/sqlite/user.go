@@ -21,6 +21,40 @@ type UserService struct {
 	DataStore *DataStore
 }
 
+// AnonymousCollections returns the collections unauthenticated users can read
+func (s UserService) AnonymousCollections(ctx context.Context) (cabby.UserCollectionList, error) {
+	resource, action := "AnonymousCollections", "read"
+	start := cabby.LogServiceStart(ctx, resource, action)
+	result, err := s.anonymousCollections()
+	cabby.LogServiceEnd(ctx, resource, action, start)
+	return result, err
+}
+
+func (s UserService) anonymousCollections() (cabby.UserCollectionList, error) {
+	sql := `select c.id from collection c where c.anonymous_read = 1 and c.deleted_at is null`
+	args := []interface{}{}
+
+	ucl := cabby.UserCollectionList{Email: cabby.AnonymousEmail, CollectionAccessList: map[cabby.ID]cabby.CollectionAccess{}}
+
+	rows, err := s.DB.Query(sql, args...)
+	if err != nil {
+		logSQLError(sql, args, err)
+		return ucl, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		ca := cabby.CollectionAccess{CanRead: true}
+		if err := rows.Scan(&ca.ID); err != nil {
+			return ucl, err
+		}
+		ucl.CollectionAccessList[ca.ID] = ca
+	}
+
+	err = rows.Err()
+	return ucl, err
+}
+
 // CreateUser creates a user in the data store
 func (s UserService) CreateUser(ctx context.Context, user cabby.User, password string) error {
 	resource, action := "User", "create"
@@ -238,7 +272,11 @@ func (s UserService) userCollections(user string) (cabby.UserCollectionList, err
 						user tu
 						inner join user_collection tuc
 							on tu.email = tuc.email
-					where tu.email = ?`
+					where
+						tu.email = ?
+						and not exists (
+							select 1 from collection c where c.id = tuc.collection_id and c.deleted_at is not null
+						)`
 	args := []interface{}{user}
 
 	ucl := cabby.UserCollectionList{Email: user, CollectionAccessList: map[cabby.ID]cabby.CollectionAccess{}}
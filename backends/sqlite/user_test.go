@@ -8,6 +8,56 @@ import (
 	"github.com/pladdy/cabby/tester"
 )
 
+func TestUserServiceAnonymousCollections(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+	s := ds.UserService()
+
+	result, err := s.AnonymousCollections(tester.Context)
+	if err != nil {
+		t.Error("Got:", err, "Expected no error")
+	}
+	if len(result.CollectionAccessList) != 0 {
+		t.Error("Got:", len(result.CollectionAccessList), "Expected:", 0)
+	}
+
+	c := tester.Collection
+	c.AnonymousRead = true
+	err = ds.CollectionService().UpdateCollection(tester.Context, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err = s.AnonymousCollections(tester.Context)
+	if err != nil {
+		t.Error("Got:", err, "Expected no error")
+	}
+
+	expected := cabby.CollectionAccess{ID: c.ID, CanRead: true}
+	if result.CollectionAccessList[c.ID] != expected {
+		t.Error("Got:", result.CollectionAccessList[c.ID], "Expected:", expected)
+	}
+	if result.Email != cabby.AnonymousEmail {
+		t.Error("Got:", result.Email, "Expected:", cabby.AnonymousEmail)
+	}
+}
+
+func TestUserServiceAnonymousCollectionsQueryErr(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+	s := ds.UserService()
+
+	err := dropTable(ds, "collection")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = s.AnonymousCollections(tester.Context)
+	if err == nil {
+		t.Error("Got:", err, "Expected an error")
+	}
+}
+
 func TestUserServiceCreateUser(t *testing.T) {
 	setupSQLite()
 	ds := testDataStore()
@@ -230,9 +280,16 @@ func TestUserServiceCreateUserCollection(t *testing.T) {
 	ds := testDataStore()
 	s := ds.UserService()
 
+	// access can only be given to a collection that exists
 	id, _ := cabby.NewID()
+	err := ds.CollectionService().CreateCollection(
+		context.Background(), cabby.Collection{ID: id, APIRootPath: tester.APIRootPath, Title: "a title"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
 	expected := cabby.CollectionAccess{ID: id}
-	err := s.CreateUserCollection(context.Background(), tester.UserEmail, expected)
+	err = s.CreateUserCollection(context.Background(), tester.UserEmail, expected)
 	if err != nil {
 		t.Error("Got:", err)
 	}
@@ -321,8 +378,13 @@ func TestUserServiceUpdateUserCollection(t *testing.T) {
 
 	// create a collection with no access for the user
 	id, _ := cabby.NewID()
+	err := ds.CollectionService().CreateCollection(
+		context.Background(), cabby.Collection{ID: id, APIRootPath: tester.APIRootPath, Title: "a title"})
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	err := s.CreateUserCollection(context.Background(), tester.UserEmail, cabby.CollectionAccess{ID: id})
+	err = s.CreateUserCollection(context.Background(), tester.UserEmail, cabby.CollectionAccess{ID: id})
 	if err != nil {
 		t.Error("Got:", err)
 	}
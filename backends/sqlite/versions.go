@@ -32,6 +32,7 @@ func (s VersionsService) versions(cid, oid string, p *cabby.Page, f cabby.Filter
 						from objects_data
 						where
 							collection_id = ?
+							and id = ?
 							and $filter
 					)
 					select version
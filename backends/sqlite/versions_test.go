@@ -2,6 +2,7 @@ package sqlite
 
 import (
 	"context"
+	"strings"
 	"testing"
 
 	"github.com/pladdy/cabby"
@@ -24,6 +25,54 @@ func TestVersionsServiceVersions(t *testing.T) {
 	}
 }
 
+func TestVersionsServiceVersionsFilterProperties(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+	createLabeledVersion(t, ds)
+
+	tests := []struct {
+		properties       map[string][]string
+		expectedVersions []string
+	}{
+		{map[string][]string{}, []string{"2016-04-06T20:07:09Z", "2017-04-06T20:07:09Z"}},
+		{map[string][]string{"labels": {"backdoor"}}, []string{"2017-04-06T20:07:09Z"}},
+		{map[string][]string{"created_by_ref": {"identity--f431f809-377b-45e0-aa1c-6a4751cae5ff"}},
+			[]string{"2016-04-06T20:07:09Z"}},
+	}
+
+	for _, test := range tests {
+		f := cabby.Filter{Properties: test.properties, Versions: "all"}
+
+		result, err := ds.VersionsService().Versions(
+			context.Background(), tester.CollectionID, tester.ObjectID, &cabby.Page{}, f)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if strings.Join(result.Versions, ",") != strings.Join(test.expectedVersions, ",") {
+			t.Error("Got:", result.Versions, "Expected:", test.expectedVersions, "Properties:", test.properties)
+		}
+	}
+}
+
+func TestVersionsServiceVersionsOtherObjects(t *testing.T) {
+	setupSQLite()
+	ds := testDataStore()
+	createObject(ds, "malware--6b4f1a2e-9c3d-4e5f-8a7b-1c2d3e4f5a6b")
+	createObjectVersion(ds, "malware--6b4f1a2e-9c3d-4e5f-8a7b-1c2d3e4f5a6b", "2018-04-06T20:07:09Z")
+
+	result, err := ds.VersionsService().Versions(
+		context.Background(), tester.CollectionID, tester.ObjectID, &cabby.Page{}, cabby.Filter{Versions: "all"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"2016-04-06T20:07:09Z"}
+	if strings.Join(result.Versions, ",") != strings.Join(expected, ",") {
+		t.Error("Got:", result.Versions, "Expected:", expected)
+	}
+}
+
 func TestVersionsServiceVersionQueryErr(t *testing.T) {
 	setupSQLite()
 	ds := testDataStore()
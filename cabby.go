@@ -10,6 +10,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gofrs/uuid"
 	"github.com/pladdy/stones"
@@ -24,16 +25,38 @@ const (
 	// DefaultProductionConfig is the path to the packaged config file
 	DefaultProductionConfig = "/etc/cabby/cabby.json"
 
+	// DuplicateIdentical accepts an object version that already exists if its content is the same; nothing is written
+	DuplicateIdentical = "identical"
+	// DuplicateOverwrite replaces the content of an object version that already exists
+	DuplicateOverwrite = "overwrite"
+	// DuplicateReject fails an object version that already exists; it's the default duplicate policy
+	DuplicateReject = "reject"
+
+	// SpecVersion20 is the stix 2.0 spec version; objects without a 'spec_version' are stix 2.0
+	SpecVersion20 = "2.0"
+	// SpecVersion21 is the stix 2.1 spec version
+	SpecVersion21 = "2.1"
+
 	// StixContentType20 represents a stix 2.0 content type
 	StixContentType20 = "application/vnd.oasis.stix+json;version=2.0"
 	// StixContentType represents a stix 2 content type
 	StixContentType = "application/vnd.oasis.stix+json"
+	// StixMediaType represents the stix 2 media type from the final specification
+	StixMediaType = "application/stix+json"
+	// TaxiiContentType20 represents a taxii 2.0 content type
+	TaxiiContentType20 = "application/vnd.oasis.taxii+json;version=2.0"
 	// TaxiiContentType21 represents a taxii 2.1 content type
 	TaxiiContentType21 = "application/vnd.oasis.taxii+json;version=2.1"
 	// TaxiiContentType represents a taxii 2 content type
 	TaxiiContentType = "application/vnd.oasis.taxii+json"
+	// TaxiiMediaType21 represents the taxii 2.1 media type from the final specification
+	TaxiiMediaType21 = "application/taxii+json;version=2.1"
+	// TaxiiMediaType represents the taxii 2 media type from the final specification
+	TaxiiMediaType = "application/taxii+json"
 	// TaxiiVersion notes the supported version of the server
 	TaxiiVersion = "taxii-2.1"
+	// TaxiiVersion20 notes the legacy version an api root can support
+	TaxiiVersion20 = "taxii-2.0"
 
 	// UnsetUnixNano is the value returned from an unset time.Time{}.UnixNano() call
 	UnsetUnixNano = -6795364578871345152
@@ -60,6 +83,16 @@ func (a *APIRoot) IncludesMinVersion(vs []string) bool {
 	return b
 }
 
+// SupportsVersion checks if the api root lists a taxii version
+func (a *APIRoot) SupportsVersion(version string) bool {
+	for _, v := range a.Versions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
 // Validate an API Root
 func (a *APIRoot) Validate() error {
 	if a.Path == "" {
@@ -81,21 +114,29 @@ func (a *APIRoot) Validate() error {
 // APIRootService for interacting with APIRoots
 type APIRootService interface {
 	APIRoot(ctx context.Context, path string) (APIRoot, error)
+	APIRootDependents(ctx context.Context, path string) (Dependents, error)
 	APIRoots(ctx context.Context) ([]APIRoot, error)
 	CreateAPIRoot(ctx context.Context, a APIRoot) error
-	DeleteAPIRoot(ctx context.Context, path string) error
+	DeleteAPIRoot(ctx context.Context, path string, cascade bool) error
 	UpdateAPIRoot(ctx context.Context, a APIRoot) error
 }
 
 // Collection resource
 type Collection struct {
-	APIRootPath string   `json:"api_root_path,omitempty"`
-	ID          ID       `json:"id"`
-	CanRead     bool     `json:"can_read"`
-	CanWrite    bool     `json:"can_write"`
-	Title       string   `json:"title"`
-	Description string   `json:"description,omitempty"`
-	MediaTypes  []string `json:"media_types,omitempty"`
+	APIRootPath string `json:"api_root_path,omitempty"`
+	ID          ID     `json:"id"`
+	CanRead     bool   `json:"can_read"`
+	CanWrite    bool   `json:"can_write"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	// MediaTypes are the media types of the objects in the collection
+	MediaTypes []string `json:"media_types,omitempty"`
+	// AnonymousRead allows unauthenticated clients to read the collection
+	AnonymousRead bool `json:"-"`
+	// IngestPolicy limits the objects that can be added to the collection
+	IngestPolicy IngestPolicy `json:"-"`
+	// RetentionPolicy limits how long objects are kept in the collection
+	RetentionPolicy RetentionPolicy `json:"-"`
 }
 
 // NewCollection returns a collection resource; it takes an optional id string
@@ -110,8 +151,6 @@ func NewCollection(id ...string) (Collection, error) {
 	} else {
 		c.ID, err = NewID()
 	}
-
-	c.MediaTypes = []string{TaxiiContentType}
 	return c, err
 }
 
@@ -125,7 +164,11 @@ func (c *Collection) Validate() (err error) {
 		return fmt.Errorf("Invalid title: %s", c.Title)
 	}
 
-	return
+	err = c.IngestPolicy.Validate()
+	if err != nil {
+		return err
+	}
+	return c.RetentionPolicy.Validate()
 }
 
 // CollectionAccess defines read/write access on a collection
@@ -151,9 +194,12 @@ type CollectionService interface {
 	Collection(ctx context.Context, apiRoot, collectionID string) (Collection, error)
 	Collections(ctx context.Context, apiRoot string, cr *Page) (Collections, error)
 	CollectionsInAPIRoot(ctx context.Context, apiRoot string) (CollectionsInAPIRoot, error)
+	CollectionDependents(ctx context.Context, collectionID string) (Dependents, error)
+	CollectionLastModified(ctx context.Context, collectionID string) (stones.Timestamp, error)
 	CreateCollection(ctx context.Context, c Collection) error
-	DeleteCollection(ctx context.Context, collectionID string) error
-	UpdateCollection(ctx context.Context, c Collection) error
+	DeleteCollection(ctx context.Context, collectionID string, cascade bool) error
+	// UpdateCollection changes the fields of a collection named by their cabby-cli flags, all of them if none are
+	UpdateCollection(ctx context.Context, c Collection, fields ...string) error
 }
 
 // Config for a server
@@ -163,6 +209,37 @@ type Config struct {
 	SSLCert   string            `json:"ssl_cert"`
 	SSLKey    string            `json:"ssl_key"`
 	DataStore map[string]string `json:"data_store"`
+	// HTTP2 enables HTTP/2 for clients that negotiate it over TLS
+	HTTP2 bool `json:"http2"`
+	// Timeouts are in seconds; zero values use the server defaults
+	ReadHeaderTimeout int `json:"read_header_timeout"`
+	ReadTimeout       int `json:"read_timeout"`
+	WriteTimeout      int `json:"write_timeout"`
+	IdleTimeout       int `json:"idle_timeout"`
+	MaxHeaderBytes    int `json:"max_header_bytes"`
+	// TLS policy; empty values use the default profile
+	TLS TLSConfig `json:"tls"`
+	// PlainHTTP serves HTTP without TLS; use it when a reverse proxy terminates TLS
+	PlainHTTP bool `json:"plain_http"`
+	// TrustedProxies are CIDRs (or IPs) of proxies whose forwarded headers are used
+	TrustedProxies []string `json:"trusted_proxies"`
+	// ExternalURL is the base URL clients use to reach the server; discovery uses it instead of guessing
+	ExternalURL string `json:"external_url"`
+	// APIRootURLs map API root paths to the URL discovery should list for them
+	APIRootURLs map[string]string `json:"api_root_urls"`
+	// AnonymousDiscovery allows unauthenticated clients to read discovery
+	AnonymousDiscovery bool `json:"anonymous_discovery"`
+	// AnonymousAPIRoots are API root paths unauthenticated clients can read, along with their collections listing
+	AnonymousAPIRoots []string `json:"anonymous_api_roots"`
+	// TrashRetentionDays is how long deleted resources can be restored before they're purged; zero keeps them
+	TrashRetentionDays int `json:"trash_retention_days"`
+	// MetricsAddress is where the server serves its metrics, e.g. "localhost:6060"; empty doesn't serve them
+	MetricsAddress string `json:"metrics_address"`
+	// StatusTTLHours is how long the status of a request is kept after it was created, once it's complete; zero keeps
+	// them
+	StatusTTLHours int `json:"status_ttl_hours"`
+	// RetentionIntervalHours is how often the retention policies of collections are enforced; zero doesn't enforce them
+	RetentionIntervalHours int `json:"retention_interval_hours"`
 }
 
 // Parse takes a path to a config file and converts to Configs
@@ -193,19 +270,45 @@ type DataStore interface {
 	ManifestService() ManifestService
 	MigrationService() MigrationService
 	ObjectService() ObjectService
+	ObservableService() ObservableService
 	Open() error
+	RetentionService() RetentionService
+	SearchService() SearchService
 	StatusService() StatusService
+	TrashService() TrashService
 	UserService() UserService
 	VersionsService() VersionsService
 }
 
+// DeletedObject is an object version in the trash
+type DeletedObject struct {
+	ID           string    `json:"id"`
+	Version      string    `json:"version"`
+	CollectionID string    `json:"collection_id"`
+	DeletedAt    time.Time `json:"deleted_at"`
+	DeletedBy    string    `json:"deleted_by"`
+}
+
+// Dependents counts what is deleted along with an API root or collection.  Collections and objects are only deleted
+// when the delete cascades; access to the collections is removed when they're purged.
+type Dependents struct {
+	Collections     int64 `json:"collections"`
+	Objects         int64 `json:"objects"`
+	UserCollections int64 `json:"user_collections"`
+}
+
+// Cascades returns true if deleting the resource would delete collections or objects too
+func (d *Dependents) Cascades() bool {
+	return d.Collections > 0 || d.Objects > 0
+}
+
 // Discovery resource
 type Discovery struct {
 	Title       string   `json:"title"`
 	Description string   `json:"description,omitempty"`
 	Contact     string   `json:"contact,omitempty"`
 	Default     string   `json:"default,omitempty"`
-	APIRoots    []string `json:"api_roots,omitempty"`
+	APIRoots    []string `json:"api_roots"`
 }
 
 // Validate a discovery resource
@@ -221,7 +324,7 @@ func (d *Discovery) Validate() error {
 type DiscoveryService interface {
 	CreateDiscovery(ctx context.Context, d Discovery) error
 	DeleteDiscovery(ctx context.Context) error
-	Discovery(ctx context.Context) (Discovery, error)
+	Discovery(ctx context.Context, u User) (Discovery, error)
 	UpdateDiscovery(ctx context.Context, d Discovery) error
 }
 
@@ -242,12 +345,24 @@ type Error struct {
 	Details         map[string]string `json:"details,omitempty"`
 }
 
-// Filter for filtering results based on URL parameters
+// Filter for filtering results based on URL parameters; Properties are the values to match by object property,
+// IE: {"labels": ["malicious-activity", "benign"]}
 type Filter struct {
-	AddedAfter stones.Timestamp
-	IDs        string
-	Types      string
-	Versions   string
+	AddedAfter   stones.Timestamp
+	AsOf         stones.Timestamp
+	IDs          string
+	Properties   map[string][]string
+	SpecVersions string
+	Types        string
+	Versions     string
+}
+
+// Forwarded holds how a client reached the server through a trusted proxy; values are empty if a proxy didn't
+// forward them
+type Forwarded struct {
+	For   string
+	Host  string
+	Proto string
 }
 
 // ID for taxii resources
@@ -288,6 +403,71 @@ func (id *ID) IsEmpty() bool {
 	return false
 }
 
+// IngestPolicy limits the objects a collection accepts; an empty list doesn't limit anything.  A media type without a
+// version accepts every version of it.  Duplicates is what happens to object versions that already exist.
+type IngestPolicy struct {
+	MediaTypes   []string
+	AllowedTypes []string
+	DeniedTypes  []string
+	Duplicates   string
+}
+
+// Check returns why an object with the given media type can't be added to a collection; it's nil if it can
+func (p *IngestPolicy) Check(o stones.Object, mediaType string) error {
+	if len(p.MediaTypes) > 0 && !p.acceptsMediaType(mediaType) {
+		return fmt.Errorf("Media type '%v' isn't accepted by the collection, accepted media types: %v",
+			mediaType, strings.Join(p.MediaTypes, ", "))
+	}
+
+	if len(p.AllowedTypes) > 0 && !includes(p.AllowedTypes, o.Type) {
+		return fmt.Errorf("Type '%v' isn't allowed in the collection, allowed types: %v",
+			o.Type, strings.Join(p.AllowedTypes, ", "))
+	}
+
+	if includes(p.DeniedTypes, o.Type) {
+		return fmt.Errorf("Type '%v' is denied by the collection", o.Type)
+	}
+	return nil
+}
+
+// DuplicatePolicy returns the duplicate policy; an unset policy rejects duplicates
+func (p *IngestPolicy) DuplicatePolicy() string {
+	if p.Duplicates == "" {
+		return DuplicateReject
+	}
+	return p.Duplicates
+}
+
+// Validate an ingest policy
+func (p *IngestPolicy) Validate() error {
+	switch p.DuplicatePolicy() {
+	case DuplicateIdentical, DuplicateOverwrite, DuplicateReject:
+		return nil
+	}
+	return fmt.Errorf("Invalid duplicate policy: %s, expecting one of: %s, %s, %s",
+		p.Duplicates, DuplicateIdentical, DuplicateOverwrite, DuplicateReject)
+}
+
+func (p *IngestPolicy) acceptsMediaType(mediaType string) bool {
+	base := strings.Split(mediaType, ";")[0]
+
+	for _, accepted := range p.MediaTypes {
+		if accepted == mediaType || accepted == base {
+			return true
+		}
+	}
+	return false
+}
+
+func includes(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
 // Manifest resource lists a summary of objects in a collection
 type Manifest struct {
 	Objects []ManifestEntry `json:"objects,omitempty"`
@@ -301,6 +481,35 @@ type ManifestEntry struct {
 	MediaTypes []string         `json:"media_types"`
 }
 
+// Manifest20 returns the manifest in its TAXII 2.0 shape; entries for the same object are combined
+func (m *Manifest) Manifest20() (m20 Manifest20) {
+	entries := map[string]int{}
+
+	for _, e := range m.Objects {
+		i, ok := entries[e.ID]
+		if !ok {
+			entries[e.ID] = len(m20.Objects)
+			m20.Objects = append(m20.Objects, ManifestEntry20{ID: e.ID, DateAdded: e.DateAdded, MediaTypes: e.MediaTypes})
+			i = entries[e.ID]
+		}
+		m20.Objects[i].Versions = append(m20.Objects[i].Versions, e.Version)
+	}
+	return
+}
+
+// Manifest20 resource is a TAXII 2.0 manifest
+type Manifest20 struct {
+	Objects []ManifestEntry20 `json:"objects,omitempty"`
+}
+
+// ManifestEntry20 is a summary of an object and all its versions in a TAXII 2.0 manifest
+type ManifestEntry20 struct {
+	ID         string             `json:"id"`
+	DateAdded  stones.Timestamp   `json:"date_added"`
+	Versions   []stones.Timestamp `json:"versions"`
+	MediaTypes []string           `json:"media_types"`
+}
+
 // ManifestService provides manifest data
 type ManifestService interface {
 	Manifest(ctx context.Context, collectionID string, cr *Page, f Filter) (Manifest, error)
@@ -316,19 +525,53 @@ type MigrationService interface {
 type ObjectService interface {
 	CreateEnvelope(ctx context.Context, e Envelope, collectionID string, s Status, ss StatusService)
 	CreateObject(ctx context.Context, collectionID string, o stones.Object) error
-	DeleteObject(ctx context.Context, collectionID, objecteID string) error
+	CreateObjects(ctx context.Context, objects <-chan json.RawMessage, collectionID string, s Status, ss StatusService)
+	DeleteObject(ctx context.Context, collectionID, objecteID string, f Filter) error
 	Object(ctx context.Context, collectionID, objectID string, f Filter) ([]stones.Object, error)
 	Objects(ctx context.Context, collectionID string, cr *Page, f Filter) ([]stones.Object, error)
+	ValidateObjects(ctx context.Context, objects <-chan json.RawMessage, collectionID string) ValidationReport
+}
+
+// Observable is a value an object indicates or observed; types are cyber observable types and file hashes are typed
+// by their hash, IE: "ipv4-addr" or "file:hashes.SHA-256"
+type Observable struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// ObservableMatch is an object version with an observable that was searched for
+type ObservableMatch struct {
+	APIRootPath  string           `json:"api_root"`
+	CollectionID string           `json:"collection_id"`
+	ID           string           `json:"id"`
+	Version      stones.Timestamp `json:"version"`
+	Observable   Observable       `json:"observable"`
+	Object       json.RawMessage  `json:"object"`
+}
+
+// ObservableMatches are the object versions with the observables searched for
+type ObservableMatches struct {
+	Objects []ObservableMatch `json:"objects"`
+}
+
+// ObservableService finds objects by the observables extracted from them when they're added
+type ObservableService interface {
+	IndexObservables(ctx context.Context) (int64, error)
+	ObservableMatches(ctx context.Context, values []string, p *Page) (ObservableMatches, error)
 }
 
 // Page is used for paginated requests to represent the requested data range
 type Page struct {
 	Limit uint64
+	// Offset is the first item of a TAXII 2.0 range
+	Offset uint64
 	// Used for setting X-TAXII-Date-Added-First
 	MinimumAddedAfter stones.Timestamp
 	// Used for setting X-TAXII-Date-Added-Last
 	MaximumAddedAfter stones.Timestamp
 	Total             uint64
+	// PerObject pages over objects instead of their versions; a TAXII 2.0 manifest lists each object once
+	PerObject bool
 }
 
 // NewPage returns a Page given a string from the 'Page' HTTP header string
@@ -351,6 +594,28 @@ func NewPage(limit string) (p Page, err error) {
 	return p, errors.New("Invalid limit specified")
 }
 
+// NewRangePage returns a Page given a TAXII 2.0 'Range' HTTP header with the syntax 'items X-Y' ('items=X-Y' is
+// accepted too)
+func NewRangePage(r string) (p Page, err error) {
+	var first, last uint64
+
+	_, err = fmt.Sscanf(strings.Replace(strings.TrimSpace(r), "=", " ", 1), "items %d-%d", &first, &last)
+	if err != nil || last < first {
+		return p, fmt.Errorf("Invalid range specified: %v", r)
+	}
+
+	p.Offset, p.Limit = first, last-first+1
+	return p, err
+}
+
+// ContentRange returns a TAXII 2.0 'Content-Range' HTTP header for the items returned in the page
+func (p *Page) ContentRange(items int) string {
+	if items <= 0 {
+		return fmt.Sprintf("items */%d", p.Total)
+	}
+	return fmt.Sprintf("items %d-%d/%d", p.Offset, p.Offset+uint64(items)-1, p.Total)
+}
+
 // AddedAfterFirst returns the first added after as a string
 func (p *Page) AddedAfterFirst() string {
 	return p.MinimumAddedAfter.String()
@@ -386,6 +651,83 @@ func (p *Page) Valid() bool {
 	return false
 }
 
+// Purged counts the resources permanently removed from the trash
+type Purged struct {
+	APIRoots    int64 `json:"api_roots"`
+	Collections int64 `json:"collections"`
+	Objects     int64 `json:"objects"`
+}
+
+// Retention counts the object versions removed from a collection by each rule of its retention policy
+type Retention struct {
+	CollectionID string `json:"collection_id"`
+	MaxAge       int64  `json:"max_age"`
+	MaxVersions  int64  `json:"max_versions"`
+	Expired      int64  `json:"expired"`
+}
+
+// Total returns how many object versions were removed
+func (r *Retention) Total() int64 {
+	return r.MaxAge + r.MaxVersions + r.Expired
+}
+
+// RetentionPolicy limits how long objects are kept in a collection; zero values don't limit anything.  MaxAgeDays
+// removes object versions added more than that many days ago, MaxVersions keeps that many of the latest versions of an
+// object and DropExpired removes object versions whose valid_until has passed.
+type RetentionPolicy struct {
+	MaxAgeDays  int
+	MaxVersions int
+	DropExpired bool
+}
+
+// Enforced returns true if the policy removes anything
+func (p *RetentionPolicy) Enforced() bool {
+	return p.MaxAgeDays > 0 || p.MaxVersions > 0 || p.DropExpired
+}
+
+// Validate a retention policy
+func (p *RetentionPolicy) Validate() error {
+	if p.MaxAgeDays < 0 {
+		return fmt.Errorf("Invalid max age days: %v, expecting 0 or more", p.MaxAgeDays)
+	}
+	if p.MaxVersions < 0 {
+		return fmt.Errorf("Invalid max versions: %v, expecting 0 or more", p.MaxVersions)
+	}
+	return nil
+}
+
+// RetentionService interface for enforcing the retention policies of collections
+type RetentionService interface {
+	EnforceRetention(ctx context.Context, dryRun bool) ([]Retention, error)
+}
+
+// SearchHit is an object version in a manifest entry's shape, with the object, whose text matched a search
+type SearchHit struct {
+	CollectionID string           `json:"collection_id"`
+	ID           string           `json:"id"`
+	DateAdded    stones.Timestamp `json:"date_added"`
+	Version      stones.Timestamp `json:"version"`
+	MediaTypes   []string         `json:"media_types"`
+	Object       json.RawMessage  `json:"object"`
+}
+
+// SearchHits are the object versions that matched a search, best matches first
+type SearchHits struct {
+	Objects []SearchHit `json:"objects"`
+}
+
+// ErrSearchUnavailable is returned by a SearchService that can't search text
+var ErrSearchUnavailable = errors.New("Searching objects isn't available on this server")
+
+// ErrIdempotencyKeyExists is returned by a StatusService when a request of the user to the collection already has the
+// idempotency key
+var ErrIdempotencyKeyExists = errors.New("Idempotency key is already used")
+
+// SearchService searches the text of objects (name, description, labels and external references)
+type SearchService interface {
+	Search(ctx context.Context, apiRoot, query string, p *Page) (SearchHits, error)
+}
+
 // Status represents a TAXII status object
 type Status struct {
 	ID               ID               `json:"id"`
@@ -393,11 +735,14 @@ type Status struct {
 	RequestTimestamp stones.Timestamp `json:"request_timestamp"`
 	TotalCount       int64            `json:"total_count"`
 	SuccessCount     int64            `json:"success_count"`
-	Successes        []string         `json:"successes"`
+	Successes        []StatusDetails  `json:"successes"`
 	FailureCount     int64            `json:"failure_count"`
-	Failures         []string         `json:"failures"`
+	Failures         []StatusDetails  `json:"failures"`
 	PendingCount     int64            `json:"pending_count"`
 	Pendings         []string         `json:"pendings"`
+	// CollectionID is the collection the objects were posted to; CreatedBy is the user that posted them
+	CollectionID string `json:"-"`
+	CreatedBy    string `json:"-"`
 }
 
 // NewStatus returns a status struct
@@ -415,19 +760,134 @@ func NewStatus(objects int) (Status, error) {
 	return Status{ID: id, Status: "pending", TotalCount: count, PendingCount: count}, err
 }
 
+// Status20 returns the status in its TAXII 2.0 shape
+func (s *Status) Status20() Status20 {
+	s20 := Status20{
+		ID:               s.ID,
+		Status:           s.Status,
+		RequestTimestamp: s.RequestTimestamp,
+		TotalCount:       s.TotalCount,
+		SuccessCount:     s.SuccessCount,
+		Successes:        []string{},
+		FailureCount:     s.FailureCount,
+		Failures:         []StatusFailure20{},
+		PendingCount:     s.PendingCount,
+		Pendings:         s.Pendings,
+	}
+
+	for _, sd := range s.Successes {
+		s20.Successes = append(s20.Successes, sd.ID)
+	}
+	for _, f := range s.Failures {
+		s20.Failures = append(s20.Failures, StatusFailure20{ID: f.ID, Message: f.Message})
+	}
+	return s20
+}
+
+// Status20 represents a TAXII 2.0 status object; successes are object ids and failures don't have versions
+type Status20 struct {
+	ID               ID                `json:"id"`
+	Status           string            `json:"status"`
+	RequestTimestamp stones.Timestamp  `json:"request_timestamp"`
+	TotalCount       int64             `json:"total_count"`
+	SuccessCount     int64             `json:"success_count"`
+	Successes        []string          `json:"successes"`
+	FailureCount     int64             `json:"failure_count"`
+	Failures         []StatusFailure20 `json:"failures"`
+	PendingCount     int64             `json:"pending_count"`
+	Pendings         []string          `json:"pendings"`
+}
+
+// StatusFailure20 identifies an object that failed in a TAXII 2.0 status
+type StatusFailure20 struct {
+	ID      string `json:"id"`
+	Message string `json:"message,omitempty"`
+}
+
+// StatusDetails identifies an object version in a status and why it failed
+type StatusDetails struct {
+	ID      string `json:"id"`
+	Version string `json:"version"`
+	Message string `json:"message,omitempty"`
+}
+
+// NewStatusDetails returns the details of an object that failed; properties the object didn't have are left empty
+func NewStatusDetails(o stones.Object, err error) StatusDetails {
+	var sd StatusDetails
+
+	if o.ID.Type != "" {
+		sd.ID = o.ID.String()
+	}
+	if !o.Modified.IsZero() {
+		sd.Version = o.Modified.String()
+	}
+	if err != nil {
+		sd.Message = err.Error()
+	}
+	return sd
+}
+
 // StatusService for status structs
 type StatusService interface {
+	CreateIdempotencyKey(ctx context.Context, collectionID, key, statusID string) error
 	CreateStatus(ctx context.Context, s Status) error
-	Status(ctx context.Context, statusID string) (Status, error)
+	DeleteIdempotencyKey(ctx context.Context, collectionID, key string) error
+	ExpireStatuses(ctx context.Context, createdBefore time.Time) (int64, error)
+	IdempotentStatus(ctx context.Context, collectionID, key string) (Status, error)
+	Status(ctx context.Context, apiRoot, statusID string) (Status, error)
+	Statuses(ctx context.Context, apiRoot string, p *Page) (Statuses, error)
 	UpdateStatus(ctx context.Context, s Status) error
 }
 
+// Statuses are the statuses of the requests a user made, most recent first
+type Statuses struct {
+	Statuses []Status `json:"statuses"`
+}
+
+// TLSConfig for a server.  Versions are strings like "1.2", cipher suites and curves use their Go names (IE:
+// "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", "P256") and client auth is one of "none", "request", "require",
+// "verify_if_given" or "require_and_verify"; the verify modes need a client CA file.
+type TLSConfig struct {
+	MinVersion   string   `json:"min_version"`
+	MaxVersion   string   `json:"max_version"`
+	CipherSuites []string `json:"cipher_suites"`
+	Curves       []string `json:"curves"`
+	ClientAuth   string   `json:"client_auth"`
+	ClientCAFile string   `json:"client_ca_file"`
+}
+
+// TrashService provides resources that were deleted; they can be restored until they're purged
+type TrashService interface {
+	DeletedObjects(ctx context.Context, collectionID string) ([]DeletedObject, error)
+	Purge(ctx context.Context, deletedBefore time.Time) (Purged, error)
+	RestoreAPIRoot(ctx context.Context, path string) error
+	RestoreCollection(ctx context.Context, collectionID string) error
+	RestoreObject(ctx context.Context, collectionID, objectID string) error
+}
+
+// AnonymousEmail identifies the user of unauthenticated requests; it isn't a valid e-mail so no user can have it
+const AnonymousEmail = "anonymous"
+
 // User represents a cabby user
 // should User and UserCollectionList be combined?
 type User struct {
 	Email                string `json:"email"`
 	CanAdmin             bool   `json:"can_admin"`
 	CollectionAccessList map[ID]CollectionAccess
+	// APIRootPaths are API roots the user can read without being able to read a collection in them
+	APIRootPaths []string `json:"-"`
+}
+
+// NewAnonymousUser returns a user for unauthenticated requests that can only read the given collections
+func NewAnonymousUser(ucl UserCollectionList) User {
+	u := User{Email: AnonymousEmail, CollectionAccessList: map[ID]CollectionAccess{}}
+
+	for id, ca := range ucl.CollectionAccessList {
+		if ca.CanRead {
+			u.CollectionAccessList[id] = CollectionAccess{ID: ca.ID, CanRead: true}
+		}
+	}
+	return u
 }
 
 // Defined returns a bool indicating if a user is defined
@@ -456,6 +916,7 @@ type UserCollectionList struct {
 
 // UserService provides Users behavior
 type UserService interface {
+	AnonymousCollections(ctx context.Context) (UserCollectionList, error)
 	CreateUser(ctx context.Context, u User, password string) error
 	DeleteUser(ctx context.Context, u string) error
 	UpdateUser(ctx context.Context, u User) error
@@ -466,6 +927,15 @@ type UserService interface {
 	UserCollections(ctx context.Context, user string) (UserCollectionList, error)
 }
 
+// ValidationReport lists what would happen to each object if it were added to a collection
+type ValidationReport struct {
+	TotalCount   int64           `json:"total_count"`
+	SuccessCount int64           `json:"success_count"`
+	Successes    []StatusDetails `json:"successes"`
+	FailureCount int64           `json:"failure_count"`
+	Failures     []StatusDetails `json:"failures"`
+}
+
 // Versions contains a list of versions for an object
 type Versions struct {
 	Versions []string `json:"versions"`
@@ -3,6 +3,7 @@ package cabby
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"os"
 	"strconv"
@@ -65,6 +66,27 @@ func TestAPIRootIncludesMinVersion(t *testing.T) {
 	}
 }
 
+func TestAPIRootSupportsVersion(t *testing.T) {
+	tests := []struct {
+		apiRoot  APIRoot
+		version  string
+		expected bool
+	}{
+		{APIRoot{Versions: []string{TaxiiVersion}}, TaxiiVersion, true},
+		{APIRoot{Versions: []string{TaxiiVersion}}, TaxiiVersion20, false},
+		{APIRoot{Versions: []string{TaxiiVersion20, TaxiiVersion}}, TaxiiVersion20, true},
+		{APIRoot{}, TaxiiVersion20, false},
+	}
+
+	for _, test := range tests {
+		result := test.apiRoot.SupportsVersion(test.version)
+
+		if result != test.expected {
+			t.Error("Got:", result, "Expected:", test.expected, "Versions:", test.apiRoot.Versions)
+		}
+	}
+}
+
 func TestConfigParse(t *testing.T) {
 	c := Config{}.Parse("config/cabby.example.json")
 
@@ -74,6 +96,27 @@ func TestConfigParse(t *testing.T) {
 	if c.Port != 1234 {
 		t.Error("Got:", strconv.Itoa(1234), "Expected:", strconv.Itoa(1234))
 	}
+	if !c.HTTP2 {
+		t.Error("Got:", c.HTTP2, "Expected:", true)
+	}
+	if c.ReadHeaderTimeout != 10 {
+		t.Error("Got:", c.ReadHeaderTimeout, "Expected:", 10)
+	}
+	if c.MaxHeaderBytes != 1048576 {
+		t.Error("Got:", c.MaxHeaderBytes, "Expected:", 1048576)
+	}
+	if c.TrashRetentionDays != 30 {
+		t.Error("Got:", c.TrashRetentionDays, "Expected:", 30)
+	}
+	if c.MetricsAddress != "localhost:6060" {
+		t.Error("Got:", c.MetricsAddress, "Expected:", "localhost:6060")
+	}
+	if c.StatusTTLHours != 168 {
+		t.Error("Got:", c.StatusTTLHours, "Expected:", 168)
+	}
+	if c.RetentionIntervalHours != 1 {
+		t.Error("Got:", c.RetentionIntervalHours, "Expected:", 1)
+	}
 }
 
 func TestParseConfigNotFound(t *testing.T) {
@@ -160,6 +203,7 @@ func TestCollectionValidate(t *testing.T) {
 		{Collection{Title: validTitle}, true},
 		{Collection{ID: validID}, true},
 		{Collection{}, true},
+		{Collection{ID: validID, Title: validTitle, RetentionPolicy: RetentionPolicy{MaxVersions: -1}}, true},
 	}
 
 	for _, test := range tests {
@@ -175,6 +219,79 @@ func TestCollectionValidate(t *testing.T) {
 	}
 }
 
+func TestIngestPolicyCheck(t *testing.T) {
+	malware := stones.Object{Type: "malware"}
+	indicator := stones.Object{Type: "indicator"}
+	stix21 := SpecVersionMediaType(SpecVersion21)
+
+	tests := []struct {
+		policy      IngestPolicy
+		object      stones.Object
+		mediaType   string
+		expectError bool
+	}{
+		{IngestPolicy{}, malware, StixContentType20, false},
+		{IngestPolicy{MediaTypes: []string{StixContentType20}}, malware, StixContentType20, false},
+		{IngestPolicy{MediaTypes: []string{StixContentType20}}, malware, stix21, true},
+		{IngestPolicy{MediaTypes: []string{StixMediaType}}, malware, stix21, false},
+		{IngestPolicy{AllowedTypes: []string{"indicator"}}, indicator, StixContentType20, false},
+		{IngestPolicy{AllowedTypes: []string{"indicator"}}, malware, StixContentType20, true},
+		{IngestPolicy{DeniedTypes: []string{"malware"}}, indicator, StixContentType20, false},
+		{IngestPolicy{DeniedTypes: []string{"malware"}}, malware, StixContentType20, true},
+	}
+
+	for _, test := range tests {
+		err := test.policy.Check(test.object, test.mediaType)
+		if (err != nil) != test.expectError {
+			t.Error("Got:", err, "Expected error:", test.expectError, "Policy:", test.policy, "Type:", test.object.Type)
+		}
+	}
+}
+
+func TestIngestPolicyValidate(t *testing.T) {
+	tests := []struct {
+		duplicates  string
+		expectError bool
+	}{
+		{"", false},
+		{DuplicateIdentical, false},
+		{DuplicateOverwrite, false},
+		{DuplicateReject, false},
+		{"ignore", true},
+	}
+
+	for _, test := range tests {
+		p := IngestPolicy{Duplicates: test.duplicates}
+		err := p.Validate()
+		if (err != nil) != test.expectError {
+			t.Error("Got:", err, "Expected error:", test.expectError, "Duplicates:", test.duplicates)
+		}
+	}
+}
+
+func TestManifestManifest20(t *testing.T) {
+	first, _ := stones.TimestampFromString("2016-04-06T20:03:48.000Z")
+	second, _ := stones.TimestampFromString("2016-04-07T20:03:48.000Z")
+
+	m := Manifest{Objects: []ManifestEntry{
+		{ID: "indicator--1", DateAdded: first, Version: first, MediaTypes: []string{StixContentType20}},
+		{ID: "malware--2", DateAdded: first, Version: first, MediaTypes: []string{StixContentType20}},
+		{ID: "indicator--1", DateAdded: second, Version: second, MediaTypes: []string{StixContentType20}},
+	}}
+
+	result := m.Manifest20()
+
+	if len(result.Objects) != 2 {
+		t.Fatal("Got:", len(result.Objects), "Expected:", 2)
+	}
+	if result.Objects[0].ID != "indicator--1" || len(result.Objects[0].Versions) != 2 {
+		t.Error("Got:", result.Objects[0], "Expected: 2 versions of indicator--1")
+	}
+	if result.Objects[1].ID != "malware--2" || len(result.Objects[1].Versions) != 1 {
+		t.Error("Got:", result.Objects[1], "Expected: 1 version of malware--2")
+	}
+}
+
 func TestNewPage(t *testing.T) {
 	tests := []struct {
 		input       string
@@ -201,6 +318,53 @@ func TestNewPage(t *testing.T) {
 	}
 }
 
+func TestNewRangePage(t *testing.T) {
+	tests := []struct {
+		input       string
+		resultPage  Page
+		expectError bool
+	}{
+		{"items 0-9", Page{Offset: 0, Limit: 10}, false},
+		{"items 10-10", Page{Offset: 10, Limit: 1}, false},
+		{"items=5-14", Page{Offset: 5, Limit: 10}, false},
+		{"items 9-0", Page{}, true},
+		{"items 0-", Page{}, true},
+		{"bytes 0-9", Page{}, true},
+		{"", Page{}, true},
+	}
+
+	for _, test := range tests {
+		result, err := NewRangePage(test.input)
+		if result != test.resultPage {
+			t.Error("Got:", result, "Expected:", test.resultPage, "Range:", test.input)
+		}
+
+		if (err != nil) != test.expectError {
+			t.Error("Got:", err, "Expected error:", test.expectError, "Range:", test.input)
+		}
+	}
+}
+
+func TestPageContentRange(t *testing.T) {
+	tests := []struct {
+		page     Page
+		items    int
+		expected string
+	}{
+		{Page{Offset: 0, Limit: 10, Total: 20}, 10, "items 0-9/20"},
+		{Page{Offset: 15, Limit: 10, Total: 20}, 5, "items 15-19/20"},
+		{Page{Offset: 30, Limit: 10, Total: 20}, 0, "items */20"},
+	}
+
+	for _, test := range tests {
+		result := test.page.ContentRange(test.items)
+
+		if result != test.expected {
+			t.Error("Got:", result, "Expected:", test.expected)
+		}
+	}
+}
+
 func TestPageAddedAfterFirst(t *testing.T) {
 	now := stones.NewTimestamp()
 
@@ -408,6 +572,124 @@ func TestNewStatusError(t *testing.T) {
 	}
 }
 
+func TestRetentionTotal(t *testing.T) {
+	r := Retention{MaxAge: 1, MaxVersions: 2, Expired: 3}
+	if r.Total() != 6 {
+		t.Error("Got:", r.Total(), "Expected:", 6)
+	}
+}
+
+func TestRetentionPolicyEnforced(t *testing.T) {
+	tests := []struct {
+		policy   RetentionPolicy
+		expected bool
+	}{
+		{RetentionPolicy{}, false},
+		{RetentionPolicy{MaxAgeDays: 30}, true},
+		{RetentionPolicy{MaxVersions: 3}, true},
+		{RetentionPolicy{DropExpired: true}, true},
+	}
+
+	for _, test := range tests {
+		result := test.policy.Enforced()
+		if result != test.expected {
+			t.Error("Got:", result, "Expected:", test.expected, "Policy:", test.policy)
+		}
+	}
+}
+
+func TestRetentionPolicyValidate(t *testing.T) {
+	tests := []struct {
+		policy      RetentionPolicy
+		expectError bool
+	}{
+		{RetentionPolicy{}, false},
+		{RetentionPolicy{MaxAgeDays: 30, MaxVersions: 3, DropExpired: true}, false},
+		{RetentionPolicy{MaxAgeDays: -1}, true},
+		{RetentionPolicy{MaxVersions: -1}, true},
+	}
+
+	for _, test := range tests {
+		err := test.policy.Validate()
+		if (err != nil) != test.expectError {
+			t.Error("Got:", err, "Expected error:", test.expectError, "Policy:", test.policy)
+		}
+	}
+}
+
+func TestStatusStatus20(t *testing.T) {
+	s, _ := NewStatus(3)
+	s.Successes = []StatusDetails{{ID: "indicator--1", Message: "Object version already exists with the same content"}}
+	s.Failures = []StatusDetails{
+		{ID: "indicator--2", Version: "2016-04-06T20:07:09.000Z", Message: "Type 'indicator' is denied by the collection"},
+		{ID: "malware--3"}}
+
+	result := s.Status20()
+
+	if result.ID != s.ID || result.TotalCount != s.TotalCount {
+		t.Error("Got:", result, "Expected:", s)
+	}
+	if len(result.Successes) != 1 || result.Successes[0] != s.Successes[0].ID {
+		t.Error("Got:", result.Successes, "Expected:", s.Successes[0].ID)
+	}
+	if len(result.Failures) != len(s.Failures) {
+		t.Fatal("Got:", len(result.Failures), "Expected:", len(s.Failures))
+	}
+	for i, f := range result.Failures {
+		if f.ID != s.Failures[i].ID || f.Message != s.Failures[i].Message {
+			t.Error("Got:", f, "Expected:", s.Failures[i])
+		}
+	}
+}
+
+func TestNewStatusDetails(t *testing.T) {
+	o, err := stones.NewObject("malware")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		object   stones.Object
+		err      error
+		expected StatusDetails
+	}{
+		{o, errors.New("Invalid object"), StatusDetails{ID: o.ID.String(), Version: o.Modified.String(), Message: "Invalid object"}},
+		{stones.Object{}, errors.New("Invalid object"), StatusDetails{Message: "Invalid object"}},
+	}
+
+	for _, test := range tests {
+		result := NewStatusDetails(test.object, test.err)
+		if result != test.expected {
+			t.Error("Got:", result, "Expected:", test.expected)
+		}
+	}
+}
+
+func TestNewAnonymousUser(t *testing.T) {
+	readable, _ := NewID()
+	writable, _ := NewID()
+
+	u := NewAnonymousUser(UserCollectionList{CollectionAccessList: map[ID]CollectionAccess{
+		readable: CollectionAccess{ID: readable, CanRead: true, CanWrite: true},
+		writable: CollectionAccess{ID: writable, CanWrite: true},
+	}})
+
+	if u.Email != AnonymousEmail || u.CanAdmin {
+		t.Error("Got:", u, "Expected an anonymous user")
+	}
+	if !u.Defined() {
+		t.Error("Expected the anonymous user to be defined")
+	}
+
+	expected := CollectionAccess{ID: readable, CanRead: true}
+	if u.CollectionAccessList[readable] != expected {
+		t.Error("Got:", u.CollectionAccessList[readable], "Expected:", expected)
+	}
+	if _, ok := u.CollectionAccessList[writable]; ok {
+		t.Error("Expected collections that can't be read to be excluded")
+	}
+}
+
 func TestUserDefined(t *testing.T) {
 	tests := []struct {
 		user     User
@@ -42,12 +42,24 @@ func cmdDeleteAPIRoot() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "apiRoot",
 		Short: "Delete a apiRoot",
-		Long:  `delete apiRoot is used to delete a apiRoot from a server`,
+		Long: `delete apiRoot is used to delete a apiRoot from a server; its collections and their objects are only
+deleted with --cascade, and --dry-run reports what would be deleted`,
 		Run: func(cmd *cobra.Command, args []string) {
 			ds := dataStoreFromConfig(configPath)
 			defer ds.Close()
 
-			err := ds.APIRootService().DeleteAPIRoot(context.Background(), apiRootPath)
+			d, err := ds.APIRootService().APIRootDependents(cliContext(), apiRootPath)
+			if err != nil {
+				log.WithFields(log.Fields{"error": err, "path": apiRootPath}).Error("Failed to read dependents")
+				return
+			}
+			if deleteDryRun {
+				printDependents(d)
+				return
+			}
+
+			log.WithFields(log.Fields{"dependents": d, "path": apiRootPath}).Info("Deleting")
+			err = ds.APIRootService().DeleteAPIRoot(cliContext(), apiRootPath, deleteCascade)
 			if err != nil {
 				log.WithFields(log.Fields{"error": err}).Error("Failed to delete")
 			}
@@ -59,7 +71,7 @@ func cmdDeleteAPIRoot() *cobra.Command {
 		},
 	}
 
-	return withAPIRootPathFlag(cmd)
+	return withDeleteFlags(withAPIRootPathFlag(cmd))
 }
 
 func cmdUpdateAPIRoot() *cobra.Command {
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"strings"
 
 	"github.com/pladdy/cabby"
 	log "github.com/sirupsen/logrus"
@@ -22,10 +23,13 @@ func cmdCreateCollection() *cobra.Command {
 				log.WithFields(log.Fields{"error": err, "id": collectionID}).Error("Failed to create ID")
 			}
 			newCollection := cabby.Collection{
-				APIRootPath: apiRootPath,
-				ID:          id,
-				Title:       collectionTitle,
-				Description: collectionDescription}
+				APIRootPath:     apiRootPath,
+				ID:              id,
+				Title:           collectionTitle,
+				Description:     collectionDescription,
+				AnonymousRead:   collectionAnonymous,
+				IngestPolicy:    ingestPolicyFromFlags(),
+				RetentionPolicy: retentionPolicyFromFlags()}
 
 			err = ds.CollectionService().CreateCollection(context.Background(), newCollection)
 			if err != nil {
@@ -44,12 +48,24 @@ func cmdDeleteCollection() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "collection",
 		Short: "Delete a collection",
-		Long:  `delete collection is used to delete a collection from a server`,
+		Long: `delete collection is used to delete a collection from a server; its objects are only deleted with
+--cascade, and --dry-run reports what would be deleted`,
 		Run: func(cmd *cobra.Command, args []string) {
 			ds := dataStoreFromConfig(configPath)
 			defer ds.Close()
 
-			err := ds.CollectionService().DeleteCollection(context.Background(), collectionID)
+			d, err := ds.CollectionService().CollectionDependents(cliContext(), collectionID)
+			if err != nil {
+				log.WithFields(log.Fields{"error": err, "id": collectionID}).Error("Failed to read dependents")
+				return
+			}
+			if deleteDryRun {
+				printDependents(d)
+				return
+			}
+
+			log.WithFields(log.Fields{"dependents": d, "id": collectionID}).Info("Deleting")
+			err = ds.CollectionService().DeleteCollection(cliContext(), collectionID, deleteCascade)
 			if err != nil {
 				log.WithFields(log.Fields{"error": err, "id": collectionID}).Error("Failed to delete")
 			}
@@ -61,14 +77,15 @@ func cmdDeleteCollection() *cobra.Command {
 		},
 	}
 
-	return withCollectionIDFlag(cmd)
+	return withDeleteFlags(withCollectionIDFlag(cmd))
 }
 
 func cmdUpdateCollection() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "collection",
 		Short: "Update a collection",
-		Long:  `update collection is used to update a collection on the server`,
+		Long: `update collection is used to update a collection on the server; settings without a flag keep their
+values`,
 		Run: func(cmd *cobra.Command, args []string) {
 			ds := dataStoreFromConfig(configPath)
 			defer ds.Close()
@@ -78,14 +95,17 @@ func cmdUpdateCollection() *cobra.Command {
 				log.WithFields(log.Fields{"error": err, "id": id}).Error("Failed to create ID")
 			}
 			newCollection := cabby.Collection{
-				APIRootPath: apiRootPath,
-				ID:          id,
-				Title:       collectionTitle,
-				Description: collectionDescription}
+				APIRootPath:     apiRootPath,
+				ID:              id,
+				Title:           collectionTitle,
+				Description:     collectionDescription,
+				AnonymousRead:   collectionAnonymous,
+				IngestPolicy:    ingestPolicyFromFlags(),
+				RetentionPolicy: retentionPolicyFromFlags()}
 
-			err = ds.CollectionService().UpdateCollection(context.Background(), newCollection)
+			err = ds.CollectionService().UpdateCollection(context.Background(), newCollection, changedFlags(cmd, collectionFlags)...)
 			if err != nil {
-				log.WithFields(log.Fields{"error": err, "collection": newCollection}).Error("Failed to create")
+				log.WithFields(log.Fields{"error": err, "collection": newCollection}).Error("Failed to update")
 			}
 		},
 		PreRun: func(cmd *cobra.Command, args []string) {
@@ -96,6 +116,44 @@ func cmdUpdateCollection() *cobra.Command {
 	return withCollectionFlags(cmd)
 }
 
+// collectionFlags name the settings of a collection that can be updated
+var collectionFlags = []string{
+	"api_root_path", "title", "description", "anonymous_read", "media_types", "allowed_types", "denied_types",
+	"duplicates", "max_age_days", "max_versions", "drop_expired"}
+
+// changedFlags returns the names of the flags set on the command line
+func changedFlags(cmd *cobra.Command, names []string) (changed []string) {
+	for _, name := range names {
+		if cmd.Flags().Changed(name) {
+			changed = append(changed, name)
+		}
+	}
+	return
+}
+
+func ingestPolicyFromFlags() cabby.IngestPolicy {
+	return cabby.IngestPolicy{
+		MediaTypes:   splitFlag(collectionMediaTypes),
+		AllowedTypes: splitFlag(collectionAllowedTypes),
+		DeniedTypes:  splitFlag(collectionDeniedTypes),
+		Duplicates:   collectionDuplicates}
+}
+
+func retentionPolicyFromFlags() cabby.RetentionPolicy {
+	return cabby.RetentionPolicy{
+		MaxAgeDays:  collectionMaxAgeDays,
+		MaxVersions: collectionMaxVersions,
+		DropExpired: collectionDropExpired}
+}
+
+// splitFlag returns the values of a comma separated flag; an unset flag has none
+func splitFlag(flag string) []string {
+	if flag == "" {
+		return nil
+	}
+	return strings.Split(flag, ",")
+}
+
 func validateCollectionFlags() {
 	if apiRootPath == "" {
 		log.Fatal("API Root Path required")
@@ -4,6 +4,7 @@ import (
 	"context"
 	"os"
 	"os/exec"
+	"strings"
 	"testing"
 
 	"github.com/pladdy/cabby"
@@ -16,7 +17,9 @@ func TestCreateCollection(t *testing.T) {
 
 	command, resource := "create", "collection"
 	expected := tester.Collection
-	createTestUser(tester.Collection.ID.String())
+	// a new collection has no objects, so it has no media types
+	expected.MediaTypes = nil
+	createTestAPIRoot(expected.APIRootPath)
 
 	tests := []struct {
 		args        []string
@@ -43,6 +46,8 @@ func TestCreateCollection(t *testing.T) {
 		}
 
 		if !test.expectError {
+			createTestUser(expected.ID.String())
+
 			ds := testDataStore()
 			ctx := cabby.WithUser(context.Background(), tester.User)
 			result, _ := ds.CollectionService().Collection(ctx, expected.APIRootPath, expected.ID.String())
@@ -94,12 +99,54 @@ func TestDeleteCollection(t *testing.T) {
 	}
 }
 
+func TestDeleteCollectionCascade(t *testing.T) {
+	setUp()
+	defer tearDown()
+
+	createTestCollection()
+	ds := testDataStore()
+	err := ds.ObjectService().CreateObject(context.Background(), tester.CollectionID, tester.Object)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a dry run reports what would be deleted
+	out, err := exec.Command(
+		CLICommand, "delete", "collection", "--config", CLIConfig, "-i", tester.CollectionID, "--dry-run").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"collections":0,"objects":1,"user_collections":1}`
+	if strings.TrimSpace(string(out)) != expected {
+		t.Error("Got:", string(out), "Expected:", expected)
+	}
+
+	tests := []struct {
+		args    []string
+		deleted bool
+	}{
+		{[]string{"delete", "collection", "-i", tester.CollectionID, "--dry-run"}, false},
+		{[]string{"delete", "collection", "-i", tester.CollectionID}, false},
+		{[]string{"delete", "collection", "-i", tester.CollectionID, "--cascade"}, true},
+	}
+
+	for _, test := range tests {
+		runCommand(t, test.args...)
+
+		result, _ := ds.CollectionService().Collection(tester.Context, tester.APIRootPath, tester.CollectionID)
+		if result.ID.IsEmpty() != test.deleted {
+			t.Error("Got:", result.ID, "Expected deleted:", test.deleted, "Args:", test.args)
+		}
+	}
+}
+
 func TestUpdateCollection(t *testing.T) {
 	setUp()
 	defer tearDown()
 
-	createTestUser(tester.Collection.ID.String())
 	expected := tester.Collection
+	expected.MediaTypes = nil
+	createTestAPIRoot(expected.APIRootPath)
 
 	// create a collection to modify
 	cmd := exec.Command(CLICommand, "create", "collection", "--config", CLIConfig,
@@ -114,9 +161,11 @@ func TestUpdateCollection(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+	createTestUser(expected.ID.String())
 
-	// test updates
+	// test updates; collections can only move to api roots that exist
 	expected.APIRootPath = "/updated/api/root/path/"
+	createTestAPIRoot(expected.APIRootPath)
 	expected.Title = "an updated title"
 	expected.Description = "an updated description"
 
@@ -157,3 +206,128 @@ func TestUpdateCollection(t *testing.T) {
 		}
 	}
 }
+
+func TestCreateCollectionAnonymousRead(t *testing.T) {
+	setUp()
+	defer tearDown()
+
+	expected := tester.Collection
+	createTestAPIRoot(expected.APIRootPath)
+
+	cmd := exec.Command(CLICommand, "create", "collection", "--config", CLIConfig,
+		"-a", expected.APIRootPath,
+		"-i", expected.ID.String(),
+		"-t", expected.Title,
+		"--anonymous_read")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stdout
+
+	err := cmd.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ds := testDataStore()
+	result, err := ds.UserService().AnonymousCollections(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !result.CollectionAccessList[expected.ID].CanRead {
+		t.Error("Got:", result.CollectionAccessList, "Expected:", expected.ID)
+	}
+}
+
+func TestCreateCollectionIngestPolicy(t *testing.T) {
+	setUp()
+	defer tearDown()
+
+	expected := tester.Collection
+	createTestAPIRoot(expected.APIRootPath)
+
+	cmd := exec.Command(CLICommand, "create", "collection", "--config", CLIConfig,
+		"-a", expected.APIRootPath,
+		"-i", expected.ID.String(),
+		"-t", expected.Title,
+		"--media_types", cabby.StixMediaType,
+		"--denied_types", "malware,tool",
+		"--duplicates", cabby.DuplicateIdentical)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stdout
+
+	err := cmd.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	createTestUser(expected.ID.String())
+
+	ds := testDataStore()
+	ctx := cabby.WithUser(context.Background(), tester.User)
+	result, err := ds.CollectionService().Collection(ctx, expected.APIRootPath, expected.ID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	policy := result.IngestPolicy
+	if len(policy.MediaTypes) != 1 || policy.MediaTypes[0] != cabby.StixMediaType {
+		t.Error("Got:", policy.MediaTypes, "Expected:", cabby.StixMediaType)
+	}
+	if policy.AllowedTypes != nil {
+		t.Error("Got:", policy.AllowedTypes, "Expected: no allowed types")
+	}
+	if len(policy.DeniedTypes) != 2 || policy.DeniedTypes[1] != "tool" {
+		t.Error("Got:", policy.DeniedTypes, "Expected: malware and tool")
+	}
+	if policy.Duplicates != cabby.DuplicateIdentical {
+		t.Error("Got:", policy.Duplicates, "Expected:", cabby.DuplicateIdentical)
+	}
+}
+
+func TestUpdateCollectionKeepsSettings(t *testing.T) {
+	setUp()
+	defer tearDown()
+
+	expected := tester.Collection
+	createTestAPIRoot(expected.APIRootPath)
+
+	runCommand(t, "create", "collection",
+		"-a", expected.APIRootPath,
+		"-i", expected.ID.String(),
+		"-t", expected.Title,
+		"--anonymous_read",
+		"--denied_types", "malware",
+		"--duplicates", cabby.DuplicateIdentical,
+		"--max_versions", "3")
+	createTestUser(expected.ID.String())
+
+	// renaming the collection doesn't change the settings without flags
+	runCommand(t, "update", "collection", "-a", expected.APIRootPath, "-i", expected.ID.String(), "-t", "renamed")
+
+	ds := testDataStore()
+	ctx := cabby.WithUser(context.Background(), tester.User)
+	result, err := ds.CollectionService().Collection(ctx, expected.APIRootPath, expected.ID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Title != "renamed" {
+		t.Error("Got:", result.Title, "Expected:", "renamed")
+	}
+	if len(result.IngestPolicy.DeniedTypes) != 1 || result.IngestPolicy.DeniedTypes[0] != "malware" {
+		t.Error("Got:", result.IngestPolicy.DeniedTypes, "Expected:", "malware")
+	}
+	if result.IngestPolicy.Duplicates != cabby.DuplicateIdentical {
+		t.Error("Got:", result.IngestPolicy.Duplicates, "Expected:", cabby.DuplicateIdentical)
+	}
+	if result.RetentionPolicy.MaxVersions != 3 {
+		t.Error("Got:", result.RetentionPolicy.MaxVersions, "Expected:", 3)
+	}
+
+	anonymous, err := ds.UserService().AnonymousCollections(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !anonymous.CollectionAccessList[expected.ID].CanRead {
+		t.Error("Got:", anonymous.CollectionAccessList, "Expected:", expected.ID)
+	}
+}
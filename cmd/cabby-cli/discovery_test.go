@@ -44,7 +44,7 @@ func TestCreateDiscovery(t *testing.T) {
 
 		if !test.expectError {
 			ds := testDataStore()
-			result, _ := ds.DiscoveryService().Discovery(context.Background())
+			result, _ := ds.DiscoveryService().Discovery(context.Background(), tester.User)
 
 			passed := tester.CompareDiscovery(result, expected)
 			if !passed {
@@ -69,7 +69,7 @@ func TestDeleteDiscovery(t *testing.T) {
 
 	expected := cabby.Discovery{}
 	ds := testDataStore()
-	result, _ := ds.DiscoveryService().Discovery(context.Background())
+	result, _ := ds.DiscoveryService().Discovery(context.Background(), tester.User)
 
 	passed := tester.CompareDiscovery(result, expected)
 	if !passed {
@@ -133,7 +133,7 @@ func TestUpdateDiscovery(t *testing.T) {
 
 		if !test.expectError {
 			ds := testDataStore()
-			result, _ := ds.DiscoveryService().Discovery(context.Background())
+			result, _ := ds.DiscoveryService().Discovery(context.Background(), tester.User)
 
 			passed := tester.CompareDiscovery(result, expected)
 			if !passed {
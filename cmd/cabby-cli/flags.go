@@ -56,9 +56,17 @@ func withCollectionFlags(cmd *cobra.Command) *cobra.Command {
 	cmd = withAPIRootPathFlag(cmd)
 	cmd = withCollectionIDFlag(cmd)
 	cmd = withCollectionTitleFlag(cmd)
+	cmd = withCollectionAnonymousFlag(cmd)
+	cmd = withCollectionIngestPolicyFlags(cmd)
+	cmd = withCollectionRetentionPolicyFlags(cmd)
 	return withCollectionDescriptionFlag(cmd)
 }
 
+func withCollectionAnonymousFlag(cmd *cobra.Command) *cobra.Command {
+	cmd.PersistentFlags().BoolVarP(&collectionAnonymous, "anonymous_read", "n", false, "allow unauthenticated reads")
+	return cmd
+}
+
 func withCollectionDescriptionFlag(cmd *cobra.Command) *cobra.Command {
 	cmd.PersistentFlags().StringVarP(&collectionDescription, "description", "d", "", "collection description")
 	return cmd
@@ -71,6 +79,28 @@ func withCollectionIDFlag(cmd *cobra.Command) *cobra.Command {
 	return cmd
 }
 
+func withCollectionIngestPolicyFlags(cmd *cobra.Command) *cobra.Command {
+	cmd.PersistentFlags().StringVarP(
+		&collectionMediaTypes, "media_types", "m", "", "comma separated media types the collection accepts")
+	cmd.PersistentFlags().StringVarP(
+		&collectionAllowedTypes, "allowed_types", "o", "", "comma separated object types the collection allows")
+	cmd.PersistentFlags().StringVarP(
+		&collectionDeniedTypes, "denied_types", "x", "", "comma separated object types the collection denies")
+	cmd.PersistentFlags().StringVarP(&collectionDuplicates, "duplicates", "", cabby.DuplicateReject,
+		"what to do with object versions that already exist: reject, identical or overwrite")
+	return cmd
+}
+
+func withCollectionRetentionPolicyFlags(cmd *cobra.Command) *cobra.Command {
+	cmd.PersistentFlags().IntVarP(
+		&collectionMaxAgeDays, "max_age_days", "", 0, "remove object versions added more than this many days ago")
+	cmd.PersistentFlags().IntVarP(
+		&collectionMaxVersions, "max_versions", "", 0, "keep this many of the latest versions of each object")
+	cmd.PersistentFlags().BoolVarP(
+		&collectionDropExpired, "drop_expired", "", false, "remove object versions whose valid_until has passed")
+	return cmd
+}
+
 func withCollectionTitleFlag(cmd *cobra.Command) *cobra.Command {
 	cmd.PersistentFlags().StringVarP(&collectionTitle, "title", "t", "", "collection title")
 	/* #nosec G104 */
@@ -78,6 +108,14 @@ func withCollectionTitleFlag(cmd *cobra.Command) *cobra.Command {
 	return cmd
 }
 
+/* delete flags */
+
+func withDeleteFlags(cmd *cobra.Command) *cobra.Command {
+	cmd.PersistentFlags().BoolVarP(&deleteCascade, "cascade", "", false, "delete what depends on the resource too")
+	cmd.PersistentFlags().BoolVarP(&deleteDryRun, "dry-run", "", false, "report what would be deleted without deleting")
+	return cmd
+}
+
 /* discovery flags */
 
 func withDiscoveryContactFlag(cmd *cobra.Command) *cobra.Command {
@@ -102,6 +140,15 @@ func withDiscoveryTitleFlag(cmd *cobra.Command) *cobra.Command {
 	return cmd
 }
 
+/* object flags */
+
+func withObjectIDFlag(cmd *cobra.Command) *cobra.Command {
+	cmd.PersistentFlags().StringVarP(&objectID, "object_id", "o", "", "object id")
+	/* #nosec G104 */
+	cmd.MarkFlagRequired("object_id")
+	return cmd
+}
+
 /* user flags */
 
 func withAdminFlag(cmd *cobra.Command) *cobra.Command {
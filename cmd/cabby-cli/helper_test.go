@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"os"
 	"os/exec"
 
@@ -21,7 +22,7 @@ var (
 )
 
 func init() {
-	cmd := exec.Command("go", "build", "-tags", "json1")
+	cmd := exec.Command("go", "build", "-tags", "json1 fts5")
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stdout
 
@@ -31,6 +32,38 @@ func init() {
 	}
 }
 
+func createTestAPIRoot(path string) {
+	ds := testDataStore()
+	defer ds.Close()
+
+	a := tester.APIRoot
+	a.Path = path
+
+	err := ds.APIRootService().CreateAPIRoot(context.Background(), a)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// createTestCollection creates the test collection in its api root and a user that can read and write it
+func createTestCollection() {
+	createTestAPIRoot(tester.Collection.APIRootPath)
+
+	cmd := exec.Command(CLICommand, "create", "collection", "--config", CLIConfig,
+		"-a", tester.Collection.APIRootPath,
+		"-i", tester.Collection.ID.String(),
+		"-t", tester.Collection.Title)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stdout
+
+	err := cmd.Run()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	createTestUser(tester.Collection.ID.String())
+}
+
 func createTestUser(collectionID string) {
 	cmd := exec.Command(CLICommand, "create", "user",
 		"--config", CLIConfig, "-u", tester.User.Email, "-p", tester.UserPassword)
@@ -1,6 +1,11 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/user"
+
 	"github.com/pladdy/cabby"
 	"github.com/pladdy/cabby/backends/sqlite"
 	log "github.com/sirupsen/logrus"
@@ -17,12 +22,25 @@ var (
 	collectionID           string
 	collectionTitle        string
 	collectionDescription  string
+	collectionAnonymous    bool
+	collectionMediaTypes   string
+	collectionAllowedTypes string
+	collectionDeniedTypes  string
+	collectionDuplicates   string
+	collectionMaxAgeDays   int
+	collectionMaxVersions  int
+	collectionDropExpired  bool
+	deleteCascade          bool
+	deleteDryRun           bool
 	discoveryContact       string
 	discoveryDefault       string
 	discoveryDescription   string
 	discoveryTitle         string
 	maxContentLength       int64
 	migrateVersion         int
+	objectID               string
+	purgeDays              int
+	retentionDryRun        bool
 	userAdmin              bool
 	userCollectionCanRead  bool
 	userCollectionCanWrite bool
@@ -54,6 +72,38 @@ func cmdMigrate() *cobra.Command {
 	}
 }
 
+func cmdObjects() *cobra.Command {
+	return &cobra.Command{
+		Use:   "objects [trash/restore]",
+		Short: "Manage deleted objects",
+		Args:  cobra.MinimumNArgs(1),
+	}
+}
+
+func cmdObservables() *cobra.Command {
+	return &cobra.Command{
+		Use:   "observables [index]",
+		Short: "Manage the observables index",
+		Args:  cobra.MinimumNArgs(1),
+	}
+}
+
+func cmdRetention() *cobra.Command {
+	return &cobra.Command{
+		Use:   "retention [run]",
+		Short: "Enforce the retention policies of collections",
+		Args:  cobra.MinimumNArgs(1),
+	}
+}
+
+func cmdRestore() *cobra.Command {
+	return &cobra.Command{
+		Use:   "restore [command/resource]",
+		Short: "Restore a deleted resource",
+		Args:  cobra.MinimumNArgs(1),
+	}
+}
+
 func cmdUpdate() *cobra.Command {
 	return &cobra.Command{
 		Use:   "update [command/resource]",
@@ -62,6 +112,15 @@ func cmdUpdate() *cobra.Command {
 	}
 }
 
+// cliContext returns a context with the user running the cli, so the changes it makes are attributed to them
+func cliContext() context.Context {
+	name := "cabby-cli"
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	return cabby.WithUser(context.Background(), cabby.User{Email: name})
+}
+
 func dataStoreFromConfig(path string) cabby.DataStore {
 	config := cabby.Config{}.Parse(path)
 	ds, err := sqlite.NewDataStore(config.DataStore["path"])
@@ -71,6 +130,16 @@ func dataStoreFromConfig(path string) cabby.DataStore {
 	return ds
 }
 
+// printDependents prints what a delete would remove
+func printDependents(d cabby.Dependents) {
+	b, err := json.Marshal(d)
+	if err != nil {
+		log.WithFields(log.Fields{"error": err, "dependents": d}).Error("Failed to marshal")
+		return
+	}
+	fmt.Println(string(b))
+}
+
 func main() {
 	log.SetLevel(log.InfoLevel)
 
@@ -83,8 +152,12 @@ func main() {
 	cmdCreate := cmdCreate()
 	cmdDelete := cmdDelete()
 	cmdMigrate := cmdMigrate()
+	cmdObjects := cmdObjects()
+	cmdObservables := cmdObservables()
+	cmdRestore := cmdRestore()
+	cmdRetention := cmdRetention()
 	cmdUpdate := cmdUpdate()
-	rootCmd.AddCommand(cmdCreate, cmdDelete, cmdMigrate, cmdUpdate)
+	rootCmd.AddCommand(cmdCreate, cmdDelete, cmdMigrate, cmdObjects, cmdObservables, cmdPurge(), cmdRestore, cmdRetention, cmdUpdate)
 
 	cmdCreate.AddCommand(
 		cmdCreateAPIRoot(),
@@ -103,6 +176,20 @@ func main() {
 	cmdMigrate.AddCommand(
 		cmdMigrateUp())
 
+	cmdObjects.AddCommand(
+		cmdObjectsRestore(),
+		cmdObjectsTrash())
+
+	cmdObservables.AddCommand(
+		cmdObservablesIndex())
+
+	cmdRestore.AddCommand(
+		cmdRestoreAPIRoot(),
+		cmdRestoreCollection())
+
+	cmdRetention.AddCommand(
+		cmdRetentionRun())
+
 	cmdUpdate.AddCommand(
 		cmdUpdateAPIRoot(),
 		cmdUpdateCollection(),
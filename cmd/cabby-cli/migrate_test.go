@@ -33,8 +33,8 @@ func TestMigrateUp(t *testing.T) {
 			ds := testDataStore()
 			result, _ := ds.MigrationService().CurrentVersion()
 
-			if result != 1 {
-				t.Error("Expected schema verstion to be 1")
+			if result != 12 {
+				t.Error("Expected schema verstion to be 12")
 			}
 		}
 	}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func cmdObservablesIndex() *cobra.Command {
+	return &cobra.Command{
+		Use:   "index",
+		Short: "Index the observables of objects",
+		Long: `observables index is used to extract and index the observables of objects added before observables were
+indexed at ingest; it prints how many object versions were indexed`,
+		Run: func(cmd *cobra.Command, args []string) {
+			ds := dataStoreFromConfig(configPath)
+			defer ds.Close()
+
+			indexed, err := ds.ObservableService().IndexObservables(cliContext())
+			if err != nil {
+				log.WithFields(log.Fields{"error": err}).Error("Failed to index observables")
+				return
+			}
+			fmt.Println(indexed)
+		},
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/pladdy/cabby"
+	"github.com/pladdy/cabby/backends/sqlite"
+	"github.com/pladdy/cabby/tester"
+)
+
+func TestObservablesIndex(t *testing.T) {
+	setUp()
+	defer tearDown()
+
+	createTestCollection()
+
+	ds := testDataStore()
+	o, _, err := cabby.ObjectFromBytes([]byte(`{"type": "ipv4-addr", "spec_version": "2.1",
+		"id": "ipv4-addr--ff26c055-6336-5bc5-b98d-13d6226742dd", "value": "203.0.113.7"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ds.ObjectService().CreateObject(context.Background(), tester.CollectionID, o)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// act like the object was added before observables were indexed
+	_, err = ds.(*sqlite.DataStore).DB.Exec(`update objects set observables = null`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		expectedIndexed string
+		expectedMatches int
+	}{
+		{"1", 1},
+		{"0", 1},
+	}
+
+	for _, test := range tests {
+		out, err := exec.Command(CLICommand, "observables", "index", "--config", CLIConfig).Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.TrimSpace(string(out)) != test.expectedIndexed {
+			t.Error("Got:", string(out), "Expected:", test.expectedIndexed)
+		}
+
+		matches, err := ds.ObservableService().ObservableMatches(tester.Context, []string{"203.0.113.7"}, &cabby.Page{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(matches.Objects) != test.expectedMatches {
+			t.Error("Got:", matches.Objects, "Expected:", test.expectedMatches)
+		}
+	}
+}
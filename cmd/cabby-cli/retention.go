@@ -0,0 +1,39 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func cmdRetentionRun() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "run",
+		Short: "Run the retention policies",
+		Long: `retention run is used to remove the object versions the retention policies of collections don't keep;
+--dry-run reports what would be removed without removing it`,
+		Run: func(cmd *cobra.Command, args []string) {
+			ds := dataStoreFromConfig(configPath)
+			defer ds.Close()
+
+			rs, err := ds.RetentionService().EnforceRetention(cliContext(), retentionDryRun)
+			if err != nil {
+				log.WithFields(log.Fields{"error": err}).Error("Failed to enforce retention policies")
+			}
+
+			for _, r := range rs {
+				b, err := json.Marshal(r)
+				if err != nil {
+					log.WithFields(log.Fields{"error": err, "retention": r}).Error("Failed to marshal")
+					continue
+				}
+				fmt.Println(string(b))
+			}
+		},
+	}
+
+	cmd.PersistentFlags().BoolVarP(&retentionDryRun, "dry-run", "", false, "report what would be removed without removing")
+	return cmd
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/pladdy/cabby"
+	"github.com/pladdy/cabby/tester"
+	"github.com/pladdy/stones"
+)
+
+func TestRetentionRun(t *testing.T) {
+	setUp()
+	defer tearDown()
+
+	createTestCollection()
+	runCommand(t, "update", "collection",
+		"-a", tester.Collection.APIRootPath, "-i", tester.CollectionID, "-t", tester.Collection.Title, "--max_versions", "1")
+
+	ds := testDataStore()
+	for _, modified := range []string{"2017-01-01T01:00:00.000Z", "2018-01-01T01:00:00.000Z"} {
+		o := tester.Object
+		o.Modified, _ = stones.TimestampFromString(modified)
+
+		err := ds.ObjectService().CreateObject(context.Background(), tester.CollectionID, o)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a dry run reports what would be removed
+	out, err := exec.Command(CLICommand, "retention", "run", "--config", CLIConfig, "--dry-run").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"collection_id":"` + tester.CollectionID + `","max_age":0,"max_versions":1,"expired":0}`
+	if strings.TrimSpace(string(out)) != expected {
+		t.Error("Got:", string(out), "Expected:", expected)
+	}
+
+	tests := []struct {
+		args     []string
+		versions int
+	}{
+		{[]string{"retention", "run", "--dry-run"}, 2},
+		{[]string{"retention", "run"}, 1},
+	}
+
+	for _, test := range tests {
+		runCommand(t, test.args...)
+
+		versions, _ := ds.VersionsService().Versions(
+			tester.Context, tester.CollectionID, tester.ObjectID, &cabby.Page{}, cabby.Filter{})
+		if len(versions.Versions) != test.versions {
+			t.Error("Got:", versions.Versions, "Expected:", test.versions, "Args:", test.args)
+		}
+	}
+}
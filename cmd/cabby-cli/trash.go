@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pladdy/cabby"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func cmdObjectsRestore() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "restore",
+		Short: "Restore an object",
+		Long:  `objects restore is used to take the deleted versions of an object in a collection out of the trash`,
+		Run: func(cmd *cobra.Command, args []string) {
+			ds := dataStoreFromConfig(configPath)
+			defer ds.Close()
+
+			err := ds.TrashService().RestoreObject(cliContext(), collectionID, objectID)
+			if err != nil {
+				log.WithFields(log.Fields{"error": err, "id": objectID}).Error("Failed to restore")
+			}
+		},
+		PreRun: func(cmd *cobra.Command, args []string) {
+			if collectionID == "" || objectID == "" {
+				log.Fatal("Collection ID and Object ID required")
+			}
+		},
+	}
+
+	return withObjectIDFlag(withCollectionIDFlag(cmd))
+}
+
+func cmdObjectsTrash() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "trash",
+		Short: "List deleted objects",
+		Long:  `objects trash is used to list the object versions of a collection that are in the trash`,
+		Run: func(cmd *cobra.Command, args []string) {
+			ds := dataStoreFromConfig(configPath)
+			defer ds.Close()
+
+			dos, err := ds.TrashService().DeletedObjects(cliContext(), collectionID)
+			if err != nil {
+				log.WithFields(log.Fields{"error": err, "id": collectionID}).Error("Failed to read the trash")
+				return
+			}
+
+			for _, do := range dos {
+				b, err := json.Marshal(do)
+				if err != nil {
+					log.WithFields(log.Fields{"error": err, "object": do}).Error("Failed to marshal")
+					continue
+				}
+				fmt.Println(string(b))
+			}
+		},
+		PreRun: func(cmd *cobra.Command, args []string) {
+			if collectionID == "" {
+				log.Fatal("ID required")
+			}
+		},
+	}
+
+	return withCollectionIDFlag(cmd)
+}
+
+func cmdPurge() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "purge",
+		Short: "Purge the trash",
+		Long: `purge is used to permanently remove resources that have been in the trash longer than the retention
+period; it defaults to the trash_retention_days of the config, and nothing is purged if that's zero`,
+		Run: func(cmd *cobra.Command, args []string) {
+			days := purgeDays
+			if !cmd.Flags().Changed("days") {
+				days = cabby.Config{}.Parse(configPath).TrashRetentionDays
+
+				// zero in the config keeps the trash, only an explicit '--days 0' empties it
+				if days == 0 {
+					log.Info("trash_retention_days is zero, nothing purged; pass --days to purge the trash")
+					return
+				}
+			}
+
+			ds := dataStoreFromConfig(configPath)
+			defer ds.Close()
+
+			p, err := ds.TrashService().Purge(cliContext(), time.Now().AddDate(0, 0, -days))
+			if err != nil {
+				log.WithFields(log.Fields{"error": err}).Error("Failed to purge")
+				return
+			}
+			log.WithFields(log.Fields{"purged": p}).Info("Purged the trash")
+		},
+		PreRun: func(cmd *cobra.Command, args []string) {
+			if purgeDays < 0 {
+				log.Fatal("Days can't be negative")
+			}
+		},
+	}
+
+	cmd.PersistentFlags().IntVarP(&purgeDays, "days", "", 0, "purge resources deleted more than this many days ago")
+	return cmd
+}
+
+func cmdRestoreAPIRoot() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "apiRoot",
+		Short: "Restore an apiRoot",
+		Long:  `restore apiRoot is used to take an apiRoot out of the trash`,
+		Run: func(cmd *cobra.Command, args []string) {
+			ds := dataStoreFromConfig(configPath)
+			defer ds.Close()
+
+			err := ds.TrashService().RestoreAPIRoot(cliContext(), apiRootPath)
+			if err != nil {
+				log.WithFields(log.Fields{"error": err, "path": apiRootPath}).Error("Failed to restore")
+			}
+		},
+		PreRun: func(cmd *cobra.Command, args []string) {
+			if apiRootPath == "" {
+				log.Fatal("API Root Path required")
+			}
+		},
+	}
+
+	return withAPIRootPathFlag(cmd)
+}
+
+func cmdRestoreCollection() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "collection",
+		Short: "Restore a collection",
+		Long:  `restore collection is used to take a collection out of the trash`,
+		Run: func(cmd *cobra.Command, args []string) {
+			ds := dataStoreFromConfig(configPath)
+			defer ds.Close()
+
+			err := ds.TrashService().RestoreCollection(cliContext(), collectionID)
+			if err != nil {
+				log.WithFields(log.Fields{"error": err, "id": collectionID}).Error("Failed to restore")
+			}
+		},
+		PreRun: func(cmd *cobra.Command, args []string) {
+			if collectionID == "" {
+				log.Fatal("ID required")
+			}
+		},
+	}
+
+	return withCollectionIDFlag(cmd)
+}
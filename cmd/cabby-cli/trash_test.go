@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/pladdy/cabby"
+	"github.com/pladdy/cabby/tester"
+)
+
+func runCommand(t *testing.T, args ...string) {
+	cmd := exec.Command(CLICommand, append(args, "--config", CLIConfig)...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stdout
+
+	err := cmd.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestObjectsRestore(t *testing.T) {
+	setUp()
+	defer tearDown()
+
+	createTestCollection()
+	objectID := tester.Object.ID.String()
+
+	ds := testDataStore()
+	err := ds.ObjectService().CreateObject(context.Background(), tester.CollectionID, tester.Object)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ds.ObjectService().DeleteObject(tester.Context, tester.CollectionID, objectID, cabby.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the trash lists the deleted object
+	out, err := exec.Command(CLICommand, "objects", "trash", "--config", CLIConfig, "-i", tester.CollectionID).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), objectID) || !strings.Contains(string(out), tester.UserEmail) {
+		t.Error("Got:", string(out), "Expected:", objectID, "deleted by", tester.UserEmail)
+	}
+
+	runCommand(t, "objects", "restore", "-i", tester.CollectionID, "-o", objectID)
+
+	results, _ := ds.ObjectService().Object(context.Background(), tester.CollectionID, objectID, cabby.Filter{})
+	if len(results) != 1 {
+		t.Error("Got:", len(results), "Expected:", 1)
+	}
+}
+
+func TestRestoreCollection(t *testing.T) {
+	setUp()
+	defer tearDown()
+
+	createTestCollection()
+	runCommand(t, "delete", "collection", "-i", tester.CollectionID)
+	runCommand(t, "restore", "collection", "-i", tester.CollectionID)
+
+	ds := testDataStore()
+	result, _ := ds.CollectionService().Collection(tester.Context, tester.Collection.APIRootPath, tester.CollectionID)
+	if result.ID.String() != tester.CollectionID {
+		t.Error("Got:", result.ID, "Expected:", tester.CollectionID)
+	}
+}
+
+func TestPurge(t *testing.T) {
+	setUp()
+	defer tearDown()
+
+	createTestCollection()
+	ds := testDataStore()
+
+	// without days the config is used, its trash_retention_days is zero so nothing is purged
+	tests := []struct {
+		days     []string
+		restored bool
+	}{
+		{[]string{}, true},
+		{[]string{"--days", "1"}, true},
+		{[]string{"--days", "0"}, false},
+	}
+
+	for _, test := range tests {
+		runCommand(t, "delete", "collection", "-i", tester.CollectionID)
+		runCommand(t, append([]string{"purge"}, test.days...)...)
+		runCommand(t, "restore", "collection", "-i", tester.CollectionID)
+
+		result, _ := ds.CollectionService().Collection(tester.Context, tester.Collection.APIRootPath, tester.CollectionID)
+		if result.ID.IsEmpty() == test.restored {
+			t.Error("Got:", result.ID, "Expected restored:", test.restored, "Days:", test.days)
+		}
+	}
+}
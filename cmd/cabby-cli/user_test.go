@@ -153,7 +153,7 @@ func TestCreateUserCollection(t *testing.T) {
 	setUp()
 	defer tearDown()
 
-	createTestUser(tester.Collection.ID.String())
+	createTestCollection()
 	command, resource := "create", "userCollection"
 	expected := tester.UserCollectionList
 
@@ -244,7 +244,7 @@ func TestUpdateUserCollection(t *testing.T) {
 	defer tearDown()
 
 	expected := tester.UserCollectionList
-	createTestUser(tester.Collection.ID.String())
+	createTestCollection()
 
 	// create a userCollection to modify
 	cmd := exec.Command(CLICommand, "create", "userCollection", "--config", CLIConfig,
@@ -1,7 +1,10 @@
 package main
 
 import (
+	"context"
+	"expvar"
 	"flag"
+	"time"
 
 	"github.com/pladdy/cabby"
 	"github.com/pladdy/cabby/backends/sqlite"
@@ -9,6 +12,15 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+// how often resources that have been in the trash longer than the retention period are purged
+const trashPurgeInterval = time.Hour
+
+// how often statuses older than their TTL are expired
+const statusExpiryInterval = time.Hour
+
+// retentionMetrics count the runs of the retention job, its errors and the object versions each rule removed
+var retentionMetrics = expvar.NewMap("retention")
+
 func main() {
 	log.SetLevel(log.InfoLevel)
 
@@ -22,6 +34,88 @@ func main() {
 		log.WithFields(log.Fields{"error": err, "config-path": configPath}).Panic("Can't start server")
 	}
 
-	server := http.NewCabby(ds, c)
+	server, err := http.NewCabby(ds, c)
+	if err != nil {
+		log.WithFields(log.Fields{"error": err, "config-path": configPath}).Fatal("Invalid server config")
+	}
+
+	if c.TrashRetentionDays > 0 {
+		go purgeTrash(ds.TrashService(), c.TrashRetentionDays)
+	}
+	if c.StatusTTLHours > 0 {
+		go expireStatuses(ds.StatusService(), c.StatusTTLHours)
+	}
+	if c.RetentionIntervalHours > 0 {
+		go enforceRetention(ds.RetentionService(), c.RetentionIntervalHours)
+	}
+
+	if c.MetricsAddress != "" {
+		go func() {
+			log.WithFields(log.Fields{"error": http.NewMetricsServer(c.MetricsAddress).ListenAndServe()}).Error(
+				"Metrics server stopped")
+		}()
+	}
+
+	if c.PlainHTTP {
+		log.Warn("Serving plain HTTP; TLS should be terminated by a reverse proxy")
+		log.Fatal(server.ListenAndServe())
+	}
 	log.Fatal(server.ListenAndServeTLS(c.SSLCert, c.SSLKey))
 }
+
+func enforceRetention(rs cabby.RetentionService, intervalHours int) {
+	ticker := time.NewTicker(time.Duration(intervalHours) * time.Hour)
+	defer ticker.Stop()
+
+	for {
+		results, err := rs.EnforceRetention(context.Background(), false)
+		retentionMetrics.Add("runs", 1)
+		if err != nil {
+			retentionMetrics.Add("errors", 1)
+			log.WithFields(log.Fields{"error": err}).Error("Failed to enforce retention policies")
+		}
+
+		for _, r := range results {
+			retentionMetrics.Add("max_age", r.MaxAge)
+			retentionMetrics.Add("max_versions", r.MaxVersions)
+			retentionMetrics.Add("expired", r.Expired)
+
+			if r.Total() > 0 {
+				log.WithFields(log.Fields{"retention": r}).Info("Trashed object versions by retention policy")
+			}
+		}
+		<-ticker.C
+	}
+}
+
+func expireStatuses(ss cabby.StatusService, ttlHours int) {
+	ttl := time.Duration(ttlHours) * time.Hour
+	ticker := time.NewTicker(statusExpiryInterval)
+	defer ticker.Stop()
+
+	for {
+		expired, err := ss.ExpireStatuses(context.Background(), time.Now().Add(-ttl))
+		if err != nil {
+			log.WithFields(log.Fields{"error": err}).Error("Failed to expire statuses")
+		} else {
+			log.WithFields(log.Fields{"expired": expired}).Info("Expired statuses")
+		}
+		<-ticker.C
+	}
+}
+
+func purgeTrash(ts cabby.TrashService, retentionDays int) {
+	retention := time.Duration(retentionDays) * 24 * time.Hour
+	ticker := time.NewTicker(trashPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		p, err := ts.Purge(context.Background(), time.Now().Add(-retention))
+		if err != nil {
+			log.WithFields(log.Fields{"error": err}).Error("Failed to purge the trash")
+		} else {
+			log.WithFields(log.Fields{"purged": p}).Info("Purged the trash")
+		}
+		<-ticker.C
+	}
+}
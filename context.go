@@ -13,6 +13,15 @@ const (
 	// KeyBytes stores the bytes written for a response
 	KeyBytes Key = iota
 
+	// KeyCompressedBytes stores the bytes written for a response after compression
+	KeyCompressedBytes
+
+	// KeyForwarded stores the forwarded values from a trusted proxy
+	KeyForwarded
+
+	// KeyMediaType stores the media type negotiated for a response
+	KeyMediaType
+
 	// KeyTransactionID to track a request from http request to response (including service calls)
 	KeyTransactionID
 
@@ -29,6 +38,33 @@ func TakeBytes(ctx context.Context) int {
 	return bytes
 }
 
+// TakeCompressedBytes returns the compressed bytes stored in a context
+func TakeCompressedBytes(ctx context.Context) int {
+	bytes, ok := ctx.Value(KeyCompressedBytes).(int)
+	if !ok {
+		return 0
+	}
+	return bytes
+}
+
+// TakeForwarded returns the forwarded values stored in a context
+func TakeForwarded(ctx context.Context) Forwarded {
+	f, ok := ctx.Value(KeyForwarded).(Forwarded)
+	if !ok {
+		return Forwarded{}
+	}
+	return f
+}
+
+// TakeMediaType returns the negotiated media type stored in a context
+func TakeMediaType(ctx context.Context) string {
+	mediaType, ok := ctx.Value(KeyMediaType).(string)
+	if !ok {
+		return ""
+	}
+	return mediaType
+}
+
 // TakeTransactionID returns the transaction id stored in a context
 func TakeTransactionID(ctx context.Context) uuid.UUID {
 	id, ok := ctx.Value(KeyTransactionID).(uuid.UUID)
@@ -52,6 +88,21 @@ func WithBytes(ctx context.Context, bytes int) context.Context {
 	return context.WithValue(ctx, KeyBytes, bytes)
 }
 
+// WithCompressedBytes decorates a context with compressed bytes written
+func WithCompressedBytes(ctx context.Context, bytes int) context.Context {
+	return context.WithValue(ctx, KeyCompressedBytes, bytes)
+}
+
+// WithForwarded decorates a context with forwarded values from a trusted proxy
+func WithForwarded(ctx context.Context, f Forwarded) context.Context {
+	return context.WithValue(ctx, KeyForwarded, f)
+}
+
+// WithMediaType decorates a context with the negotiated media type
+func WithMediaType(ctx context.Context, mediaType string) context.Context {
+	return context.WithValue(ctx, KeyMediaType, mediaType)
+}
+
 // WithTransactionID decorates a context with a transaction id
 func WithTransactionID(ctx context.Context, transactionID uuid.UUID) context.Context {
 	return context.WithValue(ctx, KeyTransactionID, transactionID)
@@ -29,6 +29,34 @@ func TestContextBytesInvalid(t *testing.T) {
 	}
 }
 
+func TestContextForwarded(t *testing.T) {
+	ctx := context.Background()
+	f := Forwarded{For: "192.0.2.1", Host: "taxii.example.com", Proto: "https"}
+
+	ctx = WithForwarded(ctx, f)
+	result := TakeForwarded(ctx)
+
+	if result != f {
+		t.Error("Got:", result, "Expected:", f)
+	}
+}
+
+func TestContextMediaType(t *testing.T) {
+	ctx := context.Background()
+
+	ctx = WithMediaType(ctx, TaxiiMediaType21)
+	result := TakeMediaType(ctx)
+
+	if result != TaxiiMediaType21 {
+		t.Error("Got:", result, "Expected:", TaxiiMediaType21)
+	}
+
+	result = TakeMediaType(context.Background())
+	if result != "" {
+		t.Error("Got:", result, "Expected no media type")
+	}
+}
+
 func TestContextTransactionID(t *testing.T) {
 	ctx := context.Background()
 
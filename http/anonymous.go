@@ -0,0 +1,136 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pladdy/cabby"
+)
+
+const discoveryPath = "taxii2"
+
+// anonymousCollectionsTTL is how long the collections anyone can read are cached; marking a collection anonymous
+// takes up to this long to apply
+const anonymousCollectionsTTL = 30 * time.Second
+
+// anonymousAccess is what clients can read without authenticating; collections are marked in the data store and cached
+// so they aren't read on every request
+type anonymousAccess struct {
+	discovery   bool
+	apiRoots    map[string]bool
+	collections *collectionsCache
+}
+
+// collectionsCache holds the collections anyone can read until it expires
+type collectionsCache struct {
+	sync.Mutex
+	ucl     cabby.UserCollectionList
+	expires time.Time
+}
+
+func newAnonymousAccess(c cabby.Config) anonymousAccess {
+	a := anonymousAccess{discovery: c.AnonymousDiscovery, apiRoots: map[string]bool{}, collections: &collectionsCache{}}
+
+	for _, path := range c.AnonymousAPIRoots {
+		a.apiRoots[trimSlashes(path)] = true
+	}
+	return a
+}
+
+// anonymousUser returns a read only user if the request can be served without authenticating; the user is undefined
+// if it can't
+func anonymousUser(r *http.Request, us cabby.UserService, a anonymousAccess) (cabby.User, error) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return cabby.User{}, nil
+	}
+
+	// only collections need to be read to know if they're public
+	if takeCollectionID(r) == "" {
+		if a.allows(r, cabby.User{}) {
+			return a.newUser(cabby.UserCollectionList{}), nil
+		}
+		return cabby.User{}, nil
+	}
+
+	ucl, err := a.anonymousCollections(r.Context(), us)
+	if err != nil {
+		return cabby.User{}, err
+	}
+	user := a.newUser(ucl)
+
+	if a.allows(r, user) {
+		return user, nil
+	}
+	return cabby.User{}, nil
+}
+
+// newUser returns an anonymous user that can read the collections and the configured API roots, so discovery lists the
+// API roots it can read
+func (a anonymousAccess) newUser(ucl cabby.UserCollectionList) cabby.User {
+	u := cabby.NewAnonymousUser(ucl)
+	for path := range a.apiRoots {
+		u.APIRootPaths = append(u.APIRootPaths, path)
+	}
+	return u
+}
+
+// allows returns whether an anonymous user can read the requested resource; status resources are never public
+func (a anonymousAccess) allows(r *http.Request, u cabby.User) bool {
+	path := trimSlashes(r.URL.Path)
+
+	if path == discoveryPath {
+		return a.discovery
+	}
+
+	if takeCollectionID(r) != "" {
+		id, err := cabby.IDFromString(takeCollectionID(r))
+		return err == nil && u.CollectionAccessList[id].CanRead
+	}
+
+	return a.apiRoots[strings.TrimSuffix(path, "/collections")]
+}
+
+// anonymousCollections returns the collections anyone can read; they're read from the data store when the cache has
+// expired, or every time if there's no cache
+func (a anonymousAccess) anonymousCollections(ctx context.Context, us cabby.UserService) (cabby.UserCollectionList, error) {
+	if a.collections == nil {
+		return us.AnonymousCollections(ctx)
+	}
+
+	a.collections.Lock()
+	defer a.collections.Unlock()
+
+	if time.Now().Before(a.collections.expires) {
+		return a.collections.ucl, nil
+	}
+
+	ucl, err := us.AnonymousCollections(ctx)
+	if err != nil {
+		return ucl, err
+	}
+
+	a.collections.ucl, a.collections.expires = ucl, time.Now().Add(anonymousCollectionsTTL)
+	return ucl, nil
+}
+
+// withAnonymousCollections adds the collections anyone can read to a user's access list
+func (a anonymousAccess) withAnonymousCollections(ctx context.Context, u cabby.User, us cabby.UserService) (cabby.User, error) {
+	ucl, err := a.anonymousCollections(ctx, us)
+	if err != nil {
+		return u, err
+	}
+
+	if u.CollectionAccessList == nil {
+		u.CollectionAccessList = map[cabby.ID]cabby.CollectionAccess{}
+	}
+
+	for id, ca := range ucl.CollectionAccessList {
+		access := u.CollectionAccessList[id]
+		access.ID, access.CanRead = id, access.CanRead || ca.CanRead
+		u.CollectionAccessList[id] = access
+	}
+	return u, nil
+}
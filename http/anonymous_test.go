@@ -0,0 +1,203 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pladdy/cabby"
+	"github.com/pladdy/cabby/tester"
+)
+
+func anonymousUserService() tester.UserService {
+	us := mockUserService()
+	us.AnonymousCollectionsFn = func(ctx context.Context) (cabby.UserCollectionList, error) {
+		id, _ := cabby.IDFromString(tester.CollectionID)
+		return cabby.UserCollectionList{
+			Email:                cabby.AnonymousEmail,
+			CollectionAccessList: map[cabby.ID]cabby.CollectionAccess{id: cabby.CollectionAccess{ID: id, CanRead: true}},
+		}, nil
+	}
+	return us
+}
+
+func TestNewAnonymousAccess(t *testing.T) {
+	a := newAnonymousAccess(cabby.Config{AnonymousDiscovery: true, AnonymousAPIRoots: []string{"/" + tester.APIRootPath + "/"}})
+
+	if !a.discovery {
+		t.Error("Got:", a.discovery, "Expected:", true)
+	}
+	if !a.apiRoots[tester.APIRootPath] {
+		t.Error("Got:", a.apiRoots, "Expected:", tester.APIRootPath)
+	}
+}
+
+func TestAnonymousUserAPIRootPaths(t *testing.T) {
+	a := newAnonymousAccess(cabby.Config{AnonymousDiscovery: true, AnonymousAPIRoots: []string{"/" + tester.APIRootPath + "/"}})
+	us := mockUserService()
+
+	// discovery lists the api roots the user can read, so the configured ones are on the user
+	req := httptest.NewRequest(http.MethodGet, testDiscoveryURL, nil)
+	user, err := anonymousUser(req, &us, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(user.APIRootPaths) != 1 || user.APIRootPaths[0] != tester.APIRootPath {
+		t.Error("Got:", user.APIRootPaths, "Expected:", []string{tester.APIRootPath})
+	}
+}
+
+func TestWithBasicAuthAnonymous(t *testing.T) {
+	public := anonymousAccess{discovery: true, apiRoots: map[string]bool{tester.APIRootPath: true}}
+	unknownCollection := testCollectionsURL + "ab6d7e4f-c1da-4ad4-9d65-1ed4a5bbc3b4/objects/"
+
+	tests := []struct {
+		method         string
+		url            string
+		access         anonymousAccess
+		expectedStatus int
+	}{
+		{http.MethodGet, testDiscoveryURL, public, http.StatusOK},
+		{http.MethodHead, testDiscoveryURL, public, http.StatusOK},
+		{http.MethodGet, testDiscoveryURL, anonymousAccess{}, http.StatusUnauthorized},
+		{http.MethodGet, testAPIRootURL, public, http.StatusOK},
+		{http.MethodGet, testCollectionsURL, public, http.StatusOK},
+		{http.MethodGet, testAPIRootURL, anonymousAccess{}, http.StatusUnauthorized},
+		{http.MethodGet, testStatusURL, public, http.StatusUnauthorized},
+		// collections are public because of the data store, not the config
+		{http.MethodGet, testObjectsURL, anonymousAccess{}, http.StatusOK},
+		{http.MethodGet, testManifestURL, anonymousAccess{}, http.StatusOK},
+		{http.MethodGet, unknownCollection, public, http.StatusUnauthorized},
+		// writes always need credentials
+		{http.MethodPost, testObjectsURL, public, http.StatusUnauthorized},
+		{http.MethodDelete, testObjectURL, public, http.StatusUnauthorized},
+	}
+
+	for _, test := range tests {
+		var user cabby.User
+		h := func(w http.ResponseWriter, r *http.Request) {
+			user = cabby.TakeUser(r.Context())
+		}
+
+		us := anonymousUserService()
+		req := httptest.NewRequest(test.method, test.url, nil)
+		res := httptest.NewRecorder()
+
+		withBasicAuth(http.HandlerFunc(h), &us, test.access).ServeHTTP(res, req)
+
+		if res.Code != test.expectedStatus {
+			t.Error("Got:", res.Code, "Expected:", test.expectedStatus, "Method:", test.method, "URL:", test.url)
+		}
+
+		if res.Code == http.StatusOK {
+			if user.Email != cabby.AnonymousEmail || user.CanAdmin {
+				t.Error("Got:", user, "Expected an anonymous user")
+			}
+			for _, ca := range user.CollectionAccessList {
+				if ca.CanWrite {
+					t.Error("Got:", ca, "Expected read only access")
+				}
+			}
+		}
+	}
+}
+
+func TestAnonymousAccessCollectionsCached(t *testing.T) {
+	reads := 0
+	us := anonymousUserService()
+	us.AnonymousCollectionsFn = func(ctx context.Context) (cabby.UserCollectionList, error) {
+		reads++
+		return cabby.UserCollectionList{}, nil
+	}
+
+	tests := []struct {
+		access        anonymousAccess
+		expectedReads int
+	}{
+		{newAnonymousAccess(cabby.Config{}), 1},
+		{anonymousAccess{}, 3},
+	}
+
+	for _, test := range tests {
+		reads = 0
+		for i := 0; i < 3; i++ {
+			req := httptest.NewRequest(http.MethodGet, testObjectsURL, nil)
+			withBasicAuth(testHandlerFunc(t.Name()), &us, test.access).ServeHTTP(httptest.NewRecorder(), req)
+		}
+
+		if reads != test.expectedReads {
+			t.Error("Got:", reads, "Expected:", test.expectedReads)
+		}
+	}
+}
+
+func TestAnonymousUserSkipsCollections(t *testing.T) {
+	us := mockUserService()
+	us.AnonymousCollectionsFn = func(ctx context.Context) (cabby.UserCollectionList, error) {
+		t.Error("Expected collections not to be read")
+		return cabby.UserCollectionList{}, nil
+	}
+
+	for _, url := range []string{testDiscoveryURL, testAPIRootURL, testCollectionsURL} {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		withBasicAuth(testHandlerFunc(t.Name()), &us, newAnonymousAccess(cabby.Config{})).ServeHTTP(
+			httptest.NewRecorder(), req)
+	}
+}
+
+func TestWithBasicAuthAnonymousCollectionsFail(t *testing.T) {
+	us := mockUserService()
+	us.AnonymousCollectionsFn = func(ctx context.Context) (cabby.UserCollectionList, error) {
+		return cabby.UserCollectionList{}, errors.New("service error")
+	}
+
+	tests := []struct {
+		basicAuth bool
+	}{
+		{true},
+		{false},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, testObjectsURL, nil)
+		if test.basicAuth {
+			req.SetBasicAuth("user", "password")
+		}
+		res := httptest.NewRecorder()
+
+		withBasicAuth(testHandlerFunc(t.Name()), &us, anonymousAccess{discovery: true}).ServeHTTP(res, req)
+
+		if res.Code != http.StatusInternalServerError {
+			t.Error("Got:", res.Code, "Expected:", http.StatusInternalServerError, "Basic auth:", test.basicAuth)
+		}
+	}
+}
+
+func TestWithBasicAuthAddsAnonymousCollections(t *testing.T) {
+	var user cabby.User
+	h := func(w http.ResponseWriter, r *http.Request) {
+		user = cabby.TakeUser(r.Context())
+	}
+
+	us := anonymousUserService()
+	req := httptest.NewRequest(http.MethodGet, testObjectsURL, nil)
+	req.SetBasicAuth("user", "password")
+
+	withBasicAuth(http.HandlerFunc(h), &us, anonymousAccess{}).ServeHTTP(httptest.NewRecorder(), req)
+
+	if user.Email != tester.UserEmail {
+		t.Error("Got:", user.Email, "Expected:", tester.UserEmail)
+	}
+
+	req = newClientRequest(http.MethodGet, testObjectsURL, nil)
+	req = req.WithContext(cabby.WithUser(req.Context(), user))
+	if !requestIsReadAuthorized(req) {
+		t.Error("Expected user to read a public collection")
+	}
+	if requestIsWriteAuthorized(req) {
+		t.Error("Expected user not to write to a public collection")
+	}
+}
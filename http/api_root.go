@@ -38,7 +38,7 @@ func (h APIRootHandler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	writeContent(w, r, cabby.TaxiiContentType, resourceToJSON(apiRoot))
+	writeContent(w, r, responseMediaType(r), resourceToJSON(apiRoot))
 }
 
 // Post handler
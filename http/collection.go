@@ -41,7 +41,7 @@ func (h CollectionHandler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	writeContent(w, r, cabby.TaxiiContentType, resourceToJSON(collection))
+	writeContent(w, r, responseMediaType(r), resourceToJSON(collection))
 }
 
 // Post handler
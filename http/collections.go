@@ -3,6 +3,7 @@ package http
 import (
 	"errors"
 	"net/http"
+	"time"
 
 	"github.com/pladdy/cabby"
 	log "github.com/sirupsen/logrus"
@@ -30,7 +31,7 @@ func (h CollectionsHandler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	p, err := cabby.NewPage(takeLimit(r))
+	p, err := takePage(r)
 	if err != nil {
 		badRequest(w, err)
 		return
@@ -43,11 +44,15 @@ func (h CollectionsHandler) Get(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if noResources(len(collections.Collections)) {
-		resourceNotFound(w, errors.New("No resources available for this request"))
+		noPage(w, r, p)
 		return
 	}
 
-	writeContent(w, r, cabby.TaxiiContentType, resourceToJSON(collections))
+	if notModified(w, r, collectionsETag(collections), time.Time{}) {
+		return
+	}
+
+	writePage(w, r, p, len(collections.Collections), responseMediaType(r), resourceToJSON(collections))
 }
 
 // Post handler
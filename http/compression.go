@@ -0,0 +1,230 @@
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/pladdy/cabby"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	deflateEncoding  = "deflate"
+	gzipEncoding     = "gzip"
+	identityEncoding = "identity"
+
+	// responses smaller than this aren't worth compressing
+	minCompressionBytes = 1024
+
+	supportedEncodings = "identity, gzip, deflate"
+)
+
+// compressWriter buffers a response until it's big enough to compress; smaller responses are written as is
+type compressWriter struct {
+	http.ResponseWriter
+	buffer      bytes.Buffer
+	compressed  *byteCounter
+	encoder     io.WriteCloser
+	encoding    string
+	status      int
+	wroteHeader bool
+}
+
+func newCompressWriter(w http.ResponseWriter, encoding string) *compressWriter {
+	return &compressWriter{ResponseWriter: w, encoding: encoding, compressed: &byteCounter{w: w}}
+}
+
+// Close writes anything buffered and flushes the encoder if the response was compressed
+func (c *compressWriter) Close() error {
+	if c.encoder != nil {
+		return c.encoder.Close()
+	}
+
+	c.writeHeader()
+	_, err := c.ResponseWriter.Write(c.buffer.Bytes())
+	c.buffer.Reset()
+	return err
+}
+
+// Write buffers content until there's enough to compress
+func (c *compressWriter) Write(b []byte) (int, error) {
+	if c.encoder != nil {
+		return c.encoder.Write(b)
+	}
+
+	if c.wroteHeader {
+		return c.ResponseWriter.Write(b)
+	}
+
+	c.buffer.Write(b)
+	if c.buffer.Len() < minCompressionBytes {
+		return len(b), nil
+	}
+
+	err := c.startEncoding()
+	return len(b), err
+}
+
+// WriteHeader is deferred until it's known whether the response will be compressed
+func (c *compressWriter) WriteHeader(status int) {
+	if c.status == 0 {
+		c.status = status
+	}
+}
+
+func (c *compressWriter) startEncoding() error {
+	// the handler encoded the content itself
+	if c.Header().Get("Content-Encoding") != "" {
+		c.writeHeader()
+		_, err := c.ResponseWriter.Write(c.buffer.Bytes())
+		c.buffer.Reset()
+		return err
+	}
+
+	c.Header().Set("Content-Encoding", c.encoding)
+	c.Header().Del("Content-Length")
+	c.writeHeader()
+
+	switch c.encoding {
+	case deflateEncoding:
+		c.encoder = zlib.NewWriter(c.compressed)
+	default:
+		c.encoder = gzip.NewWriter(c.compressed)
+	}
+
+	_, err := c.encoder.Write(c.buffer.Bytes())
+	c.buffer.Reset()
+	return err
+}
+
+func (c *compressWriter) writeHeader() {
+	if c.wroteHeader {
+		return
+	}
+	c.wroteHeader = true
+
+	if c.status != 0 {
+		c.ResponseWriter.WriteHeader(c.status)
+	}
+}
+
+// byteCounter counts the bytes written to the underlying writer
+type byteCounter struct {
+	w     io.Writer
+	bytes int
+}
+
+func (b *byteCounter) Write(p []byte) (int, error) {
+	n, err := b.w.Write(p)
+	b.bytes += n
+	return n, err
+}
+
+// withCompression compresses responses based on the 'Accept-Encoding' header of the request
+func withCompression(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding == identityEncoding {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		cw := newCompressWriter(w, encoding)
+		h.ServeHTTP(cw, r)
+
+		err := cw.Close()
+		if err != nil {
+			log.WithFields(log.Fields{"encoding": encoding, "error": err}).Error("Failed to compress response")
+		}
+
+		if cw.encoder != nil {
+			*r = *r.WithContext(cabby.WithCompressedBytes(r.Context(), cw.compressed.bytes))
+		}
+	})
+}
+
+/* helpers */
+
+// decompressedBody returns a reader that decodes the request body based on its 'Content-Encoding' header
+func decompressedBody(r *http.Request) (body io.ReadCloser, err error) {
+	switch takeContentEncoding(r) {
+	case gzipEncoding:
+		body, err = gzip.NewReader(r.Body)
+	case deflateEncoding:
+		body, err = zlib.NewReader(r.Body)
+	default:
+		return r.Body, nil
+	}
+
+	if err != nil {
+		err = fmt.Errorf("Unable to decompress request body, error: %v", err)
+	}
+	return
+}
+
+// negotiateEncoding picks gzip or deflate from an 'Accept-Encoding' header, preferring the higher quality value
+func negotiateEncoding(acceptEncoding string) string {
+	qualities := map[string]float64{}
+
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		coding, params := splitMimeType(part)
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding == "" {
+			continue
+		}
+
+		qualities[coding] = takeQuality(params)
+	}
+
+	encoding, best := identityEncoding, 0.0
+	for _, candidate := range []string{gzipEncoding, deflateEncoding} {
+		q, ok := qualities[candidate]
+		if !ok {
+			q, ok = qualities["*"]
+		}
+
+		if ok && q > best {
+			encoding, best = candidate, q
+		}
+	}
+	return encoding
+}
+
+func supportedContentEncoding(r *http.Request) bool {
+	switch takeContentEncoding(r) {
+	case "", identityEncoding, gzipEncoding, deflateEncoding:
+		return true
+	}
+	return false
+}
+
+func takeContentEncoding(r *http.Request) string {
+	return strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+}
+
+// takeQuality returns the 'q' value from a header parameter; it defaults to 1
+func takeQuality(params string) float64 {
+	params = strings.TrimSpace(params)
+	if !strings.HasPrefix(params, "q=") {
+		return 1
+	}
+
+	q, err := strconv.ParseFloat(strings.TrimPrefix(params, "q="), 64)
+	if err != nil {
+		return 0
+	}
+	return q
+}
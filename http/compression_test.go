@@ -0,0 +1,209 @@
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/pladdy/cabby"
+	"github.com/pladdy/cabby/tester"
+	log "github.com/sirupsen/logrus"
+)
+
+func largeContentHandler(w http.ResponseWriter, r *http.Request) {
+	writeContent(w, r, cabby.TaxiiContentType, strings.Repeat("a", minCompressionBytes*2))
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		acceptEncoding string
+		expected       string
+	}{
+		{"", identityEncoding},
+		{"br", identityEncoding},
+		{"gzip", gzipEncoding},
+		{"deflate", deflateEncoding},
+		{"deflate, gzip", gzipEncoding},
+		{"gzip;q=0.5, deflate", deflateEncoding},
+		{"gzip;q=0, deflate;q=0", identityEncoding},
+		{"*", gzipEncoding},
+		{"*;q=0.1, deflate;q=0.5", deflateEncoding},
+		{"GZIP", gzipEncoding},
+	}
+
+	for _, test := range tests {
+		result := negotiateEncoding(test.acceptEncoding)
+		if result != test.expected {
+			t.Error("Got:", result, "Expected:", test.expected, "Accept-Encoding:", test.acceptEncoding)
+		}
+	}
+}
+
+func TestWithCompression(t *testing.T) {
+	tests := []struct {
+		method         string
+		acceptEncoding string
+		handler        http.HandlerFunc
+		expected       string
+	}{
+		{http.MethodGet, "gzip", largeContentHandler, gzipEncoding},
+		{http.MethodGet, "deflate", largeContentHandler, deflateEncoding},
+		{http.MethodGet, "", largeContentHandler, ""},
+		{http.MethodHead, "gzip", largeContentHandler, ""},
+		{http.MethodGet, "gzip", testHandler("small content"), ""},
+	}
+
+	for _, test := range tests {
+		req := newClientRequest(test.method, testObjectsURL, nil)
+		req.Header.Set("Accept-Encoding", test.acceptEncoding)
+
+		res := httptest.NewRecorder()
+		withCompression(test.handler).ServeHTTP(res, req)
+
+		encoding := res.Header().Get("Content-Encoding")
+		if encoding != test.expected {
+			t.Error("Got:", encoding, "Expected:", test.expected, "Test:", test.method, test.acceptEncoding)
+		}
+
+		var body io.Reader = res.Body
+		switch encoding {
+		case gzipEncoding:
+			body, _ = gzip.NewReader(res.Body)
+		case deflateEncoding:
+			body, _ = zlib.NewReader(res.Body)
+		}
+
+		content, err := ioutil.ReadAll(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if test.method == http.MethodGet && len(content) == 0 {
+			t.Error("Got: empty content", "Expected: content", "Test:", test.method, test.acceptEncoding)
+		}
+	}
+}
+
+func TestWithCompressionKeepsStatus(t *testing.T) {
+	h := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		largeContentHandler(w, r)
+	}
+
+	req := newClientRequest(http.MethodGet, testObjectsURL, nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	res := httptest.NewRecorder()
+	withCompression(http.HandlerFunc(h)).ServeHTTP(res, req)
+
+	if res.Code != http.StatusAccepted {
+		t.Error("Got:", res.Code, "Expected:", http.StatusAccepted)
+	}
+	if res.Header().Get("Vary") != "Accept-Encoding" {
+		t.Error("Got:", res.Header().Get("Vary"), "Expected: Accept-Encoding")
+	}
+}
+
+func TestWithCompressionLogging(t *testing.T) {
+	// redirect log output for test
+	var buf bytes.Buffer
+
+	log.SetFormatter(&log.JSONFormatter{})
+	log.SetOutput(&buf)
+
+	defer func() {
+		log.SetFormatter(&log.TextFormatter{})
+		log.SetOutput(os.Stderr)
+	}()
+
+	req := newClientRequest(http.MethodGet, testObjectsURL, nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	res := httptest.NewRecorder()
+	withLogging(withCompression(http.HandlerFunc(largeContentHandler))).ServeHTTP(res, req)
+
+	var result struct {
+		Bytes           int    `json:"bytes"`
+		CompressedBytes int    `json:"compressed_bytes"`
+		ContentEncoding string `json:"content_encoding"`
+	}
+	err := json.Unmarshal([]byte(tester.LastLog(buf)), &result)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Bytes != minCompressionBytes*2 {
+		t.Error("Got:", result.Bytes, "Expected:", minCompressionBytes*2)
+	}
+	if result.CompressedBytes <= 0 || result.CompressedBytes >= result.Bytes {
+		t.Error("Got:", result.CompressedBytes, "Expected: fewer bytes than", result.Bytes)
+	}
+	if result.ContentEncoding != gzipEncoding {
+		t.Error("Got:", result.ContentEncoding, "Expected:", gzipEncoding)
+	}
+}
+
+func TestObjectsHandlerPostCompressed(t *testing.T) {
+	envelopeFile, _ := os.Open("testdata/malware_envelope.json")
+	envelope, _ := ioutil.ReadAll(envelopeFile)
+
+	var gzipped bytes.Buffer
+	gw := gzip.NewWriter(&gzipped)
+	gw.Write(envelope)
+	gw.Close()
+
+	var deflated bytes.Buffer
+	zw := zlib.NewWriter(&deflated)
+	zw.Write(envelope)
+	zw.Close()
+
+	tests := []struct {
+		encoding string
+		body     []byte
+		expected int
+	}{
+		{gzipEncoding, gzipped.Bytes(), http.StatusAccepted},
+		{deflateEncoding, deflated.Bytes(), http.StatusAccepted},
+		{gzipEncoding, envelope, http.StatusBadRequest},
+		{"br", envelope, http.StatusUnsupportedMediaType},
+	}
+
+	for _, test := range tests {
+		osv := mockObjectService()
+		h := ObjectsHandler{MaxContentLength: int64(2048), ObjectService: osv, StatusService: mockStatusService()}
+
+		req := newClientRequest(http.MethodPost, testObjectsURL, bytes.NewBuffer(test.body))
+		req.Header.Set("Content-Encoding", test.encoding)
+		status, _, _ := callHandler(h.Post, req)
+
+		if status != test.expected {
+			t.Error("Got:", status, "Expected:", test.expected, "Encoding:", test.encoding)
+		}
+	}
+}
+
+func TestObjectsHandlerPostCompressedTooLarge(t *testing.T) {
+	// a small compressed body can expand past the limit
+	var gzipped bytes.Buffer
+	gw := gzip.NewWriter(&gzipped)
+	gw.Write([]byte(`{"objects": [{"id": "` + strings.Repeat("a", 4096) + `"}]}`))
+	gw.Close()
+
+	h := ObjectsHandler{MaxContentLength: int64(1024), ObjectService: mockObjectService(), StatusService: mockStatusService()}
+
+	req := newClientRequest(http.MethodPost, testObjectsURL, &gzipped)
+	req.Header.Set("Content-Encoding", gzipEncoding)
+	status, _, _ := callHandler(h.Post, req)
+
+	if status != http.StatusRequestEntityTooLarge {
+		t.Error("Got:", status, "Expected:", http.StatusRequestEntityTooLarge)
+	}
+}
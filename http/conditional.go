@@ -0,0 +1,95 @@
+package http
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pladdy/cabby"
+	"github.com/pladdy/stones"
+)
+
+// weakETag returns a weak entity tag built from the parts that identify a representation
+func weakETag(parts ...string) string {
+	hash := sha256.New()
+	for _, part := range parts {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	return fmt.Sprintf(`W/"%x"`, hash.Sum(nil))
+}
+
+func collectionsETag(cs cabby.Collections) string {
+	return weakETag(resourceToJSON(cs))
+}
+
+func manifestETag(m cabby.Manifest) string {
+	parts := []string{}
+	for _, entry := range m.Objects {
+		parts = append(parts, entry.ID, entry.Version.String(), entry.DateAdded.String())
+	}
+	return weakETag(parts...)
+}
+
+// objectsETag uses the date added range of the page since objects don't carry when they were added
+func objectsETag(objects []stones.Object, p cabby.Page) string {
+	parts := []string{p.AddedAfterFirst(), p.AddedAfterLast()}
+	for _, o := range objects {
+		parts = append(parts, o.ID.String(), o.Modified.String())
+	}
+	return weakETag(parts...)
+}
+
+// notModified sets the 'ETag' and 'Last-Modified' headers and checks them against the request's 'If-None-Match' and
+// 'If-Modified-Since' headers; if the client's copy is current a 304 is written and true is returned.  A zero
+// lastModified is not used.  TAXII 2.0 representations get their own tag.
+func notModified(w http.ResponseWriter, r *http.Request, etag string, lastModified time.Time) bool {
+	if legacyRequest(r) {
+		etag = weakETag(etag, legacyVersion)
+	}
+	w.Header().Set("ETag", etag)
+	if !lastModified.IsZero() {
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	// If-Modified-Since is ignored when If-None-Match is present (RFC 7232, section 3.3)
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		if !etagMatches(ifNoneMatch, etag) {
+			return false
+		}
+	} else if !unmodifiedSince(r.Header.Get("If-Modified-Since"), lastModified) {
+		return false
+	}
+
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+/* helpers */
+
+// etagMatches uses the weak comparison of entity tags from an 'If-None-Match' header
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// unmodifiedSince returns true if lastModified is no later than the 'If-Modified-Since' header; HTTP dates only have
+// second precision
+func unmodifiedSince(ifModifiedSince string, lastModified time.Time) bool {
+	if ifModifiedSince == "" || lastModified.IsZero() {
+		return false
+	}
+
+	since, err := http.ParseTime(ifModifiedSince)
+	if err != nil {
+		return false
+	}
+	return !lastModified.Truncate(time.Second).After(since)
+}
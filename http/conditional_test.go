@@ -0,0 +1,206 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pladdy/cabby"
+	"github.com/pladdy/cabby/tester"
+	"github.com/pladdy/stones"
+)
+
+func TestEtagMatches(t *testing.T) {
+	etag := weakETag("test")
+
+	tests := []struct {
+		ifNoneMatch string
+		expected    bool
+	}{
+		{etag, true},
+		{"*", true},
+		{`W/"other", ` + etag, true},
+		{etag[2:], true},
+		{`W/"other"`, false},
+	}
+
+	for _, test := range tests {
+		result := etagMatches(test.ifNoneMatch, etag)
+		if result != test.expected {
+			t.Error("Got:", result, "Expected:", test.expected, "If-None-Match:", test.ifNoneMatch)
+		}
+	}
+}
+
+func TestWeakETag(t *testing.T) {
+	if weakETag("a", "b") == weakETag("ab") {
+		t.Error("Expected parts to be delimited")
+	}
+	if weakETag("a", "b") != weakETag("a", "b") {
+		t.Error("Expected the same parts to produce the same tag")
+	}
+}
+
+func TestNotModified(t *testing.T) {
+	etag := weakETag("test")
+	lastModified := tester.LastModified.Time
+
+	tests := []struct {
+		ifNoneMatch     string
+		ifModifiedSince string
+		lastModified    time.Time
+		expected        bool
+	}{
+		{"", "", lastModified, false},
+		{etag, "", lastModified, true},
+		{`W/"other"`, "", lastModified, false},
+		{"", lastModified.Format(http.TimeFormat), lastModified, true},
+		{"", lastModified.Add(time.Hour).Format(http.TimeFormat), lastModified, true},
+		{"", lastModified.Add(-time.Hour).Format(http.TimeFormat), lastModified, false},
+		{"", "not a date", lastModified, false},
+		{"", lastModified.Format(http.TimeFormat), time.Time{}, false},
+		// If-None-Match takes precedence
+		{`W/"other"`, lastModified.Format(http.TimeFormat), lastModified, false},
+	}
+
+	for _, test := range tests {
+		req := newClientRequest(http.MethodGet, testObjectsURL, nil)
+		req.Header.Set("If-None-Match", test.ifNoneMatch)
+		req.Header.Set("If-Modified-Since", test.ifModifiedSince)
+
+		res := httptest.NewRecorder()
+		result := notModified(res, req, etag, test.lastModified)
+
+		if result != test.expected {
+			t.Error("Got:", result, "Expected:", test.expected, "Test:", test)
+		}
+		if result && res.Code != http.StatusNotModified {
+			t.Error("Got:", res.Code, "Expected:", http.StatusNotModified)
+		}
+		if res.Header().Get("ETag") != etag {
+			t.Error("Got:", res.Header().Get("ETag"), "Expected:", etag)
+		}
+		if !test.lastModified.IsZero() && res.Header().Get("Last-Modified") != lastModified.Format(http.TimeFormat) {
+			t.Error("Got:", res.Header().Get("Last-Modified"), "Expected:", lastModified.Format(http.TimeFormat))
+		}
+	}
+}
+
+func TestHandlersConditionalGet(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		url     string
+	}{
+		{"collections", CollectionsHandler{CollectionService: mockCollectionService()}.Get, testCollectionsURL},
+		{"manifest",
+			ManifestHandler{CollectionService: mockCollectionService(), ManifestService: mockManifestService()}.Get,
+			testManifestURL},
+		{"object",
+			ObjectHandler{CollectionService: mockCollectionService(), ObjectService: mockObjectService()}.Get,
+			testObjectURL},
+		{"objects",
+			ObjectsHandler{CollectionService: mockCollectionService(), ObjectService: mockObjectService()}.Get,
+			testObjectsURL},
+	}
+
+	for _, test := range tests {
+		status, body, headers := callHandler(test.handler, newClientRequest(http.MethodGet, test.url, nil))
+		if status != http.StatusOK {
+			t.Fatal("Got:", status, "Expected:", http.StatusOK, "Handler:", test.name)
+		}
+
+		etag := headers.Get("ETag")
+		if etag == "" {
+			t.Error("Got: no ETag", "Expected: an ETag", "Handler:", test.name)
+		}
+
+		req := newClientRequest(http.MethodGet, test.url, nil)
+		req.Header.Set("If-None-Match", etag)
+		status, body, _ = callHandler(test.handler, req)
+
+		if status != http.StatusNotModified {
+			t.Error("Got:", status, "Expected:", http.StatusNotModified, "Handler:", test.name)
+		}
+		if body != "" {
+			t.Error("Got:", body, "Expected: no body", "Handler:", test.name)
+		}
+
+		req = newClientRequest(http.MethodGet, test.url, nil)
+		req.Header.Set("If-None-Match", `W/"stale"`)
+		status, _, _ = callHandler(test.handler, req)
+
+		if status != http.StatusOK {
+			t.Error("Got:", status, "Expected:", http.StatusOK, "Handler:", test.name)
+		}
+	}
+}
+
+func TestHandlersLastModified(t *testing.T) {
+	lastModified := tester.LastModified.Time.Format(http.TimeFormat)
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		url     string
+	}{
+		{"manifest",
+			ManifestHandler{CollectionService: mockCollectionService(), ManifestService: mockManifestService()}.Get,
+			testManifestURL},
+		{"object",
+			ObjectHandler{CollectionService: mockCollectionService(), ObjectService: mockObjectService()}.Get,
+			testObjectURL},
+		{"objects",
+			ObjectsHandler{CollectionService: mockCollectionService(), ObjectService: mockObjectService()}.Get,
+			testObjectsURL},
+	}
+
+	for _, test := range tests {
+		_, _, headers := callHandler(test.handler, newClientRequest(http.MethodGet, test.url, nil))
+		if headers.Get("Last-Modified") != lastModified {
+			t.Error("Got:", headers.Get("Last-Modified"), "Expected:", lastModified, "Handler:", test.name)
+		}
+
+		req := newClientRequest(http.MethodGet, test.url, nil)
+		req.Header.Set("If-Modified-Since", lastModified)
+		status, _, _ := callHandler(test.handler, req)
+
+		if status != http.StatusNotModified {
+			t.Error("Got:", status, "Expected:", http.StatusNotModified, "Handler:", test.name)
+		}
+	}
+}
+
+func TestObjectsHandlerGetLastModifiedFailure(t *testing.T) {
+	cs := mockCollectionService()
+	cs.CollectionLastModifiedFn = func(ctx context.Context, collectionID string) (stones.Timestamp, error) {
+		return stones.Timestamp{}, errors.New("Last modified failure")
+	}
+
+	h := ObjectsHandler{CollectionService: cs, ObjectService: mockObjectService()}
+	status, _ := handlerTest(h.Get, http.MethodGet, testObjectsURL, nil)
+
+	if status != http.StatusInternalServerError {
+		t.Error("Got:", status, "Expected:", http.StatusInternalServerError)
+	}
+}
+
+func TestObjectsETagChanges(t *testing.T) {
+	p := cabby.Page{}
+	etag := objectsETag(tester.Objects, p)
+
+	o := tester.Object
+	o.Modified = stones.NewTimestamp()
+	if objectsETag([]stones.Object{o}, p) == etag {
+		t.Error("Expected a new version to change the ETag")
+	}
+
+	added := stones.NewTimestamp()
+	p.SetAddedAfters(added.String())
+	if objectsETag(tester.Objects, p) == etag {
+		t.Error("Expected a new date added to change the ETag")
+	}
+}
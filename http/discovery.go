@@ -2,9 +2,11 @@ package http
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 
 	"github.com/pladdy/cabby"
 	log "github.com/sirupsen/logrus"
@@ -17,6 +19,9 @@ const DiscoveryMethods = "Get, Head"
 type DiscoveryHandler struct {
 	DiscoveryService cabby.DiscoveryService
 	Port             int
+	// ExternalURL and APIRootURLs are used verbatim when set; otherwise URLs are built from the request and port
+	ExternalURL string
+	APIRootURLs map[string]string
 }
 
 // Delete handler
@@ -28,16 +33,17 @@ func (h DiscoveryHandler) Delete(w http.ResponseWriter, r *http.Request) {
 func (h DiscoveryHandler) Get(w http.ResponseWriter, r *http.Request) {
 	log.WithFields(log.Fields{"handler": "DiscoveryHandler"}).Debug("Handler called")
 
-	discovery, err := h.DiscoveryService.Discovery(r.Context())
+	discovery, err := h.DiscoveryService.Discovery(r.Context(), cabby.TakeUser(r.Context()))
 	if err != nil {
 		internalServerError(w, err)
 		return
 	}
 
-	discovery.Default = insertPort(discovery.Default, h.Port)
+	requestURL := externalURL(r, discovery.Default, h.Port)
+	discovery.Default = h.apiRootURL(parseURL(discovery.Default).Path, requestURL)
 
 	for i := 0; i < len(discovery.APIRoots); i++ {
-		discovery.APIRoots[i] = swapPath(discovery.Default, discovery.APIRoots[i])
+		discovery.APIRoots[i] = h.apiRootURL(discovery.APIRoots[i], swapPath(requestURL, discovery.APIRoots[i]))
 	}
 
 	if discovery.Title == "" {
@@ -45,7 +51,7 @@ func (h DiscoveryHandler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	writeContent(w, r, cabby.TaxiiContentType, resourceToJSON(discovery))
+	writeContent(w, r, responseMediaType(r), resourceToJSON(discovery))
 }
 
 // Post handler
@@ -53,8 +59,29 @@ func (h DiscoveryHandler) Post(w http.ResponseWriter, r *http.Request) {
 	methodNotAllowed(w, r, DiscoveryMethods)
 }
 
+// apiRootURL returns the configured URL for an API root path, then the external URL with the path, then the fallback
+func (h DiscoveryHandler) apiRootURL(path, fallback string) string {
+	if u, ok := h.APIRootURLs[strings.Trim(path, "/")]; ok {
+		return u
+	}
+
+	if h.ExternalURL != "" {
+		return strings.TrimSuffix(h.ExternalURL, "/") + "/" + strings.TrimPrefix(path, "/")
+	}
+	return fallback
+}
+
 /* helpers */
 
+// validExternalURL checks a configured URL is absolute so it can be used verbatim
+func validExternalURL(rawurl string) error {
+	u, err := url.Parse(rawurl)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("Invalid external URL: %v, expected an absolute http or https URL", rawurl)
+	}
+	return nil
+}
+
 func parseURL(rawurl string) *url.URL {
 	u, err := url.Parse(rawurl)
 	if err != nil {
@@ -63,6 +90,24 @@ func parseURL(rawurl string) *url.URL {
 	return u
 }
 
+// externalURL rewrites a URL with the host and protocol forwarded by a trusted proxy; without them the internal port
+// is inserted
+func externalURL(r *http.Request, rawurl string, port int) string {
+	f := cabby.TakeForwarded(r.Context())
+	if f.Host == "" && f.Proto == "" {
+		return insertPort(rawurl, port)
+	}
+
+	u := parseURL(rawurl)
+	if f.Host != "" {
+		u.Host = f.Host
+	}
+	if f.Proto != "" {
+		u.Scheme = f.Proto
+	}
+	return u.Scheme + "://" + u.Host + u.Path
+}
+
 func insertPort(rawurl string, port int) string {
 	u := parseURL(rawurl)
 
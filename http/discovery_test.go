@@ -13,7 +13,7 @@ import (
 
 func TestDiscoveryHandlerDelete(t *testing.T) {
 	ds := mockDiscoveryService()
-	ds.DiscoveryFn = func(ctx context.Context) (cabby.Discovery, error) {
+	ds.DiscoveryFn = func(ctx context.Context, u cabby.User) (cabby.Discovery, error) {
 		return cabby.Discovery{Title: ""}, nil
 	}
 
@@ -46,12 +46,101 @@ func TestDiscoveryHandlerGet(t *testing.T) {
 	}
 }
 
+func TestDiscoveryHandlerGetUser(t *testing.T) {
+	var user cabby.User
+
+	ds := mockDiscoveryService()
+	ds.DiscoveryFn = func(ctx context.Context, u cabby.User) (cabby.Discovery, error) {
+		user = u
+		d := tester.Discovery
+		d.APIRoots = []string{}
+		return d, nil
+	}
+
+	h := DiscoveryHandler{DiscoveryService: ds, Port: tester.Port}
+	status, body := handlerTest(h.Get, http.MethodGet, testDiscoveryURL, nil)
+
+	if status != http.StatusOK {
+		t.Error("Got:", status, "Expected:", http.StatusOK)
+	}
+	if user.Email != tester.UserEmail {
+		t.Error("Got:", user.Email, "Expected:", tester.UserEmail)
+	}
+
+	var result map[string]interface{}
+	err := json.Unmarshal([]byte(body), &result)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	apiRoots, ok := result["api_roots"].([]interface{})
+	if !ok || len(apiRoots) != 0 {
+		t.Error("Got:", result["api_roots"], "Expected: an empty list")
+	}
+}
+
+func TestDiscoveryHandlerGetExternalURL(t *testing.T) {
+	tests := []struct {
+		externalURL      string
+		apiRootURLs      map[string]string
+		expectedDefault  string
+		expectedAPIRoots []string
+	}{
+		{"https://taxii.example.com:8443", nil,
+			"https://taxii.example.com:8443/taxii/",
+			[]string{"https://taxii.example.com:8443/" + tester.APIRootPath, "https://taxii.example.com:8443/other"}},
+		{"https://taxii.example.com/base/", nil,
+			"https://taxii.example.com/base/taxii/",
+			[]string{"https://taxii.example.com/base/" + tester.APIRootPath, "https://taxii.example.com/base/other"}},
+		{"https://taxii.example.com", map[string]string{"other": "https://other.example.com/roots/other/"},
+			"https://taxii.example.com/taxii/",
+			[]string{"https://taxii.example.com/" + tester.APIRootPath, "https://other.example.com/roots/other/"}},
+		// per api root urls are used without an external url
+		{"", map[string]string{"taxii": "https://default.example.com/taxii/"},
+			"https://default.example.com/taxii/",
+			[]string{"https://localhost:1234/" + tester.APIRootPath, "https://localhost:1234/other"}},
+	}
+
+	for _, test := range tests {
+		ds := mockDiscoveryService()
+		ds.DiscoveryFn = func(ctx context.Context, u cabby.User) (cabby.Discovery, error) {
+			d := tester.Discovery
+			d.Default = "https://localhost/taxii/"
+			d.APIRoots = []string{tester.APIRootPath, "other"}
+			return d, nil
+		}
+
+		h := DiscoveryHandler{
+			DiscoveryService: ds, Port: tester.Port, ExternalURL: test.externalURL, APIRootURLs: test.apiRootURLs}
+		status, body := handlerTest(h.Get, http.MethodGet, testDiscoveryURL, nil)
+
+		if status != http.StatusOK {
+			t.Error("Got:", status, "Expected:", http.StatusOK)
+		}
+
+		var result cabby.Discovery
+		err := json.Unmarshal([]byte(body), &result)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if result.Default != test.expectedDefault {
+			t.Error("Got:", result.Default, "Expected:", test.expectedDefault)
+		}
+		for i, expected := range test.expectedAPIRoots {
+			if result.APIRoots[i] != expected {
+				t.Error("Got:", result.APIRoots[i], "Expected:", expected)
+			}
+		}
+	}
+}
+
 func TestDiscoveryHandlerGetInternalServerError(t *testing.T) {
 	expected := cabby.Error{
 		Title: "Internal Server Error", Description: "Discovery failure", HTTPStatus: http.StatusInternalServerError}
 
 	ds := mockDiscoveryService()
-	ds.DiscoveryFn = func(ctx context.Context) (cabby.Discovery, error) {
+	ds.DiscoveryFn = func(ctx context.Context, u cabby.User) (cabby.Discovery, error) {
 		return cabby.Discovery{}, errors.New(expected.Description)
 	}
 
@@ -76,7 +165,7 @@ func TestDiscoveryHandlerGetInternalServerError(t *testing.T) {
 
 func TestDiscoveryHandlerGetNoDiscovery(t *testing.T) {
 	ds := mockDiscoveryService()
-	ds.DiscoveryFn = func(ctx context.Context) (cabby.Discovery, error) {
+	ds.DiscoveryFn = func(ctx context.Context, u cabby.User) (cabby.Discovery, error) {
 		return cabby.Discovery{Title: ""}, nil
 	}
 
@@ -104,7 +193,7 @@ func TestDiscoveryHandlerGetNoDiscovery(t *testing.T) {
 
 func TestDiscoveryHandlerPost(t *testing.T) {
 	ds := mockDiscoveryService()
-	ds.DiscoveryFn = func(ctx context.Context) (cabby.Discovery, error) {
+	ds.DiscoveryFn = func(ctx context.Context, u cabby.User) (cabby.Discovery, error) {
 		return cabby.Discovery{Title: ""}, nil
 	}
 
@@ -135,6 +224,26 @@ func TestInsertPort(t *testing.T) {
 	}
 }
 
+func TestValidExternalURL(t *testing.T) {
+	tests := []struct {
+		url         string
+		expectError bool
+	}{
+		{"https://taxii.example.com", false},
+		{"http://taxii.example.com:8080/base/", false},
+		{"taxii.example.com", true},
+		{"ftp://taxii.example.com", true},
+		{"https://", true},
+	}
+
+	for _, test := range tests {
+		err := validExternalURL(test.url)
+		if (err != nil) != test.expectError {
+			t.Error("Got:", err, "Expected error:", test.expectError, "URL:", test.url)
+		}
+	}
+}
+
 func TestSwapPath(t *testing.T) {
 	tests := []struct {
 		url      string
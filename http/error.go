@@ -12,9 +12,14 @@ import (
 )
 
 func errorStatus(w http.ResponseWriter, title string, err error, status int) {
+	errorStatusDetails(w, title, err, status, nil)
+}
+
+// errorStatusDetails responds with an error that has details about it
+func errorStatusDetails(w http.ResponseWriter, title string, err error, status int, details map[string]string) {
 	errString := fmt.Sprintf("%v", err)
 
-	te := cabby.Error{Title: title, Description: errString, HTTPStatus: status}
+	te := cabby.Error{Title: title, Description: errString, HTTPStatus: status, Details: details}
 
 	log.WithFields(log.Fields{
 		"error":       err,
@@ -37,6 +42,10 @@ func badRequest(w http.ResponseWriter, err error) {
 	errorStatus(w, "Bad Request", err, http.StatusBadRequest)
 }
 
+func conflict(w http.ResponseWriter, err error) {
+	errorStatus(w, "Conflict", err, http.StatusConflict)
+}
+
 func forbidden(w http.ResponseWriter, err error) {
 	errorStatus(w, "Forbidden", err, http.StatusForbidden)
 }
@@ -58,9 +67,18 @@ func resourceNotFound(w http.ResponseWriter, err error) {
 	errorStatus(w, "Resource Not Found", err, http.StatusNotFound)
 }
 
+func notImplemented(w http.ResponseWriter, err error) {
+	errorStatus(w, "Not Implemented", err, http.StatusNotImplemented)
+}
+
+func rangeNotSatisfiable(w http.ResponseWriter, p cabby.Page) {
+	w.Header().Set("Content-Range", p.ContentRange(0))
+	errorStatus(w, "Requested Range Not Satisfiable", errors.New("No resources available in the requested range"),
+		http.StatusRequestedRangeNotSatisfiable)
+}
+
 func requestTooLarge(w http.ResponseWriter, rc, mc int64) {
-	err := fmt.Errorf("content length is %v, content length can't be bigger than %v", rc, mc)
-	errorStatus(w, "Request Too large", err, http.StatusRequestEntityTooLarge)
+	errorStatus(w, "Request Too large", tooLargeError(rc, mc), http.StatusRequestEntityTooLarge)
 }
 
 func unauthorized(w http.ResponseWriter, err error) {
@@ -72,6 +90,14 @@ func unsupportedMediaType(w http.ResponseWriter, err error) {
 	errorStatus(w, "The media type provided in the Content-Type header is invalid", err, http.StatusUnsupportedMediaType)
 }
 
+// tooLargeError describes a request bigger than the max content length; rc is negative when the size isn't known
+func tooLargeError(rc, mc int64) error {
+	if rc < 0 {
+		return fmt.Errorf("content length can't be bigger than %v", mc)
+	}
+	return fmt.Errorf("content length is %v, content length can't be bigger than %v", rc, mc)
+}
+
 func recoverFromPanic(w http.ResponseWriter) {
 	if r := recover(); r != nil {
 		log.Error("Panic!  Printing out Stack...")
@@ -0,0 +1,182 @@
+package http
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/pladdy/cabby"
+)
+
+// parseTrustedProxies converts CIDRs to networks; a bare IP is treated as a network of one address
+func parseTrustedProxies(proxies []string) (networks []*net.IPNet, err error) {
+	for _, proxy := range proxies {
+		proxy = strings.TrimSpace(proxy)
+
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return networks, fmt.Errorf("Invalid trusted_proxies: %v is not an IP or CIDR", proxy)
+			}
+
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return networks, fmt.Errorf("Invalid trusted_proxies: %v is not an IP or CIDR", proxy)
+		}
+		networks = append(networks, network)
+	}
+	return
+}
+
+// withForwarded stores the values forwarded by a trusted proxy in the request context; forwarded headers from any
+// other client are ignored so they can't be spoofed
+func withForwarded(h http.Handler, proxies []*net.IPNet) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if trusted(remoteIP(r), proxies) {
+			*r = *r.WithContext(cabby.WithForwarded(r.Context(), takeForwarded(r, proxies)))
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// requestClientIP returns the IP of the client; the forwarded one if the request came through a trusted proxy
+func requestClientIP(r *http.Request) string {
+	if f := cabby.TakeForwarded(r.Context()); f.For != "" {
+		return f.For
+	}
+	return remoteIP(r)
+}
+
+// requestScheme returns the scheme a client used to make the request
+func requestScheme(r *http.Request) string {
+	if f := cabby.TakeForwarded(r.Context()); f.Proto != "" {
+		return f.Proto
+	}
+
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+/* helpers */
+
+// firstValue returns the first value from a comma separated header
+func firstValue(header string) string {
+	return strings.TrimSpace(strings.Split(header, ",")[0])
+}
+
+// forwardedElements splits a 'Forwarded' header (RFC 7239) into its elements of lower case parameters
+func forwardedElements(header string) (elements []map[string]string) {
+	for _, element := range strings.Split(header, ",") {
+		params := map[string]string{}
+
+		for _, pair := range strings.Split(element, ";") {
+			parts := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+			if len(parts) != 2 {
+				continue
+			}
+			params[strings.ToLower(parts[0])] = strings.Trim(parts[1], `"`)
+		}
+
+		if len(params) > 0 {
+			elements = append(elements, params)
+		}
+	}
+	return
+}
+
+// forwardedIP removes the port and brackets from a 'Forwarded' node (IE: "[2001:db8::1]:4711")
+func forwardedIP(node string) string {
+	node = strings.TrimSpace(node)
+
+	if host, _, err := net.SplitHostPort(node); err == nil {
+		return host
+	}
+	return strings.Trim(node, "[]")
+}
+
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// takeClientIP walks the chain of forwarded addresses from the closest hop and returns the first one that isn't a
+// trusted proxy
+func takeClientIP(chain []string, proxies []*net.IPNet) string {
+	for i := len(chain) - 1; i >= 0; i-- {
+		ip := forwardedIP(chain[i])
+		if ip != "" && !trusted(ip, proxies) {
+			return ip
+		}
+	}
+
+	if len(chain) > 0 {
+		return forwardedIP(chain[0])
+	}
+	return ""
+}
+
+// takeForwarded reads the 'Forwarded' header, falling back to the 'X-Forwarded-*' headers if it's not set; a host or
+// protocol that can't be used in a URL is dropped
+func takeForwarded(r *http.Request, proxies []*net.IPNet) cabby.Forwarded {
+	f := takeForwardedHeaders(r, proxies)
+
+	if f.Proto != "http" && f.Proto != "https" {
+		f.Proto = ""
+	}
+	if strings.ContainsAny(f.Host, "/\\@?# ") {
+		f.Host = ""
+	}
+	return f
+}
+
+func takeForwardedHeaders(r *http.Request, proxies []*net.IPNet) (f cabby.Forwarded) {
+	if header := r.Header.Get("Forwarded"); header != "" {
+		elements := forwardedElements(header)
+
+		var chain []string
+		for _, element := range elements {
+			chain = append(chain, element["for"])
+		}
+		f.For = takeClientIP(chain, proxies)
+
+		if len(elements) > 0 {
+			f.Host, f.Proto = elements[0]["host"], strings.ToLower(elements[0]["proto"])
+		}
+		return
+	}
+
+	if header := r.Header.Get("X-Forwarded-For"); header != "" {
+		f.For = takeClientIP(strings.Split(header, ","), proxies)
+	}
+	f.Host = firstValue(r.Header.Get("X-Forwarded-Host"))
+	f.Proto = strings.ToLower(firstValue(r.Header.Get("X-Forwarded-Proto")))
+	return
+}
+
+func trusted(ip string, proxies []*net.IPNet) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+
+	for _, proxy := range proxies {
+		if proxy.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,208 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/pladdy/cabby"
+	"github.com/pladdy/cabby/tester"
+	log "github.com/sirupsen/logrus"
+)
+
+func testProxies(t *testing.T) []*net.IPNet {
+	proxies, err := parseTrustedProxies([]string{"10.0.0.0/8", "192.0.2.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return proxies
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	tests := []struct {
+		proxies     []string
+		expectError bool
+	}{
+		{[]string{}, false},
+		{[]string{"10.0.0.0/8", "2001:db8::/32"}, false},
+		{[]string{"192.0.2.1", "2001:db8::1"}, false},
+		{[]string{"not an ip"}, true},
+		{[]string{"10.0.0.0/99"}, true},
+	}
+
+	for _, test := range tests {
+		_, err := parseTrustedProxies(test.proxies)
+		if (err != nil) != test.expectError {
+			t.Error("Got:", err, "Expected error:", test.expectError, "Proxies:", test.proxies)
+		}
+	}
+}
+
+func TestWithForwarded(t *testing.T) {
+	tests := []struct {
+		remoteAddr string
+		headers    map[string]string
+		expected   cabby.Forwarded
+	}{
+		// untrusted clients can't spoof forwarded values
+		{"203.0.113.9:1234",
+			map[string]string{"X-Forwarded-For": "198.51.100.1", "X-Forwarded-Proto": "https"},
+			cabby.Forwarded{}},
+		{"10.1.1.1:1234",
+			map[string]string{
+				"X-Forwarded-For":   "198.51.100.1, 10.2.2.2",
+				"X-Forwarded-Host":  "taxii.example.com",
+				"X-Forwarded-Proto": "HTTPS"},
+			cabby.Forwarded{For: "198.51.100.1", Host: "taxii.example.com", Proto: "https"}},
+		// the closest untrusted address is the client
+		{"10.1.1.1:1234",
+			map[string]string{"X-Forwarded-For": "203.0.113.5, 198.51.100.1, 10.2.2.2"},
+			cabby.Forwarded{For: "198.51.100.1"}},
+		{"192.0.2.1:1234",
+			map[string]string{
+				"Forwarded": `for="[2001:db8::1]:4711";host=taxii.example.com:8443;proto=https, for=10.3.3.3`},
+			cabby.Forwarded{For: "2001:db8::1", Host: "taxii.example.com:8443", Proto: "https"}},
+		// Forwarded takes precedence
+		{"192.0.2.1:1234",
+			map[string]string{"Forwarded": "for=198.51.100.2;proto=http", "X-Forwarded-For": "198.51.100.3"},
+			cabby.Forwarded{For: "198.51.100.2", Proto: "http"}},
+		// values that can't be used in a URL are dropped
+		{"10.1.1.1:1234",
+			map[string]string{"X-Forwarded-Host": "evil.com/path", "X-Forwarded-Proto": "javascript"},
+			cabby.Forwarded{}},
+	}
+
+	for _, test := range tests {
+		var result cabby.Forwarded
+		h := func(w http.ResponseWriter, r *http.Request) {
+			result = cabby.TakeForwarded(r.Context())
+		}
+
+		req := newClientRequest(http.MethodGet, testDiscoveryURL, nil)
+		req.RemoteAddr = test.remoteAddr
+		for header, value := range test.headers {
+			req.Header.Set(header, value)
+		}
+
+		withForwarded(http.HandlerFunc(h), testProxies(t)).ServeHTTP(httptest.NewRecorder(), req)
+
+		if result != test.expected {
+			t.Error("Got:", result, "Expected:", test.expected, "Headers:", test.headers)
+		}
+	}
+}
+
+func TestRequestScheme(t *testing.T) {
+	tests := []struct {
+		url       string
+		forwarded cabby.Forwarded
+		expected  string
+	}{
+		{"https://localhost/", cabby.Forwarded{}, "https"},
+		{"http://localhost/", cabby.Forwarded{}, "http"},
+		{"http://localhost/", cabby.Forwarded{Proto: "https"}, "https"},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, test.url, nil)
+		req = req.WithContext(cabby.WithForwarded(req.Context(), test.forwarded))
+
+		result := requestScheme(req)
+		if result != test.expected {
+			t.Error("Got:", result, "Expected:", test.expected)
+		}
+	}
+}
+
+func TestWithHSTS(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected bool
+	}{
+		{"https://localhost/", true},
+		{"http://localhost/", false},
+	}
+
+	for _, test := range tests {
+		res := httptest.NewRecorder()
+		withHSTS(res, httptest.NewRequest(http.MethodGet, test.url, nil))
+
+		result := res.Header().Get("Strict-Transport-Security") != ""
+		if result != test.expected {
+			t.Error("Got:", result, "Expected:", test.expected, "URL:", test.url)
+		}
+	}
+}
+
+func TestDiscoveryHandlerGetForwarded(t *testing.T) {
+	// the handler mutates api roots so use a copy of the mock discovery
+	ds := mockDiscoveryService()
+	ds.DiscoveryFn = func(ctx context.Context, u cabby.User) (cabby.Discovery, error) {
+		d := tester.Discovery
+		d.APIRoots = []string{tester.APIRootPath}
+		return d, nil
+	}
+
+	h := DiscoveryHandler{DiscoveryService: ds, Port: tester.Port}
+
+	req := newClientRequest(http.MethodGet, testDiscoveryURL, nil)
+	req = req.WithContext(cabby.WithForwarded(req.Context(), cabby.Forwarded{Host: "taxii.example.com", Proto: "https"}))
+	status, body, _ := callHandler(h.Get, req)
+
+	if status != http.StatusOK {
+		t.Error("Got:", status, "Expected:", http.StatusOK)
+	}
+
+	var result cabby.Discovery
+	err := json.Unmarshal([]byte(body), &result)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "https://taxii.example.com/taxii/"
+	if result.Default != expected {
+		t.Error("Got:", result.Default, "Expected:", expected)
+	}
+
+	expected = "https://taxii.example.com/" + tester.APIRootPath
+	if result.APIRoots[0] != expected {
+		t.Error("Got:", result.APIRoots[0], "Expected:", expected)
+	}
+}
+
+func TestWithLoggingClientIP(t *testing.T) {
+	// redirect log output for test
+	var buf bytes.Buffer
+
+	log.SetFormatter(&log.JSONFormatter{})
+	log.SetOutput(&buf)
+
+	defer func() {
+		log.SetFormatter(&log.TextFormatter{})
+		log.SetOutput(os.Stderr)
+	}()
+
+	req := newClientRequest(http.MethodGet, testDiscoveryURL, nil)
+	req.RemoteAddr = "10.1.1.1:1234"
+	req.Header.Set("X-Forwarded-For", "198.51.100.1")
+
+	h := withForwarded(withLogging(testHandler("test")), testProxies(t))
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	var result struct {
+		ClientIP string `json:"client_ip"`
+	}
+	err := json.Unmarshal([]byte(tester.LastLog(buf)), &result)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.ClientIP != "198.51.100.1" {
+		t.Error("Got:", result.ClientIP, "Expected:", "198.51.100.1")
+	}
+}
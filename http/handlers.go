@@ -22,33 +22,25 @@ func handleUndefinedRoute(w http.ResponseWriter, r *http.Request) {
 	resourceNotFound(w, fmt.Errorf("Invalid path: %v", r.URL))
 }
 
-func verifyRequestHeader(r *http.Request, h, v string) bool {
-	givenMimeType, _ := splitMimeType(r.Header.Get(h))
-	acceptedMimeType, _ := splitMimeType(v)
-
-	if givenMimeType == acceptedMimeType {
-		return true
-	}
-	return false
-}
-
-func withAcceptSet(h http.Handler, accept string) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if !verifyRequestHeader(r, "Accept", accept) {
-			notAcceptable(w, fmt.Errorf("Accept header must be '%v', not '%v'", accept, r.Header.Get("Accept")))
-			return
-		}
-		h.ServeHTTP(w, r)
-	})
-}
-
-func withBasicAuth(h http.Handler, us cabby.UserService) http.Handler {
+func withBasicAuth(h http.Handler, us cabby.UserService, a anonymousAccess) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		r = withTransactionID(r)
 
 		u, p, ok := r.BasicAuth()
 		if !ok {
-			log.WithFields(log.Fields{"user": u}).Warn("User authentication failed!")
+			user, err := anonymousUser(r, us, a)
+			if err != nil {
+				internalServerError(w, err)
+				return
+			}
+
+			if user.Defined() {
+				log.WithFields(log.Fields{"client_ip": requestClientIP(r)}).Info("Anonymous user allowed")
+				h.ServeHTTP(withHSTS(w, r), r.WithContext(cabby.WithUser(r.Context(), user)))
+				return
+			}
+
+			log.WithFields(log.Fields{"client_ip": requestClientIP(r), "user": u}).Warn("User authentication failed!")
 			unauthorized(w, errors.New("Invalid user / password combination"))
 			return
 		}
@@ -60,7 +52,7 @@ func withBasicAuth(h http.Handler, us cabby.UserService) http.Handler {
 		}
 
 		if !user.Defined() {
-			log.WithFields(log.Fields{"user": u}).Warn("User authentication failed!")
+			log.WithFields(log.Fields{"client_ip": requestClientIP(r), "user": u}).Warn("User authentication failed!")
 			unauthorized(w, errors.New("Invalid user / password combination"))
 			return
 		}
@@ -72,13 +64,22 @@ func withBasicAuth(h http.Handler, us cabby.UserService) http.Handler {
 		}
 		user.CollectionAccessList = ucs.CollectionAccessList
 
+		user, err = a.withAnonymousCollections(r.Context(), user, us)
+		if err != nil {
+			internalServerError(w, err)
+			return
+		}
+
 		log.WithFields(log.Fields{"user": u}).Info("User authenticated")
-		h.ServeHTTP(withHSTS(w), r.WithContext(cabby.WithUser(r.Context(), user)))
+		h.ServeHTTP(withHSTS(w, r), r.WithContext(cabby.WithUser(r.Context(), user)))
 	})
 }
 
-func withHSTS(w http.ResponseWriter) http.ResponseWriter {
-	w.Header().Add("Strict-Transport-Security", "max-age="+sixMonthsOfSeconds+"; includeSubDomains")
+// withHSTS only sets the header for clients using https; browsers ignore it over http
+func withHSTS(w http.ResponseWriter, r *http.Request) http.ResponseWriter {
+	if requestScheme(r) == "https" {
+		w.Header().Add("Strict-Transport-Security", "max-age="+sixMonthsOfSeconds+"; includeSubDomains")
+	}
 	return w
 }
 
@@ -88,6 +89,7 @@ func withLogging(h http.Handler) http.Handler {
 
 		start := time.Now().In(time.UTC)
 		log.WithFields(log.Fields{
+			"client_ip":              requestClientIP(r),
 			"method":                 r.Method,
 			"request_content_length": r.ContentLength,
 			"start_ms":               start.UnixNano() / milliSecondOfNanoSeconds,
@@ -102,13 +104,16 @@ func withLogging(h http.Handler) http.Handler {
 		elapsed := time.Since(start)
 
 		log.WithFields(log.Fields{
-			"bytes":          cabby.TakeBytes(r.Context()),
-			"elapsed_ms":     float64(elapsed.Nanoseconds()) / float64(milliSecondOfNanoSeconds),
-			"end_ms":         end.UnixNano() / milliSecondOfNanoSeconds,
-			"method":         r.Method,
-			"transaction_id": cabby.TakeTransactionID(r.Context()),
-			"url":            r.URL.String(),
-			"user":           cabby.TakeUser(r.Context()).Email,
+			"bytes":            cabby.TakeBytes(r.Context()),
+			"client_ip":        requestClientIP(r),
+			"compressed_bytes": cabby.TakeCompressedBytes(r.Context()),
+			"content_encoding": w.Header().Get("Content-Encoding"),
+			"elapsed_ms":       float64(elapsed.Nanoseconds()) / float64(milliSecondOfNanoSeconds),
+			"end_ms":           end.UnixNano() / milliSecondOfNanoSeconds,
+			"method":           r.Method,
+			"transaction_id":   cabby.TakeTransactionID(r.Context()),
+			"url":              r.URL.String(),
+			"user":             cabby.TakeUser(r.Context()).Email,
 		}).Info("Request served")
 	})
 }
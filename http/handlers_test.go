@@ -5,8 +5,6 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
-	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -39,40 +37,6 @@ func TestHandleUndefinedRequest(t *testing.T) {
 	}
 }
 
-func TestWithAcceptSet(t *testing.T) {
-	tests := []struct {
-		acceptedHeader string
-		acceptHeader   string
-		responseCode   int
-	}{
-		{cabby.TaxiiContentType21, "application/vnd.oasis.taxii+json;version=2.1", http.StatusOK},
-		{cabby.TaxiiContentType, "application/vnd.oasis.taxii+json", http.StatusOK},
-		{cabby.TaxiiContentType, "", http.StatusNotAcceptable},
-		{cabby.TaxiiContentType, "application/vnd.oasis.taxii+jsonp", http.StatusNotAcceptable},
-		{cabby.TaxiiContentType, "application/vnd.oasis.taxii+jsonp; version=3.0", http.StatusNotAcceptable},
-	}
-
-	for _, test := range tests {
-		serveFn := func(w http.ResponseWriter, r *http.Request) {
-			accept := r.Header.Get("Accept")
-			io.WriteString(w, fmt.Sprintf("testHandler Request Accept Header: %v", accept))
-		}
-		testHandler := withAcceptSet(http.HandlerFunc(serveFn), test.acceptedHeader)
-
-		// set up a server
-		server := httptest.NewServer(testHandler)
-		defer server.Close()
-
-		req := newServerRequest(http.MethodGet, server.URL)
-		req.Header.Set("Accept", test.acceptHeader)
-		res, _ := getResponse(req, server)
-
-		if res.StatusCode != test.responseCode {
-			t.Error("Got:", res.StatusCode, "Expected:", test.responseCode)
-		}
-	}
-}
-
 func TestWithBasicAuth(t *testing.T) {
 	tests := []struct {
 		expectedStatus    int
@@ -111,10 +75,11 @@ func TestWithBasicAuth(t *testing.T) {
 
 	for _, test := range tests {
 		// set up service
-		us := tester.UserService{UserFn: test.userFn, UserCollectionsFn: test.userCollectionsFn}
+		us := mockUserService()
+		us.UserFn, us.UserCollectionsFn = test.userFn, test.userCollectionsFn
 
 		// set up handler
-		testHandler := withBasicAuth(testHandler(t.Name()), &us)
+		testHandler := withBasicAuth(testHandler(t.Name()), &us, anonymousAccess{})
 
 		// set up a server
 		server := httptest.NewServer(testHandler)
@@ -137,10 +102,11 @@ func TestWithBasicAuthFailAuth(t *testing.T) {
 	userCollectionsFn := func(ctx context.Context, user string) (cabby.UserCollectionList, error) {
 		return cabby.UserCollectionList{}, nil
 	}
-	us := tester.UserService{UserFn: userFn, UserCollectionsFn: userCollectionsFn}
+	us := mockUserService()
+	us.UserFn, us.UserCollectionsFn = userFn, userCollectionsFn
 
 	// set up handler
-	testHandler := withBasicAuth(testHandler(t.Name()), &us)
+	testHandler := withBasicAuth(testHandler(t.Name()), &us, anonymousAccess{})
 
 	// set up a server
 	server := httptest.NewServer(testHandler)
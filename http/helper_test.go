@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"crypto/tls"
+	"encoding/json"
 	"errors"
 	"io"
 	"io/ioutil"
@@ -26,6 +27,8 @@ var (
 	testManifestURL    = testCollectionURL + "manifest/"
 	testObjectsURL     = testCollectionURL + "objects/"
 	testObjectURL      = testObjectsURL + tester.ObjectID + "/"
+	testObservablesURL = testDiscoveryURL + "observables/"
+	testSearchURL      = testAPIRootURL + "search/"
 	testStatusURL      = testAPIRootURL + "status/" + tester.StatusID + "/"
 	testVersionsURL    = testObjectURL + "/versions/"
 )
@@ -143,6 +146,15 @@ func testRequestLog(result tester.RequestLog, t *testing.T) {
 	}
 }
 
+// set up a http client that uses TLS and negotiates HTTP/2
+func h2Client() *http.Client {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: true,
+	}
+	tr := &http.Transport{TLSClientConfig: tlsConfig, ForceAttemptHTTP2: true}
+	return &http.Client{Transport: tr}
+}
+
 // set up a http client that uses TLS
 func tlsClient() *http.Client {
 	tlsConfig := &tls.Config{
@@ -175,12 +187,15 @@ func mockCollectionService() tester.CollectionService {
 	cs.CollectionsInAPIRootFn = func(ctx context.Context, apiRootPath string) (cabby.CollectionsInAPIRoot, error) {
 		return tester.CollectionsInAPIRoot, nil
 	}
+	cs.CollectionLastModifiedFn = func(ctx context.Context, collectionID string) (stones.Timestamp, error) {
+		return tester.LastModified, nil
+	}
 	return cs
 }
 
 func mockDiscoveryService() tester.DiscoveryService {
 	ds := tester.DiscoveryService{}
-	ds.DiscoveryFn = func(ctx context.Context) (cabby.Discovery, error) { return tester.Discovery, nil }
+	ds.DiscoveryFn = func(ctx context.Context, u cabby.User) (cabby.Discovery, error) { return tester.Discovery, nil }
 	return ds
 }
 
@@ -210,7 +225,12 @@ func mockObjectService() tester.ObjectService {
 	osv.CreateEnvelopeFn = func(ctx context.Context, e cabby.Envelope, collectionID string, s cabby.Status, ss cabby.StatusService) {
 		log.Debug("mock Creating Envelope")
 	}
-	osv.DeleteObjectFn = func(ctx context.Context, collectionID, objectID string) error {
+	osv.CreateObjectsFn = func(ctx context.Context, objects <-chan json.RawMessage, collectionID string, s cabby.Status, ss cabby.StatusService) {
+		log.Debug("mock Creating Objects")
+		for range objects {
+		}
+	}
+	osv.DeleteObjectFn = func(ctx context.Context, collectionID, objectID string, f cabby.Filter) error {
 		return nil
 	}
 	osv.ObjectFn = func(ctx context.Context, collectionID, objectID string, f cabby.Filter) ([]stones.Object, error) {
@@ -219,19 +239,70 @@ func mockObjectService() tester.ObjectService {
 	osv.ObjectsFn = func(ctx context.Context, collectionID string, p *cabby.Page, f cabby.Filter) ([]stones.Object, error) {
 		return tester.Objects, nil
 	}
+	osv.ValidateObjectsFn = func(ctx context.Context, objects <-chan json.RawMessage, collectionID string) cabby.ValidationReport {
+		var r cabby.ValidationReport
+		for range objects {
+			r.TotalCount++
+		}
+		r.SuccessCount = r.TotalCount
+		return r
+	}
 	return osv
 }
 
+func mockObservableService() tester.ObservableService {
+	obs := tester.ObservableService{}
+	obs.IndexObservablesFn = func(ctx context.Context) (int64, error) { return 0, nil }
+	obs.ObservableMatchesFn = func(ctx context.Context, values []string, p *cabby.Page) (cabby.ObservableMatches, error) {
+		p.Total = 1
+		return cabby.ObservableMatches{Objects: []cabby.ObservableMatch{{
+			CollectionID: tester.CollectionID,
+			ID:           tester.ObjectID,
+			Version:      tester.Object.Modified,
+			Observable:   cabby.Observable{Type: "ipv4-addr", Value: "203.0.113.7"},
+			Object:       tester.Object.Source}}}, nil
+	}
+	return obs
+}
+
+func mockSearchService() tester.SearchService {
+	srs := tester.SearchService{}
+	srs.SearchFn = func(ctx context.Context, apiRoot, query string, p *cabby.Page) (cabby.SearchHits, error) {
+		p.Total = 1
+		return cabby.SearchHits{Objects: []cabby.SearchHit{{
+			CollectionID: tester.CollectionID,
+			ID:           tester.ObjectID,
+			DateAdded:    tester.Object.Modified,
+			Version:      tester.Object.Modified,
+			MediaTypes:   []string{cabby.StixContentType},
+			Object:       tester.Object.Source}}}, nil
+	}
+	return srs
+}
+
 func mockStatusService() tester.StatusService {
 	ss := tester.StatusService{}
+	ss.CreateIdempotencyKeyFn = func(ctx context.Context, collectionID, key, statusID string) error { return nil }
 	ss.CreateStatusFn = func(ctx context.Context, status cabby.Status) error { return nil }
-	ss.StatusFn = func(ctx context.Context, statusID string) (cabby.Status, error) { return tester.Status, nil }
+	ss.DeleteIdempotencyKeyFn = func(ctx context.Context, collectionID, key string) error { return nil }
+	ss.IdempotentStatusFn = func(ctx context.Context, collectionID, key string) (cabby.Status, error) {
+		return cabby.Status{}, nil
+	}
+	ss.ExpireStatusesFn = func(ctx context.Context, createdBefore time.Time) (int64, error) { return 0, nil }
+	ss.StatusFn = func(ctx context.Context, apiRoot, statusID string) (cabby.Status, error) { return tester.Status, nil }
+	ss.StatusesFn = func(ctx context.Context, apiRoot string, p *cabby.Page) (cabby.Statuses, error) {
+		p.Total = 1
+		return cabby.Statuses{Statuses: []cabby.Status{tester.Status}}, nil
+	}
 	ss.UpdateStatusFn = func(ctx context.Context, status cabby.Status) error { return nil }
 	return ss
 }
 
 func mockUserService() tester.UserService {
 	us := tester.UserService{}
+	us.AnonymousCollectionsFn = func(ctx context.Context) (cabby.UserCollectionList, error) {
+		return cabby.UserCollectionList{}, nil
+	}
 	us.UserFn = func(ctx context.Context, user, password string) (cabby.User, error) {
 		return cabby.User{Email: tester.UserEmail}, nil
 	}
@@ -257,6 +328,8 @@ func mockDataStore() tester.DataStore {
 	md.ManifestServiceFn = func() tester.ManifestService { return mockManifestService() }
 	md.MigrationServiceFn = func() tester.MigrationService { return mockMigrationService() }
 	md.ObjectServiceFn = func() tester.ObjectService { return mockObjectService() }
+	md.ObservableServiceFn = func() tester.ObservableService { return mockObservableService() }
+	md.SearchServiceFn = func() tester.SearchService { return mockSearchService() }
 	md.StatusServiceFn = func() tester.StatusService { return mockStatusService() }
 	md.UserServiceFn = func() tester.UserService { return mockUserService() }
 	md.VersionsServiceFn = func() tester.VersionsService { return mockVersionsService() }
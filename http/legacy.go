@@ -0,0 +1,109 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/pladdy/cabby"
+	"github.com/pladdy/stones"
+)
+
+// TAXII 2.0 clients are served by api roots that list taxii-2.0 in their versions; a client asks for 2.0 with the
+// version in its Accept header.  2.0 pages with the 'Range' header, returns objects in STIX bundles and has its own
+// manifest and status shapes.
+
+const legacyVersion = "2.0"
+
+// legacyRequest returns whether the client negotiated TAXII 2.0
+func legacyRequest(r *http.Request) bool {
+	return toMediaRange(cabby.TakeMediaType(r.Context())).version == legacyVersion
+}
+
+// rangeRequest returns whether a TAXII 2.0 client asked for a range of items
+func rangeRequest(r *http.Request) bool {
+	return legacyRequest(r) && r.Header.Get("Range") != ""
+}
+
+// takePage returns the page a client asked for; TAXII 2.0 clients use the 'Range' header instead of a limit
+func takePage(r *http.Request) (cabby.Page, error) {
+	if rangeRequest(r) {
+		return cabby.NewRangePage(r.Header.Get("Range"))
+	}
+	return cabby.NewPage(takeLimit(r))
+}
+
+// withAPIRootVersions rejects TAXII 2.0 requests to an api root that only supports 2.1
+func withAPIRootVersions(h http.HandlerFunc, a cabby.APIRoot) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if legacyRequest(r) && !a.SupportsVersion(cabby.TaxiiVersion20) {
+			notAcceptable(w, fmt.Errorf(
+				"API root '%v' doesn't support TAXII %v, supported versions: %v", a.Path, legacyVersion, a.Versions))
+			return
+		}
+		h(w, r)
+	}
+}
+
+// writePage writes a page of resources; a TAXII 2.0 range request gets a partial content response with the range of
+// items returned
+func writePage(w http.ResponseWriter, r *http.Request, p cabby.Page, items int, contentType, content string) {
+	if rangeRequest(r) {
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Range", p.ContentRange(items))
+		w.WriteHeader(http.StatusPartialContent)
+	}
+	writeContent(w, r, contentType, content)
+}
+
+// writeObjects writes objects in an envelope, or a STIX bundle for TAXII 2.0 clients
+func writeObjects(w http.ResponseWriter, r *http.Request, objects []stones.Object, p cabby.Page) {
+	if !legacyRequest(r) {
+		writePage(w, r, p, len(objects), responseMediaType(r), resourceToJSON(objectsToEnvelope(objects, p)))
+		return
+	}
+
+	bundle, err := stones.NewBundle()
+	if err != nil {
+		internalServerError(w, err)
+		return
+	}
+
+	for _, o := range objects {
+		bundle.AddObject(string(o.Source))
+	}
+	writePage(w, r, p, len(objects), cabby.StixContentType20, resourceToJSON(bundle))
+}
+
+// noPage writes the error for a page without resources; a TAXII 2.0 range that can't be satisfied gets a 416
+func noPage(w http.ResponseWriter, r *http.Request, p cabby.Page) {
+	if rangeRequest(r) {
+		rangeNotSatisfiable(w, p)
+		return
+	}
+	resourceNotFound(w, errors.New("No resources available for this request"))
+}
+
+// statusResource returns a status in the shape the client expects
+func statusResource(r *http.Request, s cabby.Status) interface{} {
+	if legacyRequest(r) {
+		return s.Status20()
+	}
+	return s
+}
+
+// statusesResource returns statuses in the shape the client expects
+func statusesResource(r *http.Request, ss cabby.Statuses) interface{} {
+	if !legacyRequest(r) {
+		return ss
+	}
+
+	ss20 := struct {
+		Statuses []cabby.Status20 `json:"statuses"`
+	}{[]cabby.Status20{}}
+
+	for _, s := range ss.Statuses {
+		ss20.Statuses = append(ss20.Statuses, s.Status20())
+	}
+	return ss20
+}
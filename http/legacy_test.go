@@ -0,0 +1,230 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/pladdy/cabby"
+	"github.com/pladdy/cabby/tester"
+	"github.com/pladdy/stones"
+)
+
+func newLegacyRequest(method, url string) *http.Request {
+	req := newClientRequest(method, url, nil)
+	req.Header.Set("Accept", cabby.TaxiiContentType20)
+	return req.WithContext(cabby.WithMediaType(req.Context(), cabby.TaxiiContentType20))
+}
+
+func TestTakePage(t *testing.T) {
+	tests := []struct {
+		req         *http.Request
+		rangeHeader string
+		expected    cabby.Page
+		expectError bool
+	}{
+		{newClientRequest(http.MethodGet, testObjectsURL+"?limit=5", nil), "items 0-9", cabby.Page{Limit: 5}, false},
+		{newLegacyRequest(http.MethodGet, testObjectsURL), "items 10-19", cabby.Page{Offset: 10, Limit: 10}, false},
+		{newLegacyRequest(http.MethodGet, testObjectsURL), "items 19-10", cabby.Page{}, true},
+	}
+
+	for _, test := range tests {
+		test.req.Header.Set("Range", test.rangeHeader)
+
+		result, err := takePage(test.req)
+		if result != test.expected {
+			t.Error("Got:", result, "Expected:", test.expected)
+		}
+		if (err != nil) != test.expectError {
+			t.Error("Got:", err, "Expected error:", test.expectError)
+		}
+	}
+}
+
+func TestWithAPIRootVersions(t *testing.T) {
+	tests := []struct {
+		req            *http.Request
+		versions       []string
+		expectedStatus int
+	}{
+		{newClientRequest(http.MethodGet, testAPIRootURL, nil), []string{cabby.TaxiiVersion}, http.StatusOK},
+		{newLegacyRequest(http.MethodGet, testAPIRootURL), []string{cabby.TaxiiVersion}, http.StatusNotAcceptable},
+		{newLegacyRequest(http.MethodGet, testAPIRootURL), []string{cabby.TaxiiVersion20, cabby.TaxiiVersion}, http.StatusOK},
+	}
+
+	for _, test := range tests {
+		a := cabby.APIRoot{Path: tester.APIRootPath, Versions: test.versions}
+		status, _, _ := callHandler(withAPIRootVersions(testHandler(t.Name()), a), test.req)
+
+		if status != test.expectedStatus {
+			t.Error("Got:", status, "Expected:", test.expectedStatus, "Versions:", test.versions)
+		}
+	}
+}
+
+func TestObjectsHandlerGetLegacy(t *testing.T) {
+	obs := mockObjectService()
+	obs.ObjectsFn = func(ctx context.Context, collectionID string, p *cabby.Page, f cabby.Filter) ([]stones.Object, error) {
+		p.Total = 5
+		return []stones.Object{tester.GenerateObject("malware"), tester.GenerateObject("malware")}, nil
+	}
+	h := ObjectsHandler{CollectionService: mockCollectionService(), ObjectService: obs}
+
+	tests := []struct {
+		rangeHeader    string
+		expectedStatus int
+		contentRange   string
+	}{
+		{"", http.StatusOK, ""},
+		{"items 0-1", http.StatusPartialContent, "items 0-1/5"},
+	}
+
+	for _, test := range tests {
+		req := newLegacyRequest(http.MethodGet, testObjectsURL)
+		req.Header.Set("Range", test.rangeHeader)
+		status, body, headers := callHandler(h.Get, req)
+
+		if status != test.expectedStatus {
+			t.Error("Got:", status, "Expected:", test.expectedStatus)
+		}
+		if headers.Get("Content-Range") != test.contentRange {
+			t.Error("Got:", headers.Get("Content-Range"), "Expected:", test.contentRange)
+		}
+		if headers.Get("Content-Type") != cabby.StixContentType20 {
+			t.Error("Got:", headers.Get("Content-Type"), "Expected:", cabby.StixContentType20)
+		}
+
+		var bundle stones.Bundle
+		err := json.Unmarshal([]byte(body), &bundle)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bundle.Type != "bundle" || len(bundle.Objects) != 2 {
+			t.Error("Got:", bundle, "Expected: a bundle with 2 objects")
+		}
+	}
+}
+
+func TestObjectsHandlerGetLegacyRangeNotSatisfiable(t *testing.T) {
+	obs := mockObjectService()
+	obs.ObjectsFn = func(ctx context.Context, collectionID string, p *cabby.Page, f cabby.Filter) ([]stones.Object, error) {
+		p.Total = 5
+		return []stones.Object{}, nil
+	}
+	h := ObjectsHandler{CollectionService: mockCollectionService(), ObjectService: obs}
+
+	req := newLegacyRequest(http.MethodGet, testObjectsURL)
+	req.Header.Set("Range", "items 10-19")
+	status, _, headers := callHandler(h.Get, req)
+
+	if status != http.StatusRequestedRangeNotSatisfiable {
+		t.Error("Got:", status, "Expected:", http.StatusRequestedRangeNotSatisfiable)
+	}
+	if headers.Get("Content-Range") != "items */5" {
+		t.Error("Got:", headers.Get("Content-Range"), "Expected:", "items */5")
+	}
+}
+
+func TestManifestHandlerGetLegacy(t *testing.T) {
+	h := ManifestHandler{CollectionService: mockCollectionService(), ManifestService: mockManifestService()}
+	status, body, headers := callHandler(h.Get, newLegacyRequest(http.MethodGet, testManifestURL))
+
+	if status != http.StatusOK {
+		t.Error("Got:", status, "Expected:", http.StatusOK)
+	}
+	if headers.Get("Content-Type") != cabby.TaxiiContentType20 {
+		t.Error("Got:", headers.Get("Content-Type"), "Expected:", cabby.TaxiiContentType20)
+	}
+
+	var result cabby.Manifest20
+	err := json.Unmarshal([]byte(body), &result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Objects) != 1 || len(result.Objects[0].Versions) != 1 {
+		t.Error("Got:", result, "Expected: 1 entry with 1 version")
+	}
+}
+
+func TestManifestHandlerGetLegacyRange(t *testing.T) {
+	ms := mockManifestService()
+	ms.ManifestFn = func(ctx context.Context, collectionID string, p *cabby.Page, f cabby.Filter) (cabby.Manifest, error) {
+		if !p.PerObject {
+			t.Error("Expected a TAXII 2.0 manifest to be paged per object")
+		}
+		p.Total = 5
+
+		// two objects, one with two versions
+		second := tester.ManifestEntry
+		second.ID = "malware--6b4f1a2e-9c3d-4e5f-8a7b-1c2d3e4f5a6b"
+		return cabby.Manifest{Objects: []cabby.ManifestEntry{tester.ManifestEntry, tester.ManifestEntry, second}}, nil
+	}
+	h := ManifestHandler{CollectionService: mockCollectionService(), ManifestService: ms}
+
+	req := newLegacyRequest(http.MethodGet, testManifestURL)
+	req.Header.Set("Range", "items 0-1")
+	status, _, headers := callHandler(h.Get, req)
+
+	if status != http.StatusPartialContent {
+		t.Error("Got:", status, "Expected:", http.StatusPartialContent)
+	}
+	if headers.Get("Content-Range") != "items 0-1/5" {
+		t.Error("Got:", headers.Get("Content-Range"), "Expected:", "items 0-1/5")
+	}
+}
+
+func TestManifestHandlerGetLegacyETag(t *testing.T) {
+	h := ManifestHandler{CollectionService: mockCollectionService(), ManifestService: mockManifestService()}
+
+	_, _, headers := callHandler(h.Get, newClientRequest(http.MethodGet, testManifestURL, nil))
+	_, _, legacyHeaders := callHandler(h.Get, newLegacyRequest(http.MethodGet, testManifestURL))
+
+	if headers.Get("ETag") == legacyHeaders.Get("ETag") {
+		t.Error("Got:", legacyHeaders.Get("ETag"), "Expected a different ETag than:", headers.Get("ETag"))
+	}
+}
+
+func TestStatusHandlerGetLegacy(t *testing.T) {
+	ss := mockStatusService()
+	ss.StatusFn = func(ctx context.Context, apiRoot, statusID string) (cabby.Status, error) {
+		s := tester.Status
+		s.Failures = []cabby.StatusDetails{{ID: "malware--1"}}
+		return s, nil
+	}
+	h := StatusHandler{StatusService: ss}
+	status, body, _ := callHandler(h.Get, newLegacyRequest(http.MethodGet, testStatusURL))
+
+	if status != http.StatusOK {
+		t.Error("Got:", status, "Expected:", http.StatusOK)
+	}
+
+	var result cabby.Status20
+	err := json.Unmarshal([]byte(body), &result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Failures) != 1 || result.Failures[0].ID != "malware--1" {
+		t.Error("Got:", result.Failures, "Expected: a failure for malware--1")
+	}
+}
+
+func TestStatusHandlerGetStatusesLegacy(t *testing.T) {
+	h := StatusHandler{StatusService: mockStatusService()}
+	status, body, _ := callHandler(h.Get, newLegacyRequest(http.MethodGet, testAPIRootURL+"status/"))
+
+	if status != http.StatusOK {
+		t.Error("Got:", status, "Expected:", http.StatusOK)
+	}
+
+	var result struct {
+		Statuses []cabby.Status20 `json:"statuses"`
+	}
+	err := json.Unmarshal([]byte(body), &result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Statuses) != 1 || result.Statuses[0].ID != tester.Status.ID {
+		t.Error("Got:", result, "Expected:", tester.Status.ID)
+	}
+}
@@ -13,7 +13,8 @@ const ManifestMethods = "Get, Head"
 
 // ManifestHandler holds a cabby ManifestService
 type ManifestHandler struct {
-	ManifestService cabby.ManifestService
+	CollectionService cabby.CollectionService
+	ManifestService   cabby.ManifestService
 }
 
 // Delete handler
@@ -30,26 +31,48 @@ func (h ManifestHandler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	p, err := cabby.NewPage(takeLimit(r))
+	p, err := takePage(r)
 	if err != nil {
 		badRequest(w, err)
 		return
 	}
+	p.PerObject = legacyRequest(r)
 
-	manifest, err := h.ManifestService.Manifest(r.Context(), takeCollectionID(r), &p, newFilter(r))
+	f, err := newFilter(r)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+
+	manifest, err := h.ManifestService.Manifest(r.Context(), takeCollectionID(r), &p, f)
 	if err != nil {
 		internalServerError(w, err)
 		return
 	}
 
 	if noResources(len(manifest.Objects)) {
-		resourceNotFound(w, errors.New("No resources available for this request"))
+		noPage(w, r, p)
+		return
+	}
+
+	lastModified, err := h.CollectionService.CollectionLastModified(r.Context(), takeCollectionID(r))
+	if err != nil {
+		internalServerError(w, err)
 		return
 	}
 
 	w.Header().Set("X-TAXII-Date-Added-First", p.AddedAfterFirst())
 	w.Header().Set("X-TAXII-Date-Added-Last", p.AddedAfterLast())
-	writeContent(w, r, cabby.TaxiiContentType, resourceToJSON(manifest))
+	if notModified(w, r, manifestETag(manifest), lastModified.Time) {
+		return
+	}
+
+	if legacyRequest(r) {
+		m20 := manifest.Manifest20()
+		writePage(w, r, p, len(m20.Objects), responseMediaType(r), resourceToJSON(m20))
+		return
+	}
+	writePage(w, r, p, len(manifest.Objects), responseMediaType(r), resourceToJSON(manifest))
 }
 
 // Post handler
@@ -15,7 +15,7 @@ import (
 )
 
 func TestManifestHandlerDelete(t *testing.T) {
-	h := ManifestHandler{ManifestService: mockManifestService()}
+	h := ManifestHandler{CollectionService: mockCollectionService(), ManifestService: mockManifestService()}
 	status, _ := handlerTest(h.Delete, http.MethodDelete, testManifestURL, nil)
 
 	if status != http.StatusMethodNotAllowed {
@@ -24,7 +24,7 @@ func TestManifestHandlerDelete(t *testing.T) {
 }
 
 func TestManifestHandlerGet(t *testing.T) {
-	h := ManifestHandler{ManifestService: mockManifestService()}
+	h := ManifestHandler{CollectionService: mockCollectionService(), ManifestService: mockManifestService()}
 	req := newClientRequest(http.MethodGet, testManifestURL, nil)
 	status, body, _ := callHandler(h.Get, req)
 
@@ -49,7 +49,7 @@ func TestManifestHandlerGetBadRequest(t *testing.T) {
 	expected := cabby.Error{
 		Title: "Bad Request", Description: "Invalid limit specified", HTTPStatus: http.StatusBadRequest}
 
-	h := ManifestHandler{ManifestService: mockManifestService()}
+	h := ManifestHandler{CollectionService: mockCollectionService(), ManifestService: mockManifestService()}
 	req := newClientRequest(http.MethodGet, testManifestURL+"?limit=0", nil)
 	status, body, _ := callHandler(h.Get, req)
 
@@ -70,7 +70,7 @@ func TestManifestHandlerGetBadRequest(t *testing.T) {
 }
 
 func TestManifestHandlerGetForbidden(t *testing.T) {
-	h := ManifestHandler{ManifestService: mockManifestService()}
+	h := ManifestHandler{CollectionService: mockCollectionService(), ManifestService: mockManifestService()}
 	req := newClientRequest(http.MethodGet, testManifestURL, nil)
 	req = req.WithContext(context.Background())
 	status, _, _ := callHandler(h.Get, req)
@@ -81,7 +81,7 @@ func TestManifestHandlerGetForbidden(t *testing.T) {
 }
 
 func TestManifestHandlerGetHeaders(t *testing.T) {
-	h := ManifestHandler{ManifestService: mockManifestService()}
+	h := ManifestHandler{CollectionService: mockCollectionService(), ManifestService: mockManifestService()}
 	req := newClientRequest(http.MethodGet, testManifestURL, nil)
 	res := httptest.NewRecorder()
 	tm := time.Time{}
@@ -107,7 +107,7 @@ func TestManifestHandlerGetInternalServerError(t *testing.T) {
 		return cabby.Manifest{}, errors.New(expected.Description)
 	}
 
-	h := ManifestHandler{ManifestService: &ms}
+	h := ManifestHandler{CollectionService: mockCollectionService(), ManifestService: &ms}
 	req := newClientRequest(http.MethodGet, testManifestURL, nil)
 	status, body, _ := callHandler(h.Get, req)
 
@@ -133,7 +133,7 @@ func TestManifestHandlerGetNoManifest(t *testing.T) {
 		return cabby.Manifest{}, nil
 	}
 
-	h := ManifestHandler{ManifestService: &ms}
+	h := ManifestHandler{CollectionService: mockCollectionService(), ManifestService: &ms}
 	req := newClientRequest(http.MethodGet, testManifestURL, nil)
 	status, body, _ := callHandler(h.Get, req)
 
@@ -177,7 +177,7 @@ func TestManifestHandlerGetPage(t *testing.T) {
 			p.Total = uint64(test.expected)
 			return manifest, nil
 		}
-		h := ManifestHandler{ManifestService: ms}
+		h := ManifestHandler{CollectionService: mockCollectionService(), ManifestService: ms}
 
 		req := newClientRequest(http.MethodGet, testManifestURL+"?limit="+strconv.Itoa(test.limit), nil)
 		status, body, _ := callHandler(h.Get, req)
@@ -199,7 +199,7 @@ func TestManifestHandlerGetPage(t *testing.T) {
 }
 
 func TestManifestHandlerPost(t *testing.T) {
-	h := ManifestHandler{ManifestService: mockManifestService()}
+	h := ManifestHandler{CollectionService: mockCollectionService(), ManifestService: mockManifestService()}
 	status, _ := handlerTest(h.Post, http.MethodPost, testManifestURL, nil)
 
 	if status != http.StatusMethodNotAllowed {
@@ -0,0 +1,182 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pladdy/cabby"
+)
+
+// taxiiMediaTypes are the media types the server responds with; the first is used for wildcard Accept headers
+var taxiiMediaTypes = []string{
+	cabby.TaxiiMediaType21,
+	cabby.TaxiiContentType21,
+	cabby.TaxiiContentType20,
+	cabby.StixContentType20,
+}
+
+// mediaRange is a media type from an Accept or Content-Type header
+type mediaRange struct {
+	mediaType string
+	version   string
+	q         float64
+}
+
+// matches returns whether a supported media type satisfies the range; a range without a version matches any version
+func (mr mediaRange) matches(supported mediaRange) bool {
+	if mr.mediaType == "*/*" || mr.mediaType == "application/*" {
+		return true
+	}
+	return mr.mediaType == supported.mediaType && (mr.version == "" || mr.version == supported.version)
+}
+
+// negotiateMediaType returns the supported media type that best matches the Accept header, honoring q-values.  If
+// nothing matches, the versions requested for a supported media type are returned so they can be reported.
+func negotiateMediaType(accept string, supported []string) (mediaType string, versions []string) {
+	for _, mr := range parseAccept(accept) {
+		if mr.q <= 0 {
+			continue
+		}
+
+		for _, s := range supported {
+			if mr.matches(toMediaRange(s)) {
+				return s, nil
+			}
+		}
+
+		if mr.version != "" && supportedFamily(mr, supported) {
+			versions = append(versions, mr.version)
+		}
+	}
+	return
+}
+
+// parseAccept splits an Accept header into media ranges ordered by q-value; order is kept for equal q-values
+func parseAccept(accept string) (ranges []mediaRange) {
+	for _, part := range strings.Split(accept, ",") {
+		mr, ok := parseMediaRange(part)
+		if ok {
+			ranges = append(ranges, mr)
+		}
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].q > ranges[j].q })
+	return
+}
+
+// parseMediaRange parses a media type and its 'version' and 'q' parameters; it's not ok if the type is missing or the
+// q-value is invalid
+func parseMediaRange(s string) (mr mediaRange, ok bool) {
+	parts := strings.Split(s, ";")
+	mr.mediaType = strings.ToLower(strings.TrimSpace(parts[0]))
+	mr.q = 1
+
+	for _, param := range parts[1:] {
+		kv := strings.SplitN(param, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		value := strings.Trim(strings.TrimSpace(kv[1]), `"`)
+
+		switch strings.ToLower(strings.TrimSpace(kv[0])) {
+		case "version":
+			mr.version = value
+		case "q":
+			q, err := strconv.ParseFloat(value, 64)
+			if err != nil || q < 0 || q > 1 {
+				return mr, false
+			}
+			mr.q = q
+		}
+	}
+
+	return mr, mr.mediaType != "" && strings.Contains(mr.mediaType, "/")
+}
+
+// toMediaRange converts a supported media type to a range
+func toMediaRange(s string) mediaRange {
+	mr, _ := parseMediaRange(s)
+	return mr
+}
+
+// responseMediaType returns the media type negotiated for the request; TAXII 2.0 resources other than objects are
+// always TAXII media types
+func responseMediaType(r *http.Request) string {
+	mediaType := cabby.TakeMediaType(r.Context())
+
+	switch {
+	case mediaType == "":
+		return cabby.TaxiiContentType
+	case legacyRequest(r):
+		return cabby.TaxiiContentType20
+	}
+	return mediaType
+}
+
+// supportedContentType returns whether a Content-Type header is one of the supported media types; wildcards aren't
+// allowed
+func supportedContentType(contentType string, supported []string) bool {
+	mr, ok := parseMediaRange(contentType)
+	if !ok || strings.Contains(mr.mediaType, "*") {
+		return false
+	}
+
+	for _, s := range supported {
+		if mr.matches(toMediaRange(s)) {
+			return true
+		}
+	}
+	return false
+}
+
+func supportedFamily(mr mediaRange, supported []string) bool {
+	for _, s := range supported {
+		if toMediaRange(s).mediaType == mr.mediaType {
+			return true
+		}
+	}
+	return false
+}
+
+func supportedVersions(supported []string) string {
+	seen := map[string]bool{}
+	var versions []string
+
+	for _, s := range supported {
+		v := toMediaRange(s).version
+		if !seen[v] {
+			seen[v] = true
+			versions = append(versions, v)
+		}
+	}
+	return strings.Join(versions, ", ")
+}
+
+// withAcceptSet negotiates the response media type from the Accept header and stores it in the request context
+func withAcceptSet(h http.Handler, supported []string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accept := r.Header.Get("Accept")
+
+		mediaType, versions := negotiateMediaType(accept, supported)
+		if mediaType == "" {
+			notAcceptable(w, notAcceptableError(accept, versions, supported))
+			return
+		}
+
+		h.ServeHTTP(w, r.WithContext(cabby.WithMediaType(r.Context(), mediaType)))
+	})
+}
+
+func notAcceptableError(accept string, versions, supported []string) error {
+	if len(versions) > 0 {
+		return fmt.Errorf(
+			"Unsupported version '%v' in Accept header, supported versions: %v (%v)",
+			strings.Join(versions, ", "),
+			supportedVersions(supported),
+			strings.Join(supported, ", "))
+	}
+	return fmt.Errorf("Accept header must be one of '%v', not '%v'", strings.Join(supported, ", "), accept)
+}
@@ -0,0 +1,133 @@
+package http
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pladdy/cabby"
+)
+
+func TestNegotiateMediaType(t *testing.T) {
+	tests := []struct {
+		accept   string
+		expected string
+		versions []string
+	}{
+		{cabby.TaxiiContentType21, cabby.TaxiiContentType21, nil},
+		{cabby.TaxiiContentType, cabby.TaxiiContentType21, nil},
+		{cabby.TaxiiMediaType21, cabby.TaxiiMediaType21, nil},
+		{"application/taxii+json; version=2.1", cabby.TaxiiMediaType21, nil},
+		{cabby.TaxiiMediaType, cabby.TaxiiMediaType21, nil},
+		{"*/*", cabby.TaxiiMediaType21, nil},
+		{"application/*", cabby.TaxiiMediaType21, nil},
+		{"text/html, application/vnd.oasis.taxii+json;q=0.9", cabby.TaxiiContentType21, nil},
+		{"application/taxii+json;q=0.5, application/vnd.oasis.taxii+json;version=2.1", cabby.TaxiiContentType21, nil},
+		{"application/vnd.oasis.taxii+json;q=0, */*;q=0.1", cabby.TaxiiMediaType21, nil},
+		{"application/taxii+json;q=0", "", nil},
+		{"application/taxii+json;q=2", "", nil},
+		{"application/taxii+json;version=2.0", "", []string{"2.0"}},
+		{"application/taxii+json;version=2.0, application/taxii+json;version=3.0;q=0.5", "", []string{"2.0", "3.0"}},
+		{"application/vnd.oasis.taxii+jsonp;version=3.0", "", nil},
+		{"", "", nil},
+	}
+
+	for _, test := range tests {
+		result, versions := negotiateMediaType(test.accept, taxiiMediaTypes)
+		if result != test.expected {
+			t.Error("Got:", result, "Expected:", test.expected, "Accept:", test.accept)
+		}
+		if strings.Join(versions, ",") != strings.Join(test.versions, ",") {
+			t.Error("Got:", versions, "Expected:", test.versions, "Accept:", test.accept)
+		}
+	}
+}
+
+func TestSupportedContentType(t *testing.T) {
+	tests := []struct {
+		contentType string
+		expected    bool
+	}{
+		{cabby.TaxiiContentType, true},
+		{cabby.TaxiiContentType21, true},
+		{cabby.TaxiiMediaType, true},
+		{cabby.TaxiiMediaType21, true},
+		{"application/taxii+json;version=2.0", false},
+		{"*/*", false},
+		{"application/json", false},
+		{"", false},
+	}
+
+	for _, test := range tests {
+		result := supportedContentType(test.contentType, taxiiMediaTypes)
+		if result != test.expected {
+			t.Error("Got:", result, "Expected:", test.expected, "Content-Type:", test.contentType)
+		}
+	}
+}
+
+func TestWithAcceptSet(t *testing.T) {
+	tests := []struct {
+		acceptHeader string
+		responseCode int
+		mediaType    string
+		body         string
+	}{
+		{"application/vnd.oasis.taxii+json;version=2.1", http.StatusOK, cabby.TaxiiContentType21, ""},
+		{"application/vnd.oasis.taxii+json", http.StatusOK, cabby.TaxiiContentType21, ""},
+		{"application/taxii+json;version=2.1", http.StatusOK, cabby.TaxiiMediaType21, ""},
+		{"*/*;q=0.8", http.StatusOK, cabby.TaxiiMediaType21, ""},
+		{"", http.StatusNotAcceptable, "", "Accept header must be one of"},
+		{"application/vnd.oasis.taxii+jsonp", http.StatusNotAcceptable, "", "Accept header must be one of"},
+		{"application/vnd.oasis.taxii+jsonp; version=3.0", http.StatusNotAcceptable, "", "Accept header must be one of"},
+		{"application/taxii+json;version=2.0", http.StatusNotAcceptable, "", "supported versions: 2.1"},
+		{"application/vnd.oasis.taxii+json;version=2.0", http.StatusOK, cabby.TaxiiContentType20, ""},
+	}
+
+	for _, test := range tests {
+		serveFn := func(w http.ResponseWriter, r *http.Request) {
+			writeContent(w, r, responseMediaType(r), "test")
+		}
+
+		req := httptest.NewRequest(http.MethodGet, testDiscoveryURL, nil)
+		req.Header.Set("Accept", test.acceptHeader)
+		res := httptest.NewRecorder()
+
+		withAcceptSet(http.HandlerFunc(serveFn), taxiiMediaTypes).ServeHTTP(res, req)
+
+		if res.Code != test.responseCode {
+			t.Error("Got:", res.Code, "Expected:", test.responseCode, "Accept:", test.acceptHeader)
+		}
+		if test.mediaType != "" && res.Header().Get("Content-Type") != test.mediaType {
+			t.Error("Got:", res.Header().Get("Content-Type"), "Expected:", test.mediaType)
+		}
+
+		body, _ := ioutil.ReadAll(res.Body)
+		if !strings.Contains(string(body), test.body) {
+			t.Error("Got:", string(body), "Expected:", test.body)
+		}
+	}
+}
+
+func TestResponseMediaType(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, testDiscoveryURL, nil)
+	if responseMediaType(req) != cabby.TaxiiContentType {
+		t.Error("Got:", responseMediaType(req), "Expected:", cabby.TaxiiContentType)
+	}
+
+	req = req.WithContext(cabby.WithMediaType(req.Context(), cabby.TaxiiMediaType21))
+	if responseMediaType(req) != cabby.TaxiiMediaType21 {
+		t.Error("Got:", responseMediaType(req), "Expected:", cabby.TaxiiMediaType21)
+	}
+}
+
+func TestResponseMediaTypeLegacy(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, testDiscoveryURL, nil)
+	req = req.WithContext(cabby.WithMediaType(req.Context(), cabby.StixContentType20))
+
+	if responseMediaType(req) != cabby.TaxiiContentType20 {
+		t.Error("Got:", responseMediaType(req), "Expected:", cabby.TaxiiContentType20)
+	}
+}
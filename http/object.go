@@ -13,10 +13,12 @@ const ObjectMethods = "Get, Delete, Head"
 
 // ObjectHandler handles Objects requests
 type ObjectHandler struct {
-	ObjectService cabby.ObjectService
+	CollectionService cabby.CollectionService
+	ObjectService     cabby.ObjectService
 }
 
-//Delete handles a delete of an object; can only be done given an ID
+//Delete handles a delete of an object; can only be done given an ID.  'match[version]' and 'match[spec_version]'
+// limit the versions deleted.
 func (h ObjectHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	log.WithFields(log.Fields{"handler": "ObjectHandler"}).Debug("Handler called")
 
@@ -25,12 +27,29 @@ func (h ObjectHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := h.ObjectService.DeleteObject(r.Context(), takeCollectionID(r), takeObjectID(r))
+	f, err := newDeleteFilter(r)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+
+	objects, err := h.ObjectService.Object(r.Context(), takeCollectionID(r), takeObjectID(r), f)
+	if err != nil {
+		internalServerError(w, err)
+		return
+	}
+
+	if len(objects) <= 0 {
+		resourceNotFound(w, errors.New("No objects found"))
+		return
+	}
+
+	err = h.ObjectService.DeleteObject(r.Context(), takeCollectionID(r), takeObjectID(r), f)
 	if err != nil {
 		internalServerError(w, err)
 		return
 	}
-	writeContent(w, r, cabby.TaxiiContentType, "")
+	writeContent(w, r, responseMediaType(r), "")
 }
 
 // Get handles a get request for the objects endpoint
@@ -42,7 +61,13 @@ func (h ObjectHandler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	objects, err := h.ObjectService.Object(r.Context(), takeCollectionID(r), takeObjectID(r), newFilter(r))
+	f, err := newFilter(r)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+
+	objects, err := h.ObjectService.Object(r.Context(), takeCollectionID(r), takeObjectID(r), f)
 	if err != nil {
 		internalServerError(w, err)
 		return
@@ -53,8 +78,17 @@ func (h ObjectHandler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	envelope := objectsToEnvelope(objects, cabby.Page{})
-	writeContent(w, r, cabby.TaxiiContentType, resourceToJSON(envelope))
+	lastModified, err := h.CollectionService.CollectionLastModified(r.Context(), takeCollectionID(r))
+	if err != nil {
+		internalServerError(w, err)
+		return
+	}
+
+	if notModified(w, r, objectsETag(objects, cabby.Page{}), lastModified.Time) {
+		return
+	}
+
+	writeObjects(w, r, objects, cabby.Page{})
 }
 
 // Post handler
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"reflect"
 	"testing"
 
 	"github.com/pladdy/cabby"
@@ -15,7 +16,7 @@ import (
 /* Delete */
 
 func TestObjectHandlerDelete(t *testing.T) {
-	h := ObjectHandler{ObjectService: mockObjectService()}
+	h := ObjectHandler{CollectionService: mockCollectionService(), ObjectService: mockObjectService()}
 	req := newClientRequest(http.MethodDelete, testObjectURL, nil)
 	status, _, _ := callHandler(h.Delete, req)
 
@@ -24,8 +25,86 @@ func TestObjectHandlerDelete(t *testing.T) {
 	}
 }
 
+func TestObjectHandlerDeleteAsOf(t *testing.T) {
+	s := mockObjectService()
+	s.DeleteObjectFn = func(ctx context.Context, collectionID, objectID string, f cabby.Filter) error {
+		t.Error("Expected no delete")
+		return nil
+	}
+
+	h := ObjectHandler{CollectionService: mockCollectionService(), ObjectService: &s}
+	req := newClientRequest(http.MethodDelete, testObjectURL+"?as_of=2016-04-06T20:07:09Z", nil)
+	status, _, _ := callHandler(h.Delete, req)
+
+	if status != http.StatusBadRequest {
+		t.Error("Got:", status, "Expected:", http.StatusBadRequest)
+	}
+}
+
+func TestObjectHandlerDeleteFilter(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected cabby.Filter
+	}{
+		{testObjectURL, cabby.Filter{Versions: "all"}},
+		{testObjectURL + "?match[version]=first", cabby.Filter{Versions: "first"}},
+		{testObjectURL + "?match[spec_version]=2.0", cabby.Filter{Versions: "all", SpecVersions: "2.0"}},
+		{testObjectURL + "?match[labels]=benign", cabby.Filter{Versions: "all",
+			Properties: map[string][]string{"labels": {"benign"}}}},
+	}
+
+	for _, test := range tests {
+		var result cabby.Filter
+
+		s := mockObjectService()
+		s.ObjectFn = func(ctx context.Context, collectionID, objectID string, f cabby.Filter) ([]stones.Object, error) {
+			if !reflect.DeepEqual(f, test.expected) {
+				t.Error("Got:", f, "Expected:", test.expected)
+			}
+			return tester.Objects, nil
+		}
+		s.DeleteObjectFn = func(ctx context.Context, collectionID, objectID string, f cabby.Filter) error {
+			result = f
+			return nil
+		}
+
+		h := ObjectHandler{CollectionService: mockCollectionService(), ObjectService: &s}
+		status, _, _ := callHandler(h.Delete, newClientRequest(http.MethodDelete, test.url, nil))
+
+		if status != http.StatusOK {
+			t.Error("Got:", status, "Expected:", http.StatusOK)
+		}
+		if !reflect.DeepEqual(result, test.expected) {
+			t.Error("Got:", result, "Expected:", test.expected)
+		}
+	}
+}
+
+func TestObjectHandlerDeleteNoObjects(t *testing.T) {
+	deleted := false
+
+	s := mockObjectService()
+	s.ObjectFn = func(ctx context.Context, collectionID, objectID string, f cabby.Filter) ([]stones.Object, error) {
+		return []stones.Object{}, nil
+	}
+	s.DeleteObjectFn = func(ctx context.Context, collectionID, objectID string, f cabby.Filter) error {
+		deleted = true
+		return nil
+	}
+
+	h := ObjectHandler{CollectionService: mockCollectionService(), ObjectService: &s}
+	status, _, _ := callHandler(h.Delete, newClientRequest(http.MethodDelete, testObjectURL, nil))
+
+	if status != http.StatusNotFound {
+		t.Error("Got:", status, "Expected:", http.StatusNotFound)
+	}
+	if deleted {
+		t.Error("Expected nothing to be deleted")
+	}
+}
+
 func TestObjectHandlerDeleteForbidden(t *testing.T) {
-	h := ObjectHandler{ObjectService: mockObjectService()}
+	h := ObjectHandler{CollectionService: mockCollectionService(), ObjectService: mockObjectService()}
 	req := newClientRequest(http.MethodDelete, testObjectURL, nil)
 	req = req.WithContext(context.Background())
 	status, _, _ := callHandler(h.Delete, req)
@@ -39,35 +118,42 @@ func TestObjectHandlerDeleteInternalServerError(t *testing.T) {
 	expected := cabby.Error{
 		Title: "Internal Server Error", Description: "Object failure", HTTPStatus: http.StatusInternalServerError}
 
-	s := mockObjectService()
-	s.DeleteObjectFn = func(ctx context.Context, collectionID, objectID string) error {
-		return errors.New(expected.Description)
-	}
-
-	h := ObjectHandler{ObjectService: &s}
-	req := newClientRequest(http.MethodDelete, testObjectURL, nil)
-	status, body, _ := callHandler(h.Delete, req)
-
-	if status != expected.HTTPStatus {
-		t.Error("Got:", status, "Expected:", expected.HTTPStatus)
+	readFail := mockObjectService()
+	readFail.ObjectFn = func(ctx context.Context, collectionID, objectID string, f cabby.Filter) ([]stones.Object, error) {
+		return []stones.Object{}, errors.New(expected.Description)
 	}
 
-	var result cabby.Error
-	err := json.Unmarshal([]byte(body), &result)
-	if err != nil {
-		t.Fatal(err)
+	deleteFail := mockObjectService()
+	deleteFail.DeleteObjectFn = func(ctx context.Context, collectionID, objectID string, f cabby.Filter) error {
+		return errors.New(expected.Description)
 	}
 
-	passed := tester.CompareError(result, expected)
-	if !passed {
-		t.Error("Comparison failed")
+	for _, s := range []tester.ObjectService{readFail, deleteFail} {
+		h := ObjectHandler{CollectionService: mockCollectionService(), ObjectService: &s}
+		req := newClientRequest(http.MethodDelete, testObjectURL, nil)
+		status, body, _ := callHandler(h.Delete, req)
+
+		if status != expected.HTTPStatus {
+			t.Error("Got:", status, "Expected:", expected.HTTPStatus)
+		}
+
+		var result cabby.Error
+		err := json.Unmarshal([]byte(body), &result)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		passed := tester.CompareError(result, expected)
+		if !passed {
+			t.Error("Comparison failed")
+		}
 	}
 }
 
 /* Get */
 
 func TestObjectHandlerGet(t *testing.T) {
-	h := ObjectHandler{ObjectService: mockObjectService()}
+	h := ObjectHandler{CollectionService: mockCollectionService(), ObjectService: mockObjectService()}
 
 	// call handler for object
 	req := newClientRequest(http.MethodGet, testObjectURL, nil)
@@ -99,7 +185,7 @@ func TestObjectHandlerGet(t *testing.T) {
 }
 
 func TestObjectHandlerGetForbidden(t *testing.T) {
-	h := ObjectHandler{ObjectService: mockObjectService()}
+	h := ObjectHandler{CollectionService: mockCollectionService(), ObjectService: mockObjectService()}
 
 	req := newClientRequest(http.MethodGet, testObjectURL, nil)
 	req = req.WithContext(context.Background())
@@ -119,7 +205,7 @@ func TestObjectHandlerGetInternalServerError(t *testing.T) {
 		return []stones.Object{}, errors.New(expected.Description)
 	}
 
-	h := ObjectHandler{ObjectService: &s}
+	h := ObjectHandler{CollectionService: mockCollectionService(), ObjectService: &s}
 	req := newClientRequest(http.MethodGet, testObjectURL, nil)
 	status, body, _ := callHandler(h.Get, req)
 
@@ -145,7 +231,7 @@ func TestObjectHandlerGetNoObject(t *testing.T) {
 		return []stones.Object{}, nil
 	}
 
-	h := ObjectHandler{ObjectService: &s}
+	h := ObjectHandler{CollectionService: mockCollectionService(), ObjectService: &s}
 	req := newClientRequest(http.MethodGet, testObjectURL, nil)
 	status, body, _ := callHandler(h.Get, req.WithContext(cabby.WithUser(req.Context(), tester.User)))
 
@@ -169,7 +255,7 @@ func TestObjectHandlerGetNoObject(t *testing.T) {
 }
 
 func TestObjectHandlerPost(t *testing.T) {
-	h := ObjectHandler{ObjectService: mockObjectService()}
+	h := ObjectHandler{CollectionService: mockCollectionService(), ObjectService: mockObjectService()}
 	status, _ := handlerTest(h.Post, http.MethodDelete, testObjectsURL, nil)
 
 	if status != http.StatusMethodNotAllowed {
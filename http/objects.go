@@ -1,24 +1,33 @@
 package http
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"net/http"
+	"strings"
 
 	"github.com/pladdy/cabby"
 	log "github.com/sirupsen/logrus"
 )
 
-// ObjectsMethods lists allowed methods
-const ObjectsMethods = "Get, Head, Post"
+const (
+	// ObjectsMethods lists allowed methods
+	ObjectsMethods = "Get, Head, Post"
+
+	decodeBufferSize = 50
+
+	maxIdempotencyKeyLength = 255
+)
 
 // ObjectsHandler handles Objects requests
 type ObjectsHandler struct {
-	ObjectService    cabby.ObjectService
-	StatusService    cabby.StatusService
-	MaxContentLength int64
+	CollectionService cabby.CollectionService
+	ObjectService     cabby.ObjectService
+	StatusService     cabby.StatusService
+	MaxContentLength  int64
 }
 
 // Delete handler
@@ -35,32 +44,52 @@ func (h ObjectsHandler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	p, err := cabby.NewPage(takeLimit(r))
+	p, err := takePage(r)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+
+	f, err := newFilter(r)
 	if err != nil {
 		badRequest(w, err)
 		return
 	}
 
-	objects, err := h.ObjectService.Objects(r.Context(), takeCollectionID(r), &p, newFilter(r))
+	objects, err := h.ObjectService.Objects(r.Context(), takeCollectionID(r), &p, f)
 	if err != nil {
 		internalServerError(w, err)
 		return
 	}
 
 	if noResources(len(objects)) {
-		resourceNotFound(w, errors.New("No resources available for this request"))
+		noPage(w, r, p)
+		return
+	}
+
+	lastModified, err := h.CollectionService.CollectionLastModified(r.Context(), takeCollectionID(r))
+	if err != nil {
+		internalServerError(w, err)
 		return
 	}
 
-	envelope := objectsToEnvelope(objects, p)
 	w.Header().Set("X-TAXII-Date-Added-First", p.AddedAfterFirst())
 	w.Header().Set("X-TAXII-Date-Added-Last", p.AddedAfterLast())
-	writeContent(w, r, cabby.TaxiiContentType, resourceToJSON(envelope))
+	if notModified(w, r, objectsETag(objects, p), lastModified.Time) {
+		return
+	}
+
+	writeObjects(w, r, objects, p)
 }
 
 /* Post */
 
-// Post handles post request
+// Post handles post request; objects are decoded from the body as it's read and sent to the object service so large
+// envelopes don't have to be held in memory.  If the body can't be decoded part way through, the objects already
+// decoded are still written and the error has the id of the status that lists them.  With 'dry_run=true' the objects
+// are only validated and a report is returned.  A retry with the same 'Idempotency-Key' header returns the status of
+// the original request without reading the body; the key is reserved before objects are written and released if the
+// whole envelope can't be read.
 func (h ObjectsHandler) Post(w http.ResponseWriter, r *http.Request) {
 	log.WithFields(log.Fields{"handler": "ObjectsHandler"}).Debug("Handler called")
 
@@ -68,42 +97,184 @@ func (h ObjectsHandler) Post(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	body, err := ioutil.ReadAll(r.Body)
+	key := takeIdempotencyKey(r)
+	if key != "" && h.replayed(w, r, key) {
+		return
+	}
+
+	body, err := decompressedBody(r)
 	if err != nil {
 		badRequest(w, err)
 		return
 	}
+
+	// chunked or compressed requests don't tell us the real size, enforce the limit on the body as it's read
+	r.Body = http.MaxBytesReader(w, body, h.MaxContentLength)
 	defer r.Body.Close()
 
-	envelope, err := envelopeFromBytes(body)
-	if err != nil || len(envelope.Objects) == 0 {
-		badRequest(w, err)
+	ed := newEnvelopeDecoder(r.Body)
+
+	first, err := ed.Next()
+	if err != nil {
+		h.decodeFailed(w, r, err, nil)
 		return
 	}
 
-	status, err := cabby.NewStatus(len(envelope.Objects))
+	if takeDryRun(r) {
+		h.dryRun(w, r, ed, first)
+		return
+	}
+
+	status, err := cabby.NewStatus(1)
 	if err != nil {
 		internalServerError(w, errors.New("Unable to initialize status resource"))
 		return
 	}
+	status.CollectionID = takeCollectionID(r)
+
+	if key != "" && !h.reserved(w, r, key, status) {
+		return
+	}
 
 	err = h.StatusService.CreateStatus(r.Context(), status)
 	if err != nil {
+		h.release(r, key)
 		internalServerError(w, errors.New("Unable to store status resource"))
 		return
 	}
 
-	// write header before status or header won't be set
-	w.Header().Set("Content-Type", cabby.TaxiiContentType)
-	w.WriteHeader(http.StatusAccepted)
-	writeContent(w, r, cabby.TaxiiContentType, resourceToJSON(status))
+	// the objects are written after the response is sent, so they aren't canceled with the request; writing the
+	// response replaces the request and the status is updated below, so the goroutine gets its own copies
+	objects := make(chan json.RawMessage, decodeBufferSize)
+	created := make(chan bool)
+	go func(ctx context.Context, collectionID string, status cabby.Status) {
+		h.ObjectService.CreateObjects(ctx, objects, collectionID, status, h.StatusService)
+		close(created)
+	}(detachedContext(r), takeCollectionID(r), status)
+
+	count, err := sendObjects(ed, first, objects)
+	if err != io.EOF {
+		<-created
+		// the key is only kept for envelopes that were read completely, a retry of a failed request adds its objects
+		h.release(r, key)
+		h.readFailed(w, r, status, err)
+		return
+	}
+
+	status.TotalCount = int64(count)
+	status.PendingCount = int64(count)
+	writeAccepted(w, r, status)
+}
+
+// dryRun validates the objects of an envelope without writing them and responds with the result of each
+func (h ObjectsHandler) dryRun(w http.ResponseWriter, r *http.Request, ed *envelopeDecoder, first json.RawMessage) {
+	objects := make(chan json.RawMessage, decodeBufferSize)
+	reports := make(chan cabby.ValidationReport, 1)
+
+	go func(ctx context.Context, collectionID string) {
+		reports <- h.ObjectService.ValidateObjects(ctx, objects, collectionID)
+	}(r.Context(), takeCollectionID(r))
+
+	_, err := sendObjects(ed, first, objects)
+	report := <-reports
+
+	if err != io.EOF {
+		h.decodeFailed(w, r, err, nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", responseMediaType(r))
+	writeContent(w, r, responseMediaType(r), resourceToJSON(report))
+}
+
+// replayed responds with the status of an earlier request made with the idempotency key; it's false if there wasn't one
+func (h ObjectsHandler) replayed(w http.ResponseWriter, r *http.Request, key string) bool {
+	status, err := h.StatusService.IdempotentStatus(r.Context(), takeCollectionID(r), key)
+	if err != nil {
+		internalServerError(w, errors.New("Unable to read idempotency key"))
+		return true
+	}
+
+	if status.ID.IsEmpty() {
+		return false
+	}
+
+	w.Header().Set("Idempotent-Replayed", "true")
+	writeAccepted(w, r, status)
+	return true
+}
+
+// reserved stores the idempotency key for the status of the post; if another request has it, that request's status is
+// the response
+func (h ObjectsHandler) reserved(w http.ResponseWriter, r *http.Request, key string, status cabby.Status) bool {
+	err := h.StatusService.CreateIdempotencyKey(r.Context(), takeCollectionID(r), key, status.ID.String())
+	switch {
+	case err == nil:
+		return true
+	case err == cabby.ErrIdempotencyKeyExists:
+		// the other request stores its status after the key
+		if !h.replayed(w, r, key) {
+			conflict(w, errors.New("A request with the Idempotency-Key is in progress"))
+		}
+	default:
+		internalServerError(w, errors.New("Unable to store idempotency key"))
+	}
+	return false
+}
+
+// release removes the idempotency key of a post that didn't complete so it can be retried
+func (h ObjectsHandler) release(r *http.Request, key string) {
+	if key == "" {
+		return
+	}
+
+	err := h.StatusService.DeleteIdempotencyKey(r.Context(), takeCollectionID(r), key)
+	if err != nil {
+		log.WithFields(log.Fields{"error": err, "key": key}).Error("Unable to release idempotency key")
+	}
+}
+
+// decodeFailed responds with why an envelope couldn't be decoded; details are added to the error
+func (h ObjectsHandler) decodeFailed(w http.ResponseWriter, r *http.Request, err error, details map[string]string) {
+	switch {
+	case err == io.EOF:
+		errorStatusDetails(w, "Bad Request", errors.New("Envelope has no objects"), http.StatusBadRequest, details)
+	case bodyTooLarge(err):
+		errorStatusDetails(w, "Request Too large", tooLargeError(r.ContentLength, h.MaxContentLength),
+			http.StatusRequestEntityTooLarge, details)
+	default:
+		log.WithFields(log.Fields{"error": err}).Error("Unable to decode envelope")
+		errorStatusDetails(w, "Bad Request", fmt.Errorf("Unable to convert JSON to envelope, error: %v", err),
+			http.StatusBadRequest, details)
+	}
+}
 
-	go h.ObjectService.CreateEnvelope(r.Context(), envelope, takeCollectionID(r), status, h.StatusService)
+// readFailed records that the rest of an envelope couldn't be read as a failure in the status of the post; the objects
+// read before it are written, so the error has the id of the status that lists them
+func (h ObjectsHandler) readFailed(w http.ResponseWriter, r *http.Request, status cabby.Status, err error) {
+	st, serr := h.StatusService.Status(r.Context(), takeAPIRoot(r), status.ID.String())
+	if serr == nil {
+		st.TotalCount++
+		st.FailureCount++
+		st.Failures = append(st.Failures, cabby.StatusDetails{
+			Message: fmt.Sprintf("Unable to read the rest of the envelope: %v", err)})
+		serr = h.StatusService.UpdateStatus(r.Context(), st)
+	}
+	if serr != nil {
+		log.WithFields(log.Fields{"error": serr, "status": status.ID}).Error("Unable to record the failed read in the status")
+	}
+
+	h.decodeFailed(w, r, err, map[string]string{"status_id": status.ID.String()})
 }
 
 func (h ObjectsHandler) validPost(w http.ResponseWriter, r *http.Request) (isValid bool) {
-	if !verifyRequestHeader(r, "Content-Type", cabby.TaxiiContentType) {
-		unsupportedMediaType(w, fmt.Errorf("Content-Type header must be '%v'", cabby.TaxiiContentType))
+	if !supportedContentType(r.Header.Get("Content-Type"), taxiiMediaTypes) {
+		unsupportedMediaType(w, fmt.Errorf("Content-Type header must be one of '%v'", strings.Join(taxiiMediaTypes, ", ")))
+		return
+	}
+
+	if !supportedContentEncoding(r) {
+		unsupportedMediaType(w, fmt.Errorf("Content-Encoding header must be one of '%v'", supportedEncodings))
 		return
 	}
 
@@ -117,16 +288,127 @@ func (h ObjectsHandler) validPost(w http.ResponseWriter, r *http.Request) (isVal
 		return
 	}
 
+	if len(r.Header.Get("Idempotency-Key")) > maxIdempotencyKeyLength {
+		badRequest(w, fmt.Errorf("Idempotency-Key header can't be longer than %v characters", maxIdempotencyKeyLength))
+		return
+	}
+
 	return true
 }
 
 /* helpers */
 
-func envelopeFromBytes(b []byte) (e cabby.Envelope, err error) {
-	err = json.Unmarshal(b, &e)
-	if err != nil {
-		log.WithFields(log.Fields{"envelope": string(b), "error:": err}).Error("Unable to unmarshal envelope")
-		return e, fmt.Errorf("Unable to convert JSON to envelope, error: %v", err)
+// bodyTooLarge is true if a body from http.MaxBytesReader was read past its limit; until go 1.19 the error doesn't have
+// a type, so its message is compared
+func bodyTooLarge(err error) bool {
+	return err != nil && err.Error() == "http: request body too large"
+}
+
+// envelopeDecoder reads an envelope from a stream and returns its objects one at a time
+type envelopeDecoder struct {
+	decoder   *json.Decoder
+	started   bool
+	inObjects bool
+	done      bool
+}
+
+func newEnvelopeDecoder(r io.Reader) *envelopeDecoder {
+	return &envelopeDecoder{decoder: json.NewDecoder(r)}
+}
+
+// Next returns the next object in the envelope; io.EOF is returned when there are no more objects
+func (e *envelopeDecoder) Next() (raw json.RawMessage, err error) {
+	if e.done {
+		return raw, io.EOF
+	}
+
+	if !e.started {
+		if err = e.expectDelim('{'); err != nil {
+			return
+		}
+		e.started = true
+	}
+
+	for {
+		if e.inObjects {
+			if e.decoder.More() {
+				err = e.decoder.Decode(&raw)
+				return
+			}
+
+			if err = e.expectDelim(']'); err != nil {
+				return
+			}
+			e.inObjects = false
+		}
+
+		if !e.decoder.More() {
+			if err = e.expectDelim('}'); err != nil {
+				return
+			}
+			e.done = true
+			return raw, io.EOF
+		}
+
+		if err = e.nextField(); err != nil {
+			return
+		}
+	}
+}
+
+func writeAccepted(w http.ResponseWriter, r *http.Request, status cabby.Status) {
+	// write header before status or header won't be set
+	w.Header().Set("Content-Type", responseMediaType(r))
+	w.WriteHeader(http.StatusAccepted)
+	writeContent(w, r, responseMediaType(r), resourceToJSON(statusResource(r, status)))
+}
+
+// sendObjects sends the first object and the rest of the envelope to objects and closes it; the error that stopped
+// decoding is returned, io.EOF if the whole envelope was read
+func sendObjects(ed *envelopeDecoder, first json.RawMessage, objects chan<- json.RawMessage) (count int, err error) {
+	for raw := first; err == nil; raw, err = ed.Next() {
+		objects <- raw
+		count++
 	}
+	close(objects)
 	return
 }
+
+func (e *envelopeDecoder) expectDelim(d json.Delim) error {
+	t, err := e.decoder.Token()
+	if err != nil {
+		return err
+	}
+
+	if t != d {
+		return fmt.Errorf("Expected '%v' but found '%v'", d, t)
+	}
+	return nil
+}
+
+// nextField reads the next key of the envelope; the objects list is opened and any other value is skipped
+func (e *envelopeDecoder) nextField() error {
+	t, err := e.decoder.Token()
+	if err != nil {
+		return err
+	}
+
+	if t != "objects" {
+		var skip json.RawMessage
+		return e.decoder.Decode(&skip)
+	}
+
+	t, err = e.decoder.Token()
+	if err != nil {
+		return err
+	}
+
+	switch t {
+	case nil:
+		return nil
+	case json.Delim('['):
+		e.inObjects = true
+		return nil
+	}
+	return errors.New("Envelope objects must be a list")
+}
@@ -5,11 +5,13 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -19,32 +21,115 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-func TestEnvelopeFromBytes(t *testing.T) {
+func TestEnvelopeDecoderNext(t *testing.T) {
 	envelopeFile, err := os.Open("testdata/malware_envelope.json")
 	if err != nil {
 		t.Fatal(err)
 	}
+	defer envelopeFile.Close()
 
-	rawEnvelope, err := ioutil.ReadAll(envelopeFile)
-	if err != nil {
-		t.Fatal(err)
+	ed := newEnvelopeDecoder(envelopeFile)
+
+	count := 0
+	for {
+		raw, err := ed.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal("Error unexpected:", err)
+		}
+
+		var o stones.Object
+		err = json.Unmarshal(raw, &o)
+		if err != nil {
+			t.Fatal(err)
+		}
+		count++
 	}
 
-	_, err = envelopeFromBytes(rawEnvelope)
-	if err != nil {
-		t.Error("Error unexpected:", err)
+	expected := 3
+	if count != expected {
+		t.Error("Got:", count, "Expected:", expected)
+	}
+}
+
+func TestEnvelopeDecoderNextFields(t *testing.T) {
+	tests := []struct {
+		envelope string
+		expected int
+	}{
+		{`{"more": false, "objects": [{"id": "1"}, {"id": "2"}]}`, 2},
+		{`{"objects": [{"id": "1"}], "more": true, "next": {"foo": ["bar"]}}`, 1},
+		{`{"objects": null}`, 0},
+		{`{"objects": []}`, 0},
+		{`{}`, 0},
+	}
+
+	for _, test := range tests {
+		ed := newEnvelopeDecoder(strings.NewReader(test.envelope))
+
+		count := 0
+		var err error
+		for _, err = ed.Next(); err == nil; _, err = ed.Next() {
+			count++
+		}
+
+		if err != io.EOF {
+			t.Error("Got:", err, "Expected:", io.EOF, "Envelope:", test.envelope)
+		}
+		if count != test.expected {
+			t.Error("Got:", count, "Expected:", test.expected, "Envelope:", test.envelope)
+		}
+	}
+}
+
+func TestEnvelopeDecoderNextFail(t *testing.T) {
+	tests := []string{
+		`{"foo": "bar"`,
+		`["objects"]`,
+		`{"objects": {"id": "1"}}`,
+		`{"objects": [{"id": "1"}`,
+	}
+
+	for _, test := range tests {
+		ed := newEnvelopeDecoder(strings.NewReader(test))
+
+		var err error
+		for _, err = ed.Next(); err == nil; _, err = ed.Next() {
+		}
+
+		if err == io.EOF {
+			t.Error("Expected error for envelope:", test)
+		}
 	}
 }
 
-func TestEnvelopeFromBytesUnmarshalFail(t *testing.T) {
-	b, err := envelopeFromBytes([]byte(`{"foo": "bar"`))
-	if err == nil {
-		t.Error("Expected error for envelope:", b)
+func TestBodyTooLarge(t *testing.T) {
+	tests := []struct {
+		body     string
+		limit    int64
+		expected bool
+	}{
+		{"0123456789", 11, false},
+		{"0123456789", 10, false},
+		{"0123456789", 9, true},
+		{"0123456789", 0, true},
+	}
+
+	for _, test := range tests {
+		body := http.MaxBytesReader(httptest.NewRecorder(), ioutil.NopCloser(strings.NewReader(test.body)), test.limit)
+		_, err := ioutil.ReadAll(body)
+
+		result := bodyTooLarge(err)
+		if result != test.expected {
+			t.Error("Got:", result, "Expected:", test.expected, "Limit:", test.limit, "Error:", err)
+		}
 	}
 }
 
 func TestObjectsHandlerDelete(t *testing.T) {
-	h := ObjectsHandler{ObjectService: mockObjectService()}
+	h := ObjectsHandler{CollectionService: mockCollectionService(), ObjectService: mockObjectService()}
 	status, _ := handlerTest(h.Delete, http.MethodDelete, testObjectsURL, nil)
 
 	if status != http.StatusMethodNotAllowed {
@@ -55,7 +140,7 @@ func TestObjectsHandlerDelete(t *testing.T) {
 /* Get */
 
 func TestObjectsHandlerGet(t *testing.T) {
-	h := ObjectsHandler{ObjectService: mockObjectService()}
+	h := ObjectsHandler{CollectionService: mockCollectionService(), ObjectService: mockObjectService()}
 	req := newClientRequest(http.MethodGet, testObjectsURL, nil)
 	status, body, _ := callHandler(h.Get, req)
 
@@ -75,7 +160,7 @@ func TestObjectsHandlerGet(t *testing.T) {
 }
 
 func TestObjectsHandlerGetBadRequest(t *testing.T) {
-	h := ObjectsHandler{ObjectService: mockObjectService()}
+	h := ObjectsHandler{CollectionService: mockCollectionService(), ObjectService: mockObjectService()}
 	req := newClientRequest(http.MethodGet, testObjectsURL+"?limit=0", nil)
 	status, body, _ := callHandler(h.Get, req)
 
@@ -98,8 +183,28 @@ func TestObjectsHandlerGetBadRequest(t *testing.T) {
 	}
 }
 
+func TestObjectsHandlerGetInvalidAsOf(t *testing.T) {
+	h := ObjectsHandler{CollectionService: mockCollectionService(), ObjectService: mockObjectService()}
+	req := newClientRequest(http.MethodGet, testObjectsURL+"?as_of=yesterday", nil)
+	status, _, _ := callHandler(h.Get, req)
+
+	if status != http.StatusBadRequest {
+		t.Error("Got:", status, "Expected:", http.StatusBadRequest)
+	}
+}
+
+func TestObjectsHandlerGetUnsupportedProperty(t *testing.T) {
+	h := ObjectsHandler{CollectionService: mockCollectionService(), ObjectService: mockObjectService()}
+	req := newClientRequest(http.MethodGet, testObjectsURL+"?match[unknown]=x", nil)
+	status, _, _ := callHandler(h.Get, req)
+
+	if status != http.StatusBadRequest {
+		t.Error("Got:", status, "Expected:", http.StatusBadRequest)
+	}
+}
+
 func TestObjectsHandlerGetHeaders(t *testing.T) {
-	h := ObjectsHandler{ObjectService: mockObjectService()}
+	h := ObjectsHandler{CollectionService: mockCollectionService(), ObjectService: mockObjectService()}
 	req := newClientRequest(http.MethodGet, testObjectsURL, nil)
 	res := httptest.NewRecorder()
 	h.Get(res, req)
@@ -118,7 +223,7 @@ func TestObjectsHandlerGetHeaders(t *testing.T) {
 }
 
 func TestObjectsHandlerGetForbidden(t *testing.T) {
-	h := ObjectsHandler{ObjectService: mockObjectService()}
+	h := ObjectsHandler{CollectionService: mockCollectionService(), ObjectService: mockObjectService()}
 	req := newClientRequest(http.MethodGet, testObjectsURL, nil)
 	req = req.WithContext(context.Background())
 	status, _, _ := callHandler(h.Get, req)
@@ -137,7 +242,7 @@ func TestObjectsHandlerGetInternalServerError(t *testing.T) {
 		return []stones.Object{}, errors.New(expected.Description)
 	}
 
-	h := ObjectsHandler{ObjectService: &s}
+	h := ObjectsHandler{CollectionService: mockCollectionService(), ObjectService: &s}
 	req := newClientRequest(http.MethodGet, testObjectsURL, nil)
 	status, body, _ := callHandler(h.Get, req)
 
@@ -163,7 +268,7 @@ func TestObjectsHandlerGetNoObjects(t *testing.T) {
 		return []stones.Object{}, nil
 	}
 
-	h := ObjectsHandler{ObjectService: &s}
+	h := ObjectsHandler{CollectionService: mockCollectionService(), ObjectService: &s}
 	req := newClientRequest(http.MethodGet, testObjectsURL, nil)
 	status, body, _ := callHandler(h.Get, req)
 
@@ -207,7 +312,7 @@ func TestObjectsHandlerGetPage(t *testing.T) {
 			p.Total = uint64(test.expected)
 			return objects, nil
 		}
-		h := ObjectsHandler{ObjectService: obs}
+		h := ObjectsHandler{CollectionService: mockCollectionService(), ObjectService: obs}
 
 		req := newClientRequest(http.MethodGet, testObjectsURL+"?limit="+strconv.Itoa(test.limit), nil)
 		status, body, _ := callHandler(h.Get, req)
@@ -232,11 +337,23 @@ func TestObjectsHandlerGetPage(t *testing.T) {
 
 func TestObjectsHandlerPost(t *testing.T) {
 	osv := mockObjectService()
-	osv.CreateEnvelopeFn = func(ctx context.Context, e cabby.Envelope, collectionID string, s cabby.Status, ss cabby.StatusService) {
-		log.Debug("mock call of CreateEnvelope")
+
+	received := make(chan int)
+	osv.CreateObjectsFn = func(ctx context.Context, objects <-chan json.RawMessage, collectionID string, s cabby.Status, ss cabby.StatusService) {
+		log.Debug("mock call of CreateObjects")
+		count := 0
+		for range objects {
+			count++
+		}
+		received <- count
 	}
 
+	var statusCollectionID string
 	ssv := mockStatusService()
+	ssv.CreateStatusFn = func(ctx context.Context, status cabby.Status) error {
+		statusCollectionID = status.CollectionID
+		return nil
+	}
 	h := ObjectsHandler{MaxContentLength: int64(2048), ObjectService: osv, StatusService: ssv}
 
 	envelopeFile, _ := os.Open("testdata/malware_envelope.json")
@@ -248,6 +365,9 @@ func TestObjectsHandlerPost(t *testing.T) {
 	if status != http.StatusAccepted {
 		t.Error("Got:", status, "Expected:", http.StatusAccepted)
 	}
+	if statusCollectionID != tester.CollectionID {
+		t.Error("Got:", statusCollectionID, "Expected:", tester.CollectionID)
+	}
 
 	if headers.Get("content-type") != cabby.TaxiiContentType {
 		t.Error("Got:", headers["content-type"], "Expected:", cabby.TaxiiContentType)
@@ -265,6 +385,244 @@ func TestObjectsHandlerPost(t *testing.T) {
 	if result.PendingCount != 3 {
 		t.Error("Got:", result.PendingCount, "Expected: 3")
 	}
+
+	count := <-received
+	if count != 3 {
+		t.Error("Got:", count, "Expected: 3")
+	}
+}
+
+func TestObjectsHandlerPostIdempotencyKey(t *testing.T) {
+	envelopeFile, _ := os.Open("testdata/malware_envelope.json")
+	envelope, _ := ioutil.ReadAll(envelopeFile)
+
+	keys := map[string]string{}
+	ssv := mockStatusService()
+	ssv.CreateIdempotencyKeyFn = func(ctx context.Context, collectionID, key, statusID string) error {
+		keys[key] = statusID
+		return nil
+	}
+	ssv.IdempotentStatusFn = func(ctx context.Context, collectionID, key string) (cabby.Status, error) {
+		if _, ok := keys[key]; !ok {
+			return cabby.Status{}, nil
+		}
+		id, _ := cabby.IDFromString(keys[key])
+		return cabby.Status{ID: id, Status: "complete", TotalCount: 3, SuccessCount: 3}, nil
+	}
+
+	created := make(chan bool, 2)
+	osv := mockObjectService()
+	osv.CreateObjectsFn = func(ctx context.Context, objects <-chan json.RawMessage, collectionID string, s cabby.Status, ss cabby.StatusService) {
+		for range objects {
+		}
+		created <- true
+	}
+	h := ObjectsHandler{MaxContentLength: int64(2048), ObjectService: osv, StatusService: ssv}
+
+	var statusIDs []string
+	for i := 0; i < 2; i++ {
+		req := newClientRequest(http.MethodPost, testObjectsURL, bytes.NewBuffer(envelope))
+		req.Header.Set("Idempotency-Key", "retried-post")
+		status, body, headers := callHandler(h.Post, req)
+
+		if status != http.StatusAccepted {
+			t.Error("Got:", status, "Expected:", http.StatusAccepted)
+		}
+
+		replayed := headers.Get("Idempotent-Replayed") == "true"
+		if replayed != (i > 0) {
+			t.Error("Got:", replayed, "Expected replayed:", i > 0)
+		}
+
+		var result cabby.Status
+		err := json.Unmarshal([]byte(body), &result)
+		if err != nil {
+			t.Fatal(err)
+		}
+		statusIDs = append(statusIDs, result.ID.String())
+	}
+
+	if statusIDs[0] != statusIDs[1] {
+		t.Error("Got:", statusIDs[1], "Expected:", statusIDs[0])
+	}
+
+	// the retry doesn't create objects
+	<-created
+	if len(created) != 0 {
+		t.Error("Expected objects to be created once")
+	}
+}
+
+func TestObjectsHandlerPostIdempotencyKeyFail(t *testing.T) {
+	readFail := mockStatusService()
+	readFail.IdempotentStatusFn = func(ctx context.Context, collectionID, key string) (cabby.Status, error) {
+		return cabby.Status{}, errors.New("fail")
+	}
+	createFail := mockStatusService()
+	createFail.CreateIdempotencyKeyFn = func(ctx context.Context, collectionID, key, statusID string) error {
+		return errors.New("fail")
+	}
+
+	tests := []struct {
+		ssv            tester.StatusService
+		key            string
+		expectedStatus int
+	}{
+		{readFail, "a-key", http.StatusInternalServerError},
+		{createFail, "a-key", http.StatusInternalServerError},
+		{mockStatusService(), strings.Repeat("k", maxIdempotencyKeyLength+1), http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		h := ObjectsHandler{MaxContentLength: int64(2048), ObjectService: mockObjectService(), StatusService: test.ssv}
+
+		req := newClientRequest(http.MethodPost, testObjectsURL, bytes.NewBuffer([]byte(`{"objects": [{}]}`)))
+		req.Header.Set("Idempotency-Key", test.key)
+		status, _, _ := callHandler(h.Post, req)
+
+		if status != test.expectedStatus {
+			t.Error("Got:", status, "Expected:", test.expectedStatus)
+		}
+	}
+}
+
+func TestObjectsHandlerPostIdempotencyKeyConflict(t *testing.T) {
+	stored, _ := cabby.NewStatus(3)
+
+	tests := []struct {
+		status         cabby.Status
+		expectedStatus int
+	}{
+		// another request reserved the key after this one looked it up, its status is returned
+		{stored, http.StatusAccepted},
+		// the other request hasn't stored its status yet
+		{cabby.Status{}, http.StatusConflict},
+	}
+
+	for _, test := range tests {
+		lookups := 0
+		ssv := mockStatusService()
+		ssv.IdempotentStatusFn = func(ctx context.Context, collectionID, key string) (cabby.Status, error) {
+			lookups++
+			if lookups == 1 {
+				return cabby.Status{}, nil
+			}
+			return test.status, nil
+		}
+		ssv.CreateIdempotencyKeyFn = func(ctx context.Context, collectionID, key, statusID string) error {
+			return cabby.ErrIdempotencyKeyExists
+		}
+		ssv.CreateStatusFn = func(ctx context.Context, status cabby.Status) error {
+			t.Error("Expected a status not to be created")
+			return nil
+		}
+
+		osv := mockObjectService()
+		osv.CreateObjectsFn = func(ctx context.Context, objects <-chan json.RawMessage, collectionID string, s cabby.Status, ss cabby.StatusService) {
+			t.Error("Expected objects not to be created")
+		}
+		h := ObjectsHandler{MaxContentLength: int64(2048), ObjectService: osv, StatusService: ssv}
+
+		req := newClientRequest(http.MethodPost, testObjectsURL, bytes.NewBuffer([]byte(`{"objects": [{}]}`)))
+		req.Header.Set("Idempotency-Key", "a-key")
+		status, body, _ := callHandler(h.Post, req)
+
+		if status != test.expectedStatus {
+			t.Error("Got:", status, "Expected:", test.expectedStatus)
+		}
+		if status == http.StatusAccepted && !strings.Contains(body, test.status.ID.String()) {
+			t.Error("Got:", body, "Expected:", test.status.ID.String())
+		}
+	}
+}
+
+func TestObjectsHandlerPostIdempotencyKeyReleased(t *testing.T) {
+	tests := []struct {
+		body         string
+		statusFails  bool
+		expectedCode int
+	}{
+		// a retry of an envelope that couldn't be read completely adds the objects that weren't
+		{`{"objects": [{}, {`, false, http.StatusBadRequest},
+		{`{"objects": [{}]}`, true, http.StatusInternalServerError},
+	}
+
+	for _, test := range tests {
+		keys := map[string]bool{}
+		ssv := mockStatusService()
+		ssv.CreateIdempotencyKeyFn = func(ctx context.Context, collectionID, key, statusID string) error {
+			keys[key] = true
+			return nil
+		}
+		ssv.DeleteIdempotencyKeyFn = func(ctx context.Context, collectionID, key string) error {
+			delete(keys, key)
+			return nil
+		}
+		if test.statusFails {
+			ssv.CreateStatusFn = func(ctx context.Context, status cabby.Status) error { return errors.New("fail") }
+		}
+		h := ObjectsHandler{MaxContentLength: int64(2048), ObjectService: mockObjectService(), StatusService: ssv}
+
+		req := newClientRequest(http.MethodPost, testObjectsURL, bytes.NewBuffer([]byte(test.body)))
+		req.Header.Set("Idempotency-Key", "a-key")
+		status, _, _ := callHandler(h.Post, req)
+
+		if status != test.expectedCode {
+			t.Error("Got:", status, "Expected:", test.expectedCode)
+		}
+		if len(keys) != 0 {
+			t.Error("Got:", keys, "Expected the idempotency key to be released")
+		}
+	}
+}
+
+func TestObjectsHandlerPostDryRun(t *testing.T) {
+	osv := mockObjectService()
+	osv.CreateObjectsFn = func(ctx context.Context, objects <-chan json.RawMessage, collectionID string, s cabby.Status, ss cabby.StatusService) {
+		t.Error("Expected objects not to be created")
+	}
+
+	ssv := mockStatusService()
+	ssv.CreateStatusFn = func(ctx context.Context, status cabby.Status) error {
+		t.Error("Expected a status not to be created")
+		return nil
+	}
+	h := ObjectsHandler{MaxContentLength: int64(2048), ObjectService: osv, StatusService: ssv}
+
+	envelopeFile, _ := os.Open("testdata/malware_envelope.json")
+	envelope, _ := ioutil.ReadAll(envelopeFile)
+
+	req := newClientRequest(http.MethodPost, testObjectsURL+"?dry_run=true", bytes.NewBuffer(envelope))
+	status, body, headers := callHandler(h.Post, req)
+
+	if status != http.StatusOK {
+		t.Error("Got:", status, "Expected:", http.StatusOK)
+	}
+	if headers.Get("content-type") != cabby.TaxiiContentType {
+		t.Error("Got:", headers["content-type"], "Expected:", cabby.TaxiiContentType)
+	}
+
+	var result cabby.ValidationReport
+	err := json.Unmarshal([]byte(body), &result)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.TotalCount != 3 || result.SuccessCount != 3 {
+		t.Error("Got:", result, "Expected: 3 objects that validate")
+	}
+}
+
+func TestObjectsHandlerPostDryRunInvalidEnvelope(t *testing.T) {
+	h := ObjectsHandler{MaxContentLength: int64(2048), ObjectService: mockObjectService()}
+
+	req := newClientRequest(
+		http.MethodPost, testObjectsURL+"?dry_run=true", bytes.NewBuffer([]byte(`{"objects": [{"type": "malware"}, 1`)))
+	status, _, _ := callHandler(h.Post, req)
+
+	if status != http.StatusBadRequest {
+		t.Error("Got:", status, "Expected:", http.StatusBadRequest)
+	}
 }
 
 func TestObjectsHandlerPostContentTooLarge(t *testing.T) {
@@ -282,6 +640,113 @@ func TestObjectsHandlerPostContentTooLarge(t *testing.T) {
 	}
 }
 
+func TestObjectsHandlerPostChunkedContentTooLarge(t *testing.T) {
+	h := ObjectsHandler{MaxContentLength: int64(64), ObjectService: mockObjectService(), StatusService: mockStatusService()}
+
+	envelopeFile, _ := os.Open("testdata/malware_envelope.json")
+	envelope, _ := ioutil.ReadAll(envelopeFile)
+
+	req := newClientRequest(http.MethodPost, testObjectsURL, bytes.NewBuffer(envelope))
+	// a chunked request doesn't specify a length
+	req.ContentLength = -1
+	status, _, _ := callHandler(h.Post, req)
+
+	if status != http.StatusRequestEntityTooLarge {
+		t.Error("Got:", status, "Expected:", http.StatusRequestEntityTooLarge)
+	}
+}
+
+func TestObjectsHandlerPostPartialEnvelope(t *testing.T) {
+	envelopeFile, _ := os.Open("testdata/malware_envelope.json")
+	envelope, _ := ioutil.ReadAll(envelopeFile)
+	first := []byte(`{"objects": [` + string(tester.Object.Source) + `, `)
+
+	tests := []struct {
+		envelope         []byte
+		maxContentLength int64
+		expectedStatus   int
+	}{
+		{append(first, '{'), int64(2048), http.StatusBadRequest},
+		{envelope, int64(len(envelope) - 10), http.StatusRequestEntityTooLarge},
+	}
+
+	for _, test := range tests {
+		var written int
+		osv := mockObjectService()
+		osv.CreateObjectsFn = func(ctx context.Context, objects <-chan json.RawMessage, collectionID string, s cabby.Status, ss cabby.StatusService) {
+			for range objects {
+				written++
+			}
+		}
+
+		var updated cabby.Status
+		ssv := mockStatusService()
+		ssv.UpdateStatusFn = func(ctx context.Context, status cabby.Status) error {
+			updated = status
+			return nil
+		}
+		h := ObjectsHandler{MaxContentLength: test.maxContentLength, ObjectService: osv, StatusService: ssv}
+
+		req := newClientRequest(http.MethodPost, testObjectsURL, bytes.NewBuffer(test.envelope))
+		req.ContentLength = -1
+		status, body, _ := callHandler(h.Post, req)
+
+		if status != test.expectedStatus {
+			t.Error("Got:", status, "Expected:", test.expectedStatus)
+		}
+
+		var result cabby.Error
+		err := json.Unmarshal([]byte(body), &result)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the objects read are written and the status says the rest of the envelope failed
+		if written == 0 || result.Details["status_id"] == "" {
+			t.Error("Got:", written, result, "Expected objects written and a status id")
+		}
+		if updated.TotalCount != tester.Status.TotalCount+1 || updated.FailureCount != tester.Status.FailureCount+1 {
+			t.Error("Got:", updated, "Expected a failure added to:", tester.Status)
+		}
+		if len(updated.Failures) == 0 ||
+			!strings.HasPrefix(updated.Failures[len(updated.Failures)-1].Message, "Unable to read the rest of the envelope") {
+			t.Error("Got:", updated.Failures, "Expected a failure for the rest of the envelope")
+		}
+	}
+}
+
+func TestObjectsHandlerPostDetachedContext(t *testing.T) {
+	contexts := make(chan context.Context, 1)
+	osv := mockObjectService()
+	osv.CreateObjectsFn = func(ctx context.Context, objects <-chan json.RawMessage, collectionID string, s cabby.Status, ss cabby.StatusService) {
+		for range objects {
+		}
+		contexts <- ctx
+	}
+	h := ObjectsHandler{MaxContentLength: int64(2048), ObjectService: osv, StatusService: mockStatusService()}
+
+	envelopeFile, _ := os.Open("testdata/malware_envelope.json")
+	envelope, _ := ioutil.ReadAll(envelopeFile)
+
+	req := newClientRequest(http.MethodPost, testObjectsURL, bytes.NewBuffer(envelope))
+	ctx, cancel := context.WithCancel(req.Context())
+	status, _, _ := callHandler(h.Post, req.WithContext(ctx))
+	cancel()
+
+	if status != http.StatusAccepted {
+		t.Error("Got:", status, "Expected:", http.StatusAccepted)
+	}
+
+	// writes aren't canceled when the request ends
+	result := <-contexts
+	if result.Err() != nil {
+		t.Error("Got:", result.Err(), "Expected no error")
+	}
+	if cabby.TakeUser(result).Email != tester.UserEmail {
+		t.Error("Got:", cabby.TakeUser(result).Email, "Expected:", tester.UserEmail)
+	}
+}
+
 func TestObjectsHandlerPostEmptyEnvelope(t *testing.T) {
 	h := ObjectsHandler{MaxContentLength: int64(2048), ObjectService: mockObjectService()}
 
@@ -368,6 +833,9 @@ func TestValidPost(t *testing.T) {
 		valid       bool
 	}{
 		{cabby.TaxiiContentType, cabby.TaxiiContentType, true},
+		{cabby.TaxiiContentType, cabby.TaxiiMediaType21, true},
+		{cabby.TaxiiContentType, "application/taxii+json;version=2.0", false},
+		{cabby.TaxiiContentType, "*/*", false},
 		{cabby.TaxiiContentType, "invalid", false},
 	}
 
@@ -0,0 +1,58 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/pladdy/cabby"
+)
+
+// ObservablesMethods lists allowed methods
+const ObservablesMethods = "Get, Head"
+
+// ObservablesHandler holds a cabby ObservableService
+type ObservablesHandler struct {
+	ObservableService cabby.ObservableService
+}
+
+// Delete handler
+func (h ObservablesHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	methodNotAllowed(w, r, ObservablesMethods)
+}
+
+// Get serves the objects with the observable values matched in the collections the user can read
+func (h ObservablesHandler) Get(w http.ResponseWriter, r *http.Request) {
+	log.WithFields(log.Fields{"handler": "ObservablesHandler"}).Debug("Handler called")
+
+	values := takeMatchValues(r)
+	if len(values) == 0 {
+		badRequest(w, errors.New("Match at least one observable value with 'match[value]'"))
+		return
+	}
+
+	p, err := takePage(r)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+
+	matches, err := h.ObservableService.ObservableMatches(r.Context(), values, &p)
+	if err != nil {
+		internalServerError(w, err)
+		return
+	}
+
+	if noResources(len(matches.Objects)) {
+		noPage(w, r, p)
+		return
+	}
+
+	writePage(w, r, p, len(matches.Objects), responseMediaType(r), resourceToJSON(matches))
+}
+
+// Post handler
+func (h ObservablesHandler) Post(w http.ResponseWriter, r *http.Request) {
+	methodNotAllowed(w, r, ObservablesMethods)
+}
@@ -0,0 +1,94 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/pladdy/cabby"
+	"github.com/pladdy/cabby/tester"
+)
+
+func TestObservablesHandlerDelete(t *testing.T) {
+	h := ObservablesHandler{ObservableService: mockObservableService()}
+	status, _ := handlerTest(h.Delete, http.MethodDelete, testObservablesURL, nil)
+
+	if status != http.StatusMethodNotAllowed {
+		t.Error("Got:", status, "Expected:", http.StatusMethodNotAllowed)
+	}
+}
+
+func TestObservablesHandlerGet(t *testing.T) {
+	var searched []string
+
+	obs := mockObservableService()
+	matches := obs.ObservableMatchesFn
+	obs.ObservableMatchesFn = func(ctx context.Context, values []string, p *cabby.Page) (cabby.ObservableMatches, error) {
+		searched = values
+		return matches(ctx, values, p)
+	}
+
+	h := ObservablesHandler{ObservableService: obs}
+	status, body := handlerTest(h.Get, http.MethodGet, testObservablesURL+"?match[value]=203.0.113.7,example.com", nil)
+
+	if status != http.StatusOK {
+		t.Error("Got:", status, "Expected:", http.StatusOK)
+	}
+	if len(searched) != 2 || searched[0] != "203.0.113.7" || searched[1] != "example.com" {
+		t.Error("Got:", searched, "Expected:", []string{"203.0.113.7", "example.com"})
+	}
+
+	var result cabby.ObservableMatches
+	err := json.Unmarshal([]byte(body), &result)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Objects) != 1 || result.Objects[0].CollectionID != tester.CollectionID ||
+		result.Objects[0].Version.String() != tester.Object.Modified.String() {
+		t.Error("Got:", result, "Expected a match in:", tester.CollectionID)
+	}
+}
+
+func TestObservablesHandlerGetFailures(t *testing.T) {
+	tests := []struct {
+		matchesFn      func(ctx context.Context, values []string, p *cabby.Page) (cabby.ObservableMatches, error)
+		url            string
+		expectedStatus int
+	}{
+		{nil, testObservablesURL, http.StatusBadRequest},
+		{nil, testObservablesURL + "?match[value]=", http.StatusBadRequest},
+		{nil, testObservablesURL + "?match[value]=203.0.113.7&limit=fail", http.StatusBadRequest},
+		{func(ctx context.Context, values []string, p *cabby.Page) (cabby.ObservableMatches, error) {
+			return cabby.ObservableMatches{}, errors.New("ObservableMatches failure")
+		}, testObservablesURL + "?match[value]=203.0.113.7", http.StatusInternalServerError},
+		{func(ctx context.Context, values []string, p *cabby.Page) (cabby.ObservableMatches, error) {
+			return cabby.ObservableMatches{}, nil
+		}, testObservablesURL + "?match[value]=203.0.113.7", http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		obs := mockObservableService()
+		if test.matchesFn != nil {
+			obs.ObservableMatchesFn = test.matchesFn
+		}
+
+		h := ObservablesHandler{ObservableService: obs}
+		status, _ := handlerTest(h.Get, http.MethodGet, test.url, nil)
+
+		if status != test.expectedStatus {
+			t.Error("Got:", status, "Expected:", test.expectedStatus, "URL:", test.url)
+		}
+	}
+}
+
+func TestObservablesHandlerPost(t *testing.T) {
+	h := ObservablesHandler{ObservableService: mockObservableService()}
+	status, _ := handlerTest(h.Post, http.MethodPost, testObservablesURL, nil)
+
+	if status != http.StatusMethodNotAllowed {
+		t.Error("Got:", status, "Expected:", http.StatusMethodNotAllowed)
+	}
+}
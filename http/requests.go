@@ -1,7 +1,11 @@
 package http
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
 	"regexp"
 	"strings"
 
@@ -12,6 +16,7 @@ import (
 )
 
 const (
+	allVersions        = "all"
 	defaultVersion     = "last"
 	jsonContentType    = "application/json"
 	sixMonthsOfSeconds = "63072000"
@@ -23,7 +28,7 @@ const (
 // Regex testing tool: https://regex101.com/ (the fact I needed this may indicate this was a bad choice)
 var (
 	apiRootRegex         = `/(?P<apiroot>[\w\\\/\-]+)/`
-	apiRootPathRegex     = regexp.MustCompile(apiRootRegex + `(status|collections)/`)
+	apiRootPathRegex     = regexp.MustCompile(apiRootRegex + `(search|status|collections)/`)
 	statusPathRegex      = regexp.MustCompile(apiRootRegex + `status/(?P<statusid>[a-zA-Z\-\d]+)/?`)
 	collectionsPathRegex = regexp.MustCompile(apiRootRegex + "collections/")
 	collectionPathRegex  = regexp.MustCompile(collectionsPathRegex.String() + `(?P<collectionid>[a-zA-Z\-\d]+)/?`)
@@ -31,17 +36,49 @@ var (
 	objectsPathRegex     = regexp.MustCompile(collectionPathRegex.String() + "/objects/")
 	objectPathRegex      = regexp.MustCompile(objectsPathRegex.String() + `(?P<objectid>[a-zA-Z\-\d]+)/?`)
 	versionsPathRegex    = regexp.MustCompile(objectPathRegex.String() + "versions/?")
+
+	// matchPropertyRegex captures the property of a match parameter, IE: "labels" in "match[labels]"
+	matchPropertyRegex = regexp.MustCompile(`^match\[(?P<property>[a-z_]+)\]$`)
 )
 
-func newFilter(r *http.Request) (f cabby.Filter) {
-	f.AddedAfter = takeAddedAfter(r)
+// matchParameters are the match parameters that aren't object properties
+var matchParameters = map[string]bool{"id": true, "spec_version": true, "type": true, "value": true, "version": true}
+
+func newFilter(r *http.Request) (f cabby.Filter, err error) {
+	if f.AddedAfter, err = takeAddedAfter(r); err != nil {
+		return
+	}
+	if f.AsOf, err = takeAsOf(r); err != nil {
+		return
+	}
+
 	f.IDs = takeMatchIDs(r)
+	f.SpecVersions = takeMatchSpecVersions(r)
 	f.Types = takeMatchTypes(r)
 
 	f.Versions = takeMatchVersions(r)
 	if f.Versions == "" {
 		f.Versions = defaultVersion
 	}
+
+	f.Properties, err = takeMatchProperties(r)
+	return
+}
+
+// newDeleteFilter returns the filter for a delete; every version is deleted unless versions are matched.  Deletes
+// apply to the collection as it is now, so they can't be made as of a point in time.
+func newDeleteFilter(r *http.Request) (f cabby.Filter, err error) {
+	f, err = newFilter(r)
+	if err != nil {
+		return
+	}
+	if !f.AsOf.IsZero() {
+		return f, errors.New("Objects can't be deleted 'as_of' a point in time")
+	}
+
+	if takeMatchVersions(r) == "" {
+		f.Versions = allVersions
+	}
 	return
 }
 
@@ -67,16 +104,13 @@ func requestIsWriteAuthorized(r *http.Request) bool {
 	return false
 }
 
-func takeAddedAfter(r *http.Request) stones.Timestamp {
-	af := r.URL.Query()["added_after"]
+func takeAddedAfter(r *http.Request) (stones.Timestamp, error) {
+	return takeTimestamp(r, "added_after")
+}
 
-	if len(af) > 0 {
-		t, err := stones.TimestampFromString(af[0])
-		if err == nil {
-			return t
-		}
-	}
-	return stones.Timestamp{}
+// takeAsOf returns when a collection should be read as of; objects are read as they were then
+func takeAsOf(r *http.Request) (stones.Timestamp, error) {
+	return takeTimestamp(r, "as_of")
 }
 
 func takeAPIRoot(r *http.Request) string {
@@ -109,6 +143,18 @@ func takeCollectionID(r *http.Request) string {
 	return ""
 }
 
+func takeDryRun(r *http.Request) bool {
+	return r.URL.Query().Get("dry_run") == "true"
+}
+
+// takeIdempotencyKey returns the idempotency key of a request; dry runs don't write anything, so they don't have one
+func takeIdempotencyKey(r *http.Request) string {
+	if takeDryRun(r) {
+		return ""
+	}
+	return r.Header.Get("Idempotency-Key")
+}
+
 func takeLimit(r *http.Request) string {
 	ls := r.URL.Query()["limit"]
 
@@ -131,10 +177,68 @@ func takeMatchIDs(r *http.Request) string {
 	return takeMatchFilters(r, "match[id]")
 }
 
+// takeMatchProperties returns the values to match by object property.  Values are split on literal commas, so a value
+// holding a comma escapes it as %2C, IE: "match[name]=Poison%20Ivy%2C%20Variant"
+func takeMatchProperties(r *http.Request) (properties map[string][]string, err error) {
+	propertyIndex := 1
+
+	for _, pair := range strings.Split(r.URL.RawQuery, "&") {
+		rawParam, rawValues := pair, ""
+		if i := strings.Index(pair, "="); i >= 0 {
+			rawParam, rawValues = pair[:i], pair[i+1:]
+		}
+
+		param, err := url.QueryUnescape(rawParam)
+		if err != nil || !matchPropertyRegex.MatchString(param) {
+			continue
+		}
+
+		property := matchPropertyRegex.FindStringSubmatch(param)[propertyIndex]
+		if matchParameters[property] {
+			continue
+		}
+		if _, ok := cabby.FilterProperty(property); !ok {
+			return nil, fmt.Errorf("Results can't be filtered with '%v'", param)
+		}
+
+		for _, rawValue := range strings.Split(rawValues, ",") {
+			value, err := url.QueryUnescape(rawValue)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid value for '%v', error: %v", param, err)
+			}
+			if value == "" {
+				continue
+			}
+
+			if properties == nil {
+				properties = map[string][]string{}
+			}
+			properties[property] = append(properties[property], value)
+		}
+	}
+	return
+}
+
+func takeMatchSpecVersions(r *http.Request) string {
+	return takeMatchFilters(r, "match[spec_version]")
+}
+
 func takeMatchTypes(r *http.Request) string {
 	return takeMatchFilters(r, "match[type]")
 }
 
+// takeMatchValues returns the observable values to match; empty values are dropped
+func takeMatchValues(r *http.Request) []string {
+	values := []string{}
+
+	for _, v := range strings.Split(takeMatchFilters(r, "match[value]"), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
 func takeMatchVersions(r *http.Request) string {
 	return takeMatchFilters(r, "match[version]")
 }
@@ -151,6 +255,11 @@ func trimSlashes(s string) string {
 	return strings.Trim(s, "/")
 }
 
+// takeSearchQuery returns the text to search for
+func takeSearchQuery(r *http.Request) string {
+	return strings.TrimSpace(r.URL.Query().Get("q"))
+}
+
 func takeStatusID(r *http.Request) string {
 	statusIndex := 2
 	if statusPathRegex.Match([]byte(r.URL.Path)) {
@@ -159,6 +268,20 @@ func takeStatusID(r *http.Request) string {
 	return ""
 }
 
+// takeTimestamp returns the timestamp of a parameter; a parameter that isn't a timestamp is an error
+func takeTimestamp(r *http.Request, param string) (stones.Timestamp, error) {
+	values := r.URL.Query()[param]
+
+	if len(values) > 0 {
+		t, err := stones.TimestampFromString(values[0])
+		if err != nil {
+			return stones.Timestamp{}, fmt.Errorf("Invalid '%v' timestamp: '%v'", param, values[0])
+		}
+		return t, nil
+	}
+	return stones.Timestamp{}, nil
+}
+
 func takeVersions(r *http.Request) string {
 	if versionsPathRegex.Match([]byte(r.URL.Path)) {
 		return "versions"
@@ -166,6 +289,13 @@ func takeVersions(r *http.Request) string {
 	return ""
 }
 
+// detachedContext returns a context for work that outlives a request; it has the transaction id and user of the request
+// but isn't canceled when the request ends
+func detachedContext(r *http.Request) context.Context {
+	ctx := cabby.WithTransactionID(context.Background(), cabby.TakeTransactionID(r.Context()))
+	return cabby.WithUser(ctx, cabby.TakeUser(r.Context()))
+}
+
 func withTransactionID(r *http.Request) *http.Request {
 	transactionID := uuid.Must(uuid.NewV4())
 	return r.WithContext(cabby.WithTransactionID(r.Context(), transactionID))
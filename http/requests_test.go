@@ -3,6 +3,8 @@ package http
 import (
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/pladdy/cabby"
@@ -15,19 +17,55 @@ func TestTakeAddedAfter(t *testing.T) {
 		addedAfter string
 	}{
 		{httptest.NewRequest("GET", "/foo/bar/baz", nil), "0001-01-01T00:00:00Z"},
-		{httptest.NewRequest("GET", "/foo/bar/baz?added_after=invalid", nil), "0001-01-01T00:00:00Z"},
 		{httptest.NewRequest("GET", "/foo/bar/baz?added_after=2016-02-21T05:01:01.000Z", nil), "2016-02-21T05:01:01Z"},
 		{httptest.NewRequest("GET", "/foo/bar/baz?added_after=2016-02-21T05:01:01.123Z", nil), "2016-02-21T05:01:01.123Z"},
 	}
 
 	for _, test := range tests {
-		result := takeAddedAfter(test.request)
+		result, err := takeAddedAfter(test.request)
+		if err != nil {
+			t.Fatal(err)
+		}
 		if result.String() != test.addedAfter {
 			t.Error("Got:", result.String(), "Expected:", test.addedAfter)
 		}
 	}
 }
 
+func TestTakeAsOf(t *testing.T) {
+	tests := []struct {
+		request *http.Request
+		asOf    string
+	}{
+		{httptest.NewRequest("GET", "/foo/bar/baz", nil), "0001-01-01T00:00:00Z"},
+		{httptest.NewRequest("GET", "/foo/bar/baz?as_of=2016-02-21T05:01:01.123Z", nil), "2016-02-21T05:01:01.123Z"},
+	}
+
+	for _, test := range tests {
+		result, err := takeAsOf(test.request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.String() != test.asOf {
+			t.Error("Got:", result.String(), "Expected:", test.asOf)
+		}
+	}
+}
+
+func TestTakeTimestampInvalid(t *testing.T) {
+	tests := []*http.Request{
+		httptest.NewRequest("GET", "/foo/bar/baz?added_after=invalid", nil),
+		httptest.NewRequest("GET", "/foo/bar/baz?as_of=invalid", nil),
+		httptest.NewRequest("GET", "/foo/bar/baz?as_of=2016-02-21", nil),
+	}
+
+	for _, test := range tests {
+		if _, err := newFilter(test); err == nil {
+			t.Error("Expected error for:", test.URL)
+		}
+	}
+}
+
 func TestTakeAPIRoot(t *testing.T) {
 	tests := []struct {
 		request  *http.Request
@@ -35,6 +73,7 @@ func TestTakeAPIRoot(t *testing.T) {
 	}{
 		{httptest.NewRequest("GET", "/api_root/collections/collection_id/objects/stix_id/", nil), "api_root"},
 		{httptest.NewRequest("GET", "/multi/token/api_root/collections/collection_id/objects/stix_id", nil), "multi/token/api_root"},
+		{httptest.NewRequest("GET", "/api_root/search/?q=ivy", nil), "api_root"},
 		{httptest.NewRequest("GET", "/invalid/foobar/collection_id/objects/stix_id", nil), ""},
 	}
 
@@ -99,6 +138,87 @@ func TestTakeMatchIDs(t *testing.T) {
 	}
 }
 
+func TestTakeMatchProperties(t *testing.T) {
+	tests := []struct {
+		request    *http.Request
+		properties map[string][]string
+	}{
+		{httptest.NewRequest("GET", "/foo/bar/baz", nil), nil},
+		{httptest.NewRequest("GET", "/foo/bar/baz?match[labels]=malicious-activity", nil),
+			map[string][]string{"labels": {"malicious-activity"}}},
+		{httptest.NewRequest("GET", "/foo/bar/baz?match[labels]=a&match[labels]=b,c&match[created_by_ref]=identity--1", nil),
+			map[string][]string{"labels": {"a", "b", "c"}, "created_by_ref": {"identity--1"}}},
+		// escaped commas are part of a value
+		{httptest.NewRequest("GET", "/foo/bar/baz?match%5Bname%5D=Poison%20Ivy%2C%20Variant,Gh0st", nil),
+			map[string][]string{"name": {"Poison Ivy, Variant", "Gh0st"}}},
+		// match parameters that aren't properties are left to their own filters
+		{httptest.NewRequest("GET", "/foo/bar/baz?match[type]=malware&match[version]=all&match[labels", nil), nil},
+	}
+
+	for _, test := range tests {
+		result, err := takeMatchProperties(test.request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(result, test.properties) {
+			t.Error("Got:", result, "Expected:", test.properties)
+		}
+
+		f, err := newFilter(test.request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(f.Properties, test.properties) {
+			t.Error("Got:", f.Properties, "Expected:", test.properties)
+		}
+	}
+}
+
+func TestTakeMatchPropertiesInvalid(t *testing.T) {
+	tests := []*http.Request{
+		httptest.NewRequest("GET", "/foo/bar/baz?match[unknown]=x", nil),
+		httptest.NewRequest("GET", "/foo/bar/baz?match[labels]=a%ZZ", nil),
+	}
+
+	for _, test := range tests {
+		_, err := takeMatchProperties(test)
+		if err == nil {
+			t.Error("Expected error for:", test.URL)
+		}
+
+		_, err = newFilter(test)
+		if err == nil {
+			t.Error("Expected error for:", test.URL)
+		}
+	}
+}
+
+func TestTakeMatchSpecVersions(t *testing.T) {
+	tests := []struct {
+		request      *http.Request
+		specVersions string
+	}{
+		{httptest.NewRequest("GET", "/foo/bar/baz", nil), ""},
+		{httptest.NewRequest("GET", "/foo/bar/baz?match[spec_version]=2.1", nil), "2.1"},
+		{httptest.NewRequest("GET", "/foo/bar/baz?match[spec_version]=2.0&match[spec_version]=2.1", nil), "2.0,2.1"},
+	}
+
+	for _, test := range tests {
+		result := takeMatchSpecVersions(test.request)
+		if result != test.specVersions {
+			t.Error("Got:", result, "Expected:", test.specVersions)
+		}
+
+		f, err := newFilter(test.request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.SpecVersions != test.specVersions {
+			t.Error("Got:", f.SpecVersions, "Expected:", test.specVersions)
+		}
+	}
+}
+
 func TestTakeMatchTypes(t *testing.T) {
 	tests := []struct {
 		request   *http.Request
@@ -119,6 +239,27 @@ func TestTakeMatchTypes(t *testing.T) {
 	}
 }
 
+func TestTakeMatchValues(t *testing.T) {
+	tests := []struct {
+		request *http.Request
+		values  []string
+	}{
+		{httptest.NewRequest("GET", "/foo/bar/baz", nil), []string{}},
+		{httptest.NewRequest("GET", "/foo/bar/baz?match[value]=203.0.113.7", nil), []string{"203.0.113.7"}},
+		{httptest.NewRequest("GET", "/foo/bar/baz?match[value]=203.0.113.7,,example.com", nil),
+			[]string{"203.0.113.7", "example.com"}},
+		{httptest.NewRequest("GET", "/foo/bar/baz?match[value]=203.0.113.7&match[value]=example.com", nil),
+			[]string{"203.0.113.7", "example.com"}},
+	}
+
+	for _, test := range tests {
+		result := takeMatchValues(test.request)
+		if strings.Join(result, ",") != strings.Join(test.values, ",") || len(result) != len(test.values) {
+			t.Error("Got:", result, "Expected:", test.values)
+		}
+	}
+}
+
 func TestTakeMatchVersions(t *testing.T) {
 	tests := []struct {
 		request      *http.Request
@@ -140,6 +281,24 @@ func TestTakeMatchVersions(t *testing.T) {
 	}
 }
 
+func TestTakeSearchQuery(t *testing.T) {
+	tests := []struct {
+		request *http.Request
+		query   string
+	}{
+		{httptest.NewRequest("GET", "/foo/bar/baz", nil), ""},
+		{httptest.NewRequest("GET", "/foo/bar/baz?q=+poison+ivy+", nil), "poison ivy"},
+		{httptest.NewRequest("GET", "/foo/bar/baz?q=%22poison%22", nil), `"poison"`},
+	}
+
+	for _, test := range tests {
+		result := takeSearchQuery(test.request)
+		if result != test.query {
+			t.Error("Got:", result, "Expected:", test.query)
+		}
+	}
+}
+
 func TestTakeStatusID(t *testing.T) {
 	sid := tester.StatusID
 
@@ -19,29 +19,30 @@ func registerAPIRoots(ds cabby.DataStore, sm *http.ServeMux) {
 	}
 
 	for _, apiRoot := range apiRoots {
-		registerAPIRoot(ah, apiRoot.Path, sm)
+		registerAPIRoot(ah, apiRoot, sm)
 		registerCollectionRoutes(ds, apiRoot, sm)
 	}
 }
 
-func registerAPIRoot(ah APIRootHandler, path string, sm *http.ServeMux) {
-	if path != "" {
-		registerRoute(sm, path, routeHandler(ah))
+func registerAPIRoot(ah APIRootHandler, apiRoot cabby.APIRoot, sm *http.ServeMux) {
+	if apiRoot.Path != "" {
+		registerRoute(sm, apiRoot.Path, withAPIRootVersions(routeHandler(ah), apiRoot))
 	}
 }
 
 func registerCollectionRoutes(ds cabby.DataStore, apiRoot cabby.APIRoot, sm *http.ServeMux) {
 	csh := CollectionsHandler{CollectionService: ds.CollectionService()}
-	registerRoute(sm, apiRoot.Path+"/collections", routeHandler(csh))
+	registerRoute(sm, apiRoot.Path+"/collections", withAPIRootVersions(routeHandler(csh), apiRoot))
 
 	ss := ds.StatusService()
 	osh := ObjectsHandler{
-		MaxContentLength: apiRoot.MaxContentLength,
-		ObjectService:    ds.ObjectService(),
-		StatusService:    ss}
-	mh := ManifestHandler{ManifestService: ds.ManifestService()}
+		CollectionService: ds.CollectionService(),
+		MaxContentLength:  apiRoot.MaxContentLength,
+		ObjectService:     ds.ObjectService(),
+		StatusService:     ss}
+	mh := ManifestHandler{CollectionService: ds.CollectionService(), ManifestService: ds.ManifestService()}
 	ch := CollectionHandler{CollectionService: ds.CollectionService()}
-	oh := ObjectHandler{ObjectService: ds.ObjectService()}
+	oh := ObjectHandler{CollectionService: ds.CollectionService(), ObjectService: ds.ObjectService()}
 	vsh := VersionsHandler{VersionsService: ds.VersionsService()}
 
 	acs, err := csh.CollectionService.CollectionsInAPIRoot(context.Background(), apiRoot.Path)
@@ -54,19 +55,22 @@ func registerCollectionRoutes(ds cabby.DataStore, apiRoot cabby.APIRoot, sm *htt
 		registerRoute(
 			sm,
 			apiRoot.Path+"/collections/"+collectionID.String(),
-			routeHandler(ch))
+			withAPIRootVersions(routeHandler(ch), apiRoot))
 		registerRoute(
 			sm,
 			apiRoot.Path+"/collections/"+collectionID.String()+"/objects",
-			routeObjectsHandler(oh, osh, vsh))
+			withAPIRootVersions(routeObjectsHandler(oh, osh, vsh), apiRoot))
 		registerRoute(
 			sm,
 			apiRoot.Path+"/collections/"+collectionID.String()+"/manifest",
-			routeHandler(mh))
+			withAPIRootVersions(routeHandler(mh), apiRoot))
 	}
 
 	sh := StatusHandler{StatusService: ss}
-	registerRoute(sm, apiRoot.Path+"/status", routeHandler(sh))
+	registerRoute(sm, apiRoot.Path+"/status", withAPIRootVersions(routeHandler(sh), apiRoot))
+
+	srh := SearchHandler{SearchService: ds.SearchService()}
+	registerRoute(sm, apiRoot.Path+"/search", withAPIRootVersions(routeHandler(srh), apiRoot))
 }
 
 func registerRoute(sm *http.ServeMux, path string, h http.HandlerFunc) {
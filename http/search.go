@@ -0,0 +1,62 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/pladdy/cabby"
+)
+
+// SearchMethods lists allowed methods
+const SearchMethods = "Get, Head"
+
+// SearchHandler holds a cabby SearchService
+type SearchHandler struct {
+	SearchService cabby.SearchService
+}
+
+// Delete handler
+func (h SearchHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	methodNotAllowed(w, r, SearchMethods)
+}
+
+// Get serves the objects in the api root's collections the user can read whose text matches the query
+func (h SearchHandler) Get(w http.ResponseWriter, r *http.Request) {
+	log.WithFields(log.Fields{"handler": "SearchHandler"}).Debug("Handler called")
+
+	query := takeSearchQuery(r)
+	if query == "" {
+		badRequest(w, errors.New("Search for text with 'q'"))
+		return
+	}
+
+	p, err := takePage(r)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+
+	hits, err := h.SearchService.Search(r.Context(), takeAPIRoot(r), query, &p)
+	if err == cabby.ErrSearchUnavailable {
+		notImplemented(w, err)
+		return
+	}
+	if err != nil {
+		internalServerError(w, err)
+		return
+	}
+
+	if noResources(len(hits.Objects)) {
+		noPage(w, r, p)
+		return
+	}
+
+	writePage(w, r, p, len(hits.Objects), responseMediaType(r), resourceToJSON(hits))
+}
+
+// Post handler
+func (h SearchHandler) Post(w http.ResponseWriter, r *http.Request) {
+	methodNotAllowed(w, r, SearchMethods)
+}
@@ -0,0 +1,97 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/pladdy/cabby"
+	"github.com/pladdy/cabby/tester"
+)
+
+func TestSearchHandlerDelete(t *testing.T) {
+	h := SearchHandler{SearchService: mockSearchService()}
+	status, _ := handlerTest(h.Delete, http.MethodDelete, testSearchURL, nil)
+
+	if status != http.StatusMethodNotAllowed {
+		t.Error("Got:", status, "Expected:", http.StatusMethodNotAllowed)
+	}
+}
+
+func TestSearchHandlerGet(t *testing.T) {
+	var searchedAPIRoot, searchedQuery string
+
+	srs := mockSearchService()
+	search := srs.SearchFn
+	srs.SearchFn = func(ctx context.Context, apiRoot, query string, p *cabby.Page) (cabby.SearchHits, error) {
+		searchedAPIRoot, searchedQuery = apiRoot, query
+		return search(ctx, apiRoot, query, p)
+	}
+
+	h := SearchHandler{SearchService: srs}
+	status, body := handlerTest(h.Get, http.MethodGet, testSearchURL+"?q=poison+ivy", nil)
+
+	if status != http.StatusOK {
+		t.Error("Got:", status, "Expected:", http.StatusOK)
+	}
+	if searchedAPIRoot != tester.APIRootPath || searchedQuery != "poison ivy" {
+		t.Error("Got:", searchedAPIRoot, searchedQuery, "Expected:", tester.APIRootPath, "poison ivy")
+	}
+
+	var result cabby.SearchHits
+	err := json.Unmarshal([]byte(body), &result)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Objects) != 1 || result.Objects[0].CollectionID != tester.CollectionID ||
+		result.Objects[0].ID != tester.ObjectID {
+		t.Error("Got:", result, "Expected a hit in:", tester.CollectionID)
+	}
+}
+
+func TestSearchHandlerGetFailures(t *testing.T) {
+	tests := []struct {
+		searchFn       func(ctx context.Context, apiRoot, query string, p *cabby.Page) (cabby.SearchHits, error)
+		url            string
+		expectedStatus int
+	}{
+		{nil, testSearchURL, http.StatusBadRequest},
+		{nil, testSearchURL + "?q=+", http.StatusBadRequest},
+		{nil, testSearchURL + "?q=ivy&limit=fail", http.StatusBadRequest},
+		{func(ctx context.Context, apiRoot, query string, p *cabby.Page) (cabby.SearchHits, error) {
+			return cabby.SearchHits{}, errors.New("Search failure")
+		}, testSearchURL + "?q=ivy", http.StatusInternalServerError},
+		{func(ctx context.Context, apiRoot, query string, p *cabby.Page) (cabby.SearchHits, error) {
+			return cabby.SearchHits{}, cabby.ErrSearchUnavailable
+		}, testSearchURL + "?q=ivy", http.StatusNotImplemented},
+		{func(ctx context.Context, apiRoot, query string, p *cabby.Page) (cabby.SearchHits, error) {
+			return cabby.SearchHits{}, nil
+		}, testSearchURL + "?q=ivy", http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		srs := mockSearchService()
+		if test.searchFn != nil {
+			srs.SearchFn = test.searchFn
+		}
+
+		h := SearchHandler{SearchService: srs}
+		status, _ := handlerTest(h.Get, http.MethodGet, test.url, nil)
+
+		if status != test.expectedStatus {
+			t.Error("Got:", status, "Expected:", test.expectedStatus, "URL:", test.url)
+		}
+	}
+}
+
+func TestSearchHandlerPost(t *testing.T) {
+	h := SearchHandler{SearchService: mockSearchService()}
+	status, _ := handlerTest(h.Post, http.MethodPost, testSearchURL, nil)
+
+	if status != http.StatusMethodNotAllowed {
+		t.Error("Got:", status, "Expected:", http.StatusMethodNotAllowed)
+	}
+}
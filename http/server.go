@@ -2,60 +2,137 @@ package http
 
 import (
 	"crypto/tls"
+	"errors"
+	"expvar"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/pladdy/cabby"
 	log "github.com/sirupsen/logrus"
 )
 
-// NewCabby returns a new http server
-func NewCabby(ds cabby.DataStore, c cabby.Config) *http.Server {
+// defaults used when a timeout or limit isn't configured; read and write timeouts cover the whole body of a request
+// or response, so they're off unless configured rather than cut off large uploads and pages
+const (
+	defaultIdleTimeout       = 120 * time.Second
+	defaultMaxHeaderBytes    = 1 << 20
+	defaultReadHeaderTimeout = 10 * time.Second
+	defaultReadTimeout       = 0
+	defaultWriteTimeout      = 0
+)
+
+// NewCabby returns a new http server; an error is returned if the server config is invalid
+func NewCabby(ds cabby.DataStore, c cabby.Config) (*http.Server, error) {
 	handler := http.NewServeMux()
 
 	registerAPIRoots(ds, handler)
 
-	dh := DiscoveryHandler{DiscoveryService: ds.DiscoveryService(), Port: c.Port}
+	dh := DiscoveryHandler{
+		DiscoveryService: ds.DiscoveryService(),
+		Port:             c.Port,
+		ExternalURL:      c.ExternalURL,
+		APIRootURLs:      c.APIRootURLs}
 	registerRoute(handler, "taxii2", routeHandler(dh))
+	registerRoute(handler, discoveryPath+"/observables", routeHandler(ObservablesHandler{ObservableService: ds.ObservableService()}))
 	registerRoute(handler, "/", handleUndefinedRoute)
 
 	return setupServer(ds, handler, c)
 }
 
-func setupServer(ds cabby.DataStore, h http.Handler, c cabby.Config) *http.Server {
+// NewMetricsServer returns a server for the metrics published with expvar; it serves them at /debug/vars
+func NewMetricsServer(address string) *http.Server {
+	handler := http.NewServeMux()
+	handler.Handle("/debug/vars", expvar.Handler())
+
+	log.WithFields(log.Fields{"address": address}).Info("Metrics server configured")
+	return &http.Server{Addr: address, Handler: handler, ReadHeaderTimeout: defaultReadHeaderTimeout}
+}
+
+func setupServer(ds cabby.DataStore, h http.Handler, c cabby.Config) (*http.Server, error) {
 	p := strconv.Itoa(c.Port)
 	log.WithFields(log.Fields{"port": p}).Info("Server port configured")
 
-	return &http.Server{
+	tc, err := setupTLS(c.TLS)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.HTTP2 && !supportsHTTP2(tc) {
+		return nil, errors.New("Invalid TLS cipher_suites: HTTP/2 over TLS 1.2 requires an ECDHE AES 128 GCM suite")
+	}
+
+	proxies, err := parseTrustedProxies(c.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validExternalURLs(c)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &http.Server{
 		Addr: ":" + p,
-		// Wrap the server handler with logging, then basicAuth, then an 'Accept' header check
-		Handler: withAcceptSet(
-			withBasicAuth(withLogging(h), ds.UserService()),
-			cabby.TaxiiContentType),
-		TLSConfig:    setupTLS(),
-		TLSNextProto: make(map[string]func(*http.Server, *tls.Conn, http.Handler)),
+		// Wrap the server handler with compression, then logging, then basicAuth, then an 'Accept' header check, then
+		// forwarded values from trusted proxies
+		Handler: withForwarded(
+			withAcceptSet(withBasicAuth(withLogging(withCompression(h)), ds.UserService(), newAnonymousAccess(c)), taxiiMediaTypes),
+			proxies),
+		TLSConfig:         tc,
+		ReadHeaderTimeout: seconds(c.ReadHeaderTimeout, defaultReadHeaderTimeout),
+		ReadTimeout:       seconds(c.ReadTimeout, defaultReadTimeout),
+		WriteTimeout:      seconds(c.WriteTimeout, defaultWriteTimeout),
+		IdleTimeout:       seconds(c.IdleTimeout, defaultIdleTimeout),
+		MaxHeaderBytes:    defaultMaxHeaderBytes,
+	}
+
+	if c.MaxHeaderBytes > 0 {
+		s.MaxHeaderBytes = c.MaxHeaderBytes
+	}
+
+	// a nil TLSNextProto lets the server negotiate HTTP/2; an empty one limits it to HTTP/1.1
+	if !c.HTTP2 {
+		s.TLSNextProto = make(map[string]func(*http.Server, *tls.Conn, http.Handler))
+	}
+
+	log.WithFields(log.Fields{
+		"anonymous_api_roots": c.AnonymousAPIRoots,
+		"anonymous_discovery": c.AnonymousDiscovery,
+		"http2":               c.HTTP2,
+		"idle_timeout":        s.IdleTimeout.String(),
+		"max_header_bytes":    s.MaxHeaderBytes,
+		"plain_http":          c.PlainHTTP,
+		"port":                p,
+		"trusted_proxies":     c.TrustedProxies,
+		"read_header_timeout": s.ReadHeaderTimeout.String(),
+		"read_timeout":        s.ReadTimeout.String(),
+		"write_timeout":       s.WriteTimeout.String(),
+	}).Info("Server settings configured")
+
+	return s, nil
+}
+
+// validExternalURLs checks the configured external URLs can be used verbatim
+func validExternalURLs(c cabby.Config) error {
+	if c.ExternalURL != "" {
+		if err := validExternalURL(c.ExternalURL); err != nil {
+			return err
+		}
+	}
+
+	for _, u := range c.APIRootURLs {
+		if err := validExternalURL(u); err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
-// TODO: not this in an app...this should be done in a web server like nginx;
-//       it was neat to get work in the app though
-func setupTLS() *tls.Config {
-	return &tls.Config{
-		MinVersion:               tls.VersionTLS12,
-		CurvePreferences:         []tls.CurveID{tls.CurveP521, tls.CurveP384, tls.CurveP256},
-		PreferServerCipherSuites: true,
-		CipherSuites: []uint16{
-			// TLS 1.2
-			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
-			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
-			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
-			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
-			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
-			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
-			// TLS 1.3
-			tls.TLS_AES_256_GCM_SHA384,
-			tls.TLS_CHACHA20_POLY1305_SHA256,
-			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
-		},
+// seconds converts a configured number of seconds to a duration, using a default if it isn't positive
+func seconds(s int, d time.Duration) time.Duration {
+	if s <= 0 {
+		return d
 	}
+	return time.Duration(s) * time.Second
 }
@@ -5,10 +5,14 @@ import (
 	"context"
 	"crypto/tls"
 	"encoding/json"
+	"io/ioutil"
+	"net"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/pladdy/cabby"
 	"github.com/pladdy/cabby/tester"
@@ -17,7 +21,10 @@ import (
 
 func TestNewCabby(t *testing.T) {
 	c := cabby.Config{Port: 1212, SSLCert: "../server.crt", SSLKey: "../server.key"}
-	server := NewCabby(mockDataStore(), c)
+	server, err := NewCabby(mockDataStore(), c)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	// use tls which requires cert/key files
 	defer server.Close()
@@ -40,6 +47,39 @@ func TestNewCabby(t *testing.T) {
 	}
 }
 
+func TestNewMetricsServer(t *testing.T) {
+	server := NewMetricsServer("localhost:6060")
+
+	tests := []struct {
+		path     string
+		expected int
+	}{
+		{"/debug/vars", http.StatusOK},
+		{"/taxii2/", http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		res := httptest.NewRecorder()
+		server.Handler.ServeHTTP(res, httptest.NewRequest("GET", test.path, nil))
+
+		if res.Code != test.expected {
+			t.Error("Got:", res.Code, "Expected:", test.expected, "Path:", test.path)
+		}
+	}
+
+	res := httptest.NewRecorder()
+	server.Handler.ServeHTTP(res, httptest.NewRequest("GET", "/debug/vars", nil))
+
+	var metrics map[string]interface{}
+	err := json.Unmarshal(res.Body.Bytes(), &metrics)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := metrics["memstats"]; !ok {
+		t.Error("Got:", res.Body.String(), "Expected: memstats")
+	}
+}
+
 func TestSetupServerHandler(t *testing.T) {
 	// redirect log output for test
 	var buf bytes.Buffer
@@ -53,7 +93,7 @@ func TestSetupServerHandler(t *testing.T) {
 	}()
 
 	// mock up service; add a variable to track if User() is called
-	us := tester.UserService{}
+	us := mockUserService()
 
 	userCalled := false
 	us.UserFn = func(ctx context.Context, user, password string) (cabby.User, error) {
@@ -75,7 +115,10 @@ func TestSetupServerHandler(t *testing.T) {
 	sm.HandleFunc("/test/", h)
 
 	port := 1212
-	server := setupServer(ds, sm, cabby.Config{Port: port})
+	server, err := setupServer(ds, sm, cabby.Config{Port: port})
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	// ignore TLS, not needed for log test
 	defer server.Close()
@@ -110,6 +153,23 @@ func TestSetupServerHandler(t *testing.T) {
 	}
 }
 
+func TestSetupServerInvalidConfig(t *testing.T) {
+	tests := []cabby.Config{
+		{Port: 1234, TLS: cabby.TLSConfig{CipherSuites: []string{"TLS_FAKE_SUITE"}}},
+		{Port: 1234, HTTP2: true, TLS: cabby.TLSConfig{CipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384"}}},
+		{Port: 1234, TrustedProxies: []string{"10.0.0.0/99"}},
+		{Port: 1234, ExternalURL: "taxii.example.com"},
+		{Port: 1234, APIRootURLs: map[string]string{"root": "/root/"}},
+	}
+
+	for _, test := range tests {
+		_, err := NewCabby(mockDataStore(), test)
+		if err == nil {
+			t.Error("Got:", err, "Expected an error", "Config:", test)
+		}
+	}
+}
+
 func TestSetupServerLogging(t *testing.T) {
 	// redirect log output for test
 	var buf bytes.Buffer
@@ -123,7 +183,10 @@ func TestSetupServerLogging(t *testing.T) {
 	}()
 
 	handler := http.NewServeMux()
-	server := setupServer(mockDataStore(), handler, cabby.Config{Port: 1234})
+	server, err := setupServer(mockDataStore(), handler, cabby.Config{Port: 1234})
+	if err != nil {
+		t.Fatal(err)
+	}
 	defer server.Close()
 
 	type expectedLog struct {
@@ -135,7 +198,7 @@ func TestSetupServerLogging(t *testing.T) {
 
 	// parse log into struct
 	var result expectedLog
-	err := json.Unmarshal([]byte(tester.LastLog(buf)), &result)
+	err = json.Unmarshal([]byte(tester.LastLog(buf)), &result)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -147,7 +210,10 @@ func TestSetupServerLogging(t *testing.T) {
 
 func TestSetupServerSettings(t *testing.T) {
 	handler := http.NewServeMux()
-	server := setupServer(mockDataStore(), handler, cabby.Config{Port: 1234})
+	server, err := setupServer(mockDataStore(), handler, cabby.Config{Port: 1234})
+	if err != nil {
+		t.Fatal(err)
+	}
 	defer server.Close()
 
 	// set server settings
@@ -161,10 +227,142 @@ func TestSetupServerSettings(t *testing.T) {
 	if server.TLSNextProto == nil {
 		t.Error("TLSNextProto should not be nil")
 	}
+	if server.ReadHeaderTimeout != defaultReadHeaderTimeout {
+		t.Error("Got:", server.ReadHeaderTimeout, "Expected:", defaultReadHeaderTimeout)
+	}
+	if server.ReadTimeout != 0 || server.WriteTimeout != 0 {
+		t.Error("Got:", server.ReadTimeout, server.WriteTimeout, "Expected no read or write timeout")
+	}
+	if server.MaxHeaderBytes != defaultMaxHeaderBytes {
+		t.Error("Got:", server.MaxHeaderBytes, "Expected:", defaultMaxHeaderBytes)
+	}
+}
+
+func TestSetupServerSettingsConfigured(t *testing.T) {
+	c := cabby.Config{
+		Port:              1234,
+		HTTP2:             true,
+		ReadHeaderTimeout: 1,
+		ReadTimeout:       2,
+		WriteTimeout:      3,
+		IdleTimeout:       4,
+		MaxHeaderBytes:    4096}
+
+	server, err := setupServer(mockDataStore(), http.NewServeMux(), c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	tests := []struct {
+		setting  string
+		result   time.Duration
+		expected time.Duration
+	}{
+		{"ReadHeaderTimeout", server.ReadHeaderTimeout, time.Second},
+		{"ReadTimeout", server.ReadTimeout, 2 * time.Second},
+		{"WriteTimeout", server.WriteTimeout, 3 * time.Second},
+		{"IdleTimeout", server.IdleTimeout, 4 * time.Second},
+	}
+
+	for _, test := range tests {
+		if test.result != test.expected {
+			t.Error("Got:", test.result, "Expected:", test.expected, "Setting:", test.setting)
+		}
+	}
+
+	if server.MaxHeaderBytes != c.MaxHeaderBytes {
+		t.Error("Got:", server.MaxHeaderBytes, "Expected:", c.MaxHeaderBytes)
+	}
+	if server.TLSNextProto != nil {
+		t.Error("Got:", server.TLSNextProto, "Expected: nil so HTTP/2 can be negotiated")
+	}
+}
+
+func TestNewCabbyHTTP2(t *testing.T) {
+	tests := []struct {
+		port          int
+		http2         bool
+		expectedProto int
+	}{
+		{1213, true, 2},
+		{1214, false, 1},
+	}
+
+	for _, test := range tests {
+		c := cabby.Config{Port: test.port, SSLCert: "../server.crt", SSLKey: "../server.key", HTTP2: test.http2}
+		server, err := NewCabby(mockDataStore(), c)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		go func() {
+			log.Info(server.ListenAndServeTLS(c.SSLCert, c.SSLKey))
+		}()
+
+		req := newServerRequest("GET", "https://localhost:"+strconv.Itoa(c.Port)+"/taxii2/")
+		req.Header.Set("Accept", cabby.TaxiiContentType)
+
+		res, err := attemptRequest(h2Client(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if res.StatusCode != http.StatusOK {
+			t.Error("Got:", res.StatusCode, "Expected:", http.StatusOK)
+		}
+		if res.ProtoMajor != test.expectedProto {
+			t.Error("Got:", res.Proto, "Expected major version:", test.expectedProto)
+		}
+
+		res.Body.Close()
+		server.Close()
+	}
+}
+
+func TestSetupServerReadHeaderTimeout(t *testing.T) {
+	port := 1215
+	server, err := setupServer(mockDataStore(), http.NewServeMux(), cabby.Config{Port: port, ReadHeaderTimeout: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer server.Close()
+	go func() {
+		log.Info(server.ListenAndServe())
+	}()
+
+	var conn net.Conn
+	for i := 0; i < 3; i++ {
+		conn, err = net.Dial("tcp", "localhost:"+strconv.Itoa(port))
+		if err == nil {
+			break
+		}
+		time.Sleep(time.Duration(i+1) * time.Second)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// a slow client that never finishes its headers should be disconnected by the server
+	_, err = conn.Write([]byte("GET /taxii2/ HTTP/1.1\r\nHost: localhost\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, err = ioutil.ReadAll(conn)
+	if e, ok := err.(net.Error); ok && e.Timeout() {
+		t.Error("Got:", err, "Expected: the server to close the connection")
+	}
 }
 
 func TestSetupTLS(t *testing.T) {
-	tlsSetup := setupTLS()
+	tlsSetup, err := setupTLS(cabby.TLSConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	if tlsSetup.MinVersion != tls.VersionTLS12 {
 		t.Error("Got:", tlsSetup.MinVersion, "Expected:", tls.VersionTLS12)
@@ -20,9 +20,15 @@ func (h StatusHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	methodNotAllowed(w, r, StatusMethods)
 }
 
-// Get serves a status resource
+// Get serves a status resource; without a status id it serves the statuses of the user's recent requests
 func (h StatusHandler) Get(w http.ResponseWriter, r *http.Request) {
-	status, err := h.StatusService.Status(r.Context(), takeStatusID(r))
+	statusID := takeStatusID(r)
+	if statusID == "" {
+		h.statuses(w, r)
+		return
+	}
+
+	status, err := h.StatusService.Status(r.Context(), takeAPIRoot(r), statusID)
 	if err != nil {
 		internalServerError(w, err)
 		return
@@ -33,10 +39,31 @@ func (h StatusHandler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	writeContent(w, r, cabby.TaxiiContentType, resourceToJSON(status))
+	writeContent(w, r, responseMediaType(r), resourceToJSON(statusResource(r, status)))
 }
 
 // Post handler
 func (h StatusHandler) Post(w http.ResponseWriter, r *http.Request) {
 	methodNotAllowed(w, r, StatusMethods)
 }
+
+func (h StatusHandler) statuses(w http.ResponseWriter, r *http.Request) {
+	p, err := takePage(r)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+
+	statuses, err := h.StatusService.Statuses(r.Context(), takeAPIRoot(r), &p)
+	if err != nil {
+		internalServerError(w, err)
+		return
+	}
+
+	if noResources(len(statuses.Statuses)) {
+		noPage(w, r, p)
+		return
+	}
+
+	writePage(w, r, p, len(statuses.Statuses), responseMediaType(r), resourceToJSON(statusesResource(r, statuses)))
+}
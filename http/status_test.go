@@ -41,12 +41,29 @@ func TestStatusHandlerGet(t *testing.T) {
 	}
 }
 
+func TestStatusHandlerGetAPIRoot(t *testing.T) {
+	ms := mockStatusService()
+	ms.StatusFn = func(ctx context.Context, apiRoot, statusID string) (cabby.Status, error) {
+		if apiRoot != tester.APIRootPath {
+			t.Error("Got:", apiRoot, "Expected:", tester.APIRootPath)
+		}
+		return tester.Status, nil
+	}
+
+	h := StatusHandler{StatusService: &ms}
+	status, _ := handlerTest(h.Get, http.MethodGet, testStatusURL, nil)
+
+	if status != http.StatusOK {
+		t.Error("Got:", status, "Expected:", http.StatusOK)
+	}
+}
+
 func TestStatusHandlerGetInternalServerError(t *testing.T) {
 	expected := cabby.Error{
 		Title: "Internal Server Error", Description: "Status failure", HTTPStatus: http.StatusInternalServerError}
 
 	ms := mockStatusService()
-	ms.StatusFn = func(ctx context.Context, statusID string) (cabby.Status, error) {
+	ms.StatusFn = func(ctx context.Context, apiRoot, statusID string) (cabby.Status, error) {
 		return cabby.Status{}, errors.New(expected.Description)
 	}
 
@@ -71,7 +88,7 @@ func TestStatusHandlerGetInternalServerError(t *testing.T) {
 
 func TestStatusHandlerGetNoStatus(t *testing.T) {
 	ms := mockStatusService()
-	ms.StatusFn = func(ctx context.Context, statusID string) (cabby.Status, error) {
+	ms.StatusFn = func(ctx context.Context, apiRoot, statusID string) (cabby.Status, error) {
 		return cabby.Status{}, nil
 	}
 
@@ -97,6 +114,55 @@ func TestStatusHandlerGetNoStatus(t *testing.T) {
 	}
 }
 
+func TestStatusHandlerGetStatuses(t *testing.T) {
+	h := StatusHandler{StatusService: mockStatusService()}
+	status, body := handlerTest(h.Get, http.MethodGet, testAPIRootURL+"status/", nil)
+
+	if status != http.StatusOK {
+		t.Error("Got:", status, "Expected:", http.StatusOK)
+	}
+
+	var result cabby.Statuses
+	err := json.Unmarshal([]byte(body), &result)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Statuses) != 1 || !tester.CompareStatus(result.Statuses[0], tester.Status) {
+		t.Error("Got:", result, "Expected:", tester.Status)
+	}
+}
+
+func TestStatusHandlerGetStatusesFailures(t *testing.T) {
+	tests := []struct {
+		statusesFn     func(ctx context.Context, apiRoot string, p *cabby.Page) (cabby.Statuses, error)
+		url            string
+		expectedStatus int
+	}{
+		{func(ctx context.Context, apiRoot string, p *cabby.Page) (cabby.Statuses, error) {
+			return cabby.Statuses{}, errors.New("Statuses failure")
+		}, testAPIRootURL + "status/", http.StatusInternalServerError},
+		{func(ctx context.Context, apiRoot string, p *cabby.Page) (cabby.Statuses, error) {
+			return cabby.Statuses{}, nil
+		}, testAPIRootURL + "status/", http.StatusNotFound},
+		{nil, testAPIRootURL + "status/?limit=fail", http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		ms := mockStatusService()
+		if test.statusesFn != nil {
+			ms.StatusesFn = test.statusesFn
+		}
+
+		h := StatusHandler{StatusService: ms}
+		status, _ := handlerTest(h.Get, http.MethodGet, test.url, nil)
+
+		if status != test.expectedStatus {
+			t.Error("Got:", status, "Expected:", test.expectedStatus, "URL:", test.url)
+		}
+	}
+}
+
 func TestStatusHandlePost(t *testing.T) {
 	h := StatusHandler{StatusService: mockStatusService()}
 	status, _ := handlerTest(h.Post, http.MethodPost, testStatusURL, nil)
@@ -0,0 +1,255 @@
+package http
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/pladdy/cabby"
+)
+
+var (
+	tlsVersions = map[string]uint16{
+		"1.0": tls.VersionTLS10,
+		"1.1": tls.VersionTLS11,
+		"1.2": tls.VersionTLS12,
+		"1.3": tls.VersionTLS13,
+	}
+
+	tlsCipherSuites = map[string]uint16{
+		// TLS 1.0 - 1.2
+		"TLS_RSA_WITH_AES_128_CBC_SHA":                  tls.TLS_RSA_WITH_AES_128_CBC_SHA,
+		"TLS_RSA_WITH_AES_256_CBC_SHA":                  tls.TLS_RSA_WITH_AES_256_CBC_SHA,
+		"TLS_RSA_WITH_AES_128_GCM_SHA256":               tls.TLS_RSA_WITH_AES_128_GCM_SHA256,
+		"TLS_RSA_WITH_AES_256_GCM_SHA384":               tls.TLS_RSA_WITH_AES_256_GCM_SHA384,
+		"TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA":          tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA,
+		"TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA":          tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA,
+		"TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA":            tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA,
+		"TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA":            tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA,
+		"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256":         tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+		"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256":       tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+		"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384":         tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+		"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384":       tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+		"TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305":          tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+		"TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305":        tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+		"TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256":   tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+		"TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256": tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+		// TLS 1.3; Go doesn't allow these to be configured but they're accepted so a profile can list them
+		"TLS_AES_128_GCM_SHA256":       tls.TLS_AES_128_GCM_SHA256,
+		"TLS_AES_256_GCM_SHA384":       tls.TLS_AES_256_GCM_SHA384,
+		"TLS_CHACHA20_POLY1305_SHA256": tls.TLS_CHACHA20_POLY1305_SHA256,
+	}
+
+	tlsCurves = map[string]tls.CurveID{
+		"P256":   tls.CurveP256,
+		"P384":   tls.CurveP384,
+		"P521":   tls.CurveP521,
+		"X25519": tls.X25519,
+	}
+
+	tlsClientAuth = map[string]tls.ClientAuthType{
+		"":                   tls.NoClientCert,
+		"none":               tls.NoClientCert,
+		"request":            tls.RequestClientCert,
+		"require":            tls.RequireAnyClientCert,
+		"verify_if_given":    tls.VerifyClientCertIfGiven,
+		"require_and_verify": tls.RequireAndVerifyClientCert,
+	}
+
+	// the default profile
+	defaultTLSMinVersion = "1.2"
+	defaultTLSCurves     = []string{"P521", "P384", "P256"}
+	defaultCipherSuites  = []string{
+		// TLS 1.2
+		"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+		"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256",
+		"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384",
+		"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384",
+		"TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305",
+		"TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305",
+		// TLS 1.3
+		"TLS_AES_256_GCM_SHA384",
+		"TLS_CHACHA20_POLY1305_SHA256",
+	}
+
+	// HTTP/2 over TLS 1.2 requires one of these suites
+	http2CipherSuites = []uint16{
+		tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+		tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	}
+)
+
+// setupTLS converts a TLS policy to a tls.Config; empty values in the policy use the default profile.  An error is
+// returned for anything in the policy that isn't recognized.
+func setupTLS(c cabby.TLSConfig) (*tls.Config, error) {
+	tc := &tls.Config{PreferServerCipherSuites: true}
+	var err error
+
+	tc.MinVersion, err = tlsVersion(withDefault(c.MinVersion, defaultTLSMinVersion))
+	if err != nil {
+		return tc, fmt.Errorf("Invalid TLS min_version: %v", err)
+	}
+
+	if c.MaxVersion != "" {
+		tc.MaxVersion, err = tlsVersion(c.MaxVersion)
+		if err != nil {
+			return tc, fmt.Errorf("Invalid TLS max_version: %v", err)
+		}
+
+		if tc.MaxVersion < tc.MinVersion {
+			return tc, fmt.Errorf(
+				"Invalid TLS max_version: %v is less than min_version %v",
+				c.MaxVersion,
+				withDefault(c.MinVersion, defaultTLSMinVersion))
+		}
+	}
+
+	tc.CipherSuites, err = tlsCipherSuiteIDs(withDefaults(c.CipherSuites, defaultCipherSuites))
+	if err != nil {
+		return tc, err
+	}
+
+	tc.CurvePreferences, err = tlsCurveIDs(withDefaults(c.Curves, defaultTLSCurves))
+	if err != nil {
+		return tc, err
+	}
+
+	clientAuth, ok := tlsClientAuth[strings.ToLower(c.ClientAuth)]
+	if !ok {
+		return tc, fmt.Errorf("Invalid TLS client_auth: %v, expected one of: %v", c.ClientAuth, optionNames(tlsClientAuth))
+	}
+	tc.ClientAuth = clientAuth
+
+	if c.ClientCAFile != "" {
+		tc.ClientCAs, err = clientCAs(c.ClientCAFile)
+		if err != nil {
+			return tc, err
+		}
+	}
+
+	if tc.ClientCAs == nil && clientAuth >= tls.VerifyClientCertIfGiven {
+		return tc, fmt.Errorf("Invalid TLS client_auth: %v requires a client_ca_file", c.ClientAuth)
+	}
+
+	return tc, nil
+}
+
+// supportsHTTP2 returns false if the TLS config allows TLS 1.2 but none of the cipher suites HTTP/2 requires
+func supportsHTTP2(tc *tls.Config) bool {
+	if tc.MinVersion >= tls.VersionTLS13 {
+		return true
+	}
+
+	for _, suite := range tc.CipherSuites {
+		for _, required := range http2CipherSuites {
+			if suite == required {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+/* helpers */
+
+func clientCAs(file string) (*x509.CertPool, error) {
+	pem, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid TLS client_ca_file: %v", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("Invalid TLS client_ca_file: no certificates found in %v", file)
+	}
+	return pool, nil
+}
+
+// optionNames returns the sorted names of the options in a map for error messages
+func optionNames(m interface{}) string {
+	var result []string
+
+	switch m := m.(type) {
+	case map[string]uint16:
+		for name := range m {
+			result = append(result, name)
+		}
+	case map[string]tls.CurveID:
+		for name := range m {
+			result = append(result, name)
+		}
+	case map[string]tls.ClientAuthType:
+		for name := range m {
+			if name != "" {
+				result = append(result, name)
+			}
+		}
+	}
+
+	sort.Strings(result)
+	return strings.Join(result, ", ")
+}
+
+func tlsCipherSuiteIDs(suites []string) (ids []uint16, err error) {
+	var unknown []string
+
+	for _, suite := range suites {
+		id, ok := tlsCipherSuites[strings.ToUpper(strings.TrimSpace(suite))]
+		if !ok {
+			unknown = append(unknown, suite)
+			continue
+		}
+		ids = append(ids, id)
+	}
+
+	if len(unknown) > 0 {
+		err = fmt.Errorf(
+			"Invalid TLS cipher_suites: %v, expected any of: %v",
+			strings.Join(unknown, ", "),
+			optionNames(tlsCipherSuites))
+	}
+	return
+}
+
+func tlsCurveIDs(curves []string) (ids []tls.CurveID, err error) {
+	var unknown []string
+
+	for _, curve := range curves {
+		id, ok := tlsCurves[strings.ToUpper(strings.TrimSpace(curve))]
+		if !ok {
+			unknown = append(unknown, curve)
+			continue
+		}
+		ids = append(ids, id)
+	}
+
+	if len(unknown) > 0 {
+		err = fmt.Errorf("Invalid TLS curves: %v, expected any of: %v", strings.Join(unknown, ", "), optionNames(tlsCurves))
+	}
+	return
+}
+
+func tlsVersion(version string) (uint16, error) {
+	v, ok := tlsVersions[strings.TrimSpace(version)]
+	if !ok {
+		return 0, fmt.Errorf("unknown version %v, expected one of: %v", version, optionNames(tlsVersions))
+	}
+	return v, nil
+}
+
+func withDefault(value, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
+
+func withDefaults(values, defaultValues []string) []string {
+	if len(values) == 0 {
+		return defaultValues
+	}
+	return values
+}
@@ -0,0 +1,94 @@
+package http
+
+import (
+	"crypto/tls"
+	"strings"
+	"testing"
+
+	"github.com/pladdy/cabby"
+)
+
+func TestSetupTLSPolicy(t *testing.T) {
+	tests := []struct {
+		config      cabby.TLSConfig
+		minVersion  uint16
+		maxVersion  uint16
+		suites      int
+		curves      int
+		clientAuth  tls.ClientAuthType
+		expectedErr string
+	}{
+		{cabby.TLSConfig{}, tls.VersionTLS12, 0, len(defaultCipherSuites), len(defaultTLSCurves), tls.NoClientCert, ""},
+		{cabby.TLSConfig{MinVersion: "1.3", MaxVersion: "1.3"},
+			tls.VersionTLS13, tls.VersionTLS13, len(defaultCipherSuites), len(defaultTLSCurves), tls.NoClientCert, ""},
+		{cabby.TLSConfig{
+			CipherSuites: []string{"TLS_RSA_WITH_AES_128_GCM_SHA256"},
+			Curves:       []string{"x25519"},
+			ClientAuth:   "request"},
+			tls.VersionTLS12, 0, 1, 1, tls.RequestClientCert, ""},
+		{cabby.TLSConfig{MinVersion: "1.4"}, 0, 0, 0, 0, tls.NoClientCert, "Invalid TLS min_version"},
+		{cabby.TLSConfig{MaxVersion: "1.1"}, 0, 0, 0, 0, tls.NoClientCert, "less than min_version 1.2"},
+		{cabby.TLSConfig{CipherSuites: []string{"TLS_FAKE_SUITE"}},
+			0, 0, 0, 0, tls.NoClientCert, "Invalid TLS cipher_suites: TLS_FAKE_SUITE"},
+		{cabby.TLSConfig{Curves: []string{"P999"}}, 0, 0, 0, 0, tls.NoClientCert, "Invalid TLS curves: P999"},
+		{cabby.TLSConfig{ClientAuth: "sometimes"}, 0, 0, 0, 0, tls.NoClientCert, "Invalid TLS client_auth: sometimes"},
+		{cabby.TLSConfig{ClientAuth: "require_and_verify"}, 0, 0, 0, 0, tls.NoClientCert, "requires a client_ca_file"},
+		{cabby.TLSConfig{ClientAuth: "verify_if_given", ClientCAFile: "../server.crt"},
+			tls.VersionTLS12, 0, len(defaultCipherSuites), len(defaultTLSCurves), tls.VerifyClientCertIfGiven, ""},
+		{cabby.TLSConfig{ClientCAFile: "no_such_file.crt"}, 0, 0, 0, 0, tls.NoClientCert, "Invalid TLS client_ca_file"},
+	}
+
+	for _, test := range tests {
+		result, err := setupTLS(test.config)
+
+		if test.expectedErr != "" {
+			if err == nil || !strings.Contains(err.Error(), test.expectedErr) {
+				t.Error("Got:", err, "Expected:", test.expectedErr)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Error("Got:", err, "Expected no error", "Config:", test.config)
+			continue
+		}
+		if result.MinVersion != test.minVersion {
+			t.Error("Got:", result.MinVersion, "Expected:", test.minVersion)
+		}
+		if result.MaxVersion != test.maxVersion {
+			t.Error("Got:", result.MaxVersion, "Expected:", test.maxVersion)
+		}
+		if len(result.CipherSuites) != test.suites {
+			t.Error("Got:", len(result.CipherSuites), "Expected:", test.suites)
+		}
+		if len(result.CurvePreferences) != test.curves {
+			t.Error("Got:", len(result.CurvePreferences), "Expected:", test.curves)
+		}
+		if result.ClientAuth != test.clientAuth {
+			t.Error("Got:", result.ClientAuth, "Expected:", test.clientAuth)
+		}
+	}
+}
+
+func TestSupportsHTTP2(t *testing.T) {
+	tests := []struct {
+		config   cabby.TLSConfig
+		expected bool
+	}{
+		{cabby.TLSConfig{}, true},
+		{cabby.TLSConfig{CipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384"}}, false},
+		{cabby.TLSConfig{MinVersion: "1.3", CipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384"}}, true},
+	}
+
+	for _, test := range tests {
+		tc, err := setupTLS(test.config)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		result := supportsHTTP2(tc)
+		if result != test.expected {
+			t.Error("Got:", result, "Expected:", test.expected, "Config:", test.config)
+		}
+	}
+}
@@ -36,7 +36,13 @@ func (h VersionsHandler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	versions, err := h.VersionsService.Versions(r.Context(), takeCollectionID(r), takeObjectID(r), &p, newFilter(r))
+	f, err := newFilter(r)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+
+	versions, err := h.VersionsService.Versions(r.Context(), takeCollectionID(r), takeObjectID(r), &p, f)
 	if err != nil {
 		internalServerError(w, err)
 		return
@@ -49,7 +55,7 @@ func (h VersionsHandler) Get(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("X-TAXII-Date-Added-First", p.AddedAfterFirst())
 	w.Header().Set("X-TAXII-Date-Added-Last", p.AddedAfterLast())
-	writeContent(w, r, cabby.TaxiiContentType, resourceToJSON(versions))
+	writeContent(w, r, responseMediaType(r), resourceToJSON(versions))
 }
 
 // Post handler
@@ -0,0 +1,284 @@
+package cabby
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/pladdy/stones"
+)
+
+// cyberObservableVersion is the version of cyber observables without 'created' or 'modified'; their ids are derived from
+// their content, so every copy of one is the same version
+var cyberObservableVersion = stones.Timestamp{Time: time.Unix(0, 0).UTC()}
+
+// stix 2.1 cyber observables aren't versioned, so they don't need 'created' or 'modified'
+var cyberObservableTypes = map[string]bool{
+	"artifact":             true,
+	"autonomous-system":    true,
+	"directory":            true,
+	"domain-name":          true,
+	"email-addr":           true,
+	"email-message":        true,
+	"file":                 true,
+	"ipv4-addr":            true,
+	"ipv6-addr":            true,
+	"mac-addr":             true,
+	"mutex":                true,
+	"network-traffic":      true,
+	"process":              true,
+	"software":             true,
+	"url":                  true,
+	"user-account":         true,
+	"windows-registry-key": true,
+	"x509-certificate":     true,
+}
+
+// filterProperties are the object properties results can be filtered on with match[<property>]; true marks the list
+// properties, which match if any of their values does
+var filterProperties = map[string]bool{
+	"aliases":             true,
+	"created_by_ref":      false,
+	"identity_class":      false,
+	"indicator_types":     true,
+	"labels":              true,
+	"malware_types":       true,
+	"name":                false,
+	"object_marking_refs": true,
+	"object_refs":         true,
+	"pattern_type":        false,
+	"relationship_type":   false,
+	"report_types":        true,
+	"source_ref":          false,
+	"target_ref":          false,
+	"threat_actor_types":  true,
+	"tool_types":          true,
+}
+
+// observableValueTypes are the cyber observables whose 'value' is indexed
+var observableValueTypes = map[string]bool{
+	"domain-name": true,
+	"email-addr":  true,
+	"ipv4-addr":   true,
+	"ipv6-addr":   true,
+	"url":         true,
+}
+
+// patternComparisonRegex matches the equality comparisons of a pattern, IE: "file:hashes.'SHA-256' = '...'"; the
+// object type, the property path and the quoted value are captured
+var patternComparisonRegex = regexp.MustCompile(`([a-z0-9-]+):([A-Za-z0-9_.'-]+)\s*=\s*'((?:[^'\\]|\\.)*)'`)
+
+// stix 2.1 types are 3 to 250 lowercase letters, digits and hyphens
+var stixTypeRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,248}[a-z0-9]$`)
+
+// objectProperties are the properties used to validate and store an object of any spec version
+type objectProperties struct {
+	ID          string `json:"id"`
+	Type        string `json:"type"`
+	SpecVersion string `json:"spec_version"`
+	Created     string `json:"created"`
+	Modified    string `json:"modified"`
+}
+
+func (p objectProperties) specVersion() string {
+	if p.SpecVersion == "" {
+		return SpecVersion20
+	}
+	return p.SpecVersion
+}
+
+// ExtractObservables returns the observables of an object: the values an indicator's pattern compares to, the cyber
+// observables of observed data, and a STIX 2.1 cyber observable itself.  Objects that can't be read have none.
+func ExtractObservables(raw []byte) []Observable {
+	var o struct {
+		Type    string                            `json:"type"`
+		Pattern string                            `json:"pattern"`
+		Objects map[string]map[string]interface{} `json:"objects"`
+	}
+	if err := json.Unmarshal(raw, &o); err != nil {
+		return []Observable{}
+	}
+
+	var observables []Observable
+
+	switch o.Type {
+	case "indicator":
+		observables = patternObservables(o.Pattern)
+	case "observed-data":
+		for _, co := range o.Objects {
+			observables = append(observables, cyberObservables(co)...)
+		}
+	default:
+		var co map[string]interface{}
+		if err := json.Unmarshal(raw, &co); err == nil {
+			observables = cyberObservables(co)
+		}
+	}
+	return uniqueObservables(observables)
+}
+
+// FilterProperty returns whether results can be filtered on an object property and whether the property is a list
+func FilterProperty(name string) (list, ok bool) {
+	list, ok = filterProperties[name]
+	return
+}
+
+// ObjectFromBytes validates a raw STIX object and returns it with the spec version it's written in.  STIX 2.0 objects
+// are validated by stones.  STIX 2.1 objects without 'modified' are versioned by 'created', and cyber observables
+// without either are all the same version, the Unix epoch.
+func ObjectFromBytes(raw []byte) (o stones.Object, specVersion string, err error) {
+	var props objectProperties
+	err = json.Unmarshal(raw, &props)
+	if err != nil {
+		return
+	}
+	specVersion = props.specVersion()
+
+	switch specVersion {
+	case SpecVersion20:
+		o, err = object20(raw)
+	case SpecVersion21:
+		o, err = object21(raw, props)
+	default:
+		err = fmt.Errorf("Unsupported 'spec_version': %v", specVersion)
+	}
+	return
+}
+
+// ObjectSpecVersion returns the spec version of a raw STIX object; objects that can't be read are STIX 2.0
+func ObjectSpecVersion(raw []byte) string {
+	var props objectProperties
+	if err := json.Unmarshal(raw, &props); err != nil {
+		return SpecVersion20
+	}
+	return props.specVersion()
+}
+
+// SpecVersionMediaType returns the media type of objects written in a STIX spec version
+func SpecVersionMediaType(specVersion string) string {
+	if specVersion == SpecVersion20 {
+		return StixContentType20
+	}
+	return StixMediaType + ";version=" + specVersion
+}
+
+// cyberObservables returns the value of a cyber observable or the hashes of a file
+func cyberObservables(co map[string]interface{}) (observables []Observable) {
+	t, _ := co["type"].(string)
+
+	if value, ok := co["value"].(string); ok && observableValueTypes[t] {
+		observables = append(observables, Observable{Type: t, Value: value})
+	}
+
+	if hashes, ok := co["hashes"].(map[string]interface{}); ok && t == "file" {
+		for name, hash := range hashes {
+			if value, ok := hash.(string); ok {
+				observables = append(observables, Observable{Type: "file:hashes." + name, Value: value})
+			}
+		}
+	}
+	return
+}
+
+func object20(raw []byte) (o stones.Object, err error) {
+	err = json.Unmarshal(raw, &o)
+	if err != nil {
+		return
+	}
+
+	valid, errs := o.Valid()
+	if !valid {
+		err = stones.ErrorsToString(errs)
+	}
+	return
+}
+
+func object21(raw []byte, props objectProperties) (o stones.Object, err error) {
+	var errs []error
+	o.Type, o.Source = props.Type, raw
+
+	if !stixTypeRegex.MatchString(props.Type) {
+		errs = append(errs, fmt.Errorf("Invalid 'type': %v", props.Type))
+	}
+
+	o.ID, err = stones.IdentifierFromString(props.ID)
+	if err != nil || o.ID.Type != props.Type || o.ID.ID == uuid.Nil {
+		errs = append(errs, fmt.Errorf("Invalid 'id': %v", props.ID))
+	}
+
+	o.Created, err = optionalTimestamp(props.Created)
+	if err != nil || (o.Created.IsZero() && !cyberObservableTypes[props.Type]) {
+		errs = append(errs, fmt.Errorf("Invalid 'created': %v", props.Created))
+	}
+
+	// marking definitions are never modified
+	o.Modified, err = optionalTimestamp(props.Modified)
+	if err != nil || (o.Modified.IsZero() && !cyberObservableTypes[props.Type] && props.Type != "marking-definition") {
+		errs = append(errs, fmt.Errorf("Invalid 'modified': %v", props.Modified))
+	}
+
+	if !o.Modified.IsZero() && o.Modified.Before(o.Created.Time) {
+		errs = append(errs, errors.New("'modified' can't be before 'created'"))
+	}
+
+	if len(errs) > 0 {
+		return o, stones.ErrorsToString(errs)
+	}
+
+	if o.Created.IsZero() {
+		o.Created = cyberObservableVersion
+	}
+	if o.Modified.IsZero() {
+		o.Modified = o.Created
+	}
+	return o, nil
+}
+
+// patternObservables returns the values a pattern's equality comparisons use for indexed properties
+func patternObservables(pattern string) (observables []Observable) {
+	for _, m := range patternComparisonRegex.FindAllStringSubmatch(pattern, -1) {
+		t, path := m[1], strings.Replace(m[2], "'", "", -1)
+		value := strings.NewReplacer(`\'`, "'", `\\`, `\`).Replace(m[3])
+
+		switch {
+		case path == "value" && observableValueTypes[t]:
+			observables = append(observables, Observable{Type: t, Value: value})
+		case t == "file" && strings.HasPrefix(path, "hashes."):
+			observables = append(observables, Observable{Type: t + ":" + path, Value: value})
+		}
+	}
+	return
+}
+
+func optionalTimestamp(s string) (stones.Timestamp, error) {
+	if s == "" {
+		return stones.Timestamp{}, nil
+	}
+	return stones.TimestampFromString(s)
+}
+
+// uniqueObservables removes repeated observables; they're sorted so the same object always has the same list
+func uniqueObservables(observables []Observable) []Observable {
+	seen := map[Observable]bool{}
+	unique := []Observable{}
+
+	for _, o := range observables {
+		if !seen[o] {
+			seen[o] = true
+			unique = append(unique, o)
+		}
+	}
+
+	sort.Slice(unique, func(i, j int) bool {
+		if unique[i].Type != unique[j].Type {
+			return unique[i].Type < unique[j].Type
+		}
+		return unique[i].Value < unique[j].Value
+	})
+	return unique
+}
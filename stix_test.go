@@ -0,0 +1,174 @@
+package cabby
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestExtractObservables(t *testing.T) {
+	tests := []struct {
+		raw      string
+		expected []Observable
+	}{
+		{`{"type": "indicator", "pattern": "[ipv4-addr:value = '203.0.113.7' OR domain-name:value='Example.com']"}`,
+			[]Observable{{"domain-name", "Example.com"}, {"ipv4-addr", "203.0.113.7"}}},
+		{`{"type": "indicator",
+		   "pattern": "[file:hashes.'SHA-256' = 'aec07045' AND file:name = 'a.exe'] AND [url:value = 'http://x.com/it\\'s']"}`,
+			[]Observable{{"file:hashes.SHA-256", "aec07045"}, {"url", "http://x.com/it's"}}},
+		{`{"type": "indicator", "pattern": "[ipv4-addr:value ISSUBSET '198.51.100.0/24']"}`, []Observable{}},
+		{`{"type": "indicator", "pattern": "[email-addr:value = 'a@b.com'] FOLLOWEDBY [email-addr:value = 'a@b.com']"}`,
+			[]Observable{{"email-addr", "a@b.com"}}},
+		{`{"type": "observed-data", "objects": {
+		   "0": {"type": "ipv6-addr", "value": "2001:db8::1"},
+		   "1": {"type": "file", "name": "a.exe", "hashes": {"MD5": "3773a88f"}},
+		   "2": {"type": "process", "pid": 1}}}`,
+			[]Observable{{"file:hashes.MD5", "3773a88f"}, {"ipv6-addr", "2001:db8::1"}}},
+		{`{"type": "ipv4-addr", "spec_version": "2.1", "value": "198.51.100.3"}`,
+			[]Observable{{"ipv4-addr", "198.51.100.3"}}},
+		{`{"type": "malware", "name": "Poison Ivy"}`, []Observable{}},
+		{`not json`, []Observable{}},
+	}
+
+	for _, test := range tests {
+		result := ExtractObservables([]byte(test.raw))
+		if fmt.Sprint(result) != fmt.Sprint(test.expected) {
+			t.Error("Got:", result, "Expected:", test.expected, "Raw:", test.raw)
+		}
+	}
+}
+
+func TestFilterProperty(t *testing.T) {
+	tests := []struct {
+		name         string
+		expectedList bool
+		expectedOK   bool
+	}{
+		{"labels", true, true},
+		{"created_by_ref", false, true},
+		{"type", false, false},
+		{"labels.0", false, false},
+	}
+
+	for _, test := range tests {
+		list, ok := FilterProperty(test.name)
+		if list != test.expectedList || ok != test.expectedOK {
+			t.Error("Got:", list, ok, "Expected:", test.expectedList, test.expectedOK, "Name:", test.name)
+		}
+	}
+}
+
+func TestObjectFromBytes(t *testing.T) {
+	tests := []struct {
+		raw         string
+		specVersion string
+		expectError bool
+	}{
+		// stix 2.0
+		{`{"type": "malware", "id": "malware--31b940d4-6f7f-459a-80ea-9c1f17b5891b", "created": "2016-04-06T20:07:09.000Z",
+		  "modified": "2016-04-06T20:07:09.000Z"}`, SpecVersion20, false},
+		{`{"type": "malware", "id": "malware--31b940d4-6f7f-459a-80ea-9c1f17b5891b"}`, SpecVersion20, true},
+		{`{"type": "ipv4-addr", "id": "ipv4-addr--ff26c055-6336-5bc5-b98d-13d6226742dd", "value": "198.51.100.3"}`,
+			SpecVersion20, true},
+		// stix 2.1
+		{`{"type": "indicator", "spec_version": "2.1", "id": "indicator--e2e1a340-4415-4ba8-9671-f7343fbf0836",
+		  "created": "2017-01-27T13:49:53.935Z", "modified": "2017-01-27T13:49:53.935Z"}`, SpecVersion21, false},
+		{`{"type": "grouping", "spec_version": "2.1", "id": "grouping--84e4d88f-44ea-4bcd-bbf3-b2c1c320bcb3",
+		  "created": "2017-01-27T13:49:53.935Z", "modified": "2017-01-27T13:49:53.935Z",
+		  "granular_markings": [{"marking_ref": "marking-definition--f88d31f6-486f-44da-b317-01333bde0b82",
+		  "selectors": ["name"]}]}`, SpecVersion21, false},
+		{`{"type": "ipv4-addr", "spec_version": "2.1", "id": "ipv4-addr--ff26c055-6336-5bc5-b98d-13d6226742dd",
+		  "value": "198.51.100.3"}`, SpecVersion21, false},
+		{`{"type": "marking-definition", "spec_version": "2.1",
+		  "id": "marking-definition--34098fce-860f-48ae-8e50-ebd3cc5e41da", "created": "2017-01-20T00:00:00.000Z",
+		  "definition_type": "tlp", "definition": {"tlp": "green"}}`, SpecVersion21, false},
+		{`{"type": "indicator", "spec_version": "2.1", "id": "indicator--e2e1a340-4415-4ba8-9671-f7343fbf0836",
+		  "created": "2017-01-27T13:49:53.935Z"}`, SpecVersion21, true},
+		{`{"type": "indicator", "spec_version": "2.1", "id": "indicator--e2e1a340-4415-4ba8-9671-f7343fbf0836",
+		  "created": "2017-01-27T13:49:53.935Z", "modified": "2016-01-27T13:49:53.935Z"}`, SpecVersion21, true},
+		{`{"type": "indicator", "spec_version": "2.1", "id": "malware--e2e1a340-4415-4ba8-9671-f7343fbf0836",
+		  "created": "2017-01-27T13:49:53.935Z", "modified": "2017-01-27T13:49:53.935Z"}`, SpecVersion21, true},
+		{`{"type": "Indicator", "spec_version": "2.1", "id": "Indicator--e2e1a340-4415-4ba8-9671-f7343fbf0836",
+		  "created": "2017-01-27T13:49:53.935Z", "modified": "2017-01-27T13:49:53.935Z"}`, SpecVersion21, true},
+		{`{"type": "file", "spec_version": "2.1", "id": "file--00000000-0000-0000-0000-000000000000"}`, SpecVersion21, true},
+		// unsupported
+		{`{"type": "indicator", "spec_version": "2.2", "id": "indicator--e2e1a340-4415-4ba8-9671-f7343fbf0836"}`,
+			"2.2", true},
+		{`not json`, "", true},
+	}
+
+	for _, test := range tests {
+		o, specVersion, err := ObjectFromBytes([]byte(test.raw))
+
+		if specVersion != test.specVersion {
+			t.Error("Got:", specVersion, "Expected:", test.specVersion, "Object:", test.raw)
+		}
+		if (err != nil) != test.expectError {
+			t.Error("Got:", err, "Expected error:", test.expectError, "Object:", test.raw)
+		}
+		if err == nil && (o.Modified.IsZero() || string(o.Source) != test.raw) {
+			t.Error("Got:", o, "Expected a versioned object with its source")
+		}
+	}
+}
+
+func TestObjectFromBytesVersion(t *testing.T) {
+	tests := []struct {
+		raw      string
+		expected string
+	}{
+		{`{"type": "marking-definition", "spec_version": "2.1",
+		  "id": "marking-definition--34098fce-860f-48ae-8e50-ebd3cc5e41da", "created": "2017-01-20T00:00:00.000Z"}`,
+			"2017-01-20T00:00:00Z"},
+		{`{"type": "indicator", "spec_version": "2.1", "id": "indicator--e2e1a340-4415-4ba8-9671-f7343fbf0836",
+		  "created": "2017-01-27T13:49:53.935Z", "modified": "2017-01-28T13:49:53.935Z"}`, "2017-01-28T13:49:53.935Z"},
+		// reposting a cyber observable gives the same version
+		{`{"type": "ipv4-addr", "spec_version": "2.1", "id": "ipv4-addr--ff26c055-6336-5bc5-b98d-13d6226742dd",
+		  "value": "198.51.100.3"}`, "1970-01-01T00:00:00Z"},
+	}
+
+	for _, test := range tests {
+		o, _, err := ObjectFromBytes([]byte(test.raw))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if o.Modified.String() != test.expected {
+			t.Error("Got:", o.Modified.String(), "Expected:", test.expected)
+		}
+	}
+}
+
+func TestObjectSpecVersion(t *testing.T) {
+	tests := []struct {
+		raw      string
+		expected string
+	}{
+		{`{"type": "malware"}`, SpecVersion20},
+		{`{"type": "malware", "spec_version": "2.1"}`, SpecVersion21},
+		{``, SpecVersion20},
+	}
+
+	for _, test := range tests {
+		result := ObjectSpecVersion([]byte(test.raw))
+		if result != test.expected {
+			t.Error("Got:", result, "Expected:", test.expected)
+		}
+	}
+}
+
+func TestSpecVersionMediaType(t *testing.T) {
+	tests := []struct {
+		specVersion string
+		expected    string
+	}{
+		{SpecVersion20, StixContentType20},
+		{SpecVersion21, "application/stix+json;version=2.1"},
+	}
+
+	for _, test := range tests {
+		result := SpecVersionMediaType(test.specVersion)
+		if result != test.expected {
+			t.Error("Got:", result, "Expected:", test.expected)
+		}
+	}
+}
@@ -71,6 +71,8 @@ var (
 		Contact:     "cabby test",
 		Default:     BaseURL + "taxii/",
 		APIRoots:    []string{APIRootPath}}
+	// LastModified mock; when objects were last added to a collection
+	LastModified = objectCreated()
 	// Manifest mock
 	Manifest = cabby.Manifest{Objects: []cabby.ManifestEntry{ManifestEntry}}
 	// ManifestEntry mock
@@ -99,7 +101,8 @@ func collection() cabby.Collection {
 		Title:       "test collection",
 		Description: "collection for testing",
 		CanRead:     true,
-		CanWrite:    true}
+		CanWrite:    true,
+		MediaTypes:  []string{cabby.StixContentType20}}
 
 	var err error
 	c.ID, err = cabby.IDFromString(CollectionID)
@@ -116,7 +119,7 @@ func manifestEntry() cabby.ManifestEntry {
 		ID:         ObjectID,
 		DateAdded:  now,
 		Version:    objectCreated(),
-		MediaTypes: []string{cabby.StixContentType}}
+		MediaTypes: []string{cabby.StixContentType20}}
 }
 
 func newContext() context.Context {
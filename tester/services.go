@@ -2,6 +2,8 @@ package tester
 
 import (
 	"context"
+	"encoding/json"
+	"time"
 
 	"github.com/pladdy/cabby"
 	"github.com/pladdy/stones"
@@ -17,7 +19,11 @@ type DataStore struct {
 	ManifestServiceFn   func() ManifestService
 	MigrationServiceFn  func() MigrationService
 	ObjectServiceFn     func() ObjectService
+	ObservableServiceFn func() ObservableService
+	RetentionServiceFn  func() RetentionService
+	SearchServiceFn     func() SearchService
 	StatusServiceFn     func() StatusService
+	TrashServiceFn      func() TrashService
 	UserServiceFn       func() UserService
 	VersionsServiceFn   func() VersionsService
 }
@@ -62,16 +68,36 @@ func (s DataStore) ObjectService() cabby.ObjectService {
 	return s.ObjectServiceFn()
 }
 
+// ObservableService mock
+func (s DataStore) ObservableService() cabby.ObservableService {
+	return s.ObservableServiceFn()
+}
+
 // Open mock
 func (s DataStore) Open() error {
 	return nil
 }
 
+// RetentionService mock
+func (s DataStore) RetentionService() cabby.RetentionService {
+	return s.RetentionServiceFn()
+}
+
+// SearchService mock
+func (s DataStore) SearchService() cabby.SearchService {
+	return s.SearchServiceFn()
+}
+
 // StatusService mock
 func (s DataStore) StatusService() cabby.StatusService {
 	return s.StatusServiceFn()
 }
 
+// TrashService mock
+func (s DataStore) TrashService() cabby.TrashService {
+	return s.TrashServiceFn()
+}
+
 // UserService mock
 func (s DataStore) UserService() cabby.UserService {
 	return s.UserServiceFn()
@@ -86,11 +112,12 @@ func (s DataStore) VersionsService() cabby.VersionsService {
 
 // APIRootService is a mock implementation
 type APIRootService struct {
-	APIRootFn       func(ctx context.Context, path string) (cabby.APIRoot, error)
-	APIRootsFn      func(ctx context.Context) ([]cabby.APIRoot, error)
-	CreateAPIRootFn func(ctx context.Context, ca cabby.APIRoot) error
-	DeleteAPIRootFn func(ctx context.Context, path string) error
-	UpdateAPIRootFn func(ctx context.Context, a cabby.APIRoot) error
+	APIRootFn           func(ctx context.Context, path string) (cabby.APIRoot, error)
+	APIRootDependentsFn func(ctx context.Context, path string) (cabby.Dependents, error)
+	APIRootsFn          func(ctx context.Context) ([]cabby.APIRoot, error)
+	CreateAPIRootFn     func(ctx context.Context, ca cabby.APIRoot) error
+	DeleteAPIRootFn     func(ctx context.Context, path string, cascade bool) error
+	UpdateAPIRootFn     func(ctx context.Context, a cabby.APIRoot) error
 }
 
 // APIRoot is a mock implementation
@@ -98,6 +125,11 @@ func (s APIRootService) APIRoot(ctx context.Context, path string) (cabby.APIRoot
 	return s.APIRootFn(ctx, path)
 }
 
+// APIRootDependents is a mock implementation
+func (s APIRootService) APIRootDependents(ctx context.Context, path string) (cabby.Dependents, error) {
+	return s.APIRootDependentsFn(ctx, path)
+}
+
 // APIRoots is a mock implementation
 func (s APIRootService) APIRoots(ctx context.Context) ([]cabby.APIRoot, error) {
 	return s.APIRootsFn(ctx)
@@ -109,8 +141,8 @@ func (s APIRootService) CreateAPIRoot(ctx context.Context, a cabby.APIRoot) erro
 }
 
 // DeleteAPIRoot is a mock implementation
-func (s APIRootService) DeleteAPIRoot(ctx context.Context, path string) error {
-	return s.DeleteAPIRootFn(ctx, path)
+func (s APIRootService) DeleteAPIRoot(ctx context.Context, path string, cascade bool) error {
+	return s.DeleteAPIRootFn(ctx, path, cascade)
 }
 
 // UpdateAPIRoot is a mock implementation
@@ -120,12 +152,14 @@ func (s APIRootService) UpdateAPIRoot(ctx context.Context, a cabby.APIRoot) erro
 
 // CollectionService is a mock implementation
 type CollectionService struct {
-	CollectionFn           func(ctx context.Context, collectionID, apiRootPath string) (cabby.Collection, error)
-	CollectionsFn          func(ctx context.Context, apiRootPath string, p *cabby.Page) (cabby.Collections, error)
-	CollectionsInAPIRootFn func(ctx context.Context, apiRootPath string) (cabby.CollectionsInAPIRoot, error)
-	CreateCollectionFn     func(ctx context.Context, c cabby.Collection) error
-	DeleteCollectionFn     func(ctx context.Context, collectionID string) error
-	UpdateCollectionFn     func(ctx context.Context, c cabby.Collection) error
+	CollectionFn             func(ctx context.Context, collectionID, apiRootPath string) (cabby.Collection, error)
+	CollectionDependentsFn   func(ctx context.Context, collectionID string) (cabby.Dependents, error)
+	CollectionsFn            func(ctx context.Context, apiRootPath string, p *cabby.Page) (cabby.Collections, error)
+	CollectionsInAPIRootFn   func(ctx context.Context, apiRootPath string) (cabby.CollectionsInAPIRoot, error)
+	CollectionLastModifiedFn func(ctx context.Context, collectionID string) (stones.Timestamp, error)
+	CreateCollectionFn       func(ctx context.Context, c cabby.Collection) error
+	DeleteCollectionFn       func(ctx context.Context, collectionID string, cascade bool) error
+	UpdateCollectionFn       func(ctx context.Context, c cabby.Collection, fields ...string) error
 }
 
 // Collection is a mock implementation
@@ -133,6 +167,11 @@ func (s CollectionService) Collection(ctx context.Context, collectionID, apiRoot
 	return s.CollectionFn(ctx, collectionID, apiRootPath)
 }
 
+// CollectionDependents is a mock implementation
+func (s CollectionService) CollectionDependents(ctx context.Context, collectionID string) (cabby.Dependents, error) {
+	return s.CollectionDependentsFn(ctx, collectionID)
+}
+
 // Collections is a mock implementation
 func (s CollectionService) Collections(ctx context.Context, apiRootPath string, p *cabby.Page) (cabby.Collections, error) {
 	return s.CollectionsFn(ctx, apiRootPath, p)
@@ -143,26 +182,31 @@ func (s CollectionService) CollectionsInAPIRoot(ctx context.Context, apiRootPath
 	return s.CollectionsInAPIRootFn(ctx, apiRootPath)
 }
 
+// CollectionLastModified is a mock implementation
+func (s CollectionService) CollectionLastModified(ctx context.Context, collectionID string) (stones.Timestamp, error) {
+	return s.CollectionLastModifiedFn(ctx, collectionID)
+}
+
 // CreateCollection is a mock implementation
 func (s CollectionService) CreateCollection(ctx context.Context, c cabby.Collection) error {
 	return s.CreateCollectionFn(ctx, c)
 }
 
 // DeleteCollection is a mock implementation
-func (s CollectionService) DeleteCollection(ctx context.Context, id string) error {
-	return s.DeleteCollectionFn(ctx, id)
+func (s CollectionService) DeleteCollection(ctx context.Context, id string, cascade bool) error {
+	return s.DeleteCollectionFn(ctx, id, cascade)
 }
 
 // UpdateCollection is a mock implementation
-func (s CollectionService) UpdateCollection(ctx context.Context, c cabby.Collection) error {
-	return s.UpdateCollectionFn(ctx, c)
+func (s CollectionService) UpdateCollection(ctx context.Context, c cabby.Collection, fields ...string) error {
+	return s.UpdateCollectionFn(ctx, c, fields...)
 }
 
 // DiscoveryService is a mock implementation
 type DiscoveryService struct {
 	CreateDiscoveryFn func(ctx context.Context, d cabby.Discovery) error
 	DeleteDiscoveryFn func(ctx context.Context) error
-	DiscoveryFn       func(ctx context.Context) (cabby.Discovery, error)
+	DiscoveryFn       func(ctx context.Context, u cabby.User) (cabby.Discovery, error)
 	UpdateDiscoveryFn func(ctx context.Context, d cabby.Discovery) error
 }
 
@@ -177,8 +221,8 @@ func (s DiscoveryService) DeleteDiscovery(ctx context.Context) error {
 }
 
 // Discovery is a mock implementation
-func (s DiscoveryService) Discovery(ctx context.Context) (cabby.Discovery, error) {
-	return s.DiscoveryFn(ctx)
+func (s DiscoveryService) Discovery(ctx context.Context, u cabby.User) (cabby.Discovery, error) {
+	return s.DiscoveryFn(ctx, u)
 }
 
 // UpdateDiscovery is a mock implementation
@@ -214,12 +258,14 @@ func (s MigrationService) Up() error {
 
 // ObjectService is a mock implementation
 type ObjectService struct {
-	MaxContentLength int64
-	CreateEnvelopeFn func(ctx context.Context, e cabby.Envelope, collectionID string, s cabby.Status, ss cabby.StatusService)
-	CreateObjectFn   func(ctx context.Context, collectionID string, object stones.Object) error
-	DeleteObjectFn   func(ctx context.Context, collectionID, objectID string) error
-	ObjectFn         func(ctx context.Context, collectionID, objectID string, f cabby.Filter) ([]stones.Object, error)
-	ObjectsFn        func(ctx context.Context, collectionID string, p *cabby.Page, f cabby.Filter) ([]stones.Object, error)
+	MaxContentLength  int64
+	CreateEnvelopeFn  func(ctx context.Context, e cabby.Envelope, collectionID string, s cabby.Status, ss cabby.StatusService)
+	CreateObjectFn    func(ctx context.Context, collectionID string, object stones.Object) error
+	CreateObjectsFn   func(ctx context.Context, objects <-chan json.RawMessage, collectionID string, s cabby.Status, ss cabby.StatusService)
+	DeleteObjectFn    func(ctx context.Context, collectionID, objectID string, f cabby.Filter) error
+	ObjectFn          func(ctx context.Context, collectionID, objectID string, f cabby.Filter) ([]stones.Object, error)
+	ObjectsFn         func(ctx context.Context, collectionID string, p *cabby.Page, f cabby.Filter) ([]stones.Object, error)
+	ValidateObjectsFn func(ctx context.Context, objects <-chan json.RawMessage, collectionID string) cabby.ValidationReport
 }
 
 // CreateEnvelope is a mock implementation
@@ -232,9 +278,14 @@ func (s ObjectService) CreateObject(ctx context.Context, collectionID string, ob
 	return s.CreateObjectFn(ctx, collectionID, object)
 }
 
+// CreateObjects is a mock implementation
+func (s ObjectService) CreateObjects(ctx context.Context, objects <-chan json.RawMessage, collectionID string, st cabby.Status, ss cabby.StatusService) {
+	s.CreateObjectsFn(ctx, objects, collectionID, st, ss)
+}
+
 // DeleteObject is a mock implementation
-func (s ObjectService) DeleteObject(ctx context.Context, collectionID, objectID string) error {
-	return s.DeleteObjectFn(ctx, collectionID, objectID)
+func (s ObjectService) DeleteObject(ctx context.Context, collectionID, objectID string, f cabby.Filter) error {
+	return s.DeleteObjectFn(ctx, collectionID, objectID, f)
 }
 
 // Object is a mock implementation
@@ -247,11 +298,62 @@ func (s ObjectService) Objects(ctx context.Context, collectionID string, p *cabb
 	return s.ObjectsFn(ctx, collectionID, p, f)
 }
 
+// ValidateObjects is a mock implementation
+func (s ObjectService) ValidateObjects(ctx context.Context, objects <-chan json.RawMessage, collectionID string) cabby.ValidationReport {
+	return s.ValidateObjectsFn(ctx, objects, collectionID)
+}
+
+// ObservableService is a mock implementation
+type ObservableService struct {
+	IndexObservablesFn  func(ctx context.Context) (int64, error)
+	ObservableMatchesFn func(ctx context.Context, values []string, p *cabby.Page) (cabby.ObservableMatches, error)
+}
+
+// IndexObservables is a mock implementation
+func (s ObservableService) IndexObservables(ctx context.Context) (int64, error) {
+	return s.IndexObservablesFn(ctx)
+}
+
+// ObservableMatches is a mock implementation
+func (s ObservableService) ObservableMatches(ctx context.Context, values []string, p *cabby.Page) (cabby.ObservableMatches, error) {
+	return s.ObservableMatchesFn(ctx, values, p)
+}
+
+// RetentionService is a mock implementation
+type RetentionService struct {
+	EnforceRetentionFn func(ctx context.Context, dryRun bool) ([]cabby.Retention, error)
+}
+
+// EnforceRetention is a mock implementation
+func (s RetentionService) EnforceRetention(ctx context.Context, dryRun bool) ([]cabby.Retention, error) {
+	return s.EnforceRetentionFn(ctx, dryRun)
+}
+
+// SearchService is a mock implementation
+type SearchService struct {
+	SearchFn func(ctx context.Context, apiRoot, query string, p *cabby.Page) (cabby.SearchHits, error)
+}
+
+// Search is a mock implementation
+func (s SearchService) Search(ctx context.Context, apiRoot, query string, p *cabby.Page) (cabby.SearchHits, error) {
+	return s.SearchFn(ctx, apiRoot, query, p)
+}
+
 // StatusService is a mock implementation
 type StatusService struct {
-	CreateStatusFn func(ctx context.Context, status cabby.Status) error
-	StatusFn       func(ctx context.Context, statusID string) (cabby.Status, error)
-	UpdateStatusFn func(ctx context.Context, status cabby.Status) error
+	CreateIdempotencyKeyFn func(ctx context.Context, collectionID, key, statusID string) error
+	CreateStatusFn         func(ctx context.Context, status cabby.Status) error
+	DeleteIdempotencyKeyFn func(ctx context.Context, collectionID, key string) error
+	ExpireStatusesFn       func(ctx context.Context, createdBefore time.Time) (int64, error)
+	IdempotentStatusFn     func(ctx context.Context, collectionID, key string) (cabby.Status, error)
+	StatusFn               func(ctx context.Context, apiRoot, statusID string) (cabby.Status, error)
+	StatusesFn             func(ctx context.Context, apiRoot string, p *cabby.Page) (cabby.Statuses, error)
+	UpdateStatusFn         func(ctx context.Context, status cabby.Status) error
+}
+
+// CreateIdempotencyKey is a mock implementation
+func (s StatusService) CreateIdempotencyKey(ctx context.Context, collectionID, key, statusID string) error {
+	return s.CreateIdempotencyKeyFn(ctx, collectionID, key, statusID)
 }
 
 // CreateStatus is a mock implementation
@@ -259,9 +361,29 @@ func (s StatusService) CreateStatus(ctx context.Context, status cabby.Status) er
 	return s.CreateStatusFn(ctx, status)
 }
 
+// DeleteIdempotencyKey is a mock implementation
+func (s StatusService) DeleteIdempotencyKey(ctx context.Context, collectionID, key string) error {
+	return s.DeleteIdempotencyKeyFn(ctx, collectionID, key)
+}
+
+// ExpireStatuses is a mock implementation
+func (s StatusService) ExpireStatuses(ctx context.Context, createdBefore time.Time) (int64, error) {
+	return s.ExpireStatusesFn(ctx, createdBefore)
+}
+
+// IdempotentStatus is a mock implementation
+func (s StatusService) IdempotentStatus(ctx context.Context, collectionID, key string) (cabby.Status, error) {
+	return s.IdempotentStatusFn(ctx, collectionID, key)
+}
+
 // Status is a mock implementation
-func (s StatusService) Status(ctx context.Context, statusID string) (cabby.Status, error) {
-	return s.StatusFn(ctx, statusID)
+func (s StatusService) Status(ctx context.Context, apiRoot, statusID string) (cabby.Status, error) {
+	return s.StatusFn(ctx, apiRoot, statusID)
+}
+
+// Statuses is a mock implementation
+func (s StatusService) Statuses(ctx context.Context, apiRoot string, p *cabby.Page) (cabby.Statuses, error) {
+	return s.StatusesFn(ctx, apiRoot, p)
 }
 
 // UpdateStatus is a mock implementation
@@ -269,8 +391,43 @@ func (s StatusService) UpdateStatus(ctx context.Context, status cabby.Status) er
 	return s.UpdateStatusFn(ctx, status)
 }
 
+// TrashService is a mock implementation
+type TrashService struct {
+	DeletedObjectsFn    func(ctx context.Context, collectionID string) ([]cabby.DeletedObject, error)
+	PurgeFn             func(ctx context.Context, deletedBefore time.Time) (cabby.Purged, error)
+	RestoreAPIRootFn    func(ctx context.Context, path string) error
+	RestoreCollectionFn func(ctx context.Context, collectionID string) error
+	RestoreObjectFn     func(ctx context.Context, collectionID, objectID string) error
+}
+
+// DeletedObjects is a mock implementation
+func (s TrashService) DeletedObjects(ctx context.Context, collectionID string) ([]cabby.DeletedObject, error) {
+	return s.DeletedObjectsFn(ctx, collectionID)
+}
+
+// Purge is a mock implementation
+func (s TrashService) Purge(ctx context.Context, deletedBefore time.Time) (cabby.Purged, error) {
+	return s.PurgeFn(ctx, deletedBefore)
+}
+
+// RestoreAPIRoot is a mock implementation
+func (s TrashService) RestoreAPIRoot(ctx context.Context, path string) error {
+	return s.RestoreAPIRootFn(ctx, path)
+}
+
+// RestoreCollection is a mock implementation
+func (s TrashService) RestoreCollection(ctx context.Context, collectionID string) error {
+	return s.RestoreCollectionFn(ctx, collectionID)
+}
+
+// RestoreObject is a mock implementation
+func (s TrashService) RestoreObject(ctx context.Context, collectionID, objectID string) error {
+	return s.RestoreObjectFn(ctx, collectionID, objectID)
+}
+
 // UserService is a mock implementation
 type UserService struct {
+	AnonymousCollectionsFn func(ctx context.Context) (cabby.UserCollectionList, error)
 	CreateUserFn           func(ctx context.Context, u cabby.User, password string) error
 	DeleteUserFn           func(ctx context.Context, u string) error
 	UpdateUserFn           func(ctx context.Context, u cabby.User) error
@@ -281,6 +438,11 @@ type UserService struct {
 	UserCollectionsFn      func(ctx context.Context, user string) (cabby.UserCollectionList, error)
 }
 
+// AnonymousCollections is a mock implementation
+func (s UserService) AnonymousCollections(ctx context.Context) (cabby.UserCollectionList, error) {
+	return s.AnonymousCollectionsFn(ctx)
+}
+
 // CreateUser is a mock implementation
 func (s UserService) CreateUser(ctx context.Context, user cabby.User, password string) error {
 	return s.CreateUserFn(ctx, user, password)